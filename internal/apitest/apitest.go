@@ -0,0 +1,245 @@
+// Package apitest runs the whole API in process for end-to-end tests: the
+// handler, policy and service stack over a fresh storage, loaded with YAML
+// fixtures and called through real HTTP requests.
+package apitest
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+	"travel/internal/auth"
+	"travel/internal/config"
+	"travel/internal/database"
+	"travel/internal/handler"
+	"travel/internal/logging"
+	"travel/internal/payment"
+	"travel/internal/policy"
+	"travel/internal/quote"
+	"travel/internal/service"
+	"travel/internal/storage"
+	"travel/internal/storage/memory"
+	"travel/internal/storage/storagetest"
+	"travel/internal/tenant"
+	"travel/internal/tracing"
+	"travel/migrations"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/gorilla/mux"
+)
+
+// DriverEnv picks the storage the server runs on: "memory", the default, or
+// "sqlite3" for a migrated database in a temporary file.
+const DriverEnv = "TRAVEL_E2E_DRIVER"
+
+// DefaultTenant is the agency every storage starts with, the one tokens of
+// Token belong to.
+const DefaultTenant = 1
+
+// MaxBodyBytes caps request bodies like the default server configuration.
+const MaxBodyBytes = 1 << 20
+
+// secret signs the tokens of Token and TokenFor.
+var secret = []byte("apitest")
+
+// QuoteSecret signs the quotes of the server, tests sign quotes the API would
+// not make with it.
+var QuoteSecret = []byte("apitest quotes")
+
+// Server is the API served on a local port for the duration of a test.
+// Payments go through Gateway, its checkout page is served under
+// /fake-gateway.
+type Server struct {
+	URL     string
+	Storage storagetest.Storage
+	Gateway *payment.Fake
+
+	service *service.Service
+	refs    map[string]int
+}
+
+// New starts a server on an empty storage and loads the fixture files into it
+// in order. The server is stopped when the test ends.
+func New(t *testing.T, fixtures ...string) *Server {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := newStorage(t, logger)
+	svc := service.New(store, logger)
+	gateway := payment.NewFake(secret)
+	svc.SetPayments(config.GatewayFake, gateway, payment.DefaultSchedule, payment.DefaultIntentTTL)
+	svc.SetQuotes(quote.NewSigner(QuoteSecret, quote.DefaultTTL))
+
+	authenticator := auth.NewAuthenticator(svc, &auth.JWTVerifier{HMACSecret: secret})
+	services := tracing.NewService(policy.New(svc, store))
+	options := handler.Options{
+		Exports:      true,
+		MaxBodyBytes: MaxBodyBytes,
+		Middleware:   []mux.MiddlewareFunc{logging.RequestIDMiddleware},
+		Logger:       logger,
+	}
+	webhooks := handler.NewWebhooks(services, options)
+
+	router := http.NewServeMux()
+	router.Handle("/webhooks/", webhooks)
+	router.Handle("/fake-gateway/", http.StripPrefix("/fake-gateway", gateway.Handler(webhooks)))
+	router.Handle("/", handler.New(services, authenticator, tenant.NewResolver(store, ""), options))
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	s := &Server{
+		URL:     srv.URL,
+		Storage: store,
+		Gateway: gateway,
+		service: svc,
+		refs:    map[string]int{"default": DefaultTenant},
+	}
+
+	for _, path := range fixtures {
+		s.Load(t, path)
+	}
+
+	return s
+}
+
+func newStorage(t *testing.T, logger *slog.Logger) storagetest.Storage {
+	switch driver := os.Getenv(DriverEnv); driver {
+	case "", config.DriverMemory:
+		return memory.New()
+	case storage.DialectSQLite:
+		return storage.New(openSQLite(t), storage.DialectSQLite, logger)
+	default:
+		t.Fatalf("%s: unsupported driver %q", DriverEnv, driver)
+		return nil
+	}
+}
+
+// openSQLite returns a migrated database in a file of its own.
+func openSQLite(t *testing.T) *sql.DB {
+	cfg := config.Database{Driver: storage.DialectSQLite, Path: filepath.Join(t.TempDir(), "travel.db")}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	src, err := iofs.New(migrations.FS, cfg.Driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := database.NewMigrate(db, cfg, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// Token returns a staff JWT with role for the default agency.
+func (s *Server) Token(t *testing.T, role string) string {
+	return s.TokenFor(t, auth.Claims{Subject: role, Name: role, Role: role, TenantID: DefaultTenant})
+}
+
+// TokenFor returns a JWT with claims, valid for an hour unless claims say
+// otherwise.
+func (s *Server) TokenFor(t *testing.T, claims auth.Claims) string {
+	t.Helper()
+
+	if claims.ExpiresAt == 0 {
+		claims.ExpiresAt = time.Now().Add(time.Hour).Unix()
+	}
+
+	token, err := auth.SignHS256(claims, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// ID returns the id the fixture named ref was stored with.
+func (s *Server) ID(t *testing.T, ref string) int {
+	t.Helper()
+
+	id, ok := s.refs[ref]
+	if !ok {
+		t.Fatalf("no fixture %q", ref)
+	}
+
+	return id
+}
+
+// refPattern matches the {ref} placeholders of paths, bodies and expected
+// responses.
+var refPattern = regexp.MustCompile(`\{([A-Za-z][\w-]*)\}`)
+
+// Expand replaces every {ref} in s with the id of the fixture named ref.
+func (s *Server) Expand(t *testing.T, text string) string {
+	t.Helper()
+
+	return refPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return fmt.Sprint(s.ID(t, match[1:len(match)-1]))
+	})
+}
+
+// Request is one call of the API. Path and Body may hold {ref} placeholders.
+type Request struct {
+	Method string
+	Path   string
+	Body   string
+	// Token is sent as a bearer token, requests without one are anonymous.
+	Token  string
+	Header map[string]string
+}
+
+// Do sends req and reads the whole response.
+func (s *Server) Do(t *testing.T, req Request) *Response {
+	t.Helper()
+
+	var body io.Reader
+	if req.Body != "" {
+		body = bytes.NewBufferString(s.Expand(t, req.Body))
+	}
+
+	r, err := http.NewRequest(req.Method, s.URL+s.Expand(t, req.Path), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if req.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+req.Token)
+	}
+	for name, value := range req.Header {
+		r.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data, server: s}
+}
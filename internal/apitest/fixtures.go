@@ -0,0 +1,436 @@
+package apitest
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+	"travel/internal/auth"
+	"travel/internal/money"
+	"travel/internal/pricing"
+	"travel/internal/promo"
+	"travel/internal/service"
+	"travel/internal/storage"
+	"travel/internal/tenant"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures is the content of a fixture file. Every record has a ref, a name
+// other records, request paths, bodies and expected responses use to refer
+// to it as {ref}, except exchange rates, which are named by their currency.
+// Records belong to the agency named by their agency ref, the default agency
+// when left out.
+//
+//	agencies:
+//	  - {ref: sunny, slug: sunny, name: Sunny Travel, address: "Vitosha 1\nSofia", email: office@sunny.example, taxNumber: BG123456789}
+//	locations:
+//	  - {ref: sofia, street: Vitosha, number: "1", city: Sofia, country: Bulgaria}
+//	pricingRuleSets:
+//	  - ref: winter
+//	    name: Winter
+//	    rules: [{name: Christmas, kind: season, percent: 25, from: 12-20, to: 01-06}]
+//	exchangeRates:
+//	  - {currency: GBP, rate: 0.8541, date: 2030-01-02}
+//	holidays:
+//	  - {ref: ski, title: Ski week, location: sofia, startDate: 2030-01-10, duration: 7, price: 650, freeSlots: 10, pricingRuleSet: winter}
+//	customers:
+//	  - {ref: maria, name: Maria, phoneNumber: "0888 123 456", email: maria@example.com}
+//	promoCodes:
+//	  - {ref: winter10, code: WINTER10, percent: 10, validTo: 2030-03-01, maxPerCustomer: 1, holidays: [ski]}
+//	reservations:
+//	  - {ref: maria-ski, holiday: ski, customer: maria, contactName: Maria, phoneNumber: "0888 123 456", promoCode: WINTER10}
+type Fixtures struct {
+	Agencies        []AgencyFixture         `yaml:"agencies"`
+	Locations       []LocationFixture       `yaml:"locations"`
+	PricingRuleSets []PricingRuleSetFixture `yaml:"pricingRuleSets"`
+	ExchangeRates   []ExchangeRateFixture   `yaml:"exchangeRates"`
+	Holidays        []HolidayFixture        `yaml:"holidays"`
+	Customers       []CustomerFixture       `yaml:"customers"`
+	PromoCodes      []PromoCodeFixture      `yaml:"promoCodes"`
+	Reservations    []ReservationFixture    `yaml:"reservations"`
+}
+
+type AgencyFixture struct {
+	Ref       string `yaml:"ref"`
+	Slug      string `yaml:"slug"`
+	Name      string `yaml:"name"`
+	Address   string `yaml:"address"`
+	Email     string `yaml:"email"`
+	TaxNumber string `yaml:"taxNumber"`
+}
+
+type LocationFixture struct {
+	Ref     string `yaml:"ref"`
+	Agency  string `yaml:"agency"`
+	Street  string `yaml:"street"`
+	Number  string `yaml:"number"`
+	City    string `yaml:"city"`
+	Country string `yaml:"country"`
+}
+
+type PricingRuleSetFixture struct {
+	Ref    string               `yaml:"ref"`
+	Agency string               `yaml:"agency"`
+	Name   string               `yaml:"name"`
+	Rules  []PricingRuleFixture `yaml:"rules"`
+}
+
+// PricingRuleFixture has the fields of pricing.Rule.
+type PricingRuleFixture struct {
+	Name      string   `yaml:"name"`
+	Kind      string   `yaml:"kind"`
+	Percent   float64  `yaml:"percent"`
+	From      string   `yaml:"from"`
+	To        string   `yaml:"to"`
+	Weekdays  []string `yaml:"weekdays"`
+	Days      int      `yaml:"days"`
+	FreeSlots int      `yaml:"freeSlots"`
+	Age       int      `yaml:"age"`
+	// Amount is a decimal amount, 25.50, of Currency, the default currency
+	// of the service when left out.
+	Amount    string `yaml:"amount"`
+	Currency  string `yaml:"currency"`
+	PerPerson bool   `yaml:"perPerson"`
+}
+
+type ExchangeRateFixture struct {
+	Agency   string  `yaml:"agency"`
+	Currency string  `yaml:"currency"`
+	Rate     float64 `yaml:"rate"`
+	// Date is a date, 2030-01-02, today when left out.
+	Date string `yaml:"date"`
+}
+
+type HolidayFixture struct {
+	Ref    string `yaml:"ref"`
+	Agency string `yaml:"agency"`
+	Title  string `yaml:"title"`
+	// Location is the ref of a location.
+	Location string `yaml:"location"`
+	// StartDate is a date, 2030-01-10.
+	StartDate string `yaml:"startDate"`
+	Duration  int    `yaml:"duration"`
+	// Price is a decimal amount, 900.50, of Currency, the default currency
+	// of the service when left out.
+	Price     string `yaml:"price"`
+	Currency  string `yaml:"currency"`
+	FreeSlots int    `yaml:"freeSlots"`
+	// PricingRuleSet is the ref of a pricing rule set, if any.
+	PricingRuleSet string `yaml:"pricingRuleSet"`
+}
+
+type CustomerFixture struct {
+	Ref         string `yaml:"ref"`
+	Agency      string `yaml:"agency"`
+	Name        string `yaml:"name"`
+	PhoneNumber string `yaml:"phoneNumber"`
+	Email       string `yaml:"email"`
+}
+
+// PromoCodeFixture has the fields of promo.Code.
+type PromoCodeFixture struct {
+	Ref         string  `yaml:"ref"`
+	Agency      string  `yaml:"agency"`
+	Code        string  `yaml:"code"`
+	Description string  `yaml:"description"`
+	Percent     float64 `yaml:"percent"`
+	// Amount and MinSpend are decimal amounts, 50.00, of Currency, the
+	// default currency of the service when left out.
+	Amount   string `yaml:"amount"`
+	MinSpend string `yaml:"minSpend"`
+	Currency string `yaml:"currency"`
+	// ValidFrom and ValidTo are dates, 2030-03-01.
+	ValidFrom      string `yaml:"validFrom"`
+	ValidTo        string `yaml:"validTo"`
+	MaxRedemptions int    `yaml:"maxRedemptions"`
+	MaxPerCustomer int    `yaml:"maxPerCustomer"`
+	// Holidays and Locations are refs of holidays and locations.
+	Holidays  []string `yaml:"holidays"`
+	Locations []string `yaml:"locations"`
+}
+
+type ReservationFixture struct {
+	Ref    string `yaml:"ref"`
+	Agency string `yaml:"agency"`
+	// Holiday is the ref of a holiday.
+	Holiday string `yaml:"holiday"`
+	// Customer is the ref of a customer. Without it the customer is matched
+	// or created from the contact details, as for reservations made through
+	// the API.
+	Customer    string `yaml:"customer"`
+	ContactName string `yaml:"contactName"`
+	PhoneNumber string `yaml:"phoneNumber"`
+	Email       string `yaml:"email"`
+	// PromoCode is a promo code as entered by a customer, not a ref.
+	PromoCode string `yaml:"promoCode"`
+}
+
+// fixturePrincipal is the actor of the audit entries of fixtures.
+var fixturePrincipal = &auth.Principal{Type: auth.PrincipalUser, ID: "fixtures", Name: "fixtures"}
+
+// Load stores the records of the fixture file at path. They go through the
+// service like records created through the API, so they are validated,
+// normalized and audited the same way. Refs have to be unique across every
+// loaded file.
+func (s *Server) Load(t *testing.T, path string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fixtures Fixtures
+	if err := yaml.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+
+	for _, agency := range fixtures.Agencies {
+		id, err := s.Storage.InsertAgency(context.Background(), &storage.Agency{
+			Slug:      agency.Slug,
+			Name:      agency.Name,
+			Address:   agency.Address,
+			Email:     agency.Email,
+			TaxNumber: agency.TaxNumber,
+		})
+		if err != nil {
+			t.Fatalf("%s: agency %q: %v", path, agency.Ref, err)
+		}
+		s.define(t, agency.Ref, id)
+	}
+
+	for _, location := range fixtures.Locations {
+		id, err := s.service.InsertLocation(s.fixtureContext(t, location.Agency), service.LocationDTO{
+			Street:  location.Street,
+			Number:  location.Number,
+			City:    location.City,
+			Country: location.Country,
+		})
+		if err != nil {
+			t.Fatalf("%s: location %q: %v", path, location.Ref, err)
+		}
+		s.define(t, location.Ref, id)
+	}
+
+	for _, ruleSet := range fixtures.PricingRuleSets {
+		rules := []pricing.Rule{}
+		for _, rule := range ruleSet.Rules {
+			var amount *money.Money
+			if rule.Amount != "" {
+				parsed, err := parseMoney(rule.Amount, rule.Currency)
+				if err != nil {
+					t.Fatalf("%s: pricing rule set %q: %v", path, ruleSet.Ref, err)
+				}
+				amount = &parsed
+			}
+
+			rules = append(rules, pricing.Rule{
+				Name:      rule.Name,
+				Kind:      rule.Kind,
+				Percent:   rule.Percent,
+				From:      rule.From,
+				To:        rule.To,
+				Weekdays:  rule.Weekdays,
+				Days:      rule.Days,
+				FreeSlots: rule.FreeSlots,
+				Age:       rule.Age,
+				Amount:    amount,
+				PerPerson: rule.PerPerson,
+			})
+		}
+
+		id, err := s.service.InsertPricingRuleSet(s.fixtureContext(t, ruleSet.Agency), service.PricingRuleSetDTO{
+			Name:  ruleSet.Name,
+			Rules: rules,
+		})
+		if err != nil {
+			t.Fatalf("%s: pricing rule set %q: %v", path, ruleSet.Ref, err)
+		}
+		s.define(t, ruleSet.Ref, id)
+	}
+
+	for _, rate := range fixtures.ExchangeRates {
+		var date time.Time
+		if rate.Date != "" {
+			if date, err = time.Parse(time.DateOnly, rate.Date); err != nil {
+				t.Fatalf("%s: exchange rate %q: %v", path, rate.Currency, err)
+			}
+		}
+
+		_, err := s.service.SaveExchangeRate(s.fixtureContext(t, rate.Agency), service.ExchangeRateDTO{
+			Currency: rate.Currency,
+			Rate:     rate.Rate,
+			Date:     date,
+		})
+		if err != nil {
+			t.Fatalf("%s: exchange rate %q: %v", path, rate.Currency, err)
+		}
+	}
+
+	for _, holiday := range fixtures.Holidays {
+		var ruleSetID int
+		if holiday.PricingRuleSet != "" {
+			ruleSetID = s.ID(t, holiday.PricingRuleSet)
+		}
+
+		startDate, err := time.Parse(time.DateOnly, holiday.StartDate)
+		if err != nil {
+			t.Fatalf("%s: holiday %q: %v", path, holiday.Ref, err)
+		}
+
+		price, err := parseMoney(holiday.Price, holiday.Currency)
+		if err != nil {
+			t.Fatalf("%s: holiday %q: %v", path, holiday.Ref, err)
+		}
+
+		id, err := s.service.InsertHoliday(s.fixtureContext(t, holiday.Agency), service.HolidayDTO{
+			Title:            holiday.Title,
+			StartDate:        startDate,
+			Duration:         holiday.Duration,
+			Price:            price,
+			FreeSlots:        holiday.FreeSlots,
+			LocationID:       s.ID(t, holiday.Location),
+			PricingRuleSetID: ruleSetID,
+		})
+		if err != nil {
+			t.Fatalf("%s: holiday %q: %v", path, holiday.Ref, err)
+		}
+		s.define(t, holiday.Ref, id)
+	}
+
+	for _, customer := range fixtures.Customers {
+		id, err := s.service.InsertCustomer(s.fixtureContext(t, customer.Agency), service.CustomerDTO{
+			Name:        customer.Name,
+			PhoneNumber: customer.PhoneNumber,
+			Email:       customer.Email,
+		})
+		if err != nil {
+			t.Fatalf("%s: customer %q: %v", path, customer.Ref, err)
+		}
+		s.define(t, customer.Ref, id)
+	}
+
+	for _, code := range fixtures.PromoCodes {
+		dto, err := s.promoCode(t, code)
+		if err != nil {
+			t.Fatalf("%s: promo code %q: %v", path, code.Ref, err)
+		}
+
+		id, err := s.service.InsertPromoCode(s.fixtureContext(t, code.Agency), *dto)
+		if err != nil {
+			t.Fatalf("%s: promo code %q: %v", path, code.Ref, err)
+		}
+		s.define(t, code.Ref, id)
+	}
+
+	for _, reservation := range fixtures.Reservations {
+		var customerID int
+		if reservation.Customer != "" {
+			customerID = s.ID(t, reservation.Customer)
+		}
+
+		id, err := s.service.InsertReservation(s.fixtureContext(t, reservation.Agency), service.ReservationDTO{
+			ContactName: reservation.ContactName,
+			PhoneNumber: reservation.PhoneNumber,
+			HolidayID:   s.ID(t, reservation.Holiday),
+			CustomerID:  customerID,
+			Email:       reservation.Email,
+			PromoCode:   reservation.PromoCode,
+		})
+		if err != nil {
+			t.Fatalf("%s: reservation %q: %v", path, reservation.Ref, err)
+		}
+		s.define(t, reservation.Ref, id)
+	}
+}
+
+// promoCode converts the fixture of a promo code, the refs it is restricted
+// to included.
+func (s *Server) promoCode(t *testing.T, code PromoCodeFixture) (*service.PromoCodeDTO, error) {
+	t.Helper()
+
+	dto := &service.PromoCodeDTO{Code: promo.Code{
+		Code:           code.Code,
+		Description:    code.Description,
+		Percent:        code.Percent,
+		MaxRedemptions: code.MaxRedemptions,
+		MaxPerCustomer: code.MaxPerCustomer,
+		Holidays:       []int{},
+		Locations:      []int{},
+	}}
+
+	if code.Amount != "" {
+		amount, err := parseMoney(code.Amount, code.Currency)
+		if err != nil {
+			return nil, err
+		}
+		dto.Amount = &amount
+	}
+	if code.MinSpend != "" {
+		minSpend, err := parseMoney(code.MinSpend, code.Currency)
+		if err != nil {
+			return nil, err
+		}
+		dto.MinSpend = &minSpend
+	}
+
+	if code.ValidFrom != "" {
+		validFrom, err := time.Parse(time.DateOnly, code.ValidFrom)
+		if err != nil {
+			return nil, err
+		}
+		dto.ValidFrom = &validFrom
+	}
+	if code.ValidTo != "" {
+		validTo, err := time.Parse(time.DateOnly, code.ValidTo)
+		if err != nil {
+			return nil, err
+		}
+		dto.ValidTo = &validTo
+	}
+
+	for _, ref := range code.Holidays {
+		dto.Holidays = append(dto.Holidays, s.ID(t, ref))
+	}
+	for _, ref := range code.Locations {
+		dto.Locations = append(dto.Locations, s.ID(t, ref))
+	}
+
+	return dto, nil
+}
+
+// parseMoney parses amount of currency, the default currency of the service
+// when empty.
+func parseMoney(amount string, currency string) (money.Money, error) {
+	if currency == "" {
+		currency = service.DefaultCurrency
+	}
+
+	return money.Parse(amount, currency)
+}
+
+// fixtureContext scopes a fixture to the agency named by ref.
+func (s *Server) fixtureContext(t *testing.T, agency string) context.Context {
+	t.Helper()
+
+	tenantID := DefaultTenant
+	if agency != "" {
+		tenantID = s.ID(t, agency)
+	}
+
+	return auth.WithPrincipal(tenant.WithID(context.Background(), tenantID), fixturePrincipal)
+}
+
+func (s *Server) define(t *testing.T, ref string, id int64) {
+	t.Helper()
+
+	if ref == "" {
+		return
+	}
+	if _, ok := s.refs[ref]; ok {
+		t.Fatalf("fixture %q is defined twice", ref)
+	}
+
+	s.refs[ref] = int(id)
+}
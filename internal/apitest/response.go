@@ -0,0 +1,122 @@
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Response is a response read to the end.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	server *Server
+}
+
+// AssertStatus fails the test unless the response has status.
+func (r *Response) AssertStatus(t *testing.T, status int) *Response {
+	t.Helper()
+
+	if r.StatusCode != status {
+		t.Fatalf("status %d, want %d, body: %s", r.StatusCode, status, r.Body)
+	}
+
+	return r
+}
+
+// AssertJSON fails the test unless the body is JSON matching want, which may
+// hold {ref} placeholders. Objects in want only need to be a subset of the
+// ones in the body, so generated values like timestamps can be left out.
+// Arrays have to match element by element.
+func (r *Response) AssertJSON(t *testing.T, want string) *Response {
+	t.Helper()
+
+	var expected, actual interface{}
+	if err := json.Unmarshal([]byte(r.server.Expand(t, want)), &expected); err != nil {
+		t.Fatalf("expected JSON: %v", err)
+	}
+	if err := json.Unmarshal(r.Body, &actual); err != nil {
+		t.Fatalf("response is no JSON: %v, body: %s", err, r.Body)
+	}
+
+	if diff := match("$", expected, actual); diff != "" {
+		t.Fatalf("%s\nbody: %s", diff, r.Body)
+	}
+
+	return r
+}
+
+// AssertHeader fails the test unless the response header name starts with
+// prefix.
+func (r *Response) AssertHeader(t *testing.T, name string, prefix string) *Response {
+	t.Helper()
+
+	if value := r.Header.Get(name); !strings.HasPrefix(value, prefix) {
+		t.Fatalf("header %s is %q, want %q", name, value, prefix+"...")
+	}
+
+	return r
+}
+
+// Decode unmarshals the JSON body into v.
+func (r *Response) Decode(t *testing.T, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("decoding the response: %v, body: %s", err, r.Body)
+	}
+}
+
+// match describes the first difference of actual from expected at path, or
+// returns "" when there is none.
+func match(path string, expected interface{}, actual interface{}) string {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		object, ok := actual.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: got %v, want an object", path, actual)
+		}
+
+		keys := make([]string, 0, len(expected))
+		for key := range expected {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value, ok := object[key]
+			if !ok {
+				return fmt.Sprintf("%s.%s: missing", path, key)
+			}
+			if diff := match(path+"."+key, expected[key], value); diff != "" {
+				return diff
+			}
+		}
+	case []interface{}:
+		array, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: got %v, want an array", path, actual)
+		}
+		if len(array) != len(expected) {
+			return fmt.Sprintf("%s: got %d elements, want %d", path, len(array), len(expected))
+		}
+
+		for i := range expected {
+			if diff := match(fmt.Sprintf("%s[%d]", path, i), expected[i], array[i]); diff != "" {
+				return diff
+			}
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			return fmt.Sprintf("%s: got %v, want %v", path, actual, expected)
+		}
+	}
+
+	return ""
+}
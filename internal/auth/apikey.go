@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const apiKeyPrefix = "tk"
+
+var ErrMalformedAPIKey = errors.New("malformed api key")
+
+var keyEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// GenerateAPIKey returns a new key in the form tk_<prefix>_<secret>. Only
+// the prefix (for lookups) and the hash are meant to be stored; the key
+// itself is shown to its owner once.
+func GenerateAPIKey() (key string, prefix string, hash string, err error) {
+	random := make([]byte, 25)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = keyEncoding.EncodeToString(random[:5])
+	secret := keyEncoding.EncodeToString(random[5:])
+	key = apiKeyPrefix + "_" + prefix + "_" + secret
+
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey hashes the whole key. Keys carry 160 random bits so a plain
+// SHA-256 is enough, there is nothing to brute force.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix returns the lookup prefix of a key.
+func APIKeyPrefix(key string) (string, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", ErrMalformedAPIKey
+	}
+
+	return parts[1], nil
+}
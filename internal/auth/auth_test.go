@@ -0,0 +1,289 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"travel/internal/auth"
+)
+
+var secret = []byte("auth-test")
+
+func claims(modify func(c *auth.Claims)) auth.Claims {
+	c := auth.Claims{Subject: "maria", Role: "agent", TenantID: 1, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	if modify != nil {
+		modify(&c)
+	}
+
+	return c
+}
+
+func signHS256(t *testing.T, c auth.Claims) string {
+	t.Helper()
+
+	token, err := auth.SignHS256(c, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// sign issues a token with any header, signed by sign over header.payload.
+func sign(t *testing.T, header string, c auth.Claims, sign func(signed []byte) []byte) string {
+	t.Helper()
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey) func(signed []byte) []byte {
+	return func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	verifier := &auth.JWTVerifier{HMACSecret: secret, Issuer: "travel", Audience: "api"}
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"valid", signHS256(t, claims(func(c *auth.Claims) { c.Issuer, c.Audience = "travel", auth.Audience{"other", "api"} })), nil},
+		{"expired within the leeway", signHS256(t, claims(func(c *auth.Claims) {
+			c.Issuer, c.Audience, c.ExpiresAt = "travel", auth.Audience{"api"}, now.Add(-20*time.Second).Unix()
+		})), nil},
+		{"expired", signHS256(t, claims(func(c *auth.Claims) {
+			c.Issuer, c.Audience, c.ExpiresAt = "travel", auth.Audience{"api"}, now.Add(-time.Minute).Unix()
+		})), auth.ErrTokenExpired},
+		{"valid soon within the leeway", signHS256(t, claims(func(c *auth.Claims) {
+			c.Issuer, c.Audience, c.NotBefore = "travel", auth.Audience{"api"}, now.Add(20*time.Second).Unix()
+		})), nil},
+		{"not valid yet", signHS256(t, claims(func(c *auth.Claims) {
+			c.Issuer, c.Audience, c.NotBefore = "travel", auth.Audience{"api"}, now.Add(time.Minute).Unix()
+		})), auth.ErrTokenNotYetValid},
+		{"without expiry", signHS256(t, claims(func(c *auth.Claims) { c.Issuer, c.Audience, c.ExpiresAt = "travel", auth.Audience{"api"}, 0 })), auth.ErrInvalidClaims},
+		{"without subject", signHS256(t, claims(func(c *auth.Claims) { c.Issuer, c.Audience, c.Subject = "travel", auth.Audience{"api"}, "" })), auth.ErrInvalidClaims},
+		{"other issuer", signHS256(t, claims(func(c *auth.Claims) { c.Issuer, c.Audience = "evil", auth.Audience{"api"} })), auth.ErrInvalidClaims},
+		{"other audience", signHS256(t, claims(func(c *auth.Claims) { c.Issuer, c.Audience = "travel", auth.Audience{"web"} })), auth.ErrInvalidClaims},
+		{"other secret", sign(t, `{"alg":"HS256"}`, claims(nil), func(signed []byte) []byte { return []byte("forged") }), auth.ErrInvalidSignature},
+		{"alg none", sign(t, `{"alg":"none"}`, claims(nil), func(signed []byte) []byte { return nil }), auth.ErrUnsupportedAlg},
+		{"two segments", "a.b", auth.ErrMalformedToken},
+		{"bad base64", "a.b.!", auth.ErrMalformedToken},
+		{"bad header", "e30.e30.", auth.ErrUnsupportedAlg},
+		{"not JSON", base64.RawURLEncoding.EncodeToString([]byte("{")) + ".e30.", auth.ErrMalformedToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := verifier.Verify(test.token)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+			if err == nil && got.Subject != "maria" {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	public, err := auth.ParseRSAPublicKey(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs1, err := auth.ParseRSAPublicKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)}))
+	if err != nil || !pkcs1.Equal(public) {
+		t.Fatalf("PKCS#1 key: %v", err)
+	}
+	if _, err := auth.ParseRSAPublicKey([]byte("not a key")); err == nil {
+		t.Error("parsed a key without PEM")
+	}
+
+	verifier := &auth.JWTVerifier{RSAKey: public}
+
+	if _, err := verifier.Verify(sign(t, `{"alg":"RS256"}`, claims(nil), rs256(t, key))); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+	if _, err := verifier.Verify(sign(t, `{"alg":"RS256"}`, claims(nil), rs256(t, other))); !errors.Is(err, auth.ErrInvalidSignature) {
+		t.Errorf("token of another key: got %v", err)
+	}
+
+	// the public key is public, an HS256 token keyed with it must not pass
+	confused := sign(t, `{"alg":"HS256"}`, claims(nil), func(signed []byte) []byte {
+		token, _ := auth.SignHS256(claims(nil), publicPEM)
+		return []byte(token)
+	})
+	if _, err := verifier.Verify(confused); !errors.Is(err, auth.ErrUnsupportedAlg) {
+		t.Errorf("HS256 with an RSA verifier: got %v", err)
+	}
+	forged, err := auth.SignHS256(claims(nil), publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(forged); !errors.Is(err, auth.ErrUnsupportedAlg) {
+		t.Errorf("HS256 keyed with the public key: got %v", err)
+	}
+
+	// and an HMAC only verifier refuses RS256
+	hmacOnly := &auth.JWTVerifier{HMACSecret: secret}
+	if _, err := hmacOnly.Verify(sign(t, `{"alg":"RS256"}`, claims(nil), rs256(t, key))); !errors.Is(err, auth.ErrUnsupportedAlg) {
+		t.Errorf("RS256 with an HMAC verifier: got %v", err)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(key, "tk_"+prefix+"_") || len(key) != len("tk_")+8+1+32 {
+		t.Errorf("key %q with prefix %q", key, prefix)
+	}
+	if got, err := auth.APIKeyPrefix(key); err != nil || got != prefix {
+		t.Errorf("prefix %q, %v", got, err)
+	}
+	if auth.HashAPIKey(key) != hash || auth.HashAPIKey(key+"x") == hash || strings.Contains(hash, key) {
+		t.Errorf("hash %s", hash)
+	}
+
+	other, otherPrefix, _, err := auth.GenerateAPIKey()
+	if err != nil || other == key || otherPrefix == prefix {
+		t.Errorf("keys repeat: %s %s", key, other)
+	}
+
+	for _, malformed := range []string{"", "tk", "tk__secret", "tk_prefix_", "xx_prefix_secret", "tk_prefix_secret_more", "prefix_secret"} {
+		if _, err := auth.APIKeyPrefix(malformed); !errors.Is(err, auth.ErrMalformedAPIKey) {
+			t.Errorf("%q: got %v", malformed, err)
+		}
+	}
+}
+
+// keys knows one API key.
+type keys struct{}
+
+var errUnknownKey = errors.New("unknown api key")
+
+func (keys) AuthenticateAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	if key != "tk_abc_secret" {
+		return nil, errUnknownKey
+	}
+
+	return &auth.Principal{Type: auth.PrincipalAPIKey, ID: "1", Role: "agent", TenantID: 2}, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	authenticator := auth.NewAuthenticator(keys{}, &auth.JWTVerifier{HMACSecret: secret})
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   auth.Principal
+		err    error
+	}{
+		{"api key header", map[string]string{"X-API-Key": "tk_abc_secret"}, auth.Principal{Type: auth.PrincipalAPIKey, ID: "1", Role: "agent", TenantID: 2}, nil},
+		{"api key scheme", map[string]string{"Authorization": "apikey  tk_abc_secret"}, auth.Principal{Type: auth.PrincipalAPIKey, ID: "1", Role: "agent", TenantID: 2}, nil},
+		{"wrong api key", map[string]string{"X-API-Key": "tk_abc_guess"}, auth.Principal{}, errUnknownKey},
+		{
+			"bearer", map[string]string{"Authorization": "Bearer " + signHS256(t, claims(func(c *auth.Claims) { c.Role, c.CustomerID = "customer", 7 }))},
+			auth.Principal{Type: auth.PrincipalUser, ID: "maria", Role: "customer", CustomerID: 7, TenantID: 1}, nil,
+		},
+		{
+			"platform staff", map[string]string{"Authorization": "Bearer " + signHS256(t, claims(func(c *auth.Claims) { c.TenantID, c.Platform = 0, true }))},
+			auth.Principal{Type: auth.PrincipalUser, ID: "maria", Role: "agent"}, nil,
+		},
+		// a token that forgot its tenant must not act for every agency
+		{"without a tenant", map[string]string{"Authorization": "Bearer " + signHS256(t, claims(func(c *auth.Claims) { c.TenantID = 0 }))}, auth.Principal{}, auth.ErrInvalidClaims},
+		{"tenant and platform", map[string]string{"Authorization": "Bearer " + signHS256(t, claims(func(c *auth.Claims) { c.Platform = true }))}, auth.Principal{}, auth.ErrInvalidClaims},
+		{"expired bearer", map[string]string{"Authorization": "Bearer " + signHS256(t, claims(func(c *auth.Claims) { c.ExpiresAt = time.Now().Add(-time.Hour).Unix() }))}, auth.Principal{}, auth.ErrTokenExpired},
+		{"no credentials", nil, auth.Principal{}, auth.ErrUnauthenticated},
+		{"unknown scheme", map[string]string{"Authorization": "Basic bWFyaWE6c2VjcmV0"}, auth.Principal{}, auth.ErrUnauthenticated},
+		{"scheme only", map[string]string{"Authorization": "Bearer"}, auth.Principal{}, auth.ErrUnauthenticated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/holidays", nil)
+			for name, value := range test.header {
+				r.Header.Set(name, value)
+			}
+
+			got, err := authenticator.Authenticate(r)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+			if err == nil && *got != test.want {
+				t.Errorf("got %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+
+	// without a verifier bearer tokens are refused
+	r := httptest.NewRequest(http.MethodGet, "/holidays", nil)
+	r.Header.Set("Authorization", "Bearer "+signHS256(t, claims(nil)))
+	if _, err := auth.NewAuthenticator(keys{}, nil).Authenticate(r); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var principal *auth.Principal
+	handler := auth.NewAuthenticator(keys{}, &auth.JWTVerifier{HMACSecret: secret}).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = auth.FromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/holidays", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" || !strings.Contains(w.Body.String(), auth.ErrUnauthenticated.Error()) || principal != nil {
+		t.Errorf("got %d %v: %s", w.Code, w.Header(), w.Body)
+	}
+
+	r.Header.Set("X-API-Key", "tk_abc_secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || principal == nil || principal.ID != "1" {
+		t.Errorf("got %d with %+v", w.Code, principal)
+	}
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var ErrUnauthenticated = errors.New("authentication required")
+
+// APIKeyAuthenticator resolves a partner API key to its principal.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+type Authenticator struct {
+	keys APIKeyAuthenticator
+	jwt  *JWTVerifier
+}
+
+// NewAuthenticator accepts API keys through keys and bearer tokens through
+// jwt. A nil jwt verifier turns bearer tokens off.
+func NewAuthenticator(keys APIKeyAuthenticator, jwt *JWTVerifier) *Authenticator {
+	return &Authenticator{keys: keys, jwt: jwt}
+}
+
+// Middleware rejects requests without valid credentials with 401 and puts the
+// principal of the others in the request context.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="travel", ApiKey realm="travel"`)
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"type":   "about:blank",
+				"title":  http.StatusText(http.StatusUnauthorized),
+				"status": http.StatusUnauthorized,
+				"detail": err.Error(),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Authenticate reads either "X-API-Key: <key>", "Authorization: ApiKey <key>"
+// or "Authorization: Bearer <jwt>".
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return a.keys.AuthenticateAPIKey(r.Context(), key)
+	}
+
+	scheme, credentials, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found {
+		return nil, ErrUnauthenticated
+	}
+	credentials = strings.TrimSpace(credentials)
+
+	switch {
+	case strings.EqualFold(scheme, "ApiKey"):
+		return a.keys.AuthenticateAPIKey(r.Context(), credentials)
+	case strings.EqualFold(scheme, "Bearer") && a.jwt != nil:
+		claims, err := a.jwt.Verify(credentials)
+		if err != nil {
+			return nil, err
+		}
+
+		// a token that forgot its agency must not act for every agency
+		if (claims.TenantID == 0) != claims.Platform {
+			return nil, fmt.Errorf("%w: a token needs either a tenantID or the platform claim", ErrInvalidClaims)
+		}
+
+		return &Principal{
+			Type:       PrincipalUser,
+			ID:         claims.Subject,
+			Name:       claims.Name,
+			Role:       claims.Role,
+			CustomerID: claims.CustomerID,
+			TenantID:   claims.TenantID,
+		}, nil
+	}
+
+	return nil, ErrUnauthenticated
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	PrincipalUser   = "user"
+	PrincipalAPIKey = "apiKey"
+	// PrincipalGateway is a payment gateway reporting on a payment with a
+	// signed webhook.
+	PrincipalGateway = "gateway"
+)
+
+// Principal is whoever made the request: a staff user holding a JWT, a
+// partner integration holding an API key or a payment gateway.
+type Principal struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+	// CustomerID links users with the customer role to their customer
+	// record.
+	CustomerID int `json:"customerID,omitempty"`
+	// TenantID is the agency the credential belongs to, 0 for platform
+	// staff that pick the agency per request. Only tokens with the platform
+	// claim make platform staff.
+	TenantID int `json:"tenantID,omitempty"`
+}
+
+// ErrForbidden is wrapped by every authorization denial.
+var ErrForbidden = errors.New("forbidden")
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the authenticated principal or nil for anonymous
+// requests.
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
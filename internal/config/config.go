@@ -0,0 +1,396 @@
+// Package config loads the settings of the API. Values are taken, from lowest
+// to highest precedence, from the defaults, a YAML file, environment variables
+// and command-line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type Config struct {
+	Server     Server     `yaml:"server"`
+	Database   Database   `yaml:"database"`
+	Migrations Migrations `yaml:"migrations"`
+	Log        Log        `yaml:"log"`
+	Tracing    Tracing    `yaml:"tracing"`
+	Auth       Auth       `yaml:"auth"`
+	Tenant     Tenant     `yaml:"tenant"`
+	Currency   Currency   `yaml:"currency"`
+	Quote      Quote      `yaml:"quote"`
+	Payment    Payment    `yaml:"payment"`
+	Seed       Seed       `yaml:"seed"`
+	Features   Features   `yaml:"features" env:"FEATURES" flag:"features" usage:"comma separated feature toggles, prefix with - to disable"`
+}
+
+type Server struct {
+	Addr         string        `yaml:"addr" env:"HTTP_ADDR" flag:"http-addr" usage:"address the HTTP server listens on"`
+	ReadTimeout  time.Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT" flag:"http-read-timeout" usage:"maximum duration for reading a request"`
+	WriteTimeout time.Duration `yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT" flag:"http-write-timeout" usage:"maximum duration for writing a response"`
+	IdleTimeout  time.Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT" flag:"http-idle-timeout" usage:"how long keep-alive connections stay open"`
+
+	ReadyTimeout      time.Duration `yaml:"readyTimeout" env:"HTTP_READY_TIMEOUT" flag:"http-ready-timeout" usage:"how long /readyz waits for the database"`
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" env:"HTTP_READ_HEADER_TIMEOUT" flag:"http-read-header-timeout" usage:"maximum duration for reading request headers"`
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout" env:"HTTP_SHUTDOWN_TIMEOUT" flag:"http-shutdown-timeout" usage:"how long in-flight requests may take to finish on shutdown"`
+	MaxHeaderBytes    int           `yaml:"maxHeaderBytes" env:"HTTP_MAX_HEADER_BYTES" flag:"http-max-header-bytes" usage:"maximum size of request headers"`
+	MaxBodyBytes      int           `yaml:"maxBodyBytes" env:"HTTP_MAX_BODY_BYTES" flag:"http-max-body-bytes" usage:"maximum size of a request body"`
+}
+
+type Database struct {
+	Driver   string `yaml:"driver" env:"DB_DRIVER" flag:"db-driver" usage:"mysql, postgres, sqlite3 or memory"`
+	Host     string `yaml:"host" env:"DB_HOST" flag:"db-host" usage:"database host"`
+	Port     int    `yaml:"port" env:"DB_PORT" flag:"db-port" usage:"database port, 0 is the driver's default"`
+	User     string `yaml:"user" env:"DB_USER" flag:"db-user" usage:"database user"`
+	Password string `yaml:"password" env:"DB_PASSWORD" usage:"database password"`
+	Name     string `yaml:"name" env:"DB_NAME" flag:"db-name" usage:"database name, created when missing"`
+	SSLMode  string `yaml:"sslMode" env:"DB_SSL_MODE" flag:"db-ssl-mode" usage:"PostgreSQL sslmode"`
+	Path     string `yaml:"path" env:"DB_PATH" flag:"db-path" usage:"SQLite database file"`
+
+	MaxOpenConns    int           `yaml:"maxOpenConns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum open connections, 0 is unlimited"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"maximum idle connections"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"maximum lifetime of a connection, 0 is unlimited"`
+}
+
+type Migrations struct {
+	Path     string `yaml:"path" env:"MIGRATIONS_PATH" flag:"migrations-path" usage:"golang-migrate source URL of the migrations, file://migrations/<driver> when empty"`
+	Embedded bool   `yaml:"embedded" env:"MIGRATIONS_EMBEDDED" flag:"migrations-embedded" usage:"use the migrations compiled into the binary instead of the path"`
+	Auto     bool   `yaml:"auto" env:"MIGRATIONS_AUTO" flag:"migrations-auto" usage:"apply pending migrations before serving or seeding"`
+}
+
+type Log struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"debug, info, warn or error"`
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" usage:"none, otlp, stdout or file"`
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT" flag:"tracing-endpoint" usage:"OTLP/HTTP collector URL, the OTEL_EXPORTER_OTLP_* variables apply when empty"`
+	File        string  `yaml:"file" env:"TRACING_FILE" flag:"tracing-file" usage:"file the spans are written to with the file exporter"`
+	SampleRatio float64 `yaml:"sampleRatio" env:"TRACING_SAMPLE_RATIO" flag:"tracing-sample-ratio" usage:"share of new traces that are recorded, from 0 to 1"`
+}
+
+type Auth struct {
+	JWTHMACSecret       string `yaml:"jwtHMACSecret" env:"JWT_HMAC_SECRET" usage:"HS256 secret of staff tokens"`
+	JWTRSAPublicKeyFile string `yaml:"jwtRSAPublicKeyFile" env:"JWT_RSA_PUBLIC_KEY_FILE" flag:"jwt-rsa-public-key-file" usage:"PEM file with the RS256 key of staff tokens"`
+	JWTIssuer           string `yaml:"jwtIssuer" env:"JWT_ISSUER" flag:"jwt-issuer" usage:"required iss claim"`
+	JWTAudience         string `yaml:"jwtAudience" env:"JWT_AUDIENCE" flag:"jwt-audience" usage:"required aud claim"`
+}
+
+type Tenant struct {
+	BaseDomain string `yaml:"baseDomain" env:"TENANT_BASE_DOMAIN" flag:"tenant-base-domain" usage:"enables <agency>.<domain> hosts"`
+}
+
+// Currency is how prices converted into another currency are shown.
+type Currency struct {
+	Rounding     string `yaml:"rounding" env:"CURRENCY_ROUNDING" flag:"currency-rounding" usage:"nearest, up or down, how converted prices are rounded"`
+	RoundingStep int    `yaml:"roundingStep" env:"CURRENCY_ROUNDING_STEP" flag:"currency-rounding-step" usage:"minor units converted prices are rounded to, 100 rounds to whole units"`
+}
+
+// Quote is how price quotes are signed.
+type Quote struct {
+	Secret string        `yaml:"secret" env:"QUOTE_SECRET" usage:"HMAC secret quotes are signed with, random per process when empty"`
+	TTL    time.Duration `yaml:"ttl" env:"QUOTE_TTL" flag:"quote-ttl" usage:"how long a quote can be booked at"`
+}
+
+// Payment is how reservations are paid.
+type Payment struct {
+	Gateway        string        `yaml:"gateway" env:"PAYMENT_GATEWAY" flag:"payment-gateway" usage:"none or fake, the gateway reservations are paid through"`
+	WebhookSecret  string        `yaml:"webhookSecret" env:"PAYMENT_WEBHOOK_SECRET" usage:"secret the webhooks of the gateway are signed with"`
+	DepositPercent float64       `yaml:"depositPercent" env:"PAYMENT_DEPOSIT_PERCENT" flag:"payment-deposit-percent" usage:"percent of the price paid on booking when the balance is paid later"`
+	BalanceDays    int           `yaml:"balanceDays" env:"PAYMENT_BALANCE_DAYS" flag:"payment-balance-days" usage:"days before the start of a holiday the balance is due, holidays starting sooner are paid in full"`
+	IntentTTL      time.Duration `yaml:"intentTTL" env:"PAYMENT_INTENT_TTL" flag:"payment-intent-ttl" usage:"how long a customer has to pay before the payment may be cancelled for a new one"`
+}
+
+// Payment gateways.
+const (
+	GatewayNone = "none"
+	GatewayFake = "fake"
+)
+
+// Seed is read by the seed command only.
+type Seed struct {
+	Value        int    `yaml:"value" env:"SEED" flag:"seed" usage:"seed of the generated data, the same seed and volumes generate the same data"`
+	Agency       string `yaml:"agency" env:"SEED_AGENCY" flag:"seed-agency" usage:"slug of the agency the data is generated for"`
+	Locations    int    `yaml:"locations" env:"SEED_LOCATIONS" flag:"seed-locations" usage:"number of locations to generate"`
+	Holidays     int    `yaml:"holidays" env:"SEED_HOLIDAYS" flag:"seed-holidays" usage:"number of holidays to generate"`
+	Reservations int    `yaml:"reservations" env:"SEED_RESERVATIONS" flag:"seed-reservations" usage:"number of reservations to generate"`
+	Start        string `yaml:"start" env:"SEED_START" flag:"seed-start" usage:"first day holidays may start on, YYYY-MM-DD, January 1 of next year when empty"`
+}
+
+// Default returns the settings used when nothing else is configured. They
+// match the docker-compose setup.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:         ":8080",
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 60 * time.Second,
+			IdleTimeout:  120 * time.Second,
+
+			ReadyTimeout:      2 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			MaxHeaderBytes:    64 << 10,
+			MaxBodyBytes:      1 << 20,
+		},
+		Database: Database{
+			Driver:          "mysql",
+			Host:            "db",
+			User:            "root",
+			Password:        "root",
+			Name:            "travel",
+			SSLMode:         "disable",
+			Path:            "travel.db",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Migrations: Migrations{
+			Auto: true,
+		},
+		Log: Log{
+			Level: "info",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		Currency: Currency{
+			Rounding:     "nearest",
+			RoundingStep: 1,
+		},
+		Quote: Quote{
+			TTL: 30 * time.Minute,
+		},
+		Payment: Payment{
+			Gateway:        GatewayNone,
+			DepositPercent: 30,
+			BalanceDays:    30,
+			IntentTTL:      time.Hour,
+		},
+		Features: Features{
+			FeatureExports: true,
+		},
+		Seed: Seed{
+			Value:        1,
+			Agency:       "default",
+			Locations:    20,
+			Holidays:     200,
+			Reservations: 2000,
+		},
+	}
+}
+
+// DriverMemory keeps the data in process memory, nothing else is needed to
+// run the API with it and nothing survives a restart.
+const DriverMemory = "memory"
+
+var databaseDrivers = []string{"mysql", "postgres", "sqlite3", DriverMemory}
+
+// defaultPorts are used when database.port is 0.
+var defaultPorts = map[string]int{"mysql": 3306, "postgres": 5432}
+
+var databaseName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+var tracingExporters = []string{"none", "otlp", "stdout", "file"}
+
+var roundingModes = []string{"nearest", "up", "down"}
+
+var paymentGateways = []string{GatewayNone, GatewayFake}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if strings.TrimSpace(c.Server.Addr) == "" {
+		invalid("server.addr is required")
+	}
+	for name, timeout := range map[string]time.Duration{
+		"server.readTimeout":       c.Server.ReadTimeout,
+		"server.writeTimeout":      c.Server.WriteTimeout,
+		"server.idleTimeout":       c.Server.IdleTimeout,
+		"server.readHeaderTimeout": c.Server.ReadHeaderTimeout,
+		"server.readyTimeout":      c.Server.ReadyTimeout,
+		"server.shutdownTimeout":   c.Server.ShutdownTimeout,
+		"database.connMaxLifetime": c.Database.ConnMaxLifetime,
+	} {
+		if timeout < 0 {
+			invalid("%s must not be negative", name)
+		}
+	}
+
+	if c.Server.MaxHeaderBytes <= 0 || c.Server.MaxBodyBytes <= 0 {
+		invalid("server.maxHeaderBytes and server.maxBodyBytes must be positive")
+	}
+
+	switch c.Database.Driver {
+	case DriverMemory:
+	case "sqlite3":
+		if c.Database.Path == "" {
+			invalid("database.path is required for sqlite3")
+		}
+	case "mysql", "postgres":
+		if c.Database.Host == "" {
+			invalid("database.host is required")
+		}
+		if c.Database.Port < 0 || c.Database.Port > 65535 {
+			invalid("database.port %d is out of range", c.Database.Port)
+		}
+		if c.Database.User == "" {
+			invalid("database.user is required")
+		}
+		// the name ends up in CREATE DATABASE, which takes no placeholders
+		if !databaseName.MatchString(c.Database.Name) {
+			invalid("database.name %q may only contain letters, digits and _", c.Database.Name)
+		}
+	default:
+		invalid("database.driver %q is not one of %s", c.Database.Driver, strings.Join(databaseDrivers, ", "))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		invalid("database pool sizes must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		invalid("database.maxIdleConns %d is above database.maxOpenConns %d", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+
+	if c.Migrations.Embedded && c.Migrations.Path != "" {
+		invalid("migrations.path and migrations.embedded exclude each other")
+	}
+
+	if !contains(logLevels, c.Log.Level) {
+		invalid("log.level %q is not one of %s", c.Log.Level, strings.Join(logLevels, ", "))
+	}
+
+	if !contains(tracingExporters, c.Tracing.Exporter) {
+		invalid("tracing.exporter %q is not one of %s", c.Tracing.Exporter, strings.Join(tracingExporters, ", "))
+	}
+	if c.Tracing.Exporter == "file" && c.Tracing.File == "" {
+		invalid("tracing.file is required with the file exporter")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sampleRatio %v is not between 0 and 1", c.Tracing.SampleRatio)
+	}
+
+	if !contains(roundingModes, c.Currency.Rounding) {
+		invalid("currency.rounding %q is not one of %s", c.Currency.Rounding, strings.Join(roundingModes, ", "))
+	}
+	if c.Currency.RoundingStep <= 0 {
+		invalid("currency.roundingStep must be positive")
+	}
+
+	if c.Quote.TTL <= 0 {
+		invalid("quote.ttl must be positive")
+	}
+
+	if !contains(paymentGateways, c.Payment.Gateway) {
+		invalid("payment.gateway %q is not one of %s", c.Payment.Gateway, strings.Join(paymentGateways, ", "))
+	}
+	if c.Payment.DepositPercent <= 0 || c.Payment.DepositPercent >= 100 {
+		invalid("payment.depositPercent %v is not between 0 and 100", c.Payment.DepositPercent)
+	}
+	if c.Payment.BalanceDays < 0 {
+		invalid("payment.balanceDays must not be negative")
+	}
+	if c.Payment.IntentTTL <= 0 {
+		invalid("payment.intentTTL must be positive")
+	}
+
+	if c.Seed.Locations < 0 || c.Seed.Holidays < 0 || c.Seed.Reservations < 0 {
+		invalid("seed volumes must not be negative")
+	}
+	if c.Seed.Start != "" {
+		if _, err := time.Parse(time.DateOnly, c.Seed.Start); err != nil {
+			invalid("seed.start %q is no YYYY-MM-DD date", c.Seed.Start)
+		}
+	}
+
+	for name := range c.Features {
+		if !contains(knownFeatures, name) {
+			invalid("unknown feature %q", name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// MigrationsPath returns the configured migrations, or the set of the
+// database driver when none are configured.
+func (c *Config) MigrationsPath() string {
+	if c.Migrations.Path != "" {
+		return c.Migrations.Path
+	}
+
+	return "file://migrations/" + c.Database.Driver
+}
+
+// Addr returns host:port, with the default port of the driver when none is
+// set.
+func (d Database) Addr() string {
+	port := d.Port
+	if port == 0 {
+		port = defaultPorts[d.Driver]
+	}
+
+	return net.JoinHostPort(d.Host, strconv.Itoa(port))
+}
+
+// DSN returns the data source name of the driver, for the server only when
+// name is empty. SQLite has no server, name is ignored and the file at Path
+// is opened.
+func (d Database) DSN(name string) string {
+	switch d.Driver {
+	case "postgres":
+		// PostgreSQL always connects to a database, the maintenance one
+		// stands in for the server
+		if name == "" {
+			name = "postgres"
+		}
+
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(d.User, d.Password),
+			Host:     d.Addr(),
+			Path:     "/" + name,
+			RawQuery: url.Values{"sslmode": {d.SSLMode}}.Encode(),
+		}
+
+		return dsn.String()
+	case "sqlite3":
+		// immediate transactions take the write lock up front instead of
+		// failing with SQLITE_BUSY when a reader upgrades
+		return "file:" + d.Path + "?_foreign_keys=1&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	default:
+		cfg := mysql.NewConfig()
+		cfg.User = d.User
+		cfg.Passwd = d.Password
+		cfg.Net = "tcp"
+		cfg.Addr = d.Addr()
+		cfg.DBName = name
+		cfg.ParseTime = true
+		cfg.MultiStatements = true
+
+		return cfg.FormatDSN()
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"travel/internal/config"
+)
+
+// env looks variables up in vars like os.LookupEnv.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+// writeFile writes content to name in a temporary directory and returns its
+// path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", "server:\n  addr: \":1001\"\n  readTimeout: 1s\nlog:\n  level: debug\n")
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		addr        string
+		readTimeout time.Duration
+		level       string
+	}{
+		{name: "defaults", addr: ":8080", readTimeout: 15 * time.Second, level: "info"},
+		{name: "file", args: []string{"-config", file}, addr: ":1001", readTimeout: time.Second, level: "debug"},
+		{name: "file from the environment", env: map[string]string{"CONFIG_FILE": file}, addr: ":1001", readTimeout: time.Second, level: "debug"},
+		{
+			name: "environment over file", args: []string{"-config", file}, env: map[string]string{"HTTP_ADDR": ":1002"},
+			addr: ":1002", readTimeout: time.Second, level: "debug",
+		},
+		{
+			name: "flag over environment", args: []string{"-config", file, "-http-addr", ":1003", "-http-read-timeout", "3s"},
+			env:  map[string]string{"HTTP_ADDR": ":1002", "HTTP_READ_TIMEOUT": "2s", "LOG_LEVEL": "warn"},
+			addr: ":1003", readTimeout: 3 * time.Second, level: "warn",
+		},
+		{
+			name: "flag over default", args: []string{"-log-level", "error"},
+			addr: ":8080", readTimeout: 15 * time.Second, level: "error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := config.Load(test.args, env(test.env))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Server.Addr != test.addr || cfg.Server.ReadTimeout != test.readTimeout || cfg.Log.Level != test.level {
+				t.Errorf("got addr %q, read timeout %s, level %q", cfg.Server.Addr, cfg.Server.ReadTimeout, cfg.Log.Level)
+			}
+			// settings none of the sources set keep their default
+			if cfg.Server.WriteTimeout != config.Default().Server.WriteTimeout {
+				t.Errorf("write timeout %s", cfg.Server.WriteTimeout)
+			}
+		})
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		want    string
+	}{
+		{name: "trailing newline", content: "s3cret\n", want: "s3cret"},
+		{name: "trailing CRLF", content: "s3cret\r\n", want: "s3cret"},
+		{name: "inner and leading spaces kept", content: " s3 cret \n", want: " s3 cret "},
+		{name: "variable over file", content: "s3cret\n", env: map[string]string{"DB_PASSWORD": "direct"}, want: "direct"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vars := map[string]string{"DB_PASSWORD_FILE": writeFile(t, "password", test.content)}
+			for name, value := range test.env {
+				vars[name] = value
+			}
+
+			cfg, err := config.Load(nil, env(vars))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Database.Password != test.want {
+				t.Errorf("got %q, want %q", cfg.Database.Password, test.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := config.Load(nil, env(map[string]string{"JWT_HMAC_SECRET_FILE": filepath.Join(t.TempDir(), "missing")}))
+		if err == nil || !strings.Contains(err.Error(), "JWT_HMAC_SECRET_FILE") {
+			t.Errorf("got %v", err)
+		}
+	})
+}
+
+func TestLoadErrors(t *testing.T) {
+	unknown := writeFile(t, "unknown.yaml", "database:\n  pasword: x\n")
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{name: "duration in the environment", env: map[string]string{"HTTP_READ_TIMEOUT": "soon"}, want: "HTTP_READ_TIMEOUT"},
+		{name: "duration flag", args: []string{"-quote-ttl", "10"}, want: "-quote-ttl"},
+		{name: "port in the environment", env: map[string]string{"DB_PORT": "db"}, want: "DB_PORT"},
+		{name: "port flag", args: []string{"-db-port", "70000"}, want: "database.port 70000 is out of range"},
+		{name: "unknown driver", env: map[string]string{"DB_DRIVER": "oracle"}, want: `database.driver "oracle" is not one of`},
+		{name: "unknown gateway", args: []string{"-payment-gateway", "stripe"}, want: `payment.gateway "stripe" is not one of none, fake`},
+		{name: "unknown flag", args: []string{"-db-pasword", "x"}, want: "db-pasword"},
+		{name: "argument", args: []string{"serve"}, want: `unexpected argument "serve"`},
+		{name: "unknown file setting", args: []string{"-config", unknown}, want: "field pasword not found"},
+		{name: "not YAML", args: []string{"-config", "config.json"}, want: "only YAML files are supported"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := config.Load(test.args, env(test.env))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*config.Config)
+		want   []string
+	}{
+		{name: "port too high", change: func(c *config.Config) { c.Database.Port = 65536 }, want: []string{"database.port 65536 is out of range"}},
+		{name: "negative port", change: func(c *config.Config) { c.Database.Port = -1 }, want: []string{"database.port -1 is out of range"}},
+		{name: "negative timeout", change: func(c *config.Config) { c.Server.ShutdownTimeout = -time.Second }, want: []string{"server.shutdownTimeout must not be negative"}},
+		{name: "zero quote ttl", change: func(c *config.Config) { c.Quote.TTL = 0 }, want: []string{"quote.ttl must be positive"}},
+		{name: "zero intent ttl", change: func(c *config.Config) { c.Payment.IntentTTL = 0 }, want: []string{"payment.intentTTL must be positive"}},
+		{name: "unknown driver", change: func(c *config.Config) { c.Database.Driver = "oracle" }, want: []string{`database.driver "oracle" is not one of mysql, postgres, sqlite3, memory`}},
+		{name: "unknown gateway", change: func(c *config.Config) { c.Payment.Gateway = "stripe" }, want: []string{`payment.gateway "stripe" is not one of none, fake`}},
+		{name: "unknown log level", change: func(c *config.Config) { c.Log.Level = "loud" }, want: []string{`log.level "loud"`}},
+		{name: "unknown exporter", change: func(c *config.Config) { c.Tracing.Exporter = "jaeger" }, want: []string{`tracing.exporter "jaeger"`}},
+		{name: "unknown feature", change: func(c *config.Config) { c.Features["beta"] = true }, want: []string{`unknown feature "beta"`}},
+		{name: "sqlite without a file", change: func(c *config.Config) { c.Database.Driver, c.Database.Path = "sqlite3", "" }, want: []string{"database.path is required for sqlite3"}},
+		{
+			name: "all errors at once",
+			change: func(c *config.Config) {
+				c.Database.Port, c.Quote.TTL, c.Payment.Gateway = 99999, -time.Minute, "stripe"
+			},
+			want: []string{"database.port 99999", "quote.ttl", "payment.gateway"},
+		},
+	}
+
+	if err := config.Default().Validate(); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.Default()
+			test.change(cfg)
+
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("no error")
+			}
+			for _, want := range test.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("got %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Feature toggles.
+const (
+	// FeatureExports serves the CSV, JSON Lines and XLSX export endpoints.
+	FeatureExports = "exports"
+)
+
+var knownFeatures = []string{FeatureExports}
+
+// Features holds the state of the feature toggles by name.
+type Features map[string]bool
+
+func (f Features) Enabled(name string) bool {
+	return f[name]
+}
+
+// Set applies a comma separated list such as "exports,-beta". A name turns a
+// feature on and a name prefixed with "-" turns it off; features that are not
+// listed keep their state.
+func (f *Features) Set(value string) error {
+	if *f == nil {
+		*f = Features{}
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		enabled := !strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if name == "" {
+			return fmt.Errorf("empty feature name in %q", value)
+		}
+
+		(*f)[name] = enabled
+	}
+
+	return nil
+}
+
+func (f Features) String() string {
+	names := []string{}
+	for _, name := range knownFeatures {
+		if f[name] {
+			names = append(names, name)
+		} else {
+			names = append(names, "-"+name)
+		}
+	}
+
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileSuffix marks an environment variable that names a file holding the
+// value, the way Docker and Kubernetes secrets are mounted. DB_PASSWORD_FILE
+// is read when DB_PASSWORD is not set.
+const fileSuffix = "_FILE"
+
+// Load builds the configuration from the command-line arguments (without the
+// program name) and the environment. The YAML file is named by the -config
+// flag or the CONFIG_FILE variable. Secrets have no flags so that they do not
+// show up in the process list.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	fields := settings(reflect.ValueOf(cfg).Elem())
+
+	flags := flag.NewFlagSet("travel", flag.ContinueOnError)
+	configFile := flags.String("config", "", "YAML configuration file")
+	for _, field := range fields {
+		if field.flag != "" {
+			flags.String(field.flag, field.String(), field.usage)
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("config: unexpected argument %q", flags.Arg(0))
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range fields {
+		value, ok, err := lookupSetting(field.env, lookupEnv)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := field.set(value); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", field.env, err)
+		}
+	}
+
+	var errs []error
+	flags.Visit(func(f *flag.Flag) {
+		for _, field := range fields {
+			if field.flag == f.Name {
+				if err := field.set(f.Value.String()); err != nil {
+					errs = append(errs, fmt.Errorf("config: -%s: %w", f.Name, err))
+				}
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("config: %s: only YAML files are supported", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// lookupSetting reads the variable name or, when it is not set, the file
+// named by name_FILE.
+func lookupSetting(name string, lookupEnv func(string) (string, bool)) (string, bool, error) {
+	if name == "" {
+		return "", false, nil
+	}
+
+	if value, ok := lookupEnv(name); ok {
+		return value, true, nil
+	}
+
+	path, ok := lookupEnv(name + fileSuffix)
+	if !ok {
+		return "", false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("config: %s%s: %w", name, fileSuffix, err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// setting is a configurable field of Config.
+type setting struct {
+	value reflect.Value
+	env   string
+	flag  string
+	usage string
+}
+
+func settings(v reflect.Value) []setting {
+	result := []setting{}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			result = append(result, settings(value)...)
+			continue
+		}
+
+		result = append(result, setting{
+			value: value,
+			env:   field.Tag.Get("env"),
+			flag:  field.Tag.Get("flag"),
+			usage: field.Tag.Get("usage"),
+		})
+	}
+
+	return result
+}
+
+func (s setting) set(raw string) error {
+	if value, ok := s.value.Addr().Interface().(flag.Value); ok {
+		return value.Set(raw)
+	}
+
+	switch s.value.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		s.value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+
+	return nil
+}
+
+func (s setting) String() string {
+	if value, ok := s.value.Addr().Interface().(flag.Value); ok {
+		return value.String()
+	}
+
+	return fmt.Sprint(s.value.Interface())
+}
@@ -0,0 +1,110 @@
+// Package database opens the configured SQL backend and migrates its schema.
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"travel/internal/config"
+	"travel/migrations"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Open connects to the database of cfg, creating it on MySQL and PostgreSQL
+// servers when it is missing.
+func Open(cfg config.Database) (*sql.DB, error) {
+	if err := create(cfg); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(cfg.Driver, cfg.DSN(cfg.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// create makes the database on the server. The name is validated by the
+// config, statements like these take no placeholders.
+func create(cfg config.Database) error {
+	var statement string
+	switch cfg.Driver {
+	case "mysql":
+		statement = "CREATE DATABASE IF NOT EXISTS " + cfg.Name
+	case "postgres":
+		// PostgreSQL has no CREATE DATABASE IF NOT EXISTS
+		statement = "CREATE DATABASE " + cfg.Name
+	default:
+		return nil
+	}
+
+	db, err := sql.Open(cfg.Driver, cfg.DSN(""))
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	if cfg.Driver == "postgres" {
+		var exists bool
+		err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", cfg.Name).Scan(&exists)
+		if err != nil || exists {
+			return err
+		}
+	}
+
+	_, err = db.Exec(statement)
+	return err
+}
+
+// OpenSource returns the migrations of the configured database driver, the
+// ones compiled into the binary or the ones at the migrations path.
+func OpenSource(cfg *config.Config) (source.Driver, error) {
+	if cfg.Migrations.Embedded {
+		return iofs.New(migrations.FS, cfg.Database.Driver)
+	}
+
+	return source.Open(cfg.MigrationsPath())
+}
+
+// NewMigrate returns the migrations of src, applied to db with the
+// golang-migrate driver of cfg. Closing it closes src as well.
+func NewMigrate(db *sql.DB, cfg config.Database, src source.Driver) (*migrate.Migrate, error) {
+	var driver migratedb.Driver
+	var err error
+	switch cfg.Driver {
+	case "mysql":
+		driver, err = mysql.WithInstance(db, &mysql.Config{DatabaseName: cfg.Name})
+	case "postgres":
+		driver, err = postgres.WithInstance(db, &postgres.Config{DatabaseName: cfg.Name})
+	case "sqlite3":
+		driver, err = sqlite3.WithInstance(db, &sqlite3.Config{})
+	default:
+		err = fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("source", src, cfg.Driver, driver)
+}
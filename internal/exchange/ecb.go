@@ -0,0 +1,65 @@
+package exchange
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidECB = errors.New("invalid ECB reference rates")
+
+// ECBBase is the base currency of the ECB reference rates.
+const ECBBase = "EUR"
+
+// ecbEnvelope is the eurofxref XML of the ECB, e.g.
+//
+//	<gesmes:Envelope ...>
+//	  <Cube>
+//	    <Cube time="2024-05-17">
+//	      <Cube currency="USD" rate="1.0866"/>
+//	      ...
+//
+// The history files hold one dated cube per day, newest first.
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECB reads the newest day of ECB reference rates, as published at
+// https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml.
+func ParseECB(r io.Reader) (*Rates, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidECB, err)
+	}
+	if len(envelope.Days) == 0 {
+		return nil, fmt.Errorf("%w: no rates", ErrInvalidECB)
+	}
+
+	day := envelope.Days[0]
+	date, err := time.Parse(time.DateOnly, day.Time)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidECB, err)
+	}
+
+	rates := &Rates{Base: ECBBase, Date: date, Rates: map[string]float64{}}
+	for _, rate := range day.Rates {
+		currency := strings.ToUpper(strings.TrimSpace(rate.Currency))
+		value, err := strconv.ParseFloat(strings.TrimSpace(rate.Rate), 64)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("%w: rate %q of %s", ErrInvalidECB, rate.Rate, currency)
+		}
+		rates.Rates[currency] = value
+	}
+
+	return rates, nil
+}
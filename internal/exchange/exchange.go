@@ -0,0 +1,140 @@
+// Package exchange converts amounts of money between currencies with the
+// exchange rates an agency maintains, for showing prices in the currency of
+// the customer. Rates are quoted like the ECB reference rates: units of a
+// currency for one unit of the base currency.
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+	"travel/internal/money"
+)
+
+var (
+	ErrNoRate          = errors.New("no exchange rate")
+	ErrInvalidRounding = errors.New("invalid rounding")
+)
+
+// Rates are the exchange rates of Base on Date.
+type Rates struct {
+	Base  string             `json:"base"`
+	Date  time.Time          `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Rounding modes.
+const (
+	RoundNearest = "nearest"
+	RoundUp      = "up"
+	RoundDown    = "down"
+)
+
+// Rounding is how converted amounts are rounded: to multiples of Step minor
+// units of the target currency, the nearest one (halves away from zero), the
+// next one up or the next one down.
+type Rounding struct {
+	Mode string
+	Step int64
+}
+
+// DefaultRounding rounds to the nearest minor unit, the cent.
+var DefaultRounding = Rounding{Mode: RoundNearest, Step: 1}
+
+func (r Rounding) Validate() error {
+	switch r.Mode {
+	case RoundNearest, RoundUp, RoundDown:
+	default:
+		return fmt.Errorf("%w: mode %q is not one of %s, %s, %s", ErrInvalidRounding, r.Mode, RoundNearest, RoundUp, RoundDown)
+	}
+	if r.Step <= 0 {
+		return fmt.Errorf("%w: step %d is not positive", ErrInvalidRounding, r.Step)
+	}
+
+	return nil
+}
+
+// rate returns the rate of currency, 1 for the base currency.
+func (r Rates) rate(currency string) (*big.Rat, error) {
+	if currency == r.Base {
+		return big.NewRat(1, 1), nil
+	}
+
+	rate, ok := r.Rates[currency]
+	if !ok || rate <= 0 {
+		return nil, fmt.Errorf("%w from %s to %s", ErrNoRate, r.Base, currency)
+	}
+
+	// the rate as written in decimal, not the binary approximation of the float
+	exact, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok {
+		return nil, fmt.Errorf("%w from %s to %s", ErrNoRate, r.Base, currency)
+	}
+
+	return exact, nil
+}
+
+// Convert converts amount into currency, over the base currency when neither
+// is the base. Amounts in currency already are returned as they are.
+func (r Rates) Convert(amount money.Money, currency string, rounding Rounding) (money.Money, error) {
+	if amount.Currency == currency {
+		return amount, nil
+	}
+
+	fromDigits, err := money.Digits(amount.Currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	toDigits, err := money.Digits(currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("%w from %s to %s", ErrNoRate, r.Base, currency)
+	}
+	from, err := r.rate(amount.Currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	to, err := r.rate(currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	// minor units of currency: amount / 10^fromDigits / from * to * 10^toDigits
+	value := new(big.Rat).SetInt64(amount.Amount)
+	value.Mul(value, to)
+	value.Quo(value, from)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toDigits), pow10(fromDigits)))
+
+	return money.Money{Amount: round(value, rounding), Currency: currency}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// round rounds value to a multiple of rounding.Step.
+func round(value *big.Rat, rounding Rounding) int64 {
+	steps := new(big.Rat).Quo(value, new(big.Rat).SetInt64(rounding.Step))
+
+	quotient, remainder := new(big.Int).QuoRem(steps.Num(), steps.Denom(), new(big.Int))
+	if remainder.Sign() != 0 {
+		switch rounding.Mode {
+		case RoundUp:
+			if steps.Sign() > 0 {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		case RoundDown:
+			if steps.Sign() < 0 {
+				quotient.Sub(quotient, big.NewInt(1))
+			}
+		default:
+			twice := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+			if twice.Cmp(steps.Denom()) >= 0 {
+				quotient.Add(quotient, big.NewInt(int64(steps.Sign())))
+			}
+		}
+	}
+
+	return quotient.Int64() * rounding.Step
+}
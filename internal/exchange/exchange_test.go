@@ -0,0 +1,146 @@
+package exchange_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"travel/internal/exchange"
+	"travel/internal/money"
+)
+
+var rates = exchange.Rates{
+	Base:  "EUR",
+	Rates: map[string]float64{"USD": 1.0866, "GBP": 0.8541, "JPY": 169.21, "CAD": 1.5},
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   money.Money
+		currency string
+		rounding exchange.Rounding
+		want     money.Money
+	}{
+		{
+			name:   "from the base",
+			amount: money.Money{Amount: 90050, Currency: "EUR"}, currency: "GBP", rounding: exchange.DefaultRounding,
+			want: money.Money{Amount: 76912, Currency: "GBP"},
+		},
+		{
+			name:   "to the base",
+			amount: money.Money{Amount: 10866, Currency: "USD"}, currency: "EUR", rounding: exchange.DefaultRounding,
+			want: money.Money{Amount: 10000, Currency: "EUR"},
+		},
+		{
+			name:   "over the base",
+			amount: money.Money{Amount: 10866, Currency: "USD"}, currency: "GBP", rounding: exchange.DefaultRounding,
+			want: money.Money{Amount: 8541, Currency: "GBP"},
+		},
+		{
+			name:   "into a currency without minor units",
+			amount: money.Money{Amount: 100, Currency: "EUR"}, currency: "JPY", rounding: exchange.DefaultRounding,
+			want: money.Money{Amount: 169, Currency: "JPY"},
+		},
+		{
+			name:   "half a cent rounds away from zero",
+			amount: money.Money{Amount: 1, Currency: "EUR"}, currency: "CAD", rounding: exchange.DefaultRounding,
+			want: money.Money{Amount: 2, Currency: "CAD"},
+		},
+		{
+			name:   "discounts round away from zero too",
+			amount: money.Money{Amount: -1, Currency: "EUR"}, currency: "CAD", rounding: exchange.DefaultRounding,
+			want: money.Money{Amount: -2, Currency: "CAD"},
+		},
+		{
+			name:   "up to whole units",
+			amount: money.Money{Amount: 90050, Currency: "EUR"}, currency: "GBP", rounding: exchange.Rounding{Mode: exchange.RoundUp, Step: 100},
+			want: money.Money{Amount: 77000, Currency: "GBP"},
+		},
+		{
+			name:   "down to whole units",
+			amount: money.Money{Amount: 90050, Currency: "EUR"}, currency: "GBP", rounding: exchange.Rounding{Mode: exchange.RoundDown, Step: 100},
+			want: money.Money{Amount: 76900, Currency: "GBP"},
+		},
+		{
+			name:   "same currency",
+			amount: money.Money{Amount: 12345, Currency: "GBP"}, currency: "GBP", rounding: exchange.Rounding{Mode: exchange.RoundUp, Step: 100},
+			want: money.Money{Amount: 12345, Currency: "GBP"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := rates.Convert(test.amount, test.currency, test.rounding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestConvertWithoutRate(t *testing.T) {
+	for _, currency := range []string{"CHF", "XXX", ""} {
+		_, err := rates.Convert(money.Money{Amount: 100, Currency: "EUR"}, currency, exchange.DefaultRounding)
+		if !errors.Is(err, exchange.ErrNoRate) {
+			t.Errorf("%q: got %v, want %v", currency, err, exchange.ErrNoRate)
+		}
+	}
+}
+
+func TestRoundingValidate(t *testing.T) {
+	if err := exchange.DefaultRounding.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rounding := range []exchange.Rounding{{Mode: "ceil", Step: 1}, {Mode: exchange.RoundUp}, {Mode: exchange.RoundUp, Step: -5}} {
+		if err := rounding.Validate(); !errors.Is(err, exchange.ErrInvalidRounding) {
+			t.Errorf("%+v: got %v, want %v", rounding, err, exchange.ErrInvalidRounding)
+		}
+	}
+}
+
+const ecbDaily = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time='2024-05-17'>
+			<Cube currency='USD' rate='1.0866'/>
+			<Cube currency='JPY' rate='169.21'/>
+			<Cube currency='GBP' rate='0.8541'/>
+		</Cube>
+		<Cube time='2024-05-16'>
+			<Cube currency='USD' rate='1.0856'/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestParseECB(t *testing.T) {
+	got, err := exchange.ParseECB(strings.NewReader(ecbDaily))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Base != "EUR" || !got.Date.Equal(time.Date(2024, time.May, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %s on %s", got.Base, got.Date)
+	}
+	if len(got.Rates) != 3 || got.Rates["USD"] != 1.0866 || got.Rates["GBP"] != 0.8541 {
+		t.Errorf("got %v", got.Rates)
+	}
+
+	for _, invalid := range []string{
+		`not xml`,
+		`<Envelope><Cube></Cube></Envelope>`,
+		`<Envelope><Cube><Cube time="2024-05-17"><Cube currency="USD" rate="much"/></Cube></Cube></Envelope>`,
+	} {
+		if _, err := exchange.ParseECB(strings.NewReader(invalid)); !errors.Is(err, exchange.ErrInvalidECB) {
+			t.Errorf("%s: got %v, want %v", invalid, err, exchange.ErrInvalidECB)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// flushEvery is how many rows the text formats buffer before pushing them to
+// the client.
+const flushEvery = 100
+
+// csvWriter writes the header row with the first row or on Close, like
+// xlsxWriter.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	rows    int
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	return &csvWriter{w: csv.NewWriter(w), columns: columns}, nil
+}
+
+// header writes the column names unless a row already did.
+func (c *csvWriter) header() error {
+	if c.columns == nil {
+		return nil
+	}
+
+	columns := c.columns
+	c.columns = nil
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Write(record Record) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+
+	values := record.Values()
+	line := make([]string, len(values))
+	for i, value := range values {
+		line[i] = formatValue(value)
+		if _, text := value.(string); text {
+			line[i] = defuse(line[i])
+		}
+	}
+
+	if err := c.w.Write(line); err != nil {
+		return err
+	}
+
+	c.rows++
+	if c.rows%flushEvery == 0 {
+		c.w.Flush()
+		return c.w.Error()
+	}
+
+	return nil
+}
+
+// defuse keeps spreadsheets from reading a text cell as a formula by quoting
+// it when it starts like one. Numbers are not text and stay as they are.
+func defuse(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
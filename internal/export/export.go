@@ -0,0 +1,86 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"travel/internal/money"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Record is a single exported row. Values must follow the column order the
+// Writer was created with; JSON Lines encodes the record itself.
+type Record interface {
+	Values() []interface{}
+}
+
+// Writer writes records one by one straight to the underlying io.Writer.
+// Close must be called to flush whatever the format keeps buffered.
+type Writer interface {
+	Write(record Record) error
+	Close() error
+}
+
+func New(format string, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	return "application/octet-stream"
+}
+
+// formatValue turns a record value into the text used by csv and by the
+// string cells of xlsx.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case *int:
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case money.Money:
+		// the currency has a column of its own
+		return v.Decimal()
+	case time.Time:
+		return v.Format(time.DateOnly)
+	case nil:
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,308 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"travel/internal/export"
+	"travel/internal/money"
+)
+
+// row is a record with one value of every kind the exports write.
+type row struct {
+	ID         int         `json:"id"`
+	Title      string      `json:"title"`
+	Price      money.Money `json:"price"`
+	StartDate  time.Time   `json:"startDate"`
+	CustomerID *int        `json:"customerID"`
+	Rate       float64     `json:"rate"`
+}
+
+func (r row) Values() []interface{} {
+	return []interface{}{r.ID, r.Title, r.Price, r.StartDate, r.CustomerID, r.Rate}
+}
+
+var columns = []string{"id", "title", "price", "startDate", "customerID", "rate"}
+
+func rows() []export.Record {
+	customerID := 7
+	return []export.Record{
+		row{ID: 1, Title: "Ski week", Price: money.Money{Amount: 65000, Currency: "EUR"}, StartDate: time.Date(2030, time.January, 10, 0, 0, 0, 0, time.UTC), CustomerID: &customerID, Rate: 1.0866},
+		// separators, quotes, line breaks and markup are escaped
+		row{ID: 2, Title: "Sea, \"sun\" & <sand>\nand more", Price: money.Money{Amount: -5, Currency: "EUR"}},
+	}
+}
+
+func write(t *testing.T, format string, records []export.Record) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	w, err := export.New(format, &out, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	data := write(t, export.FormatCSV, rows())
+
+	got, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, data)
+	}
+
+	want := [][]string{
+		columns,
+		{"1", "Ski week", "650.00", "2030-01-10", "7", "1.0866"},
+		{"2", "Sea, \"sun\" & <sand>\nand more", "-0.05", "0001-01-01", "", "0"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines:\n%s", len(got), data)
+	}
+	for i := range want {
+		if strings.Join(got[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("line %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+	if !strings.Contains(string(data), `"Sea, ""sun"" & <sand>`) {
+		t.Errorf("title not quoted:\n%s", data)
+	}
+}
+
+func TestCSVFormulas(t *testing.T) {
+	data := write(t, export.FormatCSV, []export.Record{
+		row{ID: 1, Title: "=HYPERLINK(\"http://evil\")"},
+		row{ID: 2, Title: "+1-555"},
+		row{ID: 3, Title: "-2+3"},
+		row{ID: 4, Title: "@SUM(A1)"},
+		row{ID: 5, Title: "Ski - week = fun"},
+		row{ID: 6, Price: money.Money{Amount: -5, Currency: "EUR"}},
+	})
+
+	got, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, data)
+	}
+
+	want := []string{"title", "'=HYPERLINK(\"http://evil\")", "'+1-555", "'-2+3", "'@SUM(A1)", "Ski - week = fun", ""}
+	for i := range want {
+		if got[i][1] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i, got[i][1], want[i])
+		}
+	}
+	// numbers are not text and keep their sign
+	if got[6][2] != "-0.05" {
+		t.Errorf("got price %q", got[6][2])
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	data := write(t, export.FormatJSONL, rows())
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines:\n%s", len(lines), data)
+	}
+
+	// every line decodes back to its record, there is no header
+	for i, line := range lines {
+		var got row
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		want := rows()[i].(row)
+		if got.ID != want.ID || got.Title != want.Title || got.Price != want.Price {
+			t.Errorf("line %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	data := write(t, export.FormatXLSX, rows())
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[file.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("%s missing", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, cell := range []string{
+		`<c r="A1" t="inlineStr"><is><t>id</t></is></c>`,
+		// numbers and amounts are numeric cells
+		`<c r="A2"><v>1</v></c>`, `<c r="C2"><v>650.00</v></c>`, `<c r="E2"><v>7</v></c>`, `<c r="F2"><v>1.0866</v></c>`,
+		`<c r="D2" t="inlineStr"><is><t>2030-01-10</t></is></c>`,
+		`<t>Sea, &#34;sun&#34; &amp; &lt;sand&gt;&#xA;and more</t>`,
+		// a missing customer is an empty cell
+		`<c r="E3" t="inlineStr"><is><t></t></is></c>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("%s missing from\n%s", cell, sheet)
+		}
+	}
+	if !strings.HasSuffix(sheet, `</sheetData></worksheet>`) {
+		t.Error("sheet not closed")
+	}
+}
+
+// wide has more columns than letters.
+type wide []interface{}
+
+func (w wide) Values() []interface{} { return w }
+
+func TestXLSXColumnNames(t *testing.T) {
+	var out bytes.Buffer
+	names := make([]string, 28)
+	values := make(wide, 28)
+	for i := range names {
+		names[i] = "c" + strconv.Itoa(i)
+		values[i] = i
+	}
+
+	w, err := export.New(export.FormatXLSX, &out, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(values); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range archive.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, _ := file.Open()
+		sheet, _ := io.ReadAll(r)
+		r.Close()
+		for _, cell := range []string{`<c r="Z2"><v>25</v></c>`, `<c r="AA2"><v>26</v></c>`, `<c r="AB2"><v>27</v></c>`} {
+			if !bytes.Contains(sheet, []byte(cell)) {
+				t.Errorf("%s missing", cell)
+			}
+		}
+	}
+}
+
+// TestNothingBeforeFirstRow checks that a writer sends nothing until it has
+// a row or is closed, so that an export failing early can still report the
+// error instead of a broken file.
+func TestNothingBeforeFirstRow(t *testing.T) {
+	for _, format := range []string{export.FormatCSV, export.FormatJSONL, export.FormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			// enough columns for the header not to fit in a write buffer
+			many := make([]string, 2000)
+			for i := range many {
+				many[i] = "column " + strconv.Itoa(i*7919%10007)
+			}
+
+			var out bytes.Buffer
+			w, err := export.New(format, &out, many)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("%d bytes written before the first row", out.Len())
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if format == export.FormatXLSX {
+				if _, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len())); err != nil {
+					t.Errorf("empty workbook: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := export.New("pdf", io.Discard, columns); !errors.Is(err, export.ErrUnknownFormat) {
+		t.Errorf("got %v, want %v", err, export.ErrUnknownFormat)
+	}
+	if got := export.ContentType("pdf"); got != "application/octet-stream" {
+		t.Errorf("got %s", got)
+	}
+}
+
+// failing accepts limit bytes and fails every write after.
+type failing struct {
+	limit   int
+	written int
+}
+
+var errBroken = errors.New("connection reset")
+
+func (f *failing) Write(p []byte) (int, error) {
+	if f.written+len(p) > f.limit {
+		return 0, errBroken
+	}
+	f.written += len(p)
+	return len(p), nil
+}
+
+// TestWriteFailure checks that a client going away mid-stream is reported by
+// Write or Close, whatever the format buffers.
+func TestWriteFailure(t *testing.T) {
+	for _, format := range []string{export.FormatCSV, export.FormatJSONL, export.FormatXLSX} {
+		t.Run(format, func(t *testing.T) {
+			out := &failing{limit: 2048}
+			w, err := export.New(format, out, columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 1000 && err == nil; i++ {
+				err = w.Write(row{ID: i, Title: strings.Repeat("x", 50)})
+			}
+			if err == nil {
+				err = w.Close()
+			}
+			if !errors.Is(err, errBroken) {
+				t.Errorf("got %v, want %v", err, errBroken)
+			}
+		})
+	}
+}
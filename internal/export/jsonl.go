@@ -0,0 +1,37 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+type jsonlWriter struct {
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	rows int
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonlWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+// Write encodes the record as one JSON object per line, the same shape the
+// list endpoints return.
+func (j *jsonlWriter) Write(record Record) error {
+	if err := j.enc.Encode(record); err != nil {
+		return err
+	}
+
+	j.rows++
+	if j.rows%flushEvery == 0 {
+		return j.buf.Flush()
+	}
+
+	return nil
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.buf.Flush()
+}
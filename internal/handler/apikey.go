@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type RequestAPIKey struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func (h *apiHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.APIKeyGetAll(r.Context())
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, keys, http.StatusOK)
+}
+
+func (h *apiHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	data := RequestAPIKey{}
+
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(r.Context(), data.Name, data.Role)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, key, http.StatusCreated)
+}
+
+// recive the id only
+func (h *apiHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.service.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, key, http.StatusOK)
+}
+
+// recive the id only
+func (h *apiHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.service.RotateAPIKey(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, key, http.StatusCreated)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"travel/internal/service"
+)
+
+// GetAuditLog returns the history of one record, ?resource=holiday&id=7, or of
+// every record of a resource when id is left out.
+func (h *apiHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	var id int
+	var err error
+
+	if strings.TrimSpace(r.FormValue("id")) != "" {
+		id, err = strconv.Atoi(r.FormValue("id"))
+		if err != nil {
+			h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := h.service.AuditLog(r.Context(), r.FormValue("resource"), id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAuditResourceUnknown) {
+			status = http.StatusBadRequest
+		}
+		h.errorResponseWrite(w, r, err, status)
+		return
+	}
+
+	jsonResponseWrite(w, entries, http.StatusOK)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"travel/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *apiHandler) GetCustomers(w http.ResponseWriter, r *http.Request) {
+	customers, err := h.service.CustomerGetAll(r.Context())
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, customers, http.StatusOK)
+}
+
+// recive the id only
+func (h *apiHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.service.Customer(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, customer, http.StatusOK)
+}
+
+func (h *apiHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	customer := service.CustomerDTO{}
+
+	err := json.NewDecoder(r.Body).Decode(&customer)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	idResult, err := h.service.InsertCustomer(r.Context(), customer)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, idResult, http.StatusOK)
+}
+
+func (h *apiHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	customer := service.CustomerDTO{}
+
+	err := json.NewDecoder(r.Body).Decode(&customer)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	updatedCustomer, err := h.service.UpdateCustomer(r.Context(), customer)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, updatedCustomer, http.StatusOK)
+}
+
+// recive the id only
+func (h *apiHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.service.DeleteCustomer(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, customer, http.StatusOK)
+}
+
+func (h *apiHandler) GetCustomerReservations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	reservations, err := h.service.CustomerReservations(r.Context(), id, r.FormValue("currency"))
+	if err != nil {
+		h.errorResponseWrite(w, r, err, conversionStatus(err))
+		return
+	}
+
+	jsonResponseWrite(w, reservations, http.StatusOK)
+}
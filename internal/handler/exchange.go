@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"travel/internal/exchange"
+	"travel/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *apiHandler) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.service.ExchangeRateGetAll(r.Context())
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, rates, http.StatusOK)
+}
+
+// SaveExchangeRate sets the rate of one currency, creating it when the agency
+// has none yet.
+func (h *apiHandler) SaveExchangeRate(w http.ResponseWriter, r *http.Request) {
+	rate := service.ExchangeRateDTO{}
+
+	err := json.NewDecoder(r.Body).Decode(&rate)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.SaveExchangeRate(r.Context(), rate)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, result, http.StatusOK)
+}
+
+// ImportExchangeRates reads ECB reference rates XML from the body, e.g. the
+// eurofxref-daily.xml as downloaded.
+func (h *apiHandler) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.service.ImportExchangeRates(r.Context(), r.Body)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, rates, http.StatusOK)
+}
+
+func (h *apiHandler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	rate, err := h.service.DeleteExchangeRate(r.Context(), vars["currency"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, rate, http.StatusOK)
+}
+
+// conversionStatus is the status of a failed read, 400 when the prices could
+// not be shown in the requested currency.
+func conversionStatus(err error) int {
+	if errors.Is(err, exchange.ErrNoRate) || errors.Is(err, service.ErrPricingNotConvertible) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"travel/internal/export"
+	"travel/internal/storage"
+)
+
+var holidayExportColumns = []string{
+	"id", "title", "startDate", "duration", "price", "currency", "freeSlots",
+	"locationID", "street", "number", "city", "country",
+}
+
+var reservationExportColumns = []string{
+	"id", "contactName", "phoneNumber", "phoneE164", "customerID",
+	"holidayID", "holidayTitle", "startDate", "duration", "price",
+	"currency", "locationID", "city", "country",
+}
+
+type holidayRecord storage.HolidayWithLocation
+
+func (h holidayRecord) Values() []interface{} {
+	return []interface{}{
+		h.ID, h.Title, h.StartDate, h.Duration, h.Price, h.Price.Currency, h.FreeSlots,
+		h.Location.ID, h.Location.Street, h.Location.Number, h.Location.City, h.Location.Country,
+	}
+}
+
+type reservationRecord storage.ReservationResult
+
+func (r reservationRecord) Values() []interface{} {
+	return []interface{}{
+		r.ID, r.ContactName, r.PhoneNumber, r.PhoneE164, r.CustomerID,
+		r.Holiday.ID, r.Holiday.Title, r.Holiday.StartDate, r.Holiday.Duration, r.Holiday.Price,
+		r.Holiday.Price.Currency, r.Holiday.Location.ID, r.Holiday.Location.City, r.Holiday.Location.Country,
+	}
+}
+
+func (h *apiHandler) ExportHolidays(w http.ResponseWriter, r *http.Request) {
+	filter, err := holidayFilter(r)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	h.exportResponseWrite(w, r, "holidays", holidayExportColumns, func(write func(export.Record) error) error {
+		return h.service.ExportHolidays(r.Context(), filter, func(holiday storage.HolidayWithLocation) error {
+			return write(holidayRecord(holiday))
+		})
+	})
+}
+
+func (h *apiHandler) ExportReservations(w http.ResponseWriter, r *http.Request) {
+	h.exportResponseWrite(w, r, "reservations", reservationExportColumns, func(write func(export.Record) error) error {
+		return h.service.ExportReservations(r.Context(), func(reservation storage.ReservationResult) error {
+			return write(reservationRecord(reservation))
+		})
+	})
+}
+
+// exportResponseWrite streams the records produced by stream in the format
+// requested with ?format= (csv by default) as a file download. Errors raised
+// before anything reached the client are still reported as JSON.
+func (h *apiHandler) exportResponseWrite(w http.ResponseWriter, r *http.Request, name string, columns []string, stream func(write func(export.Record) error) error) {
+	format := r.FormValue("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	out := &trackingResponseWriter{ResponseWriter: w}
+
+	writer, err := export.New(format, out, columns)
+	if err != nil {
+		if errors.Is(err, export.ErrUnknownFormat) {
+			h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+			return
+		}
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102"), format)
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+	err = stream(writer.Write)
+	if err == nil {
+		err = writer.Close()
+	}
+
+	if err != nil {
+		if out.written {
+			// the status line is already out, all we can do is cut the stream short
+			h.logger.ErrorContext(r.Context(), "export failed after the first row", "export", name, "error", err)
+			return
+		}
+
+		w.Header().Del("Content-Disposition")
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+type trackingResponseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (c *trackingResponseWriter) Write(p []byte) (int, error) {
+	c.written = true
+	return c.ResponseWriter.Write(p)
+}
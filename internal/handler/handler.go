@@ -1,54 +1,152 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+	"travel/internal/auth"
+	"travel/internal/money"
 	"travel/internal/service"
+	"travel/internal/storage"
+	"travel/internal/tenant"
 
 	"github.com/gorilla/mux"
 )
 
 type Service interface {
-	ReservationGetAll() (interface{}, error)
-	Reservation(reservationID int) (*service.ReservationDTO, error)
-	InsertReservation(reservation service.ReservationDTO) (int64, error)
-	UpdateReservation(reservation service.ReservationDTO) (*service.ReservationDTO, error)
-	DeleteReservation(reservationID int) (*service.ReservationDTO, error)
-
-	LocationGetAll() ([]service.LocationDTO, error)
-	Location(locationID int) (*service.LocationDTO, error)
-	InsertLocation(Location service.LocationDTO) (int64, error)
-	UpdateLocation(Location service.LocationDTO) (*service.LocationDTO, error)
-	DeleteLocation(locationID int) (*service.LocationDTO, error)
-
-	HolidayGetAll(filterDTO service.FilterHolidays) (interface{}, error)
-	Holiday(holidayID int) (*service.HolidayDTO, error)
-	InsertHoliday(Holiday service.HolidayDTO) (int64, error)
-	UpdateHoliday(Holiday service.HolidayDTO) (*service.HolidayDTO, error)
-	DeleteHoliday(holidayID int) (*service.HolidayDTO, error)
+	ReservationGetAll(ctx context.Context, currency string) (interface{}, error)
+	ExportReservations(ctx context.Context, fn func(storage.ReservationResult) error) error
+	Reservation(ctx context.Context, reservationID int, currency string) (*service.ReservationDTO, error)
+	InsertReservation(ctx context.Context, reservation service.ReservationDTO) (int64, error)
+	UpdateReservation(ctx context.Context, reservation service.ReservationDTO) (*service.ReservationDTO, error)
+	DeleteReservation(ctx context.Context, reservationID int) (*service.ReservationDTO, error)
+
+	CustomerGetAll(ctx context.Context) ([]service.CustomerDTO, error)
+	Customer(ctx context.Context, customerID int) (*service.CustomerDTO, error)
+	InsertCustomer(ctx context.Context, customer service.CustomerDTO) (int64, error)
+	UpdateCustomer(ctx context.Context, customer service.CustomerDTO) (*service.CustomerDTO, error)
+	DeleteCustomer(ctx context.Context, customerID int) (*service.CustomerDTO, error)
+	CustomerReservations(ctx context.Context, customerID int, currency string) ([]storage.ReservationResult, error)
+
+	APIKeyGetAll(ctx context.Context) ([]service.APIKeyDTO, error)
+	CreateAPIKey(ctx context.Context, name string, role string) (*service.APIKeyDTO, error)
+	RevokeAPIKey(ctx context.Context, keyID int) (*service.APIKeyDTO, error)
+	RotateAPIKey(ctx context.Context, keyID int) (*service.APIKeyDTO, error)
+
+	LocationGetAll(ctx context.Context) ([]service.LocationDTO, error)
+	Location(ctx context.Context, locationID int) (*service.LocationDTO, error)
+	InsertLocation(ctx context.Context, Location service.LocationDTO) (int64, error)
+	UpdateLocation(ctx context.Context, Location service.LocationDTO) (*service.LocationDTO, error)
+	DeleteLocation(ctx context.Context, locationID int) (*service.LocationDTO, error)
+
+	HolidayGetAll(ctx context.Context, filterDTO service.FilterHolidays) (interface{}, error)
+	ExportHolidays(ctx context.Context, filterDTO service.FilterHolidays, fn func(storage.HolidayWithLocation) error) error
+	Holiday(ctx context.Context, holidayID int, currency string) (*service.HolidayDTO, error)
+	Quote(ctx context.Context, holidayID int, request service.QuoteRequestDTO) (*service.QuoteDTO, error)
+	InsertHoliday(ctx context.Context, Holiday service.HolidayDTO) (int64, error)
+	UpdateHoliday(ctx context.Context, Holiday service.HolidayDTO) (*service.HolidayDTO, error)
+	DeleteHoliday(ctx context.Context, holidayID int) (*service.HolidayDTO, error)
+
+	PricingRuleSetGetAll(ctx context.Context) ([]service.PricingRuleSetDTO, error)
+	PricingRuleSet(ctx context.Context, ruleSetID int) (*service.PricingRuleSetDTO, error)
+	InsertPricingRuleSet(ctx context.Context, ruleSet service.PricingRuleSetDTO) (int64, error)
+	UpdatePricingRuleSet(ctx context.Context, ruleSet service.PricingRuleSetDTO) (*service.PricingRuleSetDTO, error)
+	DeletePricingRuleSet(ctx context.Context, ruleSetID int) (*service.PricingRuleSetDTO, error)
+
+	ExchangeRateGetAll(ctx context.Context) ([]service.ExchangeRateDTO, error)
+	SaveExchangeRate(ctx context.Context, rate service.ExchangeRateDTO) (*service.ExchangeRateDTO, error)
+	ImportExchangeRates(ctx context.Context, r io.Reader) ([]service.ExchangeRateDTO, error)
+	DeleteExchangeRate(ctx context.Context, currency string) (*service.ExchangeRateDTO, error)
+
+	PromoCodeGetAll(ctx context.Context) ([]service.PromoCodeDTO, error)
+	PromoCode(ctx context.Context, promoCodeID int) (*service.PromoCodeDTO, error)
+	PromoCodeRedemptions(ctx context.Context, promoCodeID int) (*service.PromoRedemptionsDTO, error)
+	InsertPromoCode(ctx context.Context, code service.PromoCodeDTO) (int64, error)
+	UpdatePromoCode(ctx context.Context, code service.PromoCodeDTO) (*service.PromoCodeDTO, error)
+	DeletePromoCode(ctx context.Context, promoCodeID int) (*service.PromoCodeDTO, error)
+
+	ReservationPayments(ctx context.Context, reservationID int) (*service.PaymentsDTO, error)
+	Payment(ctx context.Context, paymentID int) (*service.PaymentDTO, error)
+	CreatePayment(ctx context.Context, reservationID int, request service.PaymentRequestDTO) (*service.PaymentDTO, error)
+	RefundPayment(ctx context.Context, paymentID int, request service.RefundRequestDTO) (*service.PaymentDTO, error)
+	HandlePaymentEvent(ctx context.Context, body []byte, signature string) (*service.PaymentDTO, error)
+
+	IssueInvoice(ctx context.Context, reservationID int) (*service.InvoiceDTO, error)
+	ReservationInvoice(ctx context.Context, reservationID int) (*service.InvoiceDTO, error)
+	ReservationInvoices(ctx context.Context, reservationID int) ([]service.InvoiceDTO, error)
+	Invoice(ctx context.Context, invoiceID int) (*service.InvoiceDTO, error)
+
+	AuditLog(ctx context.Context, resource string, resourceID int) ([]service.AuditEntryDTO, error)
 }
 
 type apiHandler struct {
 	service Service
+	logger  *slog.Logger
 }
 
-func New(service Service) http.Handler {
-	handler := &apiHandler{service: service}
+// Options switches optional parts of the API on.
+type Options struct {
+	// Exports serves /holidays/export and /reservations/export.
+	Exports bool
+	// MaxBodyBytes caps the size of request bodies, 0 leaves them unlimited.
+	MaxBodyBytes int64
+	// Middleware runs first on every matched route, before authentication.
+	Middleware []mux.MiddlewareFunc
+	// Logger receives the errors behind 5xx responses, slog.Default() when nil.
+	Logger *slog.Logger
+}
+
+func New(service Service, authenticator *auth.Authenticator, tenants *tenant.Resolver, options Options) http.Handler {
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	handler := &apiHandler{service: service, logger: logger}
 
 	//create route
 	route := mux.NewRouter()
+	route.Use(options.Middleware...)
+	route.Use(limitBody(options.MaxBodyBytes), authenticator.Middleware, tenants.Middleware)
 
 	//holidays
 	route.Methods(http.MethodGet).Path("/holidays").HandlerFunc(handler.GetHolidays)
+	if options.Exports {
+		route.Methods(http.MethodGet).Path("/holidays/export").HandlerFunc(handler.ExportHolidays)
+	}
 	route.Methods(http.MethodGet).Path("/holidays/{id}").HandlerFunc(handler.GetHoliday)
+	route.Methods(http.MethodPost).Path("/holidays/{id}/quote").HandlerFunc(handler.CreateQuote)
 	route.Methods(http.MethodPost).Path("/holidays").HandlerFunc(handler.CreateHoliday)
 	route.Methods(http.MethodPut).Path("/holidays").HandlerFunc(handler.UpdateHoliday)
 	route.Methods(http.MethodDelete).Path("/holidays/{id}").HandlerFunc(handler.DeleteHoliday)
 
+	//pricing rule sets
+	route.Methods(http.MethodGet).Path("/pricing-rule-sets").HandlerFunc(handler.GetPricingRuleSets)
+	route.Methods(http.MethodGet).Path("/pricing-rule-sets/{id}").HandlerFunc(handler.GetPricingRuleSet)
+	route.Methods(http.MethodPost).Path("/pricing-rule-sets").HandlerFunc(handler.CreatePricingRuleSet)
+	route.Methods(http.MethodPut).Path("/pricing-rule-sets").HandlerFunc(handler.UpdatePricingRuleSet)
+	route.Methods(http.MethodDelete).Path("/pricing-rule-sets/{id}").HandlerFunc(handler.DeletePricingRuleSet)
+
+	//exchange rates
+	route.Methods(http.MethodGet).Path("/exchange-rates").HandlerFunc(handler.GetExchangeRates)
+	route.Methods(http.MethodPut).Path("/exchange-rates").HandlerFunc(handler.SaveExchangeRate)
+	route.Methods(http.MethodPost).Path("/exchange-rates/import").HandlerFunc(handler.ImportExchangeRates)
+	route.Methods(http.MethodDelete).Path("/exchange-rates/{currency}").HandlerFunc(handler.DeleteExchangeRate)
+
+	//promo codes
+	route.Methods(http.MethodGet).Path("/promo-codes").HandlerFunc(handler.GetPromoCodes)
+	route.Methods(http.MethodGet).Path("/promo-codes/{id}").HandlerFunc(handler.GetPromoCode)
+	route.Methods(http.MethodGet).Path("/promo-codes/{id}/redemptions").HandlerFunc(handler.GetPromoCodeRedemptions)
+	route.Methods(http.MethodPost).Path("/promo-codes").HandlerFunc(handler.CreatePromoCode)
+	route.Methods(http.MethodPut).Path("/promo-codes").HandlerFunc(handler.UpdatePromoCode)
+	route.Methods(http.MethodDelete).Path("/promo-codes/{id}").HandlerFunc(handler.DeletePromoCode)
+
 	//locations
 	route.Methods(http.MethodGet).Path("/locations").HandlerFunc(handler.GetLocations)
 	route.Methods(http.MethodGet).Path("/locations/{id}").HandlerFunc(handler.GetLocation)
@@ -58,15 +156,84 @@ func New(service Service) http.Handler {
 
 	//reservations
 	route.Methods(http.MethodGet).Path("/reservations").HandlerFunc(handler.GetReservations)
+	if options.Exports {
+		route.Methods(http.MethodGet).Path("/reservations/export").HandlerFunc(handler.ExportReservations)
+	}
 	route.Methods(http.MethodGet).Path("/reservations/{id}").HandlerFunc(handler.GetReservation)
 	route.Methods(http.MethodPost).Path("/reservations").HandlerFunc(handler.CreateReservation)
 	route.Methods(http.MethodPut).Path("/reservations").HandlerFunc(handler.UpdateReservation)
 	route.Methods(http.MethodDelete).Path("/reservations/{id}").HandlerFunc(handler.DeleteReservation)
 
+	//payments
+	route.Methods(http.MethodGet).Path("/reservations/{id}/payments").HandlerFunc(handler.GetReservationPayments)
+	route.Methods(http.MethodPost).Path("/reservations/{id}/payments").HandlerFunc(handler.CreatePayment)
+	route.Methods(http.MethodGet).Path("/payments/{id}").HandlerFunc(handler.GetPayment)
+	route.Methods(http.MethodPost).Path("/payments/{id}/refund").HandlerFunc(handler.RefundPayment)
+
+	//invoices
+	route.Methods(http.MethodPost).Path("/reservations/{id}/invoice").HandlerFunc(handler.IssueInvoice)
+	route.Methods(http.MethodGet).Path("/reservations/{id}/invoice.pdf").HandlerFunc(handler.GetReservationInvoicePDF)
+	route.Methods(http.MethodGet).Path("/reservations/{id}/invoices").HandlerFunc(handler.GetReservationInvoices)
+	route.Methods(http.MethodGet).Path("/invoices/{id:[0-9]+}").HandlerFunc(handler.GetInvoice)
+	route.Methods(http.MethodGet).Path("/invoices/{id:[0-9]+}.pdf").HandlerFunc(handler.GetInvoicePDF)
+
+	//customers
+	route.Methods(http.MethodGet).Path("/customers").HandlerFunc(handler.GetCustomers)
+	route.Methods(http.MethodGet).Path("/customers/{id}").HandlerFunc(handler.GetCustomer)
+	route.Methods(http.MethodGet).Path("/customers/{id}/reservations").HandlerFunc(handler.GetCustomerReservations)
+	route.Methods(http.MethodPost).Path("/customers").HandlerFunc(handler.CreateCustomer)
+	route.Methods(http.MethodPut).Path("/customers").HandlerFunc(handler.UpdateCustomer)
+	route.Methods(http.MethodDelete).Path("/customers/{id}").HandlerFunc(handler.DeleteCustomer)
+
+	//api keys
+	route.Methods(http.MethodGet).Path("/api-keys").HandlerFunc(handler.GetAPIKeys)
+	route.Methods(http.MethodPost).Path("/api-keys").HandlerFunc(handler.CreateAPIKey)
+	route.Methods(http.MethodPost).Path("/api-keys/{id}/rotate").HandlerFunc(handler.RotateAPIKey)
+	route.Methods(http.MethodDelete).Path("/api-keys/{id}").HandlerFunc(handler.RevokeAPIKey)
+
+	//audit
+	route.Methods(http.MethodGet).Path("/audit").HandlerFunc(handler.GetAuditLog)
+
 	return route
 }
 
+// limitBody makes reading more than limit bytes of a request body fail, the
+// handlers answer 413 then.
+func limitBody(limit int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (h *apiHandler) GetHolidays(w http.ResponseWriter, r *http.Request) {
+	filter, err := holidayFilter(r)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	filter.Currency = r.FormValue("currency")
+
+	holidays, err := h.service.HolidayGetAll(r.Context(), filter)
+
+	if err != nil {
+		h.errorResponseWrite(w, r, err, conversionStatus(err))
+		return
+	}
+
+	jsonResponseWrite(w, holidays, http.StatusOK)
+}
+
+// holidayFilter reads the location, duration and startDate query parameters
+// shared by the holiday list and export endpoints.
+func holidayFilter(r *http.Request) (service.FilterHolidays, error) {
 	location := r.FormValue("location")
 
 	var duration int
@@ -76,49 +243,68 @@ func (h *apiHandler) GetHolidays(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimSpace(r.FormValue("duration")) != "" {
 		duration, err = strconv.Atoi(r.FormValue("duration"))
 		if err != nil {
-			jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
-			return
+			return service.FilterHolidays{}, err
 		}
 	}
 
 	if strings.TrimSpace(r.FormValue("startDate")) != "" {
 		startDate, err = time.Parse(time.DateOnly, r.FormValue("startDate"))
 		if err != nil {
-			jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
-			return
+			return service.FilterHolidays{}, err
 		}
 	}
 
-	holidays, err := h.service.HolidayGetAll(service.FilterHolidays{
+	return service.FilterHolidays{
 		StartDate: startDate,
 		Duration:  duration,
 		Location:  location,
-	})
+	}, nil
+}
 
+func (h *apiHandler) GetHoliday(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	jsonResponseWrite(w, holidays, http.StatusOK)
+	holiday, err := h.service.Holiday(r.Context(), id, r.FormValue("currency"))
+	if err != nil {
+		h.errorResponseWrite(w, r, err, conversionStatus(err))
+		return
+	}
+
+	jsonResponseWrite(w, holiday, http.StatusOK)
 }
 
-func (h *apiHandler) GetHoliday(w http.ResponseWriter, r *http.Request) {
+// CreateQuote prices a booking of the holiday for a party, the token of the
+// quote can be booked with until it expires.
+func (h *apiHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	holiday, err := h.service.Holiday(id)
+	request := service.QuoteRequestDTO{}
+
+	err = json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	jsonResponseWrite(w, holiday, http.StatusOK)
+	quote, err := h.service.Quote(r.Context(), id, request)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, quote, http.StatusOK)
 }
 
 func (h *apiHandler) CreateHoliday(w http.ResponseWriter, r *http.Request) {
@@ -126,34 +312,38 @@ func (h *apiHandler) CreateHoliday(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&data)
 	if err != nil {
-		fmt.Println("error: ", err)
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
 	startDate, err := time.Parse(time.DateOnly, data.StartDate)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	price, err := strconv.ParseFloat(data.Price, 64)
+	currency := data.Currency
+	if currency == "" {
+		currency = service.DefaultCurrency
+	}
+	price, err := money.Parse(data.Price, currency)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	idResult, err := h.service.InsertHoliday(service.HolidayDTO{
-		Title:      data.Title,
-		StartDate:  startDate,
-		Duration:   data.Duration,
-		Price:      float64(price),
-		FreeSlots:  data.FreeSlots,
-		LocationID: data.LocationID,
+	idResult, err := h.service.InsertHoliday(r.Context(), service.HolidayDTO{
+		Title:            data.Title,
+		StartDate:        startDate,
+		Duration:         data.Duration,
+		Price:            price,
+		FreeSlots:        data.FreeSlots,
+		LocationID:       data.LocationID,
+		PricingRuleSetID: data.PricingRuleSetID,
 	})
 
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
@@ -165,13 +355,13 @@ func (h *apiHandler) UpdateHoliday(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&holiday)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	idResult, err := h.service.UpdateHoliday(holiday)
+	idResult, err := h.service.UpdateHoliday(r.Context(), holiday)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
@@ -184,13 +374,13 @@ func (h *apiHandler) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	holiday, err := h.service.DeleteHoliday(id)
+	holiday, err := h.service.DeleteHoliday(r.Context(), id)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -198,9 +388,9 @@ func (h *apiHandler) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *apiHandler) GetLocations(w http.ResponseWriter, r *http.Request) {
-	locations, err := h.service.LocationGetAll()
+	locations, err := h.service.LocationGetAll(r.Context())
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -213,13 +403,13 @@ func (h *apiHandler) GetLocation(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	location, err := h.service.Location(id)
+	location, err := h.service.Location(r.Context(), id)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -231,13 +421,13 @@ func (h *apiHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&location)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	idResult, err := h.service.InsertLocation(location)
+	idResult, err := h.service.InsertLocation(r.Context(), location)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
@@ -249,13 +439,13 @@ func (h *apiHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&location)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	updatedLocation, err := h.service.UpdateLocation(location)
+	updatedLocation, err := h.service.UpdateLocation(r.Context(), location)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
@@ -268,13 +458,13 @@ func (h *apiHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	location, err := h.service.DeleteLocation(id)
+	location, err := h.service.DeleteLocation(r.Context(), id)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -282,9 +472,9 @@ func (h *apiHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *apiHandler) GetReservations(w http.ResponseWriter, r *http.Request) {
-	reservations, err := h.service.ReservationGetAll()
+	reservations, err := h.service.ReservationGetAll(r.Context(), r.FormValue("currency"))
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, conversionStatus(err))
 		return
 	}
 
@@ -297,13 +487,13 @@ func (h *apiHandler) GetReservation(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	reservation, err := h.service.Reservation(id)
+	reservation, err := h.service.Reservation(r.Context(), id, r.FormValue("currency"))
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, conversionStatus(err))
 		return
 	}
 
@@ -315,13 +505,13 @@ func (h *apiHandler) CreateReservation(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&reservation)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	idResult, err := h.service.InsertReservation(reservation)
+	idResult, err := h.service.InsertReservation(r.Context(), reservation)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
@@ -333,13 +523,13 @@ func (h *apiHandler) UpdateReservation(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&reservation)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	result, err := h.service.UpdateReservation(reservation)
+	result, err := h.service.UpdateReservation(r.Context(), reservation)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
@@ -352,19 +542,29 @@ func (h *apiHandler) DeleteReservation(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusBadRequest)
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	reservations, err := h.service.DeleteReservation(id)
+	reservations, err := h.service.DeleteReservation(r.Context(), id)
 	if err != nil {
-		jsonResponseWrite(w, err.Error(), http.StatusInternalServerError)
+		h.errorResponseWrite(w, r, err, cancelStatus(err))
 		return
 	}
 
 	jsonResponseWrite(w, reservations, http.StatusOK)
 }
 
+// cancelStatus answers 409 when the payments of a reservation keep it from
+// being cancelled, it can be once they are settled or refunded.
+func cancelStatus(err error) int {
+	if errors.Is(err, service.ErrPaymentPending) || errors.Is(err, service.ErrReservationPaid) {
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
+
 func jsonResponseWrite(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -374,3 +574,50 @@ func jsonResponseWrite(w http.ResponseWriter, data interface{}, statusCode int)
 		return
 	}
 }
+
+// errorResponseWrite reports err with statusCode, except for authorization
+// denials which always become a 403 problem response. Server errors are
+// logged.
+func (h *apiHandler) errorResponseWrite(w http.ResponseWriter, r *http.Request, err error, statusCode int) {
+	if errors.Is(err, auth.ErrForbidden) {
+		problemResponseWrite(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		problemResponseWrite(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		h.logger.ErrorContext(r.Context(), "request failed", "status", statusCode, "error", err)
+	}
+
+	jsonResponseWrite(w, err.Error(), statusCode)
+}
+
+// Problem is an RFC 7807 problem details body.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func problemResponseWrite(w http.ResponseWriter, statusCode int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(statusCode)
+
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+	}
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
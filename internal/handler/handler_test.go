@@ -0,0 +1,1574 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"travel/internal/apitest"
+	"travel/internal/auth"
+	"travel/internal/export"
+	"travel/internal/handler"
+	"travel/internal/payment"
+	"travel/internal/pricing"
+	"travel/internal/quote"
+	"travel/internal/storage"
+	"travel/internal/tenant"
+)
+
+const fixtures = "testdata/fixtures.yaml"
+
+// routeTest is one request against a server loaded with the fixtures. role
+// picks the token: admin, agent, customer (Maria) or none for "".
+type routeTest struct {
+	name   string
+	role   string
+	method string
+	path   string
+	body   string
+	header map[string]string
+	status int
+	// want is matched against the response with apitest.AssertJSON
+	want string
+	// check runs after want, e.g. to read back what was written
+	check func(t *testing.T, s *apitest.Server, r *apitest.Response)
+}
+
+func runRoutes(t *testing.T, tests []routeTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := apitest.New(t, fixtures)
+
+			r := s.Do(t, apitest.Request{
+				Method: test.method,
+				Path:   test.path,
+				Body:   test.body,
+				Token:  token(t, s, test.role),
+				Header: test.header,
+			})
+
+			r.AssertStatus(t, test.status)
+			if test.want != "" {
+				r.AssertJSON(t, test.want)
+			}
+			if test.check != nil {
+				test.check(t, s, r)
+			}
+		})
+	}
+}
+
+func token(t *testing.T, s *apitest.Server, role string) string {
+	switch role {
+	case "":
+		return ""
+	case "customer":
+		return s.TokenFor(t, auth.Claims{Subject: "maria", Name: "Maria Ivanova", Role: role, CustomerID: s.ID(t, "maria"), TenantID: apitest.DefaultTenant})
+	default:
+		return s.Token(t, role)
+	}
+}
+
+// readBack checks that GET path as admin answers with want.
+func readBack(path string, status int, want string) func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+	return func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+		t.Helper()
+
+		// {created} is the id a create answered with
+		var id int
+		if strings.Contains(path, "{created}") {
+			r.Decode(t, &id)
+		}
+
+		read := s.Do(t, apitest.Request{Method: http.MethodGet, Path: strings.ReplaceAll(path, "{created}", strconv.Itoa(id)), Token: s.Token(t, "admin")}).AssertStatus(t, status)
+		if want != "" {
+			read.AssertJSON(t, want)
+		}
+	}
+}
+
+const notFound = `"sql: no rows in result set"`
+
+func TestHolidays(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "list", role: "admin", method: http.MethodGet, path: "/holidays", status: http.StatusOK,
+			want: `[
+				{"id": {ski}, "title": "Ski week", "duration": 7, "startDate": "2030-01-10T00:00:00Z", "price": {"amount": "650.00", "currency": "EUR"}, "freeSlots": 10,
+				 "location": {"id": {sofia}, "street": "Vitosha", "number": "1", "city": "Sofia", "country": "Bulgaria"}},
+				{"id": {sea}, "title": "Black sea", "price": {"amount": "900.50", "currency": "EUR"}, "location": {"id": {varna}}},
+				{"id": {city-break}, "title": "City break", "location": {"id": {sofia}}}
+			]`,
+		},
+		{name: "list as customer", role: "customer", method: http.MethodGet, path: "/holidays", status: http.StatusOK},
+		{name: "list anonymous", method: http.MethodGet, path: "/holidays", status: http.StatusUnauthorized, want: `{"status": 401}`},
+		{name: "filter by city", role: "agent", method: http.MethodGet, path: "/holidays?location=varna", status: http.StatusOK, want: `[{"id": {sea}}]`},
+		{name: "filter by country", role: "agent", method: http.MethodGet, path: "/holidays?location=bulg", status: http.StatusOK, want: `[{"id": {ski}}, {"id": {sea}}, {"id": {city-break}}]`},
+		{name: "filter by duration", role: "agent", method: http.MethodGet, path: "/holidays?duration=7", status: http.StatusOK, want: `[{"id": {ski}}]`},
+		{name: "filter by start date", role: "agent", method: http.MethodGet, path: "/holidays?startDate=2030-07-01", status: http.StatusOK, want: `[{"id": {sea}}]`},
+		{name: "filter by invalid date", role: "agent", method: http.MethodGet, path: "/holidays?startDate=July", status: http.StatusBadRequest},
+		{
+			name: "get", role: "agent", method: http.MethodGet, path: "/holidays/{ski}", status: http.StatusOK,
+			want: `{"id": {ski}, "title": "Ski week", "startDate": "2030-01-10T00:00:00Z", "duration": 7, "price": {"amount": "650.00", "currency": "EUR"}, "freeSlots": 10, "location": {sofia},
+				"pricing": {"basePrice": {"amount": "650.00", "currency": "EUR"}, "adjustments": [], "price": {"amount": "650.00", "currency": "EUR"}}}`,
+		},
+		{
+			name: "get priced", role: "customer", method: http.MethodGet, path: "/holidays/{sea}", status: http.StatusOK,
+			want: `{"id": {sea}, "price": {"amount": "900.50", "currency": "EUR"}, "pricingRuleSet": {summer}, "pricing": {
+				"basePrice": {"amount": "900.50", "currency": "EUR"},
+				"adjustments": [
+					{"rule": "High season", "kind": "season", "percent": 20, "amount": {"amount": "180.10", "currency": "EUR"}},
+					{"rule": "Early bird", "kind": "earlyBird", "percent": -10, "amount": {"amount": "-108.06", "currency": "EUR"}},
+					{"rule": "Almost full", "kind": "occupancy", "percent": 10, "amount": {"amount": "97.25", "currency": "EUR"}}
+				],
+				"price": {"amount": "1069.79", "currency": "EUR"}
+			}}`,
+		},
+		{
+			name: "get in another currency", role: "customer", method: http.MethodGet, path: "/holidays/{sea}?currency=gbp", status: http.StatusOK,
+			want: `{"id": {sea}, "price": {"amount": "769.12", "currency": "GBP"}, "pricing": {
+				"basePrice": {"amount": "769.12", "currency": "GBP"},
+				"adjustments": [{"amount": {"amount": "153.82", "currency": "GBP"}}, {"amount": {"amount": "-92.29", "currency": "GBP"}}, {"amount": {"amount": "83.06", "currency": "GBP"}}],
+				"price": {"amount": "913.71", "currency": "GBP"}
+			}}`,
+		},
+		{name: "get in the default currency", role: "agent", method: http.MethodGet, path: "/holidays/{ski}?currency=EUR", status: http.StatusOK, want: `{"price": {"amount": "650.00", "currency": "EUR"}}`},
+		{name: "get in a currency without a rate", role: "agent", method: http.MethodGet, path: "/holidays/{ski}?currency=CHF", status: http.StatusBadRequest},
+		{name: "get in an unknown currency", role: "agent", method: http.MethodGet, path: "/holidays/{ski}?currency=XYZ", status: http.StatusBadRequest},
+		{
+			name: "list in another currency", role: "agent", method: http.MethodGet, path: "/holidays?currency=USD&location=sofia", status: http.StatusOK,
+			want: `[{"id": {ski}, "price": {"amount": "706.29", "currency": "USD"}}, {"id": {city-break}, "price": {"amount": "216.23", "currency": "USD"}}]`,
+		},
+		{name: "list in a currency without a rate", role: "agent", method: http.MethodGet, path: "/holidays?currency=CHF", status: http.StatusBadRequest},
+		{name: "get invalid id", role: "agent", method: http.MethodGet, path: "/holidays/ski", status: http.StatusBadRequest},
+		{name: "get missing", role: "agent", method: http.MethodGet, path: "/holidays/999", status: http.StatusInternalServerError, want: notFound},
+		{name: "get of another agency", role: "admin", method: http.MethodGet, path: "/holidays/{roman-holiday}", status: http.StatusInternalServerError, want: notFound},
+		{
+			name: "create", role: "admin", method: http.MethodPost, path: "/holidays", status: http.StatusOK,
+			body:  `{"title": "Spa weekend", "duration": 2, "startDate": "2030-02-14", "price": "320.50", "freeSlots": 6, "location": {plovdiv}}`,
+			check: readBack("/holidays/{created}", http.StatusOK, `{"title": "Spa weekend", "startDate": "2030-02-14T00:00:00Z", "price": {"amount": "320.50", "currency": "EUR"}, "location": {plovdiv}}`),
+		},
+		{
+			name: "create with a rule set", role: "admin", method: http.MethodPost, path: "/holidays", status: http.StatusOK,
+			body:  `{"title": "New year in Sofia", "duration": 3, "startDate": "2030-12-30", "price": "400", "freeSlots": 30, "location": {sofia}, "pricingRuleSet": {winter}}`,
+			check: readBack("/holidays/{created}", http.StatusOK, `{"pricingRuleSet": {winter}, "pricing": {"adjustments": [{"rule": "Christmas", "amount": {"amount": "100.00", "currency": "EUR"}}], "price": {"amount": "500.00", "currency": "EUR"}}}`),
+		},
+		{
+			name: "create with a rule set of another agency", role: "admin", method: http.MethodPost, path: "/holidays", status: http.StatusBadRequest,
+			body: `{"title": "Spa", "startDate": "2030-02-14", "price": "1", "location": {plovdiv}, "pricingRuleSet": {roman-rules}}`,
+		},
+		{name: "create with invalid date", role: "admin", method: http.MethodPost, path: "/holidays", body: `{"title": "Spa", "startDate": "14.02.2030", "price": "1", "location": {plovdiv}}`, status: http.StatusBadRequest},
+		{name: "create with invalid price", role: "admin", method: http.MethodPost, path: "/holidays", body: `{"title": "Spa", "startDate": "2030-02-14", "price": "cheap", "location": {plovdiv}}`, status: http.StatusBadRequest},
+		{
+			name: "create in another currency", role: "admin", method: http.MethodPost, path: "/holidays", status: http.StatusOK,
+			body:  `{"title": "Onsen", "duration": 4, "startDate": "2030-04-01", "price": "98000", "currency": "jpy", "freeSlots": 6, "location": {plovdiv}}`,
+			check: readBack("/holidays/{created}", http.StatusOK, `{"price": {"amount": "98000", "currency": "JPY"}, "pricing": {"price": {"amount": "98000", "currency": "JPY"}}}`),
+		},
+		{name: "create with fractions of a cent", role: "admin", method: http.MethodPost, path: "/holidays", body: `{"title": "Spa", "startDate": "2030-02-14", "price": "499.989", "location": {plovdiv}}`, status: http.StatusBadRequest},
+		{name: "create with unknown currency", role: "admin", method: http.MethodPost, path: "/holidays", body: `{"title": "Spa", "startDate": "2030-02-14", "price": "1", "currency": "ABC", "location": {plovdiv}}`, status: http.StatusBadRequest},
+		{name: "create with negative price", role: "admin", method: http.MethodPost, path: "/holidays", body: `{"title": "Spa", "startDate": "2030-02-14", "price": "-1", "location": {plovdiv}}`, status: http.StatusBadRequest},
+		{name: "create as agent", role: "agent", method: http.MethodPost, path: "/holidays", body: `{"title": "Spa", "startDate": "2030-02-14", "price": "1", "location": {plovdiv}}`, status: http.StatusForbidden},
+		{
+			name: "update", role: "admin", method: http.MethodPut, path: "/holidays", status: http.StatusOK,
+			body:  `{"id": {ski}, "title": "Ski fortnight", "startDate": "2030-01-10T00:00:00Z", "duration": 14, "price": {"amount": "1100.00", "currency": "EUR"}, "freeSlots": 5, "location": {sofia}}`,
+			want:  `{"id": {ski}, "title": "Ski fortnight", "duration": 14}`,
+			check: readBack("/holidays/{ski}", http.StatusOK, `{"title": "Ski fortnight", "duration": 14, "price": {"amount": "1100.00", "currency": "EUR"}, "freeSlots": 5}`),
+		},
+		{
+			name: "update without a currency", role: "admin", method: http.MethodPut, path: "/holidays", status: http.StatusInternalServerError,
+			body: `{"id": {ski}, "title": "Ski week", "startDate": "2030-01-10T00:00:00Z", "duration": 7, "price": {"amount": "650.00"}, "freeSlots": 10, "location": {sofia}}`,
+		},
+		{
+			name: "update with a float price", role: "admin", method: http.MethodPut, path: "/holidays", status: http.StatusInternalServerError,
+			body: `{"id": {ski}, "title": "Ski week", "startDate": "2030-01-10T00:00:00Z", "duration": 7, "price": 650, "freeSlots": 10, "location": {sofia}}`,
+		},
+		{name: "update as customer", role: "customer", method: http.MethodPut, path: "/holidays", body: `{"id": {ski}, "title": "Free"}`, status: http.StatusForbidden},
+		{
+			name: "delete", role: "admin", method: http.MethodDelete, path: "/holidays/{city-break}", status: http.StatusOK,
+			want:  `{"id": {city-break}, "title": "City break"}`,
+			check: readBack("/holidays/{city-break}", http.StatusInternalServerError, notFound),
+		},
+		{name: "delete reserved", role: "admin", method: http.MethodDelete, path: "/holidays/{ski}", status: http.StatusInternalServerError},
+	})
+}
+
+func TestPricingRuleSets(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "list", role: "agent", method: http.MethodGet, path: "/pricing-rule-sets", status: http.StatusOK,
+			want: `[
+				{"id": {summer}, "name": "Summer", "rules": [
+					{"name": "High season", "kind": "season", "percent": 20, "from": "06-15", "to": "08-31"},
+					{"name": "Weekend departure", "kind": "weekday", "weekdays": ["friday", "saturday"]},
+					{"name": "Early bird", "kind": "earlyBird", "percent": -10, "days": 30},
+					{"name": "Almost full", "kind": "occupancy", "freeSlots": 5},
+					{"name": "Infant", "kind": "child", "percent": -100, "age": 2},
+					{"name": "Child", "kind": "child", "percent": -50, "age": 12},
+					{"name": "Airport transfer", "kind": "extra", "amount": {"amount": "25.00", "currency": "EUR"}, "perPerson": true},
+					{"name": "Booking fee", "kind": "fee", "amount": {"amount": "15.00", "currency": "EUR"}},
+					{"name": "Tourist tax", "kind": "tax", "percent": 9}
+				]},
+				{"id": {winter}, "name": "Winter"}
+			]`,
+		},
+		{name: "list as customer", role: "customer", method: http.MethodGet, path: "/pricing-rule-sets", status: http.StatusForbidden},
+		{name: "get", role: "agent", method: http.MethodGet, path: "/pricing-rule-sets/{winter}", status: http.StatusOK, want: `{"id": {winter}, "rules": [{"name": "Christmas"}]}`},
+		{name: "get of another agency", role: "admin", method: http.MethodGet, path: "/pricing-rule-sets/{roman-rules}", status: http.StatusInternalServerError, want: notFound},
+		{
+			name: "create", role: "admin", method: http.MethodPost, path: "/pricing-rule-sets", status: http.StatusOK,
+			body:  `{"name": "Last minute", "rules": [{"name": "Last week", "kind": "lastMinute", "percent": -25, "days": 7}]}`,
+			check: readBack("/pricing-rule-sets/{created}", http.StatusOK, `{"name": "Last minute", "rules": [{"name": "Last week", "kind": "lastMinute", "percent": -25, "days": 7}]}`),
+		},
+		{
+			name: "create without rules", role: "admin", method: http.MethodPost, path: "/pricing-rule-sets", status: http.StatusOK,
+			body:  `{"name": "Flat"}`,
+			check: readBack("/pricing-rule-sets/{created}", http.StatusOK, `{"name": "Flat", "rules": []}`),
+		},
+		{name: "create without a name", role: "admin", method: http.MethodPost, path: "/pricing-rule-sets", body: `{"rules": []}`, status: http.StatusBadRequest},
+		{
+			name: "create with an invalid rule", role: "admin", method: http.MethodPost, path: "/pricing-rule-sets", status: http.StatusBadRequest,
+			body: `{"name": "Summer", "rules": [{"name": "Summer", "kind": "season", "percent": 10, "from": "June", "to": "08-31"}]}`,
+			want: `"invalid pricing rule 1 (Summer): from: \"June\" is no MM-DD date"`,
+		},
+		{name: "create as agent", role: "agent", method: http.MethodPost, path: "/pricing-rule-sets", body: `{"name": "Cheap"}`, status: http.StatusForbidden},
+		{
+			name: "update", role: "admin", method: http.MethodPut, path: "/pricing-rule-sets", status: http.StatusOK,
+			body:  `{"id": {summer}, "name": "Summer", "rules": [{"name": "High season", "kind": "season", "percent": 50, "from": "06-15", "to": "08-31"}]}`,
+			want:  `{"id": {summer}, "rules": [{"percent": 50}]}`,
+			check: readBack("/holidays/{sea}", http.StatusOK, `{"pricing": {"adjustments": [{"rule": "High season", "amount": {"amount": "450.25", "currency": "EUR"}}], "price": {"amount": "1350.75", "currency": "EUR"}}}`),
+		},
+		{name: "update as agent", role: "agent", method: http.MethodPut, path: "/pricing-rule-sets", body: `{"id": {summer}, "name": "Free"}`, status: http.StatusForbidden},
+		{
+			name: "delete", role: "admin", method: http.MethodDelete, path: "/pricing-rule-sets/{winter}", status: http.StatusOK,
+			want:  `{"id": {winter}, "name": "Winter"}`,
+			check: readBack("/pricing-rule-sets/{winter}", http.StatusInternalServerError, notFound),
+		},
+		{name: "delete in use", role: "admin", method: http.MethodDelete, path: "/pricing-rule-sets/{summer}", status: http.StatusInternalServerError},
+		{
+			name: "audited", role: "admin", method: http.MethodDelete, path: "/pricing-rule-sets/{winter}", status: http.StatusOK,
+			check: readBack("/audit?resource=pricingruleset&id={winter}", http.StatusOK, `[{"action": "create"}, {"action": "delete", "diff": {"name": {"before": "Winter", "after": null}}}]`),
+		},
+	})
+}
+
+const ecbDaily = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time='2030-01-03'>
+			<Cube currency='USD' rate='1.1'/>
+			<Cube currency='JPY' rate='160.5'/>
+			<Cube currency='XDR' rate='0.8'/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestExchangeRates(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "list", role: "agent", method: http.MethodGet, path: "/exchange-rates", status: http.StatusOK,
+			want: `[{"currency": "GBP", "rate": 0.8541, "date": "2030-01-02T00:00:00Z"}, {"currency": "USD", "rate": 1.0866, "date": "2030-01-02T00:00:00Z"}]`,
+		},
+		{name: "list as customer", role: "customer", method: http.MethodGet, path: "/exchange-rates", status: http.StatusForbidden},
+		{
+			name: "create", role: "admin", method: http.MethodPut, path: "/exchange-rates", status: http.StatusOK,
+			body:  `{"currency": "chf", "rate": 0.98, "date": "2030-01-03T00:00:00Z"}`,
+			want:  `{"currency": "CHF", "rate": 0.98, "date": "2030-01-03T00:00:00Z"}`,
+			check: readBack("/exchange-rates", http.StatusOK, `[{"currency": "CHF"}, {"currency": "GBP"}, {"currency": "USD"}]`),
+		},
+		{
+			name: "update", role: "admin", method: http.MethodPut, path: "/exchange-rates", status: http.StatusOK,
+			body:  `{"currency": "GBP", "rate": 0.9, "date": "2030-01-03T00:00:00Z"}`,
+			check: readBack("/holidays/{ski}?currency=GBP", http.StatusOK, `{"price": {"amount": "585.00", "currency": "GBP"}}`),
+		},
+		{name: "create without a date", role: "admin", method: http.MethodPut, path: "/exchange-rates", body: `{"currency": "SEK", "rate": 11.5}`, status: http.StatusOK, want: `{"currency": "SEK"}`},
+		{name: "create for the default currency", role: "admin", method: http.MethodPut, path: "/exchange-rates", body: `{"currency": "EUR", "rate": 1}`, status: http.StatusBadRequest},
+		{name: "create without a rate", role: "admin", method: http.MethodPut, path: "/exchange-rates", body: `{"currency": "SEK"}`, status: http.StatusBadRequest},
+		{name: "create for an unknown currency", role: "admin", method: http.MethodPut, path: "/exchange-rates", body: `{"currency": "XYZ", "rate": 2}`, status: http.StatusBadRequest},
+		{name: "create as agent", role: "agent", method: http.MethodPut, path: "/exchange-rates", body: `{"currency": "SEK", "rate": 11.5}`, status: http.StatusForbidden},
+		{
+			name: "import", role: "admin", method: http.MethodPost, path: "/exchange-rates/import", status: http.StatusOK,
+			body:  ecbDaily,
+			want:  `[{"currency": "JPY", "rate": 160.5, "date": "2030-01-03T00:00:00Z"}, {"currency": "USD", "rate": 1.1, "date": "2030-01-03T00:00:00Z"}]`,
+			check: readBack("/exchange-rates", http.StatusOK, `[{"currency": "GBP", "rate": 0.8541}, {"currency": "JPY", "rate": 160.5}, {"currency": "USD", "rate": 1.1}]`),
+		},
+		{name: "import invalid", role: "admin", method: http.MethodPost, path: "/exchange-rates/import", body: `{"USD": 1.1}`, status: http.StatusBadRequest},
+		{name: "import as agent", role: "agent", method: http.MethodPost, path: "/exchange-rates/import", body: ecbDaily, status: http.StatusForbidden},
+		{
+			name: "delete", role: "admin", method: http.MethodDelete, path: "/exchange-rates/gbp", status: http.StatusOK,
+			want:  `{"currency": "GBP", "rate": 0.8541}`,
+			check: readBack("/holidays/{ski}?currency=GBP", http.StatusBadRequest, ""),
+		},
+		{name: "delete missing", role: "admin", method: http.MethodDelete, path: "/exchange-rates/CHF", status: http.StatusInternalServerError, want: notFound},
+		{name: "delete as agent", role: "agent", method: http.MethodDelete, path: "/exchange-rates/GBP", status: http.StatusForbidden},
+		{
+			name: "audited", role: "admin", method: http.MethodPut, path: "/exchange-rates", status: http.StatusOK,
+			body:  `{"currency": "GBP", "rate": 0.9, "date": "2030-01-03T00:00:00Z"}`,
+			check: readBack("/audit?resource=exchangerate", http.StatusOK, `[{"action": "create"}, {"action": "create"}, {"action": "update", "diff": {"rate": {"before": 0.8541, "after": 0.9}}}]`),
+		},
+	})
+}
+
+// bookQuote books the quote a request answered with for Petar as agent and
+// checks that it answers with status, and that the reservation reads as want
+// with ?currency=currency.
+func bookQuote(status int, currency string, want string) func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+	return func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+		t.Helper()
+
+		var quote struct {
+			Token string `json:"token"`
+		}
+		r.Decode(t, &quote)
+
+		booked := s.Do(t, apitest.Request{
+			Method: http.MethodPost,
+			Path:   "/reservations",
+			Body:   `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "quoteToken": "` + quote.Token + `"}`,
+			Token:  s.Token(t, "agent"),
+		}).AssertStatus(t, status)
+		if status != http.StatusOK {
+			return
+		}
+
+		readBack("/reservations/{created}?currency="+currency, http.StatusOK, want)(t, s, booked)
+	}
+}
+
+const familyQuote = `{"adults": 2, "children": [{"age": 8}], "extras": ["airport transfer"]}`
+
+func TestQuotes(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "quote", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: familyQuote,
+			want: `{"holiday": {sea}, "party": {"adults": 2, "children": [{"age": 8}]}, "extras": ["airport transfer"],
+				"pricing": {"basePrice": {"amount": "900.50", "currency": "EUR"}, "price": {"amount": "1069.79", "currency": "EUR"}},
+				"items": [
+					{"kind": "traveller", "name": "Adult", "quantity": 2, "unitPrice": {"amount": "1069.79", "currency": "EUR"}, "amount": {"amount": "2139.58", "currency": "EUR"}},
+					{"kind": "traveller", "name": "Child", "quantity": 1, "amount": {"amount": "1069.79", "currency": "EUR"}},
+					{"kind": "child", "name": "Child", "quantity": 1, "percent": -50, "amount": {"amount": "-534.90", "currency": "EUR"}},
+					{"kind": "extra", "name": "Airport transfer", "quantity": 3, "unitPrice": {"amount": "25.00", "currency": "EUR"}, "amount": {"amount": "75.00", "currency": "EUR"}},
+					{"kind": "fee", "name": "Booking fee", "quantity": 1, "amount": {"amount": "15.00", "currency": "EUR"}},
+					{"kind": "tax", "name": "Tourist tax", "quantity": 1, "percent": 9, "amount": {"amount": "248.80", "currency": "EUR"}}
+				],
+				"total": {"amount": "3013.27", "currency": "EUR"}}`,
+		},
+		{
+			name: "quote without a rule set", role: "agent", method: http.MethodPost, path: "/holidays/{ski}/quote", status: http.StatusOK,
+			body: `{"adults": 1}`,
+			want: `{"holiday": {ski}, "party": {"adults": 1, "children": []}, "extras": [], "items": [{"kind": "traveller", "name": "Adult", "quantity": 1}], "total": {"amount": "650.00", "currency": "EUR"}}`,
+		},
+		{name: "quote anonymous", method: http.MethodPost, path: "/holidays/{sea}/quote", body: familyQuote, status: http.StatusUnauthorized},
+		{name: "quote without an adult", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", body: `{"children": [{"age": 8}]}`, status: http.StatusBadRequest},
+		{name: "quote for a grown up child", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", body: `{"adults": 1, "children": [{"age": 30}]}`, status: http.StatusBadRequest},
+		{name: "quote for too many", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", body: `{"adults": 5}`, status: http.StatusBadRequest},
+		{name: "quote with an unknown extra", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", body: `{"adults": 1, "extras": ["Spa"]}`, status: http.StatusBadRequest},
+		{name: "quote with an unknown promo code", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", body: `{"adults": 1, "promoCode": "NOPE"}`, status: http.StatusBadRequest},
+		{name: "quote of another agency", role: "customer", method: http.MethodPost, path: "/holidays/{roman-holiday}/quote", body: `{"adults": 1}`, status: http.StatusBadRequest},
+		{name: "quote invalid id", role: "customer", method: http.MethodPost, path: "/holidays/sea/quote", body: `{"adults": 1}`, status: http.StatusBadRequest},
+		{
+			name: "book", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: familyQuote,
+			check: bookQuote(http.StatusOK, "", `{"holiday": {sea}, "customerID": {petar},
+				"pricing": {"price": {"amount": "1069.79", "currency": "EUR"}},
+				"quote": {"holiday": {sea}, "party": {"adults": 2}, "total": {"amount": "3013.27", "currency": "EUR"}}}`),
+		},
+		{
+			name: "book in another currency", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body:  familyQuote,
+			check: bookQuote(http.StatusOK, "GBP", `{"quote": {"pricing": {"price": {"amount": "913.71", "currency": "GBP"}}, "total": {"amount": "2573.63", "currency": "GBP"}}}`),
+		},
+		{
+			name: "book at the quoted price", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: familyQuote,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				s.Do(t, apitest.Request{
+					Method: http.MethodPut,
+					Path:   "/holidays",
+					Body:   `{"id": {sea}, "title": "Black sea", "startDate": "2030-07-01T00:00:00Z", "duration": 10, "price": {"amount": "1500.00", "currency": "EUR"}, "freeSlots": 4, "location": {varna}}`,
+					Token:  s.Token(t, "admin"),
+				}).AssertStatus(t, http.StatusOK)
+
+				bookQuote(http.StatusOK, "", `{"pricing": {"basePrice": {"amount": "900.50", "currency": "EUR"}}, "quote": {"total": {"amount": "3013.27", "currency": "EUR"}}}`)(t, s, r)
+			},
+		},
+		{
+			name: "book a changed quote", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: familyQuote,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var quote struct {
+					Token string `json:"token"`
+				}
+				r.Decode(t, &quote)
+				payload, signature, _ := strings.Cut(quote.Token, ".")
+
+				s.Do(t, apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "quoteToken": "` + payload + "x." + signature + `"}`,
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusBadRequest)
+			},
+		},
+		{
+			name: "book a quote for another holiday", role: "customer", method: http.MethodPost, path: "/holidays/{ski}/quote", status: http.StatusOK,
+			body: `{"adults": 1}`,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var quote struct {
+					Token string `json:"token"`
+				}
+				r.Decode(t, &quote)
+
+				s.Do(t, apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   s.Expand(t, `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "holiday": {sea}, "quoteToken": "`+quote.Token+`"}`),
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusBadRequest)
+			},
+		},
+		{
+			name: "moving a booked reservation drops its quote", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: familyQuote,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var quote struct {
+					Token string `json:"token"`
+				}
+				r.Decode(t, &quote)
+
+				var id int
+				s.Do(t, apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "quoteToken": "` + quote.Token + `"}`,
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusOK).Decode(t, &id)
+
+				var moved map[string]interface{}
+				s.Do(t, apitest.Request{
+					Method: http.MethodPut,
+					Path:   "/reservations",
+					Body:   s.Expand(t, `{"id": `+strconv.Itoa(id)+`, "contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "holiday": {ski}}`),
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusOK).Decode(t, &moved)
+				if _, ok := moved["quote"]; ok {
+					t.Errorf("moved reservation kept its quote: %v", moved)
+				}
+
+				// the quote was used up by the moved reservation
+				s.Do(t, apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "quoteToken": "` + quote.Token + `"}`,
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusBadRequest)
+			},
+		},
+		{
+			name: "book a quote twice", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: familyQuote,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var quote struct {
+					Token string `json:"token"`
+				}
+				r.Decode(t, &quote)
+
+				book := apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "quoteToken": "` + quote.Token + `"}`,
+					Token:  s.Token(t, "agent"),
+				}
+				s.Do(t, book).AssertStatus(t, http.StatusOK)
+				s.Do(t, book).AssertStatus(t, http.StatusBadRequest).AssertJSON(t, `"the quote is booked already"`)
+			},
+		},
+	})
+}
+
+// reserve books holiday for Petar as agent with promo code and checks the
+// status of the answer.
+func reserve(t *testing.T, s *apitest.Server, holiday string, code string, status int) *apitest.Response {
+	t.Helper()
+
+	return s.Do(t, apitest.Request{
+		Method: http.MethodPost,
+		Path:   "/reservations",
+		Body:   s.Expand(t, `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "holiday": {`+holiday+`}, "promoCode": "`+code+`"}`),
+		Token:  s.Token(t, "agent"),
+	}).AssertStatus(t, status)
+}
+
+func TestPromoCodes(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "list", role: "agent", method: http.MethodGet, path: "/promo-codes", status: http.StatusOK,
+			want: `[
+				{"id": {summer10}, "code": "SUMMER10", "description": "Summer sale", "percent": 10, "holidays": [{sea}], "locations": []},
+				{"id": {welcome50}, "code": "WELCOME50", "amount": {"amount": "50.00", "currency": "EUR"}, "minSpend": {"amount": "500.00", "currency": "EUR"}, "maxPerCustomer": 1},
+				{"id": {sofia5}, "code": "SOFIA5", "percent": 5, "maxRedemptions": 1, "locations": [{sofia}]},
+				{"id": {expired}, "code": "EXPIRED", "validTo": "2020-01-01T00:00:00Z"}
+			]`,
+		},
+		{name: "list as customer", role: "customer", method: http.MethodGet, path: "/promo-codes", status: http.StatusForbidden},
+		{name: "get", role: "agent", method: http.MethodGet, path: "/promo-codes/{summer10}", status: http.StatusOK, want: `{"id": {summer10}, "code": "SUMMER10"}`},
+		{name: "get of another agency", role: "admin", method: http.MethodGet, path: "/promo-codes/{roman10}", status: http.StatusInternalServerError, want: notFound},
+		{name: "get invalid id", role: "admin", method: http.MethodGet, path: "/promo-codes/summer10", status: http.StatusBadRequest},
+		{
+			name: "create", role: "admin", method: http.MethodPost, path: "/promo-codes", status: http.StatusOK,
+			body:  `{"code": " spring-20 ", "description": "Spring", "percent": 20, "validFrom": "2030-03-01T00:00:00Z", "validTo": "2030-06-01T00:00:00Z", "maxRedemptions": 100, "holidays": [{city-break}]}`,
+			check: readBack("/promo-codes/{created}", http.StatusOK, `{"code": "SPRING-20", "percent": 20, "validFrom": "2030-03-01T00:00:00Z", "validTo": "2030-06-01T00:00:00Z", "maxRedemptions": 100, "holidays": [{city-break}], "locations": []}`),
+		},
+		{name: "create a taken code", role: "admin", method: http.MethodPost, path: "/promo-codes", body: `{"code": "summer10", "percent": 5}`, status: http.StatusBadRequest},
+		{
+			name: "create with percent and amount", role: "admin", method: http.MethodPost, path: "/promo-codes", status: http.StatusBadRequest,
+			body: `{"code": "BOTH", "percent": 5, "amount": {"amount": "10.00", "currency": "EUR"}}`,
+			want: `"invalid promo code BOTH: either percent or amount"`,
+		},
+		{name: "create for a missing holiday", role: "admin", method: http.MethodPost, path: "/promo-codes", body: `{"code": "NOWHERE", "percent": 5, "holidays": [999]}`, status: http.StatusBadRequest},
+		{name: "create for a holiday of another agency", role: "admin", method: http.MethodPost, path: "/promo-codes", body: `{"code": "ROME", "percent": 5, "holidays": [{roman-holiday}]}`, status: http.StatusBadRequest},
+		{name: "create as agent", role: "agent", method: http.MethodPost, path: "/promo-codes", body: `{"code": "CHEAP", "percent": 50}`, status: http.StatusForbidden},
+		{
+			name: "update", role: "admin", method: http.MethodPut, path: "/promo-codes", status: http.StatusOK,
+			body:  `{"id": {summer10}, "code": "SUMMER15", "percent": 15, "holidays": [{sea}]}`,
+			want:  `{"id": {summer10}, "code": "SUMMER15", "percent": 15}`,
+			check: readBack("/promo-codes/{summer10}", http.StatusOK, `{"code": "SUMMER15", "percent": 15, "holidays": [{sea}]}`),
+		},
+		{name: "update missing", role: "admin", method: http.MethodPut, path: "/promo-codes", body: `{"id": 999, "code": "MISSING", "percent": 5}`, status: http.StatusBadRequest},
+		{name: "update as agent", role: "agent", method: http.MethodPut, path: "/promo-codes", body: `{"id": {summer10}, "code": "SUMMER90", "percent": 90}`, status: http.StatusForbidden},
+		{
+			name: "delete", role: "admin", method: http.MethodDelete, path: "/promo-codes/{expired}", status: http.StatusOK,
+			want:  `{"id": {expired}, "code": "EXPIRED"}`,
+			check: readBack("/promo-codes/{expired}", http.StatusInternalServerError, notFound),
+		},
+		{
+			name: "delete redeemed", role: "admin", method: http.MethodGet, path: "/promo-codes/{welcome50}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "ski", "WELCOME50", http.StatusOK)
+
+				s.Do(t, apitest.Request{Method: http.MethodDelete, Path: s.Expand(t, "/promo-codes/{welcome50}"), Token: s.Token(t, "admin")}).AssertStatus(t, http.StatusInternalServerError)
+			},
+		},
+		{name: "delete as agent", role: "agent", method: http.MethodDelete, path: "/promo-codes/{expired}", status: http.StatusForbidden},
+		{
+			name: "audited", role: "admin", method: http.MethodDelete, path: "/promo-codes/{expired}", status: http.StatusOK,
+			check: readBack("/audit?resource=promocode&id={expired}", http.StatusOK, `[{"action": "create"}, {"action": "delete", "diff": {"code": {"before": "EXPIRED", "after": null}}}]`),
+		},
+		{
+			name: "redeem a percent", role: "admin", method: http.MethodGet, path: "/promo-codes/{summer10}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				booked := reserve(t, s, "sea", " summer10 ", http.StatusOK)
+
+				readBack("/reservations/{created}", http.StatusOK, `{"promo": {"code": "SUMMER10", "discount": {"amount": "-106.98", "currency": "EUR"}},
+					"pricing": {"price": {"amount": "962.81", "currency": "EUR"}}}`)(t, s, booked)
+				readBack("/reservations/{created}?currency=GBP", http.StatusOK, `{"promo": {"discount": {"amount": "-91.37", "currency": "GBP"}}}`)(t, s, booked)
+			},
+		},
+		{
+			name: "redeem an amount", role: "admin", method: http.MethodGet, path: "/promo-codes/{welcome50}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				booked := reserve(t, s, "ski", "WELCOME50", http.StatusOK)
+
+				readBack("/reservations/{created}", http.StatusOK, `{"customerID": {petar}, "promo": {"code": "WELCOME50", "discount": {"amount": "-50.00", "currency": "EUR"}},
+					"pricing": {"adjustments": [{"rule": "WELCOME50", "kind": "promo", "amount": {"amount": "-50.00", "currency": "EUR"}}], "price": {"amount": "600.00", "currency": "EUR"}}}`)(t, s, booked)
+			},
+		},
+		{
+			name: "redeem once per customer", role: "admin", method: http.MethodGet, path: "/promo-codes/{welcome50}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "ski", "WELCOME50", http.StatusOK)
+				reserve(t, s, "ski", "WELCOME50", http.StatusBadRequest)
+
+				// Maria has not redeemed it yet
+				s.Do(t, apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   s.Expand(t, `{"contactName": "Maria Ivanova", "phoneNumber": "0888 123 456", "holiday": {ski}, "promoCode": "WELCOME50"}`),
+					Token:  token(t, s, "customer"),
+				}).AssertStatus(t, http.StatusOK)
+			},
+		},
+		{
+			name: "redeem a used up code", role: "admin", method: http.MethodGet, path: "/promo-codes/{sofia5}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "city-break", "SOFIA5", http.StatusOK)
+
+				s.Do(t, apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   s.Expand(t, `{"contactName": "Maria Ivanova", "phoneNumber": "0888 123 456", "holiday": {ski}, "promoCode": "SOFIA5"}`),
+					Token:  token(t, s, "customer"),
+				}).AssertStatus(t, http.StatusBadRequest)
+			},
+		},
+		{
+			name: "redeem on another holiday", role: "admin", method: http.MethodGet, path: "/promo-codes/{summer10}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "ski", "SUMMER10", http.StatusBadRequest)
+			},
+		},
+		{
+			name: "redeem in another location", role: "admin", method: http.MethodGet, path: "/promo-codes/{sofia5}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "sea", "SOFIA5", http.StatusBadRequest)
+			},
+		},
+		{
+			name: "redeem below the minimum spend", role: "admin", method: http.MethodGet, path: "/promo-codes/{welcome50}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "city-break", "WELCOME50", http.StatusBadRequest)
+			},
+		},
+		{
+			name: "redeem an expired code", role: "admin", method: http.MethodGet, path: "/promo-codes/{expired}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "ski", "EXPIRED", http.StatusBadRequest)
+			},
+		},
+		{
+			name: "redeem a code of another agency", role: "admin", method: http.MethodGet, path: "/promo-codes/{summer10}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "ski", "ROMAN10", http.StatusBadRequest)
+			},
+		},
+		{
+			name: "quote", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: `{"adults": 2, "children": [{"age": 8}], "extras": ["airport transfer"], "promoCode": "summer10"}`,
+			want: `{"promoCode": "SUMMER10", "items": [
+					{"kind": "traveller", "name": "Adult"},
+					{"kind": "traveller", "name": "Child"},
+					{"kind": "child", "name": "Child"},
+					{"kind": "extra", "name": "Airport transfer"},
+					{"kind": "fee", "name": "Booking fee"},
+					{"kind": "promo", "name": "SUMMER10", "quantity": 1, "percent": -10, "amount": {"amount": "-276.45", "currency": "EUR"}},
+					{"kind": "tax", "name": "Tourist tax", "amount": {"amount": "223.92", "currency": "EUR"}}
+				],
+				"total": {"amount": "2711.94", "currency": "EUR"}}`,
+			check: bookQuote(http.StatusOK, "", `{"promo": {"code": "SUMMER10", "discount": {"amount": "-276.45", "currency": "EUR"}}, "quote": {"total": {"amount": "2711.94", "currency": "EUR"}}}`),
+		},
+		{
+			name: "book a quote without the discount of its code", role: "customer", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: `{"adults": 1, "promoCode": "SUMMER10"}`,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var quoted struct {
+					Token string `json:"token"`
+				}
+				r.Decode(t, &quoted)
+				payload, _, _ := strings.Cut(quoted.Token, ".")
+				data, err := base64.RawURLEncoding.DecodeString(payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var q quote.Quote
+				if err := json.Unmarshal(data, &q); err != nil {
+					t.Fatal(err)
+				}
+
+				// a quote naming the code without taking it off would book
+				// without redeeming it
+				items := []pricing.Item{}
+				for _, item := range q.Items {
+					if item.Kind != pricing.KindPromo {
+						items = append(items, item)
+					}
+				}
+				q.Items = items
+				_, token, err := quote.NewSigner(apitest.QuoteSecret, quote.DefaultTTL).Sign(q)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				s.Do(t, apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "quoteToken": "` + token + `"}`,
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusBadRequest).AssertJSON(t, `"the promo code of the quote takes nothing off: SUMMER10"`)
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: s.Expand(t, "/promo-codes/{summer10}/redemptions"), Token: s.Token(t, "agent")}).
+					AssertStatus(t, http.StatusOK).
+					AssertJSON(t, `{"count": 0}`)
+			},
+		},
+		{name: "quote with a code for another holiday", role: "customer", method: http.MethodPost, path: "/holidays/{ski}/quote", body: `{"adults": 1, "promoCode": "SUMMER10"}`, status: http.StatusBadRequest},
+		{
+			name: "book a quote of a code used up since", role: "customer", method: http.MethodPost, path: "/holidays/{ski}/quote", status: http.StatusOK,
+			body: `{"adults": 1, "promoCode": "SOFIA5"}`,
+			want: `{"total": {"amount": "617.50", "currency": "EUR"}}`,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				reserve(t, s, "city-break", "SOFIA5", http.StatusOK)
+
+				bookQuote(http.StatusBadRequest, "", "")(t, s, r)
+			},
+		},
+		{
+			name: "redemptions", role: "agent", method: http.MethodGet, path: "/promo-codes/{welcome50}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var id int
+				reserve(t, s, "ski", "WELCOME50", http.StatusOK).Decode(t, &id)
+
+				readBack("/promo-codes/{welcome50}/redemptions", http.StatusOK, `{"promoCode": {welcome50}, "code": "WELCOME50", "count": 1,
+					"discounts": [{"amount": "-50.00", "currency": "EUR"}],
+					"redemptions": [{"reservation": `+strconv.Itoa(id)+`, "holiday": {ski}, "customerID": {petar}, "discount": {"amount": "-50.00", "currency": "EUR"}}]}`)(t, s, r)
+			},
+		},
+		{name: "redemptions of an unused code", role: "agent", method: http.MethodGet, path: "/promo-codes/{sofia5}/redemptions", status: http.StatusOK, want: `{"count": 0, "discounts": [], "redemptions": []}`},
+		{name: "redemptions as customer", role: "customer", method: http.MethodGet, path: "/promo-codes/{sofia5}/redemptions", status: http.StatusForbidden},
+	})
+}
+
+func TestLocations(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "list", role: "customer", method: http.MethodGet, path: "/locations", status: http.StatusOK,
+			want: `[{"id": {sofia}, "city": "Sofia"}, {"id": {varna}, "city": "Varna"}, {"id": {plovdiv}, "city": "Plovdiv"}]`,
+		},
+		{name: "list anonymous", method: http.MethodGet, path: "/locations", status: http.StatusUnauthorized},
+		{name: "get", role: "agent", method: http.MethodGet, path: "/locations/{varna}", status: http.StatusOK, want: `{"id": {varna}, "street": "Primorski", "number": "5", "city": "Varna", "country": "Bulgaria"}`},
+		{name: "get of another agency", role: "admin", method: http.MethodGet, path: "/locations/{rome}", status: http.StatusInternalServerError, want: notFound},
+		{
+			name: "create", role: "admin", method: http.MethodPost, path: "/locations", status: http.StatusOK,
+			body:  `{"street": "Tsar Simeon", "number": "3", "city": "Burgas", "country": "Bulgaria"}`,
+			check: readBack("/locations/{created}", http.StatusOK, `{"street": "Tsar Simeon", "city": "Burgas"}`),
+		},
+		{name: "create as agent", role: "agent", method: http.MethodPost, path: "/locations", body: `{"city": "Burgas"}`, status: http.StatusForbidden},
+		{
+			name: "update", role: "admin", method: http.MethodPut, path: "/locations", status: http.StatusOK,
+			body:  `{"id": {plovdiv}, "street": "Glavna", "number": "14", "city": "Plovdiv", "country": "Bulgaria"}`,
+			want:  `{"id": {plovdiv}, "number": "14"}`,
+			check: readBack("/locations/{plovdiv}", http.StatusOK, `{"number": "14"}`),
+		},
+		{
+			name: "delete", role: "admin", method: http.MethodDelete, path: "/locations/{plovdiv}", status: http.StatusOK,
+			want:  `{"id": {plovdiv}, "city": "Plovdiv"}`,
+			check: readBack("/locations/{plovdiv}", http.StatusInternalServerError, notFound),
+		},
+		{name: "delete with holidays", role: "admin", method: http.MethodDelete, path: "/locations/{sofia}", status: http.StatusInternalServerError},
+	})
+}
+
+func TestReservations(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "list", role: "agent", method: http.MethodGet, path: "/reservations", status: http.StatusOK,
+			want: `[
+				{"id": {maria-ski}, "contactName": "Maria Ivanova", "customerID": {maria}, "phoneE164": "+359888123456", "holiday": {"id": {ski}, "location": {"id": {sofia}}}},
+				{"id": {petar-sea}, "customerID": {petar}, "holiday": {"id": {sea}}}
+			]`,
+		},
+		{name: "list own", role: "customer", method: http.MethodGet, path: "/reservations", status: http.StatusOK, want: `[{"id": {maria-ski}}]`},
+		{
+			name: "get", role: "agent", method: http.MethodGet, path: "/reservations/{maria-ski}", status: http.StatusOK,
+			want: `{"id": {maria-ski}, "contactName": "Maria Ivanova", "phoneNumber": "0888 123 456", "holiday": {ski}, "customerID": {maria}, "phone": {"e164": "+359888123456", "region": "BG"},
+				"pricing": {"basePrice": {"amount": "650.00", "currency": "EUR"}, "adjustments": [], "price": {"amount": "650.00", "currency": "EUR"}}}`,
+		},
+		{
+			name: "get priced", role: "agent", method: http.MethodGet, path: "/reservations/{petar-sea}", status: http.StatusOK,
+			want: `{"id": {petar-sea}, "pricing": {"basePrice": {"amount": "900.50", "currency": "EUR"}, "adjustments": [{"rule": "High season"}, {"rule": "Early bird"}, {"rule": "Almost full"}], "price": {"amount": "1069.79", "currency": "EUR"}}}`,
+		},
+		{
+			name: "booked price stays", role: "admin", method: http.MethodPut, path: "/holidays", status: http.StatusOK,
+			body:  `{"id": {sea}, "title": "Black sea", "startDate": "2030-07-01T00:00:00Z", "duration": 10, "price": {"amount": "1500.00", "currency": "EUR"}, "freeSlots": 4, "location": {varna}}`,
+			check: readBack("/reservations/{petar-sea}", http.StatusOK, `{"pricing": {"basePrice": {"amount": "900.50", "currency": "EUR"}, "price": {"amount": "1069.79", "currency": "EUR"}}}`),
+		},
+		{
+			name: "get in another currency", role: "agent", method: http.MethodGet, path: "/reservations/{petar-sea}?currency=GBP", status: http.StatusOK,
+			want: `{"id": {petar-sea}, "pricing": {"basePrice": {"amount": "769.12", "currency": "GBP"}, "price": {"amount": "913.71", "currency": "GBP"}},
+				"exchangeRates": {"base": "EUR", "date": "2030-01-02T00:00:00Z", "rates": {"GBP": 0.8541, "USD": 1.0866}}}`,
+		},
+		{
+			name: "booked rates stay", role: "admin", method: http.MethodPut, path: "/exchange-rates", status: http.StatusOK,
+			body:  `{"currency": "GBP", "rate": 0.9}`,
+			check: readBack("/reservations/{petar-sea}?currency=GBP", http.StatusOK, `{"pricing": {"price": {"amount": "913.71", "currency": "GBP"}}, "exchangeRates": {"rates": {"GBP": 0.8541}}}`),
+		},
+		{name: "get in a currency without a rate", role: "agent", method: http.MethodGet, path: "/reservations/{petar-sea}?currency=CHF", status: http.StatusBadRequest},
+		{
+			name: "list in another currency", role: "agent", method: http.MethodGet, path: "/reservations?currency=GBP", status: http.StatusOK,
+			want: `[{"id": {maria-ski}, "holiday": {"price": {"amount": "555.17", "currency": "GBP"}}}, {"id": {petar-sea}, "holiday": {"price": {"amount": "769.12", "currency": "GBP"}}}]`,
+		},
+		{name: "list own in another currency", role: "customer", method: http.MethodGet, path: "/reservations?currency=USD", status: http.StatusOK, want: `[{"id": {maria-ski}, "holiday": {"price": {"amount": "706.29", "currency": "USD"}}}]`},
+		{name: "list in a currency without a rate", role: "agent", method: http.MethodGet, path: "/reservations?currency=CHF", status: http.StatusBadRequest},
+		{name: "get own", role: "customer", method: http.MethodGet, path: "/reservations/{maria-ski}", status: http.StatusOK, want: `{"id": {maria-ski}}`},
+		{name: "get of another customer", role: "customer", method: http.MethodGet, path: "/reservations/{petar-sea}", status: http.StatusForbidden},
+		{name: "get missing", role: "agent", method: http.MethodGet, path: "/reservations/999", status: http.StatusInternalServerError, want: notFound},
+		{
+			name: "create matching a customer", role: "agent", method: http.MethodPost, path: "/reservations", status: http.StatusOK,
+			body:  `{"contactName": "Petar", "phoneNumber": "+359 899 654 321", "holiday": {ski}}`,
+			check: readBack("/reservations/{created}", http.StatusOK, `{"holiday": {ski}, "customerID": {petar}}`),
+		},
+		{
+			name: "create for a new customer", role: "agent", method: http.MethodPost, path: "/reservations", status: http.StatusOK,
+			body:  `{"contactName": "Elena", "phoneNumber": "0878 999 000", "email": "elena@example.com", "holiday": {sea}}`,
+			check: readBack("/customers", http.StatusOK, `[{"id": {maria}}, {"id": {petar}}, {"id": {ivan}}, {"name": "Elena", "email": "elena@example.com"}]`),
+		},
+		{
+			name: "create own", role: "customer", method: http.MethodPost, path: "/reservations", status: http.StatusOK,
+			body:  `{"contactName": "Maria Ivanova", "phoneNumber": "0888 123 456", "holiday": {sea}, "customerID": {petar}}`,
+			check: readBack("/reservations/{created}", http.StatusOK, `{"holiday": {sea}, "customerID": {maria}}`),
+		},
+		{name: "create with invalid phone", role: "agent", method: http.MethodPost, path: "/reservations", body: `{"contactName": "X", "phoneNumber": "call me", "holiday": {ski}}`, status: http.StatusBadRequest},
+		{
+			name: "update", role: "agent", method: http.MethodPut, path: "/reservations", status: http.StatusOK,
+			body:  `{"id": {maria-ski}, "contactName": "Maria I.", "phoneNumber": "0888 123 456", "holiday": {city-break}, "customerID": {maria}}`,
+			want:  `{"id": {maria-ski}, "contactName": "Maria I.", "holiday": {city-break}}`,
+			check: readBack("/reservations/{maria-ski}", http.StatusOK, `{"contactName": "Maria I.", "holiday": {city-break}, "pricing": {"basePrice": {"amount": "199.00", "currency": "EUR"}, "price": {"amount": "199.00", "currency": "EUR"}}}`),
+		},
+		{name: "update as customer", role: "customer", method: http.MethodPut, path: "/reservations", body: `{"id": {maria-ski}}`, status: http.StatusForbidden},
+		{
+			name: "delete", role: "agent", method: http.MethodDelete, path: "/reservations/{petar-sea}", status: http.StatusOK,
+			want:  `{"id": {petar-sea}}`,
+			check: readBack("/reservations/{petar-sea}", http.StatusInternalServerError, notFound),
+		},
+		{name: "delete as customer", role: "customer", method: http.MethodDelete, path: "/reservations/{maria-ski}", status: http.StatusForbidden},
+	})
+}
+
+// created is a payment as a create answered with.
+type created struct {
+	ID           int    `json:"id"`
+	IntentID     string `json:"intent"`
+	ClientSecret string `json:"clientSecret"`
+}
+
+// pay starts a payment of the reservation ref as an agent.
+func pay(t *testing.T, s *apitest.Server, ref string, body string, status int) created {
+	t.Helper()
+
+	var payment created
+	r := s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/reservations/{" + ref + "}/payments", Body: body, Token: s.Token(t, "agent")}).AssertStatus(t, status)
+	if status == http.StatusOK {
+		r.Decode(t, &payment)
+	}
+
+	return payment
+}
+
+// settle confirms or fails an intent at the checkout page of the fake gateway,
+// which delivers its webhook.
+func settle(t *testing.T, s *apitest.Server, intentID string, outcome string) *apitest.Response {
+	t.Helper()
+
+	return s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/fake-gateway/" + intentID + "/" + outcome}).AssertStatus(t, http.StatusOK)
+}
+
+// expire moves the creation of a payment past the intent TTL.
+func expire(t *testing.T, s *apitest.Server, paymentID int) {
+	t.Helper()
+
+	ctx := tenant.WithID(context.Background(), apitest.DefaultTenant)
+	stored, err := s.Storage.Payment(ctx, paymentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.CreatedAt = stored.CreatedAt.Add(-payment.DefaultIntentTTL - time.Minute)
+	if _, err := s.Storage.UpdatePayment(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPayments(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "schedule", role: "agent", method: http.MethodGet, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			want: `{"reservation": {maria-ski}, "total": {"amount": "650.00", "currency": "EUR"}, "paid": {"amount": "0.00", "currency": "EUR"}, "outstanding": {"amount": "650.00", "currency": "EUR"},
+				"schedule": [{"kind": "deposit", "amount": {"amount": "195.00", "currency": "EUR"}}, {"kind": "balance", "amount": {"amount": "455.00", "currency": "EUR"}, "dueDate": "2029-12-11T00:00:00Z"}],
+				"payments": []}`,
+		},
+		{name: "schedule of another customer", role: "customer", method: http.MethodGet, path: "/reservations/{petar-sea}/payments", status: http.StatusForbidden},
+		{name: "schedule of own", role: "customer", method: http.MethodGet, path: "/reservations/{maria-ski}/payments", status: http.StatusOK, want: `{"reservation": {maria-ski}}`},
+		{name: "schedule of a missing reservation", role: "agent", method: http.MethodGet, path: "/reservations/999/payments", status: http.StatusInternalServerError, want: notFound},
+		{
+			name: "pay the deposit", role: "customer", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			want: `{"reservation": {maria-ski}, "kind": "deposit", "amount": {"amount": "195.00", "currency": "EUR"}, "status": "pending", "gateway": "fake", "refunded": {"amount": "0.00", "currency": "EUR"}}`,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var payment created
+				r.Decode(t, &payment)
+				if payment.IntentID == "" || payment.ClientSecret == "" {
+					t.Fatalf("got %+v", payment)
+				}
+
+				settle(t, s, payment.IntentID, "succeed").AssertJSON(t, `{"status": "succeeded"}`)
+
+				readBack("/reservations/{maria-ski}/payments", http.StatusOK, `{"paid": {"amount": "195.00", "currency": "EUR"}, "outstanding": {"amount": "455.00", "currency": "EUR"},
+					"payments": [{"id": `+strconv.Itoa(payment.ID)+`, "kind": "deposit", "status": "succeeded"}]}`)(t, s, r)
+				// the client secret is only handed out once
+				if read := s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/payments/" + strconv.Itoa(payment.ID), Token: s.Token(t, "agent")}); strings.Contains(string(read.Body), "clientSecret") {
+					t.Errorf("client secret read back: %s", read.Body)
+				}
+			},
+		},
+		{
+			name: "pay the balance", role: "agent", method: http.MethodGet, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				settle(t, s, pay(t, s, "maria-ski", "", http.StatusOK).IntentID, "succeed")
+
+				balance := s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/reservations/{maria-ski}/payments", Token: s.Token(t, "agent")}).AssertStatus(t, http.StatusOK)
+				balance.AssertJSON(t, `{"kind": "balance", "amount": {"amount": "455.00", "currency": "EUR"}, "dueDate": "2029-12-11T00:00:00Z"}`)
+				var payment created
+				balance.Decode(t, &payment)
+				settle(t, s, payment.IntentID, "succeed")
+
+				readBack("/reservations/{maria-ski}/payments", http.StatusOK, `{"paid": {"amount": "650.00", "currency": "EUR"}, "outstanding": {"amount": "0.00", "currency": "EUR"},
+					"payments": [{"kind": "deposit"}, {"kind": "balance"}]}`)(t, s, r)
+				pay(t, s, "maria-ski", "", http.StatusBadRequest)
+			},
+		},
+		{
+			name: "pay in full", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", body: `{"full": true}`, status: http.StatusOK,
+			want: `{"kind": "full", "amount": {"amount": "650.00", "currency": "EUR"}, "dueDate": "2029-12-11T00:00:00Z"}`,
+		},
+		{
+			name: "pay while a payment is pending", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				pay(t, s, "maria-ski", "", http.StatusBadRequest)
+			},
+		},
+		{
+			name: "pay after a failed payment", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var failed created
+				r.Decode(t, &failed)
+				settle(t, s, failed.IntentID, "fail").AssertJSON(t, `{"status": "failed"}`)
+
+				retry := pay(t, s, "maria-ski", "", http.StatusOK)
+				readBack("/payments/"+strconv.Itoa(retry.ID), http.StatusOK, `{"kind": "deposit", "status": "pending"}`)(t, s, r)
+				readBack("/reservations/{maria-ski}/payments", http.StatusOK, `{"paid": {"amount": "0.00", "currency": "EUR"}, "payments": [{"status": "failed"}, {"status": "pending"}]}`)(t, s, r)
+			},
+		},
+		{
+			name: "pay after an intent expired", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var stale created
+				r.Decode(t, &stale)
+
+				// the customer never paid, after the TTL a new payment supersedes it
+				expire(t, s, stale.ID)
+				retry := pay(t, s, "maria-ski", "", http.StatusOK)
+				readBack("/reservations/{maria-ski}/payments", http.StatusOK, `{"payments": [{"id": `+strconv.Itoa(stale.ID)+`, "status": "failed"}, {"id": `+strconv.Itoa(retry.ID)+`, "status": "pending"}]}`)(t, s, r)
+
+				// its intent is cancelled at the gateway
+				if _, _, err := s.Gateway.Complete(stale.IntentID, true); err == nil {
+					t.Error("the expired intent was paid")
+				}
+				settle(t, s, retry.IntentID, "succeed")
+				readBack("/reservations/{maria-ski}/payments", http.StatusOK, `{"paid": {"amount": "195.00", "currency": "EUR"}}`)(t, s, r)
+			},
+		},
+		{
+			name: "cancel after an intent expired", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var stale created
+				r.Decode(t, &stale)
+				expire(t, s, stale.ID)
+
+				s.Do(t, apitest.Request{Method: http.MethodDelete, Path: "/reservations/{maria-ski}", Token: s.Token(t, "agent")}).AssertStatus(t, http.StatusOK)
+				readBack("/payments/"+strconv.Itoa(stale.ID), http.StatusOK, `{"status": "failed"}`)(t, s, r)
+			},
+		},
+		{
+			name: "expired intent paid meanwhile", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var late created
+				r.Decode(t, &late)
+				expire(t, s, late.ID)
+
+				// paid at the gateway, the webhook is still on its way
+				body, signature, err := s.Gateway.Complete(late.IntentID, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Do(t, apitest.Request{Method: http.MethodDelete, Path: "/reservations/{maria-ski}", Token: s.Token(t, "agent")}).AssertStatus(t, http.StatusConflict)
+				pay(t, s, "maria-ski", "", http.StatusBadRequest)
+
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/webhooks/payments", Body: string(body), Header: map[string]string{"Payment-Signature": signature}}).
+					AssertStatus(t, http.StatusOK).AssertJSON(t, `{"status": "succeeded"}`)
+			},
+		},
+		{
+			name: "gateway down", role: "agent", method: http.MethodGet, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				// the payment is stored first and given up when the gateway fails
+				s.Gateway.FailNext(errors.New("gateway unavailable"))
+				pay(t, s, "maria-ski", "", http.StatusBadRequest)
+				readBack("/reservations/{maria-ski}/payments", http.StatusOK, `{"payments": [{"status": "failed"}]}`)(t, s, r)
+
+				retry := pay(t, s, "maria-ski", "", http.StatusOK)
+				settle(t, s, retry.IntentID, "succeed")
+
+				// a refund the gateway refuses pays nothing back and can be retried
+				refund := apitest.Request{Method: http.MethodPost, Path: "/payments/" + strconv.Itoa(retry.ID) + "/refund", Token: s.Token(t, "admin")}
+				s.Gateway.FailNext(errors.New("gateway unavailable"))
+				s.Do(t, refund).AssertStatus(t, http.StatusBadRequest).AssertJSON(t, `"gateway unavailable"`)
+				readBack("/payments/"+strconv.Itoa(retry.ID), http.StatusOK, `{"status": "succeeded", "refunded": {"amount": "0.00", "currency": "EUR"}}`)(t, s, r)
+				s.Do(t, refund).AssertStatus(t, http.StatusOK).AssertJSON(t, `{"status": "refunded", "refunded": {"amount": "195.00", "currency": "EUR"}}`)
+
+				readBack("/audit?resource=payment&id="+strconv.Itoa(retry.ID), http.StatusOK, `[{"action": "create"}, {"action": "update", "diff": {"intent": {"before": null}}},
+					{"action": "update", "diff": {"status": {"before": "pending", "after": "succeeded"}}},
+					{"action": "update", "diff": {"status": {"before": "succeeded", "after": "refunded"}, "refunding": {"before": {"amount": "195.00", "currency": "EUR"}, "after": null}}}]`)(t, s, r)
+			},
+		},
+		{name: "pay for another customer", role: "customer", method: http.MethodPost, path: "/reservations/{petar-sea}/payments", status: http.StatusForbidden},
+		{name: "pay a missing reservation", role: "agent", method: http.MethodPost, path: "/reservations/999/payments", status: http.StatusBadRequest},
+		{
+			name: "read a payment of another customer", role: "agent", method: http.MethodPost, path: "/reservations/{petar-sea}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var payment created
+				r.Decode(t, &payment)
+
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/payments/" + strconv.Itoa(payment.ID), Token: token(t, s, "customer")}).AssertStatus(t, http.StatusForbidden)
+			},
+		},
+		{
+			name: "webhook with a bad signature", method: http.MethodPost, path: "/webhooks/payments", status: http.StatusBadRequest,
+			body:   `{"id": "evt_1", "type": "payment.succeeded", "intent": "pi_fake_1", "metadata": {"tenant": "1", "payment": "1"}}`,
+			header: map[string]string{"Payment-Signature": "t=1893456000,v1=00"},
+			want:   `"invalid webhook signature"`,
+		},
+		{
+			name: "webhook delivered twice", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var payment created
+				r.Decode(t, &payment)
+
+				body, signature, err := s.Gateway.Complete(payment.IntentID, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 2; i++ {
+					s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/webhooks/payments", Body: string(body), Header: map[string]string{"Payment-Signature": signature}}).
+						AssertStatus(t, http.StatusOK).AssertJSON(t, `{"status": "succeeded"}`)
+				}
+
+				readBack("/audit?resource=payment&id="+strconv.Itoa(payment.ID), http.StatusOK, `[{"action": "create", "actor": {"type": "user"}},
+					{"action": "update", "actor": {"type": "user"}, "diff": {"intent": {"before": null, "after": "`+payment.IntentID+`"}}},
+					{"action": "update", "actor": {"type": "gateway", "name": "fake"}, "diff": {"status": {"before": "pending", "after": "succeeded"}}}]`)(t, s, r)
+			},
+		},
+		{
+			name: "refund", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var payment created
+				r.Decode(t, &payment)
+				refund := "/payments/" + strconv.Itoa(payment.ID) + "/refund"
+
+				// only what was paid is refunded
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: refund, Token: s.Token(t, "admin")}).AssertStatus(t, http.StatusBadRequest)
+				settle(t, s, payment.IntentID, "succeed")
+
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: refund, Body: `{"amount": {"amount": "95.00", "currency": "EUR"}}`, Token: s.Token(t, "agent")}).AssertStatus(t, http.StatusForbidden)
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: refund, Body: `{"amount": {"amount": "195.01", "currency": "EUR"}}`, Token: s.Token(t, "admin")}).AssertStatus(t, http.StatusBadRequest)
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: refund, Body: `{"amount": {"amount": "95.00", "currency": "EUR"}}`, Token: s.Token(t, "admin")}).
+					AssertStatus(t, http.StatusOK).AssertJSON(t, `{"status": "succeeded", "refunded": {"amount": "95.00", "currency": "EUR"}}`)
+				readBack("/reservations/{maria-ski}/payments", http.StatusOK, `{"paid": {"amount": "100.00", "currency": "EUR"}, "outstanding": {"amount": "550.00", "currency": "EUR"}}`)(t, s, r)
+
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: refund, Token: s.Token(t, "admin")}).
+					AssertStatus(t, http.StatusOK).AssertJSON(t, `{"status": "refunded", "refunded": {"amount": "195.00", "currency": "EUR"}}`)
+				readBack("/reservations/{maria-ski}/payments", http.StatusOK, `{"paid": {"amount": "0.00", "currency": "EUR"}}`)(t, s, r)
+			},
+		},
+		{
+			name: "cancel a paid reservation", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var payment created
+				r.Decode(t, &payment)
+				cancel := apitest.Request{Method: http.MethodDelete, Path: "/reservations/{maria-ski}", Token: s.Token(t, "agent")}
+
+				// not while the gateway may still collect, nor while the money is held
+				s.Do(t, cancel).AssertStatus(t, http.StatusConflict).AssertJSON(t, `"a payment of the reservation is pending: payment `+strconv.Itoa(payment.ID)+`"`)
+				settle(t, s, payment.IntentID, "succeed")
+				s.Do(t, cancel).AssertStatus(t, http.StatusConflict)
+
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/payments/" + strconv.Itoa(payment.ID) + "/refund", Token: s.Token(t, "admin")}).AssertStatus(t, http.StatusOK)
+				s.Do(t, cancel).AssertStatus(t, http.StatusOK)
+
+				// the payment is kept for the books
+				readBack("/payments/"+strconv.Itoa(payment.ID), http.StatusOK, `{"status": "refunded", "refunded": {"amount": "195.00", "currency": "EUR"}}`)(t, s, r)
+				readBack("/reservations/{maria-ski}/payments", http.StatusInternalServerError, notFound)(t, s, r)
+			},
+		},
+		{
+			name: "cancel after a failed payment", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/payments", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var payment created
+				r.Decode(t, &payment)
+				settle(t, s, payment.IntentID, "fail")
+
+				s.Do(t, apitest.Request{Method: http.MethodDelete, Path: "/reservations/{maria-ski}", Token: s.Token(t, "agent")}).AssertStatus(t, http.StatusOK)
+			},
+		},
+	})
+}
+
+// invoicePDF fetches the PDF at path as role and checks it holds every
+// string of the text, in PDF syntax.
+func invoicePDF(t *testing.T, s *apitest.Server, path string, role string, text ...string) *apitest.Response {
+	t.Helper()
+
+	r := s.Do(t, apitest.Request{Method: http.MethodGet, Path: path, Token: token(t, s, role)}).AssertStatus(t, http.StatusOK)
+	if got := r.Header.Get("Content-Type"); got != "application/pdf" {
+		t.Fatalf("content type %s", got)
+	}
+	if !strings.HasPrefix(string(r.Body), "%PDF-") {
+		t.Fatalf("not a PDF: %.40q", r.Body)
+	}
+	for _, s := range text {
+		if !strings.Contains(string(r.Body), s) {
+			t.Errorf("%s missing", s)
+		}
+	}
+
+	return r
+}
+
+// issueInvoice issues the invoice of the reservation at path and returns its
+// number.
+func issueInvoice(t *testing.T, s *apitest.Server, path string, role string) string {
+	t.Helper()
+
+	var issued struct {
+		Number string `json:"number"`
+	}
+	s.Do(t, apitest.Request{Method: http.MethodPost, Path: path + "/invoice", Token: token(t, s, role)}).
+		AssertStatus(t, http.StatusOK).
+		Decode(t, &issued)
+
+	return issued.Number
+}
+
+func TestInvoices(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "issue", role: "agent", method: http.MethodGet, path: "/reservations/{maria-ski}/invoice.pdf", status: http.StatusInternalServerError, want: notFound,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				// reading does not issue
+				readBack("/reservations/{maria-ski}/invoices", http.StatusOK, `[]`)(t, s, r)
+
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/reservations/{maria-ski}/invoice", Token: s.Token(t, "agent")}).
+					AssertStatus(t, http.StatusOK).
+					AssertJSON(t, `{"reservation": {maria-ski}, "kind": "invoice", "number": "INV-000001", "total": {"amount": "650.00", "currency": "EUR"}}`)
+				first := invoicePDF(t, s, "/reservations/{maria-ski}/invoice.pdf", "agent",
+					"(INVOICE)", "(INV-000001)", "(Default agency)", "(Maria Ivanova)", "(maria@example.com)", "(Ski week, 7 days", "(Adult)", "(650.00)", "(Tax 0%)", "(Total EUR)")
+				if got := first.Header.Get("Content-Disposition"); got != `inline; filename="INV-000001.pdf"` {
+					t.Errorf("content disposition %s", got)
+				}
+
+				// the invoice is served as issued, issuing again returns it
+				if number := issueInvoice(t, s, "/reservations/{maria-ski}", "agent"); number != "INV-000001" {
+					t.Errorf("issued %s again", number)
+				}
+				again := invoicePDF(t, s, "/reservations/{maria-ski}/invoice.pdf", "agent")
+				if string(again.Body) != string(first.Body) {
+					t.Error("invoice issued again")
+				}
+
+				readBack("/reservations/{maria-ski}/invoices", http.StatusOK, `[{"reservation": {maria-ski}, "customerID": {maria}, "kind": "invoice", "number": "INV-000001", "total": {"amount": "650.00", "currency": "EUR"},
+					"document": {"issuer": {"name": "Default agency"}, "customer": {"name": "Maria Ivanova", "email": "maria@example.com", "phone": "0888 123 456"},
+						"lines": [{"description": "Adult", "quantity": 1, "unitPrice": {"amount": "650.00", "currency": "EUR"}, "amount": {"amount": "650.00", "currency": "EUR"}}],
+						"taxes": [{"description": "Tax", "quantity": 1, "unitPrice": {"amount": "0.00", "currency": "EUR"}, "amount": {"amount": "0.00", "currency": "EUR"}}],
+						"subtotal": {"amount": "650.00", "currency": "EUR"}}}]`)(t, s, r)
+				issueInvoice(t, s, "/reservations/{petar-sea}", "agent")
+				invoicePDF(t, s, "/reservations/{petar-sea}/invoice.pdf", "agent", "(INV-000002)")
+			},
+		},
+		{
+			name: "invoice of own", role: "customer", method: http.MethodPost, path: "/reservations/{maria-ski}/invoice", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				invoicePDF(t, s, "/reservations/{maria-ski}/invoice.pdf", "customer", "(INV-000001)")
+			},
+		},
+		{name: "issue for another customer", role: "customer", method: http.MethodPost, path: "/reservations/{petar-sea}/invoice", status: http.StatusForbidden},
+		{
+			name: "invoice of another customer", role: "agent", method: http.MethodPost, path: "/reservations/{petar-sea}/invoice", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/reservations/{petar-sea}/invoice.pdf", Token: token(t, s, "customer")}).AssertStatus(t, http.StatusForbidden)
+			},
+		},
+		{name: "issue for a missing reservation", role: "agent", method: http.MethodPost, path: "/reservations/999/invoice", status: http.StatusBadRequest, want: notFound},
+		{name: "invoice of a missing reservation", role: "agent", method: http.MethodGet, path: "/reservations/999/invoice.pdf", status: http.StatusInternalServerError, want: notFound},
+		{name: "invalid id", role: "agent", method: http.MethodGet, path: "/reservations/ski/invoice.pdf", status: http.StatusBadRequest},
+		{name: "issue with an invalid id", role: "agent", method: http.MethodPost, path: "/reservations/ski/invoice", status: http.StatusBadRequest},
+		{name: "without a token", method: http.MethodGet, path: "/reservations/{maria-ski}/invoice.pdf", status: http.StatusUnauthorized},
+		{name: "issue without a token", method: http.MethodPost, path: "/reservations/{maria-ski}/invoice", status: http.StatusUnauthorized},
+		{
+			name: "priced by rules", role: "agent", method: http.MethodPost, path: "/reservations/{petar-sea}/invoice", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var invoices []struct {
+					Total struct {
+						Amount string `json:"amount"`
+					} `json:"total"`
+				}
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/reservations/{petar-sea}/invoices", Token: s.Token(t, "agent")}).
+					AssertStatus(t, http.StatusOK).
+					AssertJSON(t, `[{"document": {"lines": [{"description": "Adult", "quantity": 1, "unitPrice": {"amount": "1069.79", "currency": "EUR"}}],
+						"taxes": [{"description": "Tax", "amount": {"amount": "0.00", "currency": "EUR"}}]}}]`).
+					Decode(t, &invoices)
+
+				// the invoice is for what the reservation is paid
+				readBack("/reservations/{petar-sea}/payments", http.StatusOK, `{"total": {"amount": "`+invoices[0].Total.Amount+`", "currency": "EUR"}}`)(t, s, r)
+			},
+		},
+		{
+			name: "booked without a quote", role: "agent", method: http.MethodGet, path: "/holidays/{ski}", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var id int
+				reserve(t, s, "ski", "WELCOME50", http.StatusOK).Decode(t, &id)
+
+				// one traveller at the booked price, the code taken off apart
+				path := "/reservations/" + strconv.Itoa(id)
+				issueInvoice(t, s, path, "agent")
+				invoicePDF(t, s, path+"/invoice.pdf", "agent", "(Adult)", "(WELCOME50)", "(-50.00)", "(600.00)")
+				readBack(path+"/invoices", http.StatusOK, `[{"total": {"amount": "600.00", "currency": "EUR"}, "document": {
+					"lines": [
+						{"description": "Adult", "quantity": 1, "unitPrice": {"amount": "650.00", "currency": "EUR"}, "amount": {"amount": "650.00", "currency": "EUR"}},
+						{"description": "WELCOME50", "quantity": 1, "unitPrice": {"amount": "-50.00", "currency": "EUR"}, "amount": {"amount": "-50.00", "currency": "EUR"}}
+					],
+					"taxes": [{"description": "Tax", "amount": {"amount": "0.00", "currency": "EUR"}}]}}]`)(t, s, r)
+				readBack(path+"/payments", http.StatusOK, `{"total": {"amount": "600.00", "currency": "EUR"}}`)(t, s, r)
+			},
+		},
+		{
+			name: "booked with a quote", role: "agent", method: http.MethodPost, path: "/holidays/{sea}/quote", status: http.StatusOK,
+			body: familyQuote,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var quote struct {
+					Token string `json:"token"`
+				}
+				r.Decode(t, &quote)
+
+				var id int
+				s.Do(t, apitest.Request{
+					Method: http.MethodPost,
+					Path:   "/reservations",
+					Body:   `{"contactName": "Petar Petrov", "phoneNumber": "0899 654 321", "quoteToken": "` + quote.Token + `"}`,
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusOK).Decode(t, &id)
+
+				path := "/reservations/" + strconv.Itoa(id)
+				issueInvoice(t, s, path, "agent")
+				invoicePDF(t, s, path+"/invoice.pdf", "agent", "(Adult)", "(2139.58)", "(Tourist tax 9%)")
+				readBack(path+"/invoices", http.StatusOK, `[{"document": {"lines": [{"description": "Adult", "quantity": 2}, {"description": "Child"}, {"description": "Child", "percent": -50}, {}, {}],
+					"taxes": [{"description": "Tourist tax", "quantity": 1, "percent": 9}]}}]`)(t, s, r)
+			},
+		},
+		{
+			name: "credit note on cancellation", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/invoice", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				s.Do(t, apitest.Request{Method: http.MethodDelete, Path: "/reservations/{maria-ski}", Token: s.Token(t, "agent")}).AssertStatus(t, http.StatusOK)
+
+				var invoices []struct {
+					ID int `json:"id"`
+				}
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/reservations/{maria-ski}/invoices", Token: token(t, s, "customer")}).
+					AssertStatus(t, http.StatusOK).
+					AssertJSON(t, `[{"kind": "invoice", "number": "INV-000001"},
+						{"kind": "credit_note", "number": "CN-000001", "customerID": {maria}, "total": {"amount": "-650.00", "currency": "EUR"},
+							"document": {"credits": "INV-000001", "lines": [{"unitPrice": {"amount": "-650.00", "currency": "EUR"}}]}}]`).
+					Decode(t, &invoices)
+				readBack("/invoices/"+strconv.Itoa(invoices[1].ID), http.StatusOK, `{"creditedInvoice": `+strconv.Itoa(invoices[0].ID)+`}`)(t, s, r)
+
+				// the customer keeps both documents of the cancelled reservation
+				invoicePDF(t, s, "/invoices/"+strconv.Itoa(invoices[1].ID)+".pdf", "customer", "(CREDIT NOTE)", "(CN-000001)", "(Cancels invoice INV-000001)", "(-650.00)")
+				invoicePDF(t, s, "/invoices/"+strconv.Itoa(invoices[0].ID)+".pdf", "customer", "(INV-000001)")
+
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/reservations/{maria-ski}/invoice.pdf", Token: s.Token(t, "agent")}).
+					AssertStatus(t, http.StatusInternalServerError).
+					AssertJSON(t, notFound)
+				readBack("/audit?resource=invoice", http.StatusOK, `[{"resourceID": `+strconv.Itoa(invoices[0].ID)+`, "action": "create"}, {"resourceID": `+strconv.Itoa(invoices[1].ID)+`, "action": "create"}]`)(t, s, r)
+			},
+		},
+		{
+			name: "cancelled without an invoice", role: "agent", method: http.MethodDelete, path: "/reservations/{petar-sea}", status: http.StatusOK,
+			check: readBack("/reservations/{petar-sea}/invoices", http.StatusOK, `[]`),
+		},
+		{
+			name: "credit note on moving to another holiday", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/invoice", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				s.Do(t, apitest.Request{
+					Method: http.MethodPut,
+					Path:   "/reservations",
+					Body:   s.Expand(t, `{"id": {maria-ski}, "contactName": "Maria Ivanova", "phoneNumber": "0888 123 456", "holiday": {city-break}, "customerID": {maria}}`),
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusOK)
+
+				// the credited invoice is not served, the next one is issued
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/reservations/{maria-ski}/invoice.pdf", Token: s.Token(t, "agent")}).
+					AssertStatus(t, http.StatusInternalServerError)
+				issueInvoice(t, s, "/reservations/{maria-ski}", "agent")
+				invoicePDF(t, s, "/reservations/{maria-ski}/invoice.pdf", "agent", "(INV-000002)", "(City break, ", "(199.00)")
+				readBack("/reservations/{maria-ski}/invoices", http.StatusOK, `[{"number": "INV-000001"}, {"number": "CN-000001"}, {"number": "INV-000002", "total": {"amount": "199.00", "currency": "EUR"}}]`)(t, s, r)
+
+				// changing the contact keeps the invoice
+				s.Do(t, apitest.Request{
+					Method: http.MethodPut,
+					Path:   "/reservations",
+					Body:   s.Expand(t, `{"id": {maria-ski}, "contactName": "Maria I.", "phoneNumber": "0888 123 456", "holiday": {city-break}, "customerID": {maria}}`),
+					Token:  s.Token(t, "agent"),
+				}).AssertStatus(t, http.StatusOK)
+				invoicePDF(t, s, "/reservations/{maria-ski}/invoice.pdf", "agent", "(INV-000002)", "(Maria Ivanova)")
+			},
+		},
+		{
+			name: "read an invoice of another customer", role: "agent", method: http.MethodPost, path: "/reservations/{petar-sea}/invoice", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				var invoices []struct {
+					ID int `json:"id"`
+				}
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/reservations/{petar-sea}/invoices", Token: s.Token(t, "agent")}).Decode(t, &invoices)
+
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/invoices/" + strconv.Itoa(invoices[0].ID) + ".pdf", Token: token(t, s, "customer")}).AssertStatus(t, http.StatusForbidden)
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/invoices/" + strconv.Itoa(invoices[0].ID), Token: token(t, s, "customer")}).AssertStatus(t, http.StatusForbidden)
+				s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/reservations/{petar-sea}/invoices", Token: token(t, s, "customer")}).AssertJSON(t, `[]`)
+			},
+		},
+		{name: "missing invoice", role: "agent", method: http.MethodGet, path: "/invoices/999.pdf", status: http.StatusInternalServerError, want: notFound},
+		{
+			name: "agency details", role: "agent", method: http.MethodPost, path: "/reservations/{maria-ski}/invoice", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				platform := s.TokenFor(t, auth.Claims{Subject: "ops", Role: "admin", Platform: true})
+				s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/reservations/{ivan-rome}/invoice", Token: platform, Header: map[string]string{"X-Tenant": "sunny"}}).
+					AssertStatus(t, http.StatusOK)
+				sunny := s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/reservations/{ivan-rome}/invoice.pdf", Token: platform, Header: map[string]string{"X-Tenant": "sunny"}}).
+					AssertStatus(t, http.StatusOK)
+
+				// every agency numbers its invoices from 1
+				for _, text := range []string{"(INV-000001)", "(Sunny Travel)", "(Vitosha 1)", "(Sofia)", "(office@sunny.example)", "(Tax number BG123456789)", "(Roman holiday, 5 days from 1 May 2030)"} {
+					if !strings.Contains(string(sunny.Body), text) {
+						t.Errorf("%s missing", text)
+					}
+				}
+			},
+		},
+	})
+}
+
+func TestCustomers(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "list", role: "agent", method: http.MethodGet, path: "/customers", status: http.StatusOK,
+			want: `[
+				{"id": {maria}, "name": "Maria Ivanova", "phoneNumber": "0888 123 456", "email": "maria@example.com"},
+				{"id": {petar}},
+				{"id": {ivan}, "email": ""}
+			]`,
+		},
+		{name: "list as customer", role: "customer", method: http.MethodGet, path: "/customers", status: http.StatusForbidden},
+		{name: "get", role: "agent", method: http.MethodGet, path: "/customers/{petar}", status: http.StatusOK, want: `{"id": {petar}, "name": "Petar Petrov"}`},
+		{name: "get own", role: "customer", method: http.MethodGet, path: "/customers/{maria}", status: http.StatusOK, want: `{"id": {maria}}`},
+		{name: "get another", role: "customer", method: http.MethodGet, path: "/customers/{petar}", status: http.StatusForbidden},
+		{name: "reservations", role: "agent", method: http.MethodGet, path: "/customers/{petar}/reservations", status: http.StatusOK, want: `[{"id": {petar-sea}, "holiday": {"id": {sea}}}]`},
+		{name: "own reservations", role: "customer", method: http.MethodGet, path: "/customers/{maria}/reservations", status: http.StatusOK, want: `[{"id": {maria-ski}}]`},
+		{name: "reservations of another", role: "customer", method: http.MethodGet, path: "/customers/{petar}/reservations", status: http.StatusForbidden},
+		{
+			name: "create", role: "agent", method: http.MethodPost, path: "/customers", status: http.StatusOK,
+			body:  `{"name": " Elena ", "phoneNumber": "0878 999 000", "email": "Elena@Example.com"}`,
+			check: readBack("/customers/{created}", http.StatusOK, `{"name": "Elena", "email": "elena@example.com"}`),
+		},
+		{name: "create without contact", role: "agent", method: http.MethodPost, path: "/customers", body: `{"name": "Nobody"}`, status: http.StatusBadRequest},
+		{
+			name: "update", role: "agent", method: http.MethodPut, path: "/customers", status: http.StatusOK,
+			body:  `{"id": {ivan}, "name": "Ivan Georgiev", "phoneNumber": "0877 111 222", "email": "ivan@example.com"}`,
+			want:  `{"id": {ivan}, "email": "ivan@example.com"}`,
+			check: readBack("/customers/{ivan}", http.StatusOK, `{"email": "ivan@example.com"}`),
+		},
+		{
+			name: "delete", role: "agent", method: http.MethodDelete, path: "/customers/{ivan}", status: http.StatusOK,
+			want:  `{"id": {ivan}}`,
+			check: readBack("/customers/{ivan}", http.StatusInternalServerError, notFound),
+		},
+		{name: "delete as customer", role: "customer", method: http.MethodDelete, path: "/customers/{maria}", status: http.StatusForbidden},
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{name: "list", role: "admin", method: http.MethodGet, path: "/api-keys", status: http.StatusOK, want: `[]`},
+		{name: "list as agent", role: "agent", method: http.MethodGet, path: "/api-keys", status: http.StatusForbidden},
+		{name: "create", role: "admin", method: http.MethodPost, path: "/api-keys", body: `{"name": "partner", "role": "agent"}`, status: http.StatusCreated, want: `{"name": "partner", "role": "agent", "createdBy": "admin", "revokedAt": null}`},
+		{name: "create as agent", role: "agent", method: http.MethodPost, path: "/api-keys", body: `{"name": "partner", "role": "admin"}`, status: http.StatusForbidden},
+		{
+			name: "create with an unknown role", role: "admin", method: http.MethodPost, path: "/api-keys", body: `{"name": "partner", "role": "superuser"}`, status: http.StatusBadRequest,
+			want:  `"unknown api key role \"superuser\""`,
+			check: readBack("/api-keys", http.StatusOK, `[]`),
+		},
+		{name: "create with the default role", role: "admin", method: http.MethodPost, path: "/api-keys", body: `{"name": "partner"}`, status: http.StatusCreated, want: `{"role": "agent"}`},
+	})
+
+	s := apitest.New(t, fixtures)
+	admin := s.Token(t, "admin")
+
+	var created struct {
+		ID  int    `json:"id"`
+		Key string `json:"key"`
+	}
+	s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/api-keys", Body: `{"name": "partner", "role": "agent"}`, Token: admin}).
+		AssertStatus(t, http.StatusCreated).
+		Decode(t, &created)
+
+	withKey := func(key string) apitest.Request {
+		return apitest.Request{Method: http.MethodGet, Path: "/holidays", Header: map[string]string{"X-API-Key": key}}
+	}
+	s.Do(t, withKey(created.Key)).AssertStatus(t, http.StatusOK)
+	// the right prefix with a guessed secret, and keys of the wrong shape
+	s.Do(t, withKey(created.Key[:strings.LastIndex(created.Key, "_")+1]+strings.Repeat("0", 32))).AssertStatus(t, http.StatusUnauthorized)
+	s.Do(t, withKey("sk_"+created.Key[3:])).AssertStatus(t, http.StatusUnauthorized)
+	s.Do(t, withKey("tk_unknown_secret")).AssertStatus(t, http.StatusUnauthorized)
+
+	// a token naming no agency is refused instead of acting for all of them
+	noTenant := s.TokenFor(t, auth.Claims{Subject: "ops", Role: "admin"})
+	s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/holidays", Token: noTenant}).AssertStatus(t, http.StatusUnauthorized)
+
+	var rotated struct {
+		ID  int    `json:"id"`
+		Key string `json:"key"`
+	}
+	s.Do(t, apitest.Request{Method: http.MethodPost, Path: "/api-keys/" + strconv.Itoa(created.ID) + "/rotate", Token: admin}).
+		AssertStatus(t, http.StatusCreated).
+		Decode(t, &rotated)
+
+	s.Do(t, withKey(created.Key)).AssertStatus(t, http.StatusUnauthorized)
+	s.Do(t, withKey(rotated.Key)).AssertStatus(t, http.StatusOK)
+
+	s.Do(t, apitest.Request{Method: http.MethodDelete, Path: "/api-keys/" + strconv.Itoa(rotated.ID), Token: admin}).
+		AssertStatus(t, http.StatusOK).
+		AssertJSON(t, `{"name": "partner"}`)
+
+	s.Do(t, withKey(rotated.Key)).AssertStatus(t, http.StatusUnauthorized)
+}
+
+func TestAuditLog(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "record", role: "admin", method: http.MethodGet, path: "/audit?resource=holiday&id={ski}", status: http.StatusOK,
+			want: `[{"actor": {"type": "user", "id": "fixtures"}, "resource": "holiday", "resourceID": {ski}, "action": "create", "diff": {"title": {"before": null, "after": "Ski week"}}}]`,
+		},
+		{name: "resource", role: "admin", method: http.MethodGet, path: "/audit?resource=reservation", status: http.StatusOK, want: `[{"resourceID": {maria-ski}}, {"resourceID": {petar-sea}}]`},
+		{name: "unknown resource", role: "admin", method: http.MethodGet, path: "/audit?resource=agency", status: http.StatusBadRequest},
+		{name: "invalid id", role: "admin", method: http.MethodGet, path: "/audit?resource=holiday&id=ski", status: http.StatusBadRequest},
+		{name: "as agent", role: "agent", method: http.MethodGet, path: "/audit?resource=holiday", status: http.StatusForbidden},
+		{
+			name: "after a delete", role: "admin", method: http.MethodDelete, path: "/holidays/{city-break}", status: http.StatusOK,
+			check: readBack("/audit?resource=holiday&id={city-break}", http.StatusOK, `[{"action": "create"}, {"actor": {"id": "admin", "name": "admin"}, "action": "delete"}]`),
+		},
+	})
+}
+
+func TestExports(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{
+			name: "holidays", role: "agent", method: http.MethodGet, path: "/holidays/export?location=varna", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				r.AssertHeader(t, "Content-Type", "text/csv").AssertHeader(t, "Content-Disposition", `attachment; filename="holidays-`)
+				if lines := strings.Split(strings.TrimSpace(string(r.Body)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], "Black sea") {
+					t.Fatalf("export:\n%s", r.Body)
+				}
+			},
+		},
+		{
+			name: "reservations as JSON lines", role: "agent", method: http.MethodGet, path: "/reservations/export?format=jsonl", status: http.StatusOK,
+			check: func(t *testing.T, s *apitest.Server, r *apitest.Response) {
+				r.AssertHeader(t, "Content-Disposition", `attachment; filename="reservations-`)
+				if lines := strings.Split(strings.TrimSpace(string(r.Body)), "\n"); len(lines) != 2 {
+					t.Fatalf("export:\n%s", r.Body)
+				}
+			},
+		},
+		{name: "unknown format", role: "agent", method: http.MethodGet, path: "/holidays/export?format=pdf", status: http.StatusBadRequest},
+		{name: "reservations as customer", role: "customer", method: http.MethodGet, path: "/reservations/export", status: http.StatusForbidden},
+	})
+}
+
+// failingExports streams rows reservations and then fails.
+type failingExports struct {
+	handler.Service
+	rows int
+}
+
+func (f failingExports) ExportReservations(ctx context.Context, fn func(storage.ReservationResult) error) error {
+	for i := 1; i <= f.rows; i++ {
+		if err := fn(storage.ReservationResult{ID: i, ContactName: "Maria Ivanova"}); err != nil {
+			return err
+		}
+	}
+
+	return errors.New("connection lost")
+}
+
+// agencies knows the default agency only.
+type agencies struct{}
+
+func (agencies) AgencyIDBySlug(ctx context.Context, slug string) (int, error) {
+	return 0, nil
+}
+
+func TestExportFailures(t *testing.T) {
+	secret := []byte("exports")
+	token, err := auth.SignHS256(auth.Claims{Subject: "agent", Role: "agent", TenantID: apitest.DefaultTenant, ExpiresAt: time.Now().Add(time.Hour).Unix()}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(rows int, format string) *http.Response {
+		t.Helper()
+
+		api := handler.New(failingExports{rows: rows}, auth.NewAuthenticator(nil, &auth.JWTVerifier{HMACSecret: secret}), tenant.NewResolver(agencies{}, ""),
+			handler.Options{Exports: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+		r := httptest.NewRequest(http.MethodGet, "/reservations/export?format="+format, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+
+		return w.Result()
+	}
+
+	// nothing was sent yet, the error is reported
+	for _, format := range []string{export.FormatCSV, export.FormatXLSX} {
+		before := serve(0, format)
+		body, _ := io.ReadAll(before.Body)
+		if before.StatusCode != http.StatusInternalServerError || before.Header.Get("Content-Disposition") != "" || !strings.Contains(string(body), "connection lost") {
+			t.Errorf("%s: got %d %v: %s", format, before.StatusCode, before.Header, body)
+		}
+	}
+
+	// the first rows are out with a 200, the download is cut short without
+	// an error document in the middle of the file
+	after := serve(150, export.FormatCSV)
+	body, _ := io.ReadAll(after.Body)
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if after.StatusCode != http.StatusOK || !strings.HasPrefix(after.Header.Get("Content-Type"), "text/csv") || len(lines) < 2 || len(lines) > 151 {
+		t.Fatalf("got %d with %d lines", after.StatusCode, len(lines))
+	}
+	if strings.Contains(string(body), "connection lost") {
+		t.Errorf("error written into the export:\n%s", lines[len(lines)-1])
+	}
+}
+
+func TestRequests(t *testing.T) {
+	runRoutes(t, []routeTest{
+		{name: "invalid token", method: http.MethodGet, path: "/holidays", header: map[string]string{"Authorization": "Bearer nonsense"}, status: http.StatusUnauthorized},
+		{name: "tenant of another agency", role: "admin", method: http.MethodGet, path: "/holidays", header: map[string]string{"X-Tenant": "sunny"}, status: http.StatusForbidden},
+		{name: "unknown tenant", role: "admin", method: http.MethodGet, path: "/holidays", header: map[string]string{"X-Tenant": "nobody"}, status: http.StatusBadRequest},
+		{
+			name: "body too large", role: "admin", method: http.MethodPost, path: "/locations", status: http.StatusRequestEntityTooLarge,
+			body: `{"street": "` + strings.Repeat("x", apitest.MaxBodyBytes) + `"}`,
+		},
+		{name: "unknown route", role: "admin", method: http.MethodGet, path: "/agencies", status: http.StatusNotFound},
+	})
+
+	s := apitest.New(t, fixtures)
+	platform := s.TokenFor(t, auth.Claims{Subject: "ops", Role: "admin", Platform: true})
+
+	s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/holidays", Token: platform}).AssertStatus(t, http.StatusBadRequest)
+	s.Do(t, apitest.Request{Method: http.MethodGet, Path: "/holidays", Token: platform, Header: map[string]string{"X-Tenant": "sunny"}}).
+		AssertStatus(t, http.StatusOK).
+		AssertJSON(t, `[{"id": {roman-holiday}, "location": {"city": "Rome"}}]`)
+}
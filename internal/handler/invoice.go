@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"travel/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// IssueInvoice issues the invoice of a reservation, or returns the one it
+// has.
+func (h *apiHandler) IssueInvoice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.IssueInvoice(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, result, http.StatusOK)
+}
+
+// GetReservationInvoicePDF serves the current invoice of a reservation.
+func (h *apiHandler) GetReservationInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.ReservationInvoice(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	pdfResponseWrite(w, result)
+}
+
+// GetReservationInvoices lists the invoices and credit notes of a
+// reservation, without their PDFs.
+func (h *apiHandler) GetReservationInvoices(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	invoices, err := h.service.ReservationInvoices(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, invoices, http.StatusOK)
+}
+
+func (h *apiHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Invoice(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, result, http.StatusOK)
+}
+
+// GetInvoicePDF serves any invoice or credit note as it was issued.
+func (h *apiHandler) GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Invoice(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	pdfResponseWrite(w, result)
+}
+
+// pdfResponseWrite writes the PDF of an invoice named after its number.
+func pdfResponseWrite(w http.ResponseWriter, invoice *service.InvoiceDTO) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", invoice.Number+".pdf"))
+	w.Header().Set("Content-Length", strconv.Itoa(len(invoice.PDF)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(invoice.PDF)
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"travel/internal/payment"
+	"travel/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// NewWebhooks serves the webhooks payment gateways call. They carry no
+// credentials and act for no tenant until their signature is verified, so
+// they are served apart from the API and its authentication.
+func NewWebhooks(service Service, options Options) http.Handler {
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	handler := &apiHandler{service: service, logger: logger}
+
+	route := mux.NewRouter()
+	route.Use(options.Middleware...)
+	route.Use(limitBody(options.MaxBodyBytes))
+
+	route.Methods(http.MethodPost).Path("/webhooks/payments").HandlerFunc(handler.PaymentWebhook)
+
+	return route
+}
+
+func (h *apiHandler) GetReservationPayments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	payments, err := h.service.ReservationPayments(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, payments, http.StatusOK)
+}
+
+func (h *apiHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Payment(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, result, http.StatusOK)
+}
+
+// CreatePayment starts paying the next installment of a reservation. The
+// client confirms it at the gateway with the client secret of the payment.
+// The body is optional.
+func (h *apiHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	request := service.PaymentRequestDTO{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.CreatePayment(r.Context(), id, request)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, result, http.StatusOK)
+}
+
+// RefundPayment refunds the amount of the body, or all that is left of the
+// payment without one.
+func (h *apiHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	request := service.RefundRequestDTO{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.RefundPayment(r.Context(), id, request)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, result, http.StatusOK)
+}
+
+// PaymentWebhook applies an event of the gateway. Events that can never
+// apply are answered with 400, other failures with 500 so the gateway
+// delivers the event again.
+func (h *apiHandler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.HandlePaymentEvent(r.Context(), body, r.Header.Get(payment.SignatureHeader))
+	if err != nil {
+		h.errorResponseWrite(w, r, err, webhookStatus(err))
+		return
+	}
+
+	jsonResponseWrite(w, result, http.StatusOK)
+}
+
+func webhookStatus(err error) int {
+	switch {
+	case errors.Is(err, payment.ErrInvalidSignature),
+		errors.Is(err, payment.ErrMalformedEvent),
+		errors.Is(err, service.ErrPaymentMismatch),
+		errors.Is(err, service.ErrNoPaymentGateway):
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"travel/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *apiHandler) GetPricingRuleSets(w http.ResponseWriter, r *http.Request) {
+	ruleSets, err := h.service.PricingRuleSetGetAll(r.Context())
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, ruleSets, http.StatusOK)
+}
+
+func (h *apiHandler) GetPricingRuleSet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	ruleSet, err := h.service.PricingRuleSet(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, ruleSet, http.StatusOK)
+}
+
+func (h *apiHandler) CreatePricingRuleSet(w http.ResponseWriter, r *http.Request) {
+	ruleSet := service.PricingRuleSetDTO{}
+
+	err := json.NewDecoder(r.Body).Decode(&ruleSet)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	idResult, err := h.service.InsertPricingRuleSet(r.Context(), ruleSet)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, idResult, http.StatusOK)
+}
+
+func (h *apiHandler) UpdatePricingRuleSet(w http.ResponseWriter, r *http.Request) {
+	ruleSet := service.PricingRuleSetDTO{}
+
+	err := json.NewDecoder(r.Body).Decode(&ruleSet)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	updatedRuleSet, err := h.service.UpdatePricingRuleSet(r.Context(), ruleSet)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, updatedRuleSet, http.StatusOK)
+}
+
+func (h *apiHandler) DeletePricingRuleSet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	ruleSet, err := h.service.DeletePricingRuleSet(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, ruleSet, http.StatusOK)
+}
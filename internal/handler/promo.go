@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"travel/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *apiHandler) GetPromoCodes(w http.ResponseWriter, r *http.Request) {
+	codes, err := h.service.PromoCodeGetAll(r.Context())
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, codes, http.StatusOK)
+}
+
+func (h *apiHandler) GetPromoCode(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	code, err := h.service.PromoCode(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, code, http.StatusOK)
+}
+
+// GetPromoCodeRedemptions reports the reservations a promo code was redeemed
+// on and what it took off in total.
+func (h *apiHandler) GetPromoCodeRedemptions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	redemptions, err := h.service.PromoCodeRedemptions(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, redemptions, http.StatusOK)
+}
+
+func (h *apiHandler) CreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	code := service.PromoCodeDTO{}
+
+	err := json.NewDecoder(r.Body).Decode(&code)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	idResult, err := h.service.InsertPromoCode(r.Context(), code)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, idResult, http.StatusOK)
+}
+
+func (h *apiHandler) UpdatePromoCode(w http.ResponseWriter, r *http.Request) {
+	code := service.PromoCodeDTO{}
+
+	err := json.NewDecoder(r.Body).Decode(&code)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	updatedCode, err := h.service.UpdatePromoCode(r.Context(), code)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	jsonResponseWrite(w, updatedCode, http.StatusOK)
+}
+
+func (h *apiHandler) DeletePromoCode(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	code, err := h.service.DeletePromoCode(r.Context(), id)
+	if err != nil {
+		h.errorResponseWrite(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponseWrite(w, code, http.StatusOK)
+}
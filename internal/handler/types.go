@@ -1,11 +1,14 @@
 package handler
 
 type RequestHoliday struct {
-	ID         string `json:"id"`
-	Title      string `json:"title"`
-	Duration   int    `json:"duration"`
-	StartDate  string `json:"startDate"`
-	Price      string `json:"price"`
-	FreeSlots  int    `json:"freeSlots"`
-	LocationID int    `json:"location"`
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Duration  int    `json:"duration"`
+	StartDate string `json:"startDate"`
+	Price     string `json:"price"`
+	// Currency of Price, service.DefaultCurrency when left out.
+	Currency         string `json:"currency"`
+	FreeSlots        int    `json:"freeSlots"`
+	LocationID       int    `json:"location"`
+	PricingRuleSetID int    `json:"pricingRuleSet"`
 }
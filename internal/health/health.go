@@ -0,0 +1,314 @@
+// Package health serves the probes of the orchestrator: /healthz tells that
+// the process is alive, /readyz that it can serve traffic and /status
+// describes the running instance.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Migrator reports the schema version of the database, *migrate.Migrate
+// implements it.
+type Migrator interface {
+	Version() (version uint, dirty bool, err error)
+}
+
+type Checker struct {
+	db         *sql.DB
+	migrator   Migrator
+	migrations []uint
+	timeout    time.Duration
+	started    time.Time
+}
+
+// New returns a Checker expecting the database to be at the last of the
+// migrations found in src. timeout bounds every dependency check. Without a
+// database, e.g. with the in-memory storage, db, migrator and src are nil and
+// only the process itself is checked.
+func New(db *sql.DB, migrator Migrator, src source.Driver, timeout time.Duration) (*Checker, error) {
+	migrations := []uint{}
+	if src != nil {
+		var err error
+		migrations, err = versions(src)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Checker{
+		db:         db,
+		migrator:   migrator,
+		migrations: migrations,
+		timeout:    timeout,
+		started:    time.Now(),
+	}, nil
+}
+
+// Handler serves the probes, it is mounted in front of authentication.
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", c.Healthz)
+	mux.HandleFunc("/readyz", c.Readyz)
+	mux.HandleFunc("/status", c.Status)
+
+	return mux
+}
+
+func (c *Checker) Healthz(w http.ResponseWriter, r *http.Request) {
+	jsonResponseWrite(w, map[string]string{"status": "ok"}, http.StatusOK)
+}
+
+// Readyz answers 503 while the database is unreachable or its schema is not
+// at the expected version.
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{}
+	status := http.StatusOK
+	if c.db == nil {
+		jsonResponseWrite(w, map[string]interface{}{"status": "ok", "checks": checks}, status)
+		return
+	}
+
+	checks["database"] = "ok"
+	checks["migrations"] = "ok"
+	if err := c.ping(r.Context()); err != nil {
+		checks["database"] = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+
+	if state, err := c.migrationState(r.Context()); err != nil {
+		checks["migrations"] = err.Error()
+		status = http.StatusServiceUnavailable
+	} else if err := state.err(); err != nil {
+		checks["migrations"] = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+
+	result := "ok"
+	if status != http.StatusOK {
+		result = "unavailable"
+	}
+
+	jsonResponseWrite(w, map[string]interface{}{"status": result, "checks": checks}, status)
+}
+
+type Status struct {
+	Build      Build           `json:"build"`
+	StartedAt  time.Time       `json:"startedAt"`
+	Uptime     string          `json:"uptime"`
+	Hostname   string          `json:"hostname"`
+	Database   *DatabaseStatus `json:"database,omitempty"`
+	Migrations *MigrationState `json:"migrations,omitempty"`
+}
+
+type Build struct {
+	GoVersion string `json:"goVersion"`
+	Module    string `json:"module"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified"`
+}
+
+type DatabaseStatus struct {
+	Reachable bool       `json:"reachable"`
+	Error     string     `json:"error,omitempty"`
+	Pool      PoolStatus `json:"pool"`
+}
+
+// PoolStatus is sql.DBStats with JSON names.
+type PoolStatus struct {
+	MaxOpenConnections int    `json:"maxOpenConnections"`
+	OpenConnections    int    `json:"openConnections"`
+	InUse              int    `json:"inUse"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"waitCount"`
+	WaitDuration       string `json:"waitDuration"`
+	MaxIdleClosed      int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64  `json:"maxLifetimeClosed"`
+}
+
+func poolStatus(stats sql.DBStats) PoolStatus {
+	return PoolStatus{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration.String(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
+type MigrationState struct {
+	Current  uint   `json:"current"`
+	Dirty    bool   `json:"dirty"`
+	Expected uint   `json:"expected"`
+	Pending  []uint `json:"pending"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (c *Checker) Status(w http.ResponseWriter, r *http.Request) {
+	status := Status{
+		Build:     buildInfo(),
+		StartedAt: c.started.UTC(),
+		Uptime:    time.Since(c.started).Round(time.Second).String(),
+	}
+	status.Hostname, _ = os.Hostname()
+
+	if c.db == nil {
+		jsonResponseWrite(w, status, http.StatusOK)
+		return
+	}
+
+	status.Database = &DatabaseStatus{Reachable: true, Pool: poolStatus(c.db.Stats())}
+
+	if err := c.ping(r.Context()); err != nil {
+		status.Database.Reachable = false
+		status.Database.Error = err.Error()
+	}
+
+	state, err := c.migrationState(r.Context())
+	if err != nil {
+		state.Error = err.Error()
+	}
+	status.Migrations = &state
+
+	jsonResponseWrite(w, status, http.StatusOK)
+}
+
+func (c *Checker) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.db.PingContext(ctx)
+}
+
+// migrationState reads the schema version. Migrator has no context, so the
+// call is abandoned rather than cancelled when it takes too long.
+func (c *Checker) migrationState(ctx context.Context) (MigrationState, error) {
+	state := MigrationState{Pending: []uint{}}
+	if len(c.migrations) > 0 {
+		state.Expected = c.migrations[len(c.migrations)-1]
+	}
+
+	type result struct {
+		version uint
+		dirty   bool
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		version, dirty, err := c.migrator.Version()
+		done <- result{version, dirty, err}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		return state, ctx.Err()
+	}
+
+	// nothing applied yet is version 0
+	if res.err != nil && !errors.Is(res.err, migrate.ErrNilVersion) {
+		return state, res.err
+	}
+
+	state.Current = res.version
+	state.Dirty = res.dirty
+	for _, version := range c.migrations {
+		if version > state.Current {
+			state.Pending = append(state.Pending, version)
+		}
+	}
+
+	return state, nil
+}
+
+func (s MigrationState) err() error {
+	switch {
+	case s.Dirty:
+		return fmt.Errorf("migration %d is dirty", s.Current)
+	case s.Current != s.Expected:
+		return fmt.Errorf("schema is at version %d, expected %d", s.Current, s.Expected)
+	}
+
+	return nil
+}
+
+// versions lists the migrations of src in order.
+func versions(src source.Driver) ([]uint, error) {
+	result := []uint{}
+
+	version, err := src.First()
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		result = append(result, version)
+
+		version, err = src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func buildInfo() Build {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return Build{}
+	}
+
+	build := Build{
+		GoVersion: info.GoVersion,
+		Module:    info.Main.Path,
+		Version:   info.Main.Version,
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.time":
+			build.Time = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+
+	return build
+}
+
+func jsonResponseWrite(w http.ResponseWriter, body interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(body)
+}
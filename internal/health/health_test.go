@@ -0,0 +1,177 @@
+package health_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+	"travel/internal/health"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// migrator reports a fixed schema version, or blocks until the test ends.
+type migrator struct {
+	version uint
+	dirty   bool
+	err     error
+	block   chan struct{}
+}
+
+func (m migrator) Version() (uint, bool, error) {
+	if m.block != nil {
+		<-m.block
+	}
+	return m.version, m.dirty, m.err
+}
+
+func checker(t *testing.T, m migrator) (*health.Checker, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "health.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	src, err := iofs.New(fstest.MapFS{
+		"m/1_location.up.sql":   {Data: []byte("")},
+		"m/1_location.down.sql": {Data: []byte("")},
+		"m/3_holiday.up.sql":    {Data: []byte("")},
+	}, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := health.New(db, m, src, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c, db
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func ready(t *testing.T, c *health.Checker) (int, readiness) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var body readiness
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Error("probe cacheable")
+	}
+
+	return w.Code, body
+}
+
+func TestReadyz(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	tests := []struct {
+		name       string
+		migrator   migrator
+		status     int
+		migrations string
+	}{
+		{"migrated", migrator{version: 3}, http.StatusOK, "ok"},
+		{"pending", migrator{version: 1}, http.StatusServiceUnavailable, "schema is at version 1, expected 3"},
+		{"nothing applied", migrator{err: migrate.ErrNilVersion}, http.StatusServiceUnavailable, "schema is at version 0, expected 3"},
+		{"dirty", migrator{version: 3, dirty: true}, http.StatusServiceUnavailable, "migration 3 is dirty"},
+		{"unreadable", migrator{err: errors.New("no schema_migrations")}, http.StatusServiceUnavailable, "no schema_migrations"},
+		{"hanging", migrator{block: block}, http.StatusServiceUnavailable, "context deadline exceeded"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := checker(t, test.migrator)
+
+			status, body := ready(t, c)
+			if status != test.status || body.Checks["migrations"] != test.migrations || body.Checks["database"] != "ok" {
+				t.Errorf("got %d %+v", status, body)
+			}
+		})
+	}
+}
+
+func TestReadyzDatabaseDown(t *testing.T) {
+	c, db := checker(t, migrator{version: 3})
+	if status, _ := ready(t, c); status != http.StatusOK {
+		t.Fatalf("got %d before the database went away", status)
+	}
+
+	db.Close()
+
+	status, body := ready(t, c)
+	if status != http.StatusServiceUnavailable || body.Status != "unavailable" || !strings.Contains(body.Checks["database"], "closed") {
+		t.Errorf("got %d %+v", status, body)
+	}
+
+	// alive all the same, restarting would not bring the database back
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz: got %d", w.Code)
+	}
+}
+
+func TestWithoutDatabase(t *testing.T) {
+	c, err := health.New(nil, nil, nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, body := ready(t, c); status != http.StatusOK || len(body.Checks) != 0 {
+		t.Errorf("got %d %+v", status, body)
+	}
+
+	var status health.Status
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Database != nil || status.Migrations != nil || status.StartedAt.IsZero() {
+		t.Errorf("got %+v", status)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	c, db := checker(t, migrator{version: 1})
+	db.Close()
+
+	var status health.Status
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+
+	// status describes, it does not fail
+	if w.Code != http.StatusOK {
+		t.Errorf("got %d", w.Code)
+	}
+	if status.Database == nil || status.Database.Reachable || status.Database.Error == "" {
+		t.Errorf("database %+v", status.Database)
+	}
+	if m := status.Migrations; m == nil || m.Current != 1 || m.Expected != 3 || len(m.Pending) != 1 || m.Pending[0] != 3 {
+		t.Errorf("migrations %+v", status.Migrations)
+	}
+}
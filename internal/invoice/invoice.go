@@ -0,0 +1,110 @@
+// Package invoice issues the invoices of reservations and the credit notes
+// cancelling them, and renders both to PDF. Invoices and credit notes are
+// numbered in sequences of their own, per agency, without gaps.
+package invoice
+
+import (
+	"fmt"
+	"time"
+	"travel/internal/money"
+)
+
+// Kinds of documents.
+const (
+	KindInvoice    = "invoice"
+	KindCreditNote = "credit_note"
+)
+
+// Number formats the sequence number of a document of kind, INV-000001 for
+// the first invoice and CN-000001 for the first credit note.
+func Number(kind string, sequence int) string {
+	prefix := "INV"
+	if kind == KindCreditNote {
+		prefix = "CN"
+	}
+
+	return fmt.Sprintf("%s-%06d", prefix, sequence)
+}
+
+// Invoice is the content of an invoice or credit note as issued.
+type Invoice struct {
+	Kind     string    `json:"kind"`
+	Number   string    `json:"number"`
+	IssuedAt time.Time `json:"issuedAt"`
+	// Credits is the number of the invoice a credit note cancels.
+	Credits  string `json:"credits,omitempty"`
+	Issuer   Party  `json:"issuer"`
+	Customer Party  `json:"customer"`
+	// Reservation and Holiday say what was booked.
+	Reservation int    `json:"reservation"`
+	Holiday     string `json:"holiday"`
+	Lines       []Line `json:"lines"`
+	// Taxes are worked out on Subtotal, the total of Lines.
+	Taxes    []Line      `json:"taxes"`
+	Subtotal money.Money `json:"subtotal"`
+	Total    money.Money `json:"total"`
+}
+
+// Party is the agency issuing a document or the customer it is issued to.
+type Party struct {
+	Name      string   `json:"name"`
+	Address   []string `json:"address,omitempty"`
+	Email     string   `json:"email,omitempty"`
+	Phone     string   `json:"phone,omitempty"`
+	TaxNumber string   `json:"taxNumber,omitempty"`
+}
+
+// Line is Quantity times UnitPrice, or Percent of the subtotal for a tax.
+type Line struct {
+	Description string      `json:"description"`
+	Quantity    int         `json:"quantity"`
+	Percent     float64     `json:"percent,omitempty"`
+	UnitPrice   money.Money `json:"unitPrice"`
+	Amount      money.Money `json:"amount"`
+}
+
+// ZeroTax is the tax line of a document on which no tax is charged, so that
+// the document says so rather than leaving its taxes out.
+func ZeroTax(currency string) Line {
+	zero := money.Money{Currency: currency}
+	return Line{Description: "Tax", Quantity: 1, UnitPrice: zero, Amount: zero}
+}
+
+// Sum sets Subtotal and Total from the lines and taxes, in currency.
+func (i *Invoice) Sum(currency string) {
+	i.Subtotal = money.Money{Currency: currency}
+	for _, line := range i.Lines {
+		i.Subtotal.Amount += line.Amount.Amount
+	}
+
+	i.Total = i.Subtotal
+	for _, tax := range i.Taxes {
+		i.Total.Amount += tax.Amount.Amount
+	}
+}
+
+// Credit returns the credit note numbered number that cancels the invoice:
+// the same lines and taxes with the amounts negated.
+func (i Invoice) Credit(number string, issuedAt time.Time) Invoice {
+	credit := i
+	credit.Kind = KindCreditNote
+	credit.Number = number
+	credit.IssuedAt = issuedAt
+	credit.Credits = i.Number
+	credit.Lines = negate(i.Lines)
+	credit.Taxes = negate(i.Taxes)
+	credit.Sum(i.Total.Currency)
+
+	return credit
+}
+
+func negate(lines []Line) []Line {
+	result := make([]Line, len(lines))
+	for n, line := range lines {
+		line.UnitPrice.Amount = -line.UnitPrice.Amount
+		line.Amount.Amount = -line.Amount.Amount
+		result[n] = line
+	}
+
+	return result
+}
@@ -0,0 +1,162 @@
+package invoice_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+	"travel/internal/invoice"
+	"travel/internal/money"
+)
+
+func eur(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "EUR"}
+}
+
+func sample() invoice.Invoice {
+	i := invoice.Invoice{
+		Kind:        invoice.KindInvoice,
+		Number:      invoice.Number(invoice.KindInvoice, 1),
+		IssuedAt:    time.Date(2030, time.January, 10, 15, 30, 0, 0, time.UTC),
+		Issuer:      invoice.Party{Name: "Sunny Travel", Address: []string{"Vitosha 1", "Sofia, Bulgaria"}, TaxNumber: "BG123456789"},
+		Customer:    invoice.Party{Name: "Мария Щерева (€)", Phone: "+359888123456"},
+		Reservation: 7,
+		Holiday:     "Black sea, 10 days from 1 July 2030",
+		Lines: []invoice.Line{
+			{Description: "Adult", Quantity: 2, UnitPrice: eur(106979), Amount: eur(213958)},
+			{Description: "Airport transfer", Quantity: 1, UnitPrice: eur(4000), Amount: eur(4000)},
+			{Description: "SUMMER10", Quantity: 1, Percent: -10, UnitPrice: eur(-21796), Amount: eur(-21796)},
+		},
+		Taxes: []invoice.Line{{Description: "Tourist tax", Quantity: 1, Percent: 8, UnitPrice: eur(15693), Amount: eur(15693)}},
+	}
+	i.Sum("EUR")
+
+	return i
+}
+
+func TestNumber(t *testing.T) {
+	if got := invoice.Number(invoice.KindInvoice, 42); got != "INV-000042" {
+		t.Errorf("got %s", got)
+	}
+	if got := invoice.Number(invoice.KindCreditNote, 1); got != "CN-000001" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestSum(t *testing.T) {
+	i := sample()
+	if i.Subtotal != eur(196162) || i.Total != eur(211855) {
+		t.Errorf("got subtotal %s, total %s", i.Subtotal, i.Total)
+	}
+}
+
+func TestCredit(t *testing.T) {
+	i := sample()
+	issuedAt := time.Date(2030, time.February, 1, 0, 0, 0, 0, time.UTC)
+	credit := i.Credit(invoice.Number(invoice.KindCreditNote, 1), issuedAt)
+
+	if credit.Kind != invoice.KindCreditNote || credit.Number != "CN-000001" || credit.Credits != i.Number || !credit.IssuedAt.Equal(issuedAt) {
+		t.Fatalf("got %+v", credit)
+	}
+	if credit.Subtotal != eur(-196162) || credit.Total != eur(-211855) {
+		t.Errorf("got subtotal %s, total %s", credit.Subtotal, credit.Total)
+	}
+	if credit.Lines[2].Amount != eur(21796) || credit.Lines[0].Quantity != 2 || credit.Taxes[0].Amount != eur(-15693) {
+		t.Errorf("got %+v %+v", credit.Lines, credit.Taxes)
+	}
+	// the invoice is left as it was
+	if !reflect.DeepEqual(i, sample()) {
+		t.Errorf("invoice changed: %+v", i)
+	}
+}
+
+func TestRender(t *testing.T) {
+	pdf := invoice.Render(sample())
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("not a PDF: %.40q", pdf)
+	}
+	for n, b := range pdf {
+		if b > 0x7e || (b < ' ' && b != '\n') {
+			t.Fatalf("byte %d is %#x, not ASCII", n, b)
+		}
+	}
+	checkXref(t, pdf)
+
+	for _, text := range []string{
+		"(INVOICE)", "(INV-000001)", "(Issued 10 January 2030)", "(Sunny Travel)", "(Tax number BG123456789)",
+		// transliterated and escaped
+		`(Mariya Shtereva \(\200\))`,
+		"(2139.58)", "(-217.96)", "(Tourist tax 8%)", "(156.93)", "(Total EUR)", "(2118.55)",
+	} {
+		if !bytes.Contains(pdf, []byte(text)) {
+			t.Errorf("%s missing", text)
+		}
+	}
+
+	if !bytes.Contains(invoice.Render(invoice.Invoice{Issuer: invoice.Party{Name: "日本"}}), []byte("(??)")) {
+		t.Error("characters outside WinAnsi are not replaced")
+	}
+
+	// untaxed documents show a rate of 0
+	untaxed := sample()
+	untaxed.Taxes = []invoice.Line{invoice.ZeroTax("EUR")}
+	untaxed.Sum("EUR")
+	for _, text := range []string{"(Tax 0%)", "(0.00)", "(1961.62)"} {
+		if !bytes.Contains(invoice.Render(untaxed), []byte(text)) {
+			t.Errorf("%s missing", text)
+		}
+	}
+
+	credit := invoice.Render(sample().Credit("CN-000001", time.Date(2030, time.February, 1, 0, 0, 0, 0, time.UTC)))
+	for _, text := range []string{"(CREDIT NOTE)", "(Cancels invoice INV-000001)", "(-2118.55)"} {
+		if !bytes.Contains(credit, []byte(text)) {
+			t.Errorf("%s missing", text)
+		}
+	}
+}
+
+func TestRenderPages(t *testing.T) {
+	i := sample()
+	for n := 0; n < 80; n++ {
+		i.Lines = append(i.Lines, invoice.Line{Description: "Extra " + strconv.Itoa(n), Quantity: 1, UnitPrice: eur(100), Amount: eur(100)})
+	}
+	i.Sum("EUR")
+
+	pdf := invoice.Render(i)
+	checkXref(t, pdf)
+	if !bytes.Contains(pdf, []byte("/Count 2 >>")) {
+		t.Error("80 extras fit on one page")
+	}
+	if !bytes.Contains(pdf, []byte("(Extra 79)")) {
+		t.Error("last extra missing")
+	}
+}
+
+// checkXref checks that the cross reference table points at every object.
+func checkXref(t *testing.T, pdf []byte) {
+	t.Helper()
+
+	start := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if start == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(start[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d points at %.10q", xref, pdf[xref:])
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(pdf[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("no objects")
+	}
+	for n, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", n+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("object %d at %d is %.10q", n+1, offset, pdf[offset:])
+		}
+	}
+}
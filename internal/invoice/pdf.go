@@ -0,0 +1,178 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A4 in points.
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+)
+
+// document is a PDF of text in Helvetica and straight rules, the little an
+// invoice needs. Helvetica is one of the standard fonts every reader has, so
+// nothing is embedded. Text is encoded as WinAnsi with every byte outside
+// ASCII escaped and the content is not compressed: the whole file is ASCII,
+// which lets it be stored as text.
+type document struct {
+	title string
+	pages []*page
+}
+
+type page struct {
+	content bytes.Buffer
+}
+
+func (d *document) addPage() *page {
+	p := &page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// text writes s with its baseline starting at x, y from the bottom left.
+func (p *page) text(x float64, y float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, number(size), number(x), number(y), encode(s))
+}
+
+// textRight writes s ending at x.
+func (p *page) textRight(x float64, y float64, size float64, bold bool, s string) {
+	p.text(x-textWidth(s, size), y, size, bold, s)
+}
+
+// rule draws a line from x1, y1 to x2, y2.
+func (p *page) rule(x1 float64, y1 float64, x2 float64, y2 float64) {
+	fmt.Fprintf(&p.content, "0.5 w %s %s m %s %s l S\n", number(x1), number(y1), number(x2), number(y2))
+}
+
+// bytes writes the document: the catalog, the page tree, the two fonts, the
+// info dictionary and every page with its content, followed by the cross
+// reference table of their offsets.
+func (d *document) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	const firstPage = 6
+	kids := &bytes.Buffer{}
+	for n := range d.pages {
+		fmt.Fprintf(kids, "%d 0 R ", firstPage+2*n)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (travel) >>", encode(d.title)))
+	for n, p := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			number(pageWidth), number(pageHeight), firstPage+2*n+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// winAnsi maps the characters of WinAnsiEncoding outside Latin-1 to their
+// codes, Latin-1 maps to itself.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// cyrillic transliterates the Bulgarian alphabet to Latin the official way,
+// the names of Bulgarian customers stay legible without embedding a font.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "sht", 'ъ': "a", 'ь': "y", 'ю': "yu", 'я': "ya",
+}
+
+// encode turns s into the body of a PDF string in WinAnsiEncoding, escaped to
+// ASCII. Bulgarian Cyrillic is transliterated, other characters the encoding
+// lacks become a question mark.
+func encode(s string) string {
+	var out bytes.Buffer
+	for _, r := range s {
+		if latin, ok := cyrillic[unicode.ToLower(r)]; ok {
+			if unicode.IsUpper(r) {
+				latin = strings.ToUpper(latin[:1]) + latin[1:]
+			}
+			out.WriteString(latin)
+			continue
+		}
+
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r >= ' ' && r <= '~':
+			out.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&out, "\\%03o", r)
+		default:
+			if code, ok := winAnsi[r]; ok {
+				fmt.Fprintf(&out, "\\%03o", code)
+			} else {
+				out.WriteByte('?')
+			}
+		}
+	}
+
+	return out.String()
+}
+
+// helveticaWidths are the widths of the ASCII characters from space to tilde
+// in Helvetica, in thousandths of the font size. Helvetica-Bold is close
+// enough for aligning numbers, its digits are as wide.
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// textWidth is how wide s is in Helvetica at size, characters outside ASCII
+// count as wide as a digit.
+func textWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		if r >= ' ' && r <= '~' {
+			width += helveticaWidths[r-' ']
+		} else {
+			width += 556
+		}
+	}
+
+	return float64(width) * size / 1000
+}
@@ -0,0 +1,169 @@
+package invoice
+
+import (
+	"strconv"
+	"strings"
+	"travel/internal/money"
+)
+
+// Layout of the pages, in points.
+const (
+	margin     = 50.0
+	lineHeight = 14.0
+	bodySize   = 10.0
+	// columns of the line items: the description starts at the margin, the
+	// others end at these
+	quantityRight  = 360.0
+	unitPriceRight = 455.0
+	amountRight    = pageWidth - margin
+)
+
+// Render lays the invoice out on A4 pages and returns the PDF.
+func Render(i Invoice) []byte {
+	title := "INVOICE"
+	if i.Kind == KindCreditNote {
+		title = "CREDIT NOTE"
+	}
+
+	d := &document{title: title + " " + i.Number}
+	r := &renderer{doc: d}
+	r.newPage()
+
+	p := r.page
+	p.text(margin, r.y, 20, true, title)
+	p.textRight(amountRight, r.y, bodySize, true, i.Number)
+	r.y -= lineHeight
+	p.textRight(amountRight, r.y, bodySize, false, "Issued "+i.IssuedAt.Format("2 January 2006"))
+	if i.Credits != "" {
+		r.y -= lineHeight
+		p.textRight(amountRight, r.y, bodySize, false, "Cancels invoice "+i.Credits)
+	}
+	r.y -= 2 * lineHeight
+
+	top := r.y
+	r.party(margin, "From", i.Issuer)
+	issuerBottom := r.y
+	r.y = top
+	r.party(pageWidth/2, "Bill to", i.Customer)
+	if issuerBottom < r.y {
+		r.y = issuerBottom
+	}
+	r.y -= lineHeight
+
+	r.line(false, "Reservation "+strconv.Itoa(i.Reservation))
+	if i.Holiday != "" {
+		r.line(false, i.Holiday)
+	}
+	r.y -= lineHeight
+
+	currency := i.Total.Currency
+	r.header(currency)
+	for _, line := range i.Lines {
+		r.item(line)
+	}
+
+	// the totals stay together, on a page of their own if need be
+	r.currency = ""
+	r.space(lineHeight * float64(3+len(i.Taxes)))
+	r.page.rule(unitPriceRight-80, r.y+lineHeight-3, amountRight, r.y+lineHeight-3)
+	r.total("Subtotal", i.Subtotal, false)
+	for _, tax := range i.Taxes {
+		r.total(taxLabel(tax), tax.Amount, false)
+	}
+	// documents issued before every one had a tax line
+	if len(i.Taxes) == 0 {
+		r.total("No taxes", money.Money{Currency: currency}, false)
+	}
+	r.total("Total "+currency, i.Total, true)
+
+	return d.bytes()
+}
+
+// renderer keeps the page being written and how far down it is.
+type renderer struct {
+	doc      *document
+	page     *page
+	y        float64
+	currency string
+}
+
+func (r *renderer) newPage() {
+	r.page = r.doc.addPage()
+	r.y = pageHeight - margin - 20
+}
+
+// space starts a new page unless height fits above the bottom margin. Line
+// items continue under a repeated header.
+func (r *renderer) space(height float64) {
+	if r.y-height >= margin {
+		return
+	}
+
+	r.newPage()
+	if r.currency != "" {
+		r.header(r.currency)
+	}
+}
+
+func (r *renderer) line(bold bool, s string) {
+	r.space(lineHeight)
+	r.page.text(margin, r.y, bodySize, bold, s)
+	r.y -= lineHeight
+}
+
+func (r *renderer) party(x float64, label string, party Party) {
+	r.page.text(x, r.y, 8, false, strings.ToUpper(label))
+	r.y -= lineHeight
+	r.page.text(x, r.y, bodySize, true, party.Name)
+	r.y -= lineHeight
+
+	details := append([]string{}, party.Address...)
+	if party.Email != "" {
+		details = append(details, party.Email)
+	}
+	if party.Phone != "" {
+		details = append(details, party.Phone)
+	}
+	if party.TaxNumber != "" {
+		details = append(details, "Tax number "+party.TaxNumber)
+	}
+	for _, detail := range details {
+		r.page.text(x, r.y, bodySize, false, detail)
+		r.y -= lineHeight
+	}
+}
+
+func (r *renderer) header(currency string) {
+	r.currency = ""
+	r.space(2 * lineHeight)
+	r.currency = currency
+
+	r.page.text(margin, r.y, bodySize, true, "Description")
+	r.page.textRight(quantityRight, r.y, bodySize, true, "Qty")
+	r.page.textRight(unitPriceRight, r.y, bodySize, true, "Unit price")
+	r.page.textRight(amountRight, r.y, bodySize, true, "Amount "+currency)
+	r.page.rule(margin, r.y-4, amountRight, r.y-4)
+	r.y -= lineHeight + 4
+}
+
+func (r *renderer) item(line Line) {
+	r.space(lineHeight)
+	r.page.text(margin, r.y, bodySize, false, line.Description)
+	if line.Quantity != 0 {
+		r.page.textRight(quantityRight, r.y, bodySize, false, strconv.Itoa(line.Quantity))
+		r.page.textRight(unitPriceRight, r.y, bodySize, false, line.UnitPrice.Decimal())
+	}
+	r.page.textRight(amountRight, r.y, bodySize, false, line.Amount.Decimal())
+	r.y -= lineHeight
+}
+
+func (r *renderer) total(label string, amount money.Money, bold bool) {
+	r.page.textRight(unitPriceRight, r.y, bodySize, bold, label)
+	r.page.textRight(amountRight, r.y, bodySize, bold, amount.Decimal())
+	r.y -= lineHeight
+}
+
+// taxLabel names a tax with its rate, 0% included.
+func taxLabel(tax Line) string {
+	return tax.Description + " " + strconv.FormatFloat(tax.Percent, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,107 @@
+// Package logging sets up the structured JSON logger of the API. Records
+// carry the request ID and trace of the context they are logged with, and
+// personal data such as phone numbers and emails is masked before it is
+// written.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a JSON logger writing records at level and above. level is one
+// of debug, info, warn and error.
+func New(level string, w io.Writer) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("log level %q: %w", level, err)
+	}
+
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       l,
+		ReplaceAttr: redact,
+	})
+
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// contextHandler adds the request ID and trace of the context to every
+// record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String(requestIDKey, id))
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("traceID", span.TraceID().String()), slog.String("spanID", span.SpanID().String()))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Attribute keys whose values are personal data. Values logged inside a
+// struct are not looked at, log such fields one by one.
+var (
+	phoneKeys  = map[string]bool{"phone": true, "phonenumber": true, "phonee164": true}
+	emailKeys  = map[string]bool{"email": true}
+	secretKeys = map[string]bool{"contactname": true, "password": true, "secret": true, "key": true, "authorization": true, "token": true}
+)
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+
+	switch {
+	case phoneKeys[key]:
+		return slog.String(attr.Key, MaskPhone(attr.Value.String()))
+	case emailKeys[key]:
+		return slog.String(attr.Key, MaskEmail(attr.Value.String()))
+	case secretKeys[key]:
+		return slog.String(attr.Key, "[REDACTED]")
+	}
+
+	return attr
+}
+
+// MaskPhone keeps the last two digits of a phone number, enough to tell
+// numbers apart when reading logs.
+func MaskPhone(phone string) string {
+	var digits []rune
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+
+	if len(digits) <= 2 {
+		return strings.Repeat("*", len(digits))
+	}
+
+	return strings.Repeat("*", len(digits)-2) + string(digits[len(digits)-2:])
+}
+
+// MaskEmail keeps the first letter and the domain of an email address.
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return "[REDACTED]"
+	}
+
+	return email[:1] + "***" + email[at:]
+}
@@ -0,0 +1,185 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"travel/internal/logging"
+	"travel/internal/phone"
+)
+
+func TestMaskPhone(t *testing.T) {
+	tests := []struct {
+		phone, want string
+	}{
+		{"+359888123456", "**********56"},
+		{"+359 88 812 3456", "**********56"},
+		// the last two digits are kept, not whatever ends the string
+		{"(212) 555-0123 ", "********23"},
+		{"555-01 (23)", "*****23"},
+		{"12", "**"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := logging.MaskPhone(test.phone); got != test.want {
+			t.Errorf("MaskPhone(%q) = %q, want %q", test.phone, got, test.want)
+		}
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	tests := []struct {
+		email, want string
+	}{
+		{"jane.doe@example.com", "j***@example.com"},
+		{"a@b@example.com", "a***@example.com"},
+		{"@example.com", "[REDACTED]"},
+		{"nobody", "[REDACTED]"},
+	}
+	for _, test := range tests {
+		if got := logging.MaskEmail(test.email); got != test.want {
+			t.Errorf("MaskEmail(%q) = %q, want %q", test.email, got, test.want)
+		}
+	}
+}
+
+// record logs with a fresh logger and returns the JSON record it wrote.
+func record(t *testing.T, log func(logger *slog.Logger)) map[string]interface{} {
+	t.Helper()
+
+	var out bytes.Buffer
+	logger, err := logging.New("info", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log(logger)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+
+	return got
+}
+
+func TestRedact(t *testing.T) {
+	number, err := phone.Parse("088 812 3456", "BG")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := record(t, func(logger *slog.Logger) {
+		// the way reservations are logged when they are created
+		logger.InfoContext(context.Background(), "reservation created", "id", 7, "holiday", 3, "phone", number.E164(),
+			"phoneNumber", "088 812 3456", "email", "jane.doe@example.com", "contactName", "Jane Doe",
+			"password", "hunter2", "secret", "s3cr3t", "key", "tvl_abc", "Authorization", "Bearer x.y.z", "token", "x.y.z")
+	})
+
+	want := map[string]interface{}{
+		"msg":           "reservation created",
+		"id":            float64(7),
+		"holiday":       float64(3),
+		"phone":         "**********56",
+		"phoneNumber":   "********56",
+		"email":         "j***@example.com",
+		"contactName":   "[REDACTED]",
+		"password":      "[REDACTED]",
+		"secret":        "[REDACTED]",
+		"key":           "[REDACTED]",
+		"Authorization": "[REDACTED]",
+		"token":         "[REDACTED]",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s: got %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+func TestInvalidLevel(t *testing.T) {
+	if _, err := logging.New("loud", &bytes.Buffer{}); err == nil {
+		t.Error("no error for an unknown level")
+	}
+}
+
+func TestRequestIDInRecords(t *testing.T) {
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	got := record(t, func(logger *slog.Logger) {
+		logger.InfoContext(ctx, "plain")
+	})
+	if got["requestID"] != "req-1" {
+		t.Errorf("got %v", got)
+	}
+
+	// loggers derived with attributes or groups keep adding it
+	var out bytes.Buffer
+	logger, err := logging.New("info", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.With("component", "payments").WithGroup("payment").InfoContext(ctx, "derived", "id", 1)
+	if !strings.Contains(out.String(), `"requestID":"req-1"`) || !strings.Contains(out.String(), `"component":"payments"`) {
+		t.Errorf("got %s", out.String())
+	}
+
+	// no request, no ID
+	got = record(t, func(logger *slog.Logger) {
+		logger.InfoContext(context.Background(), "background")
+	})
+	if _, ok := got["requestID"]; ok {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name, header string
+		keep         bool
+	}{
+		{"kept from the caller", "lb-1234", true},
+		{"missing", "", false},
+		{"not printable", "bad id\n", false},
+		{"too long", strings.Repeat("x", 129), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger, err := logging.New("info", &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := logging.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger.InfoContext(r.Context(), "handled")
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/holidays", nil)
+			if test.header != "" {
+				r.Header.Set(logging.RequestIDHeader, test.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			id := w.Header().Get(logging.RequestIDHeader)
+			if test.keep && id != test.header {
+				t.Errorf("got ID %q, want %q", id, test.header)
+			}
+			if !test.keep && (id == test.header || len(id) != 32) {
+				t.Errorf("got ID %q", id)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got["requestID"] != id {
+				t.Errorf("logged %v, responded %q", got["requestID"], id)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+	"travel/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectTimeout bounds the queries run on a scrape.
+const collectTimeout = 5 * time.Second
+
+// StatsSource is implemented by *storage.Storage.
+type StatsSource interface {
+	HolidayStats(ctx context.Context) ([]storage.HolidayStats, error)
+	ReservationsCreated(ctx context.Context) (map[string]int, error)
+}
+
+// businessCollector reads the figures from the database when it is scraped
+// instead of counting in the process, so they stay right across restarts
+// and replicas.
+type businessCollector struct {
+	stats StatsSource
+
+	reservationsCreated *prometheus.Desc
+	seatsSold           *prometheus.Desc
+	freeSlots           *prometheus.Desc
+	freeSlotsTotal      *prometheus.Desc
+	scrapeErrors        *prometheus.Desc
+}
+
+func newBusinessCollector(stats StatsSource) *businessCollector {
+	return &businessCollector{
+		stats: stats,
+		reservationsCreated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "reservations_created_total"),
+			"Reservations created per agency.",
+			[]string{"agency"}, nil,
+		),
+		seatsSold: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "holiday", "seats_sold"),
+			"Reservations held per holiday.",
+			[]string{"agency", "holiday"}, nil,
+		),
+		freeSlots: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "holiday", "free_slots"),
+			"Free slots per holiday.",
+			[]string{"agency", "holiday"}, nil,
+		),
+		freeSlotsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "free_slots_total"),
+			"Free slots of all holidays per agency.",
+			[]string{"agency"}, nil,
+		),
+		scrapeErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "business", "scrape_error"),
+			"1 when the business figures could not be read on this scrape.",
+			nil, nil,
+		),
+	}
+}
+
+func (c *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.reservationsCreated
+	ch <- c.seatsSold
+	ch <- c.freeSlots
+	ch <- c.freeSlotsTotal
+	ch <- c.scrapeErrors
+}
+
+func (c *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	failed := 0.0
+
+	created, err := c.stats.ReservationsCreated(ctx)
+	if err != nil {
+		failed = 1
+	}
+	for agency, count := range created {
+		ch <- prometheus.MustNewConstMetric(c.reservationsCreated, prometheus.CounterValue, float64(count), agency)
+	}
+
+	holidays, err := c.stats.HolidayStats(ctx)
+	if err != nil {
+		failed = 1
+	}
+
+	totals := map[string]int{}
+	for _, holiday := range holidays {
+		id := strconv.Itoa(holiday.HolidayID)
+		ch <- prometheus.MustNewConstMetric(c.seatsSold, prometheus.GaugeValue, float64(holiday.SeatsSold), holiday.Agency, id)
+		ch <- prometheus.MustNewConstMetric(c.freeSlots, prometheus.GaugeValue, float64(holiday.FreeSlots), holiday.Agency, id)
+		totals[holiday.Agency] += holiday.FreeSlots
+	}
+
+	for agency, total := range totals {
+		ch <- prometheus.MustNewConstMetric(c.freeSlotsTotal, prometheus.GaugeValue, float64(total), agency)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeErrors, prometheus.GaugeValue, failed)
+}
@@ -0,0 +1,160 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"travel/internal/metrics"
+	"travel/internal/storage"
+	"travel/internal/storage/memory"
+	"travel/internal/tenant"
+
+	"github.com/gorilla/mux"
+)
+
+// scrape returns the lines of /metrics of the given metric families.
+func scrape(t *testing.T, m *metrics.Metrics, prefixes ...string) []string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape: %d %s", w.Code, w.Body)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(line, prefix) {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return lines
+}
+
+func assertLines(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	for _, line := range want {
+		found := false
+		for _, g := range got {
+			found = found || g == line
+		}
+		if !found {
+			t.Errorf("%s missing from\n%s", line, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m := metrics.New()
+
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.HandleFunc("/holidays/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "8" {
+			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, "{}")
+	})
+
+	for _, path := range []string{"/holidays/7", "/holidays/7", "/holidays/8"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// outside of a router the template is not known
+	m.Middleware(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/nowhere", nil))
+
+	// ids share one series, and the first status written is the one counted
+	assertLines(t, scrape(t, m, "travel_http_requests_total", "travel_http_request_duration_seconds_count"),
+		`travel_http_requests_total{method="GET",route="/holidays/{id}",status="200"} 2`,
+		`travel_http_requests_total{method="GET",route="/holidays/{id}",status="404"} 1`,
+		`travel_http_requests_total{method="POST",route="unknown",status="404"} 1`,
+		`travel_http_request_duration_seconds_count{method="GET",route="/holidays/{id}",status="200"} 2`,
+	)
+}
+
+func TestFlush(t *testing.T) {
+	w := httptest.NewRecorder()
+	metrics.New().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first rows")
+		w.(http.Flusher).Flush()
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reservations/export", nil))
+
+	if !w.Flushed {
+		t.Error("flush did not reach the connection")
+	}
+}
+
+func TestStorage(t *testing.T) {
+	m := metrics.New()
+	store := metrics.NewStorage(memory.New(), m)
+	ctx := tenant.WithID(context.Background(), 1)
+
+	if _, err := store.InsertLocation(ctx, &storage.Location{City: "Sofia"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Location(ctx, 100); err == nil {
+		t.Fatal("found a missing location")
+	}
+	// the wrapped storage still sees the transaction
+	err := store.InTx(ctx, func(ctx context.Context) error {
+		_, err := store.LocationGetAll(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertLines(t, scrape(t, m, "travel_storage_duration_seconds_count"),
+		`travel_storage_duration_seconds_count{method="InsertLocation",outcome="ok"} 1`,
+		`travel_storage_duration_seconds_count{method="Location",outcome="error"} 1`,
+		`travel_storage_duration_seconds_count{method="LocationGetAll",outcome="ok"} 1`,
+	)
+}
+
+// stats serves fixed business figures, or fails.
+type stats struct {
+	err error
+}
+
+func (s stats) HolidayStats(ctx context.Context) ([]storage.HolidayStats, error) {
+	return []storage.HolidayStats{
+		{HolidayID: 1, Agency: "sunny", FreeSlots: 3, SeatsSold: 7},
+		{HolidayID: 2, Agency: "sunny", FreeSlots: 5, SeatsSold: 0},
+		{HolidayID: 3, Agency: "alpine", FreeSlots: 1, SeatsSold: 2},
+	}, s.err
+}
+
+func (s stats) ReservationsCreated(ctx context.Context) (map[string]int, error) {
+	return map[string]int{"sunny": 7, "alpine": 2}, nil
+}
+
+func TestBusiness(t *testing.T) {
+	m := metrics.New()
+	m.RegisterBusiness(stats{})
+
+	assertLines(t, scrape(t, m, "travel_"),
+		`travel_reservations_created_total{agency="sunny"} 7`,
+		`travel_holiday_seats_sold{agency="sunny",holiday="1"} 7`,
+		`travel_holiday_free_slots{agency="alpine",holiday="3"} 1`,
+		`travel_free_slots_total{agency="sunny"} 8`,
+		`travel_free_slots_total{agency="alpine"} 1`,
+		`travel_business_scrape_error 0`,
+	)
+
+	// a failing query is reported instead of failing the scrape
+	failing := metrics.New()
+	failing.RegisterBusiness(stats{err: errors.New("database down")})
+	assertLines(t, scrape(t, failing, "travel_"),
+		`travel_reservations_created_total{agency="alpine"} 2`,
+		`travel_business_scrape_error 1`,
+	)
+}
@@ -0,0 +1,338 @@
+package metrics
+
+import (
+	"context"
+	"time"
+	"travel/internal/service"
+	"travel/internal/storage"
+)
+
+// Storage times every call to the storage it wraps.
+type Storage struct {
+	next    service.Storage
+	metrics *Metrics
+}
+
+func NewStorage(next service.Storage, metrics *Metrics) *Storage {
+	return &Storage{next: next, metrics: metrics}
+}
+
+func (s *Storage) ReservationGetAll(ctx context.Context) (result interface{}, err error) {
+	defer s.metrics.observeQuery("ReservationGetAll", time.Now(), &err)
+	return s.next.ReservationGetAll(ctx)
+}
+
+func (s *Storage) ReservationEach(ctx context.Context, fn func(storage.ReservationResult) error) (err error) {
+	defer s.metrics.observeQuery("ReservationEach", time.Now(), &err)
+	return s.next.ReservationEach(ctx, fn)
+}
+
+func (s *Storage) Reservation(ctx context.Context, reservationID int) (result *storage.Reservation, err error) {
+	defer s.metrics.observeQuery("Reservation", time.Now(), &err)
+	return s.next.Reservation(ctx, reservationID)
+}
+
+func (s *Storage) InsertReservation(ctx context.Context, reservation *storage.Reservation) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertReservation", time.Now(), &err)
+	return s.next.InsertReservation(ctx, reservation)
+}
+
+func (s *Storage) UpdateReservation(ctx context.Context, reservation *storage.Reservation) (result *storage.Reservation, err error) {
+	defer s.metrics.observeQuery("UpdateReservation", time.Now(), &err)
+	return s.next.UpdateReservation(ctx, reservation)
+}
+
+func (s *Storage) DeleteReservation(ctx context.Context, reservationID int) (result *storage.Reservation, err error) {
+	defer s.metrics.observeQuery("DeleteReservation", time.Now(), &err)
+	return s.next.DeleteReservation(ctx, reservationID)
+}
+
+func (s *Storage) LockReservation(ctx context.Context, reservationID int) (err error) {
+	defer s.metrics.observeQuery("LockReservation", time.Now(), &err)
+	return s.next.LockReservation(ctx, reservationID)
+}
+
+func (s *Storage) QuoteBooked(ctx context.Context, quoteID string) (result bool, err error) {
+	defer s.metrics.observeQuery("QuoteBooked", time.Now(), &err)
+	return s.next.QuoteBooked(ctx, quoteID)
+}
+
+func (s *Storage) CustomerGetAll(ctx context.Context) (result []storage.Customer, err error) {
+	defer s.metrics.observeQuery("CustomerGetAll", time.Now(), &err)
+	return s.next.CustomerGetAll(ctx)
+}
+
+func (s *Storage) Customer(ctx context.Context, customerID int) (result *storage.Customer, err error) {
+	defer s.metrics.observeQuery("Customer", time.Now(), &err)
+	return s.next.Customer(ctx, customerID)
+}
+
+func (s *Storage) CustomerByContact(ctx context.Context, normalizedPhone string, email string) (result *storage.Customer, err error) {
+	defer s.metrics.observeQuery("CustomerByContact", time.Now(), &err)
+	return s.next.CustomerByContact(ctx, normalizedPhone, email)
+}
+
+func (s *Storage) InsertCustomer(ctx context.Context, customer *storage.Customer) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertCustomer", time.Now(), &err)
+	return s.next.InsertCustomer(ctx, customer)
+}
+
+func (s *Storage) UpdateCustomer(ctx context.Context, customer *storage.Customer) (result *storage.Customer, err error) {
+	defer s.metrics.observeQuery("UpdateCustomer", time.Now(), &err)
+	return s.next.UpdateCustomer(ctx, customer)
+}
+
+func (s *Storage) DeleteCustomer(ctx context.Context, customerID int) (result *storage.Customer, err error) {
+	defer s.metrics.observeQuery("DeleteCustomer", time.Now(), &err)
+	return s.next.DeleteCustomer(ctx, customerID)
+}
+
+func (s *Storage) CustomerReservations(ctx context.Context, customerID int) (result []storage.ReservationResult, err error) {
+	defer s.metrics.observeQuery("CustomerReservations", time.Now(), &err)
+	return s.next.CustomerReservations(ctx, customerID)
+}
+
+func (s *Storage) APIKeyGetAll(ctx context.Context) (result []storage.APIKey, err error) {
+	defer s.metrics.observeQuery("APIKeyGetAll", time.Now(), &err)
+	return s.next.APIKeyGetAll(ctx)
+}
+
+func (s *Storage) APIKey(ctx context.Context, keyID int) (result *storage.APIKey, err error) {
+	defer s.metrics.observeQuery("APIKey", time.Now(), &err)
+	return s.next.APIKey(ctx, keyID)
+}
+
+func (s *Storage) APIKeyByPrefix(ctx context.Context, prefix string) (result *storage.APIKey, err error) {
+	defer s.metrics.observeQuery("APIKeyByPrefix", time.Now(), &err)
+	return s.next.APIKeyByPrefix(ctx, prefix)
+}
+
+func (s *Storage) InsertAPIKey(ctx context.Context, key *storage.APIKey) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertAPIKey", time.Now(), &err)
+	return s.next.InsertAPIKey(ctx, key)
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, keyID int, revokedAt time.Time) (err error) {
+	defer s.metrics.observeQuery("RevokeAPIKey", time.Now(), &err)
+	return s.next.RevokeAPIKey(ctx, keyID, revokedAt)
+}
+
+func (s *Storage) TouchAPIKey(ctx context.Context, keyID int, usedAt time.Time) (err error) {
+	defer s.metrics.observeQuery("TouchAPIKey", time.Now(), &err)
+	return s.next.TouchAPIKey(ctx, keyID, usedAt)
+}
+
+func (s *Storage) RoleExists(ctx context.Context, role string) (result bool, err error) {
+	defer s.metrics.observeQuery("RoleExists", time.Now(), &err)
+	return s.next.RoleExists(ctx, role)
+}
+
+func (s *Storage) LocationGetAll(ctx context.Context) (result []storage.Location, err error) {
+	defer s.metrics.observeQuery("LocationGetAll", time.Now(), &err)
+	return s.next.LocationGetAll(ctx)
+}
+
+func (s *Storage) Location(ctx context.Context, locationID int) (result *storage.Location, err error) {
+	defer s.metrics.observeQuery("Location", time.Now(), &err)
+	return s.next.Location(ctx, locationID)
+}
+
+func (s *Storage) InsertLocation(ctx context.Context, location *storage.Location) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertLocation", time.Now(), &err)
+	return s.next.InsertLocation(ctx, location)
+}
+
+func (s *Storage) UpdateLocation(ctx context.Context, location *storage.Location) (result *storage.Location, err error) {
+	defer s.metrics.observeQuery("UpdateLocation", time.Now(), &err)
+	return s.next.UpdateLocation(ctx, location)
+}
+
+func (s *Storage) DeleteLocation(ctx context.Context, locationID int) (result *storage.Location, err error) {
+	defer s.metrics.observeQuery("DeleteLocation", time.Now(), &err)
+	return s.next.DeleteLocation(ctx, locationID)
+}
+
+func (s *Storage) HolidaysGetAll(ctx context.Context, location string, duration int, startDate time.Time) (result []storage.HolidayWithLocation, err error) {
+	defer s.metrics.observeQuery("HolidaysGetAll", time.Now(), &err)
+	return s.next.HolidaysGetAll(ctx, location, duration, startDate)
+}
+
+func (s *Storage) HolidaysEach(ctx context.Context, location string, duration int, startDate time.Time, fn func(storage.HolidayWithLocation) error) (err error) {
+	defer s.metrics.observeQuery("HolidaysEach", time.Now(), &err)
+	return s.next.HolidaysEach(ctx, location, duration, startDate, fn)
+}
+
+func (s *Storage) Holiday(ctx context.Context, holidaysID int) (result *storage.Holiday, err error) {
+	defer s.metrics.observeQuery("Holiday", time.Now(), &err)
+	return s.next.Holiday(ctx, holidaysID)
+}
+
+func (s *Storage) InsertHolidays(ctx context.Context, holidays *storage.Holiday) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertHolidays", time.Now(), &err)
+	return s.next.InsertHolidays(ctx, holidays)
+}
+
+func (s *Storage) UpdateHolidays(ctx context.Context, holidays *storage.Holiday) (result *storage.Holiday, err error) {
+	defer s.metrics.observeQuery("UpdateHolidays", time.Now(), &err)
+	return s.next.UpdateHolidays(ctx, holidays)
+}
+
+func (s *Storage) DeleteHolidays(ctx context.Context, holidaysID int) (result *storage.Holiday, err error) {
+	defer s.metrics.observeQuery("DeleteHolidays", time.Now(), &err)
+	return s.next.DeleteHolidays(ctx, holidaysID)
+}
+
+func (s *Storage) PricingRuleSetGetAll(ctx context.Context) (result []storage.PricingRuleSet, err error) {
+	defer s.metrics.observeQuery("PricingRuleSetGetAll", time.Now(), &err)
+	return s.next.PricingRuleSetGetAll(ctx)
+}
+
+func (s *Storage) PricingRuleSet(ctx context.Context, ruleSetID int) (result *storage.PricingRuleSet, err error) {
+	defer s.metrics.observeQuery("PricingRuleSet", time.Now(), &err)
+	return s.next.PricingRuleSet(ctx, ruleSetID)
+}
+
+func (s *Storage) InsertPricingRuleSet(ctx context.Context, ruleSet *storage.PricingRuleSet) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertPricingRuleSet", time.Now(), &err)
+	return s.next.InsertPricingRuleSet(ctx, ruleSet)
+}
+
+func (s *Storage) UpdatePricingRuleSet(ctx context.Context, ruleSet *storage.PricingRuleSet) (result *storage.PricingRuleSet, err error) {
+	defer s.metrics.observeQuery("UpdatePricingRuleSet", time.Now(), &err)
+	return s.next.UpdatePricingRuleSet(ctx, ruleSet)
+}
+
+func (s *Storage) DeletePricingRuleSet(ctx context.Context, ruleSetID int) (result *storage.PricingRuleSet, err error) {
+	defer s.metrics.observeQuery("DeletePricingRuleSet", time.Now(), &err)
+	return s.next.DeletePricingRuleSet(ctx, ruleSetID)
+}
+
+func (s *Storage) ExchangeRateGetAll(ctx context.Context) (result []storage.ExchangeRate, err error) {
+	defer s.metrics.observeQuery("ExchangeRateGetAll", time.Now(), &err)
+	return s.next.ExchangeRateGetAll(ctx)
+}
+
+func (s *Storage) ExchangeRate(ctx context.Context, currency string) (result *storage.ExchangeRate, err error) {
+	defer s.metrics.observeQuery("ExchangeRate", time.Now(), &err)
+	return s.next.ExchangeRate(ctx, currency)
+}
+
+func (s *Storage) SaveExchangeRate(ctx context.Context, rate *storage.ExchangeRate) (result *storage.ExchangeRate, err error) {
+	defer s.metrics.observeQuery("SaveExchangeRate", time.Now(), &err)
+	return s.next.SaveExchangeRate(ctx, rate)
+}
+
+func (s *Storage) DeleteExchangeRate(ctx context.Context, currency string) (result *storage.ExchangeRate, err error) {
+	defer s.metrics.observeQuery("DeleteExchangeRate", time.Now(), &err)
+	return s.next.DeleteExchangeRate(ctx, currency)
+}
+
+func (s *Storage) PromoCodeGetAll(ctx context.Context) (result []storage.PromoCode, err error) {
+	defer s.metrics.observeQuery("PromoCodeGetAll", time.Now(), &err)
+	return s.next.PromoCodeGetAll(ctx)
+}
+
+func (s *Storage) PromoCode(ctx context.Context, promoCodeID int) (result *storage.PromoCode, err error) {
+	defer s.metrics.observeQuery("PromoCode", time.Now(), &err)
+	return s.next.PromoCode(ctx, promoCodeID)
+}
+
+func (s *Storage) PromoCodeByCode(ctx context.Context, code string) (result *storage.PromoCode, err error) {
+	defer s.metrics.observeQuery("PromoCodeByCode", time.Now(), &err)
+	return s.next.PromoCodeByCode(ctx, code)
+}
+
+func (s *Storage) InsertPromoCode(ctx context.Context, code *storage.PromoCode) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertPromoCode", time.Now(), &err)
+	return s.next.InsertPromoCode(ctx, code)
+}
+
+func (s *Storage) UpdatePromoCode(ctx context.Context, code *storage.PromoCode) (result *storage.PromoCode, err error) {
+	defer s.metrics.observeQuery("UpdatePromoCode", time.Now(), &err)
+	return s.next.UpdatePromoCode(ctx, code)
+}
+
+func (s *Storage) DeletePromoCode(ctx context.Context, promoCodeID int) (result *storage.PromoCode, err error) {
+	defer s.metrics.observeQuery("DeletePromoCode", time.Now(), &err)
+	return s.next.DeletePromoCode(ctx, promoCodeID)
+}
+
+func (s *Storage) PromoCodeRedemptions(ctx context.Context, promoCodeID int) (result []storage.Reservation, err error) {
+	defer s.metrics.observeQuery("PromoCodeRedemptions", time.Now(), &err)
+	return s.next.PromoCodeRedemptions(ctx, promoCodeID)
+}
+
+func (s *Storage) LockPromoCode(ctx context.Context, promoCodeID int) (err error) {
+	defer s.metrics.observeQuery("LockPromoCode", time.Now(), &err)
+	return s.next.LockPromoCode(ctx, promoCodeID)
+}
+
+func (s *Storage) PromoCodeRedemptionCount(ctx context.Context, promoCodeID int, customerID *int) (result int, err error) {
+	defer s.metrics.observeQuery("PromoCodeRedemptionCount", time.Now(), &err)
+	return s.next.PromoCodeRedemptionCount(ctx, promoCodeID, customerID)
+}
+
+func (s *Storage) ReservationPayments(ctx context.Context, reservationID int) (result []storage.Payment, err error) {
+	defer s.metrics.observeQuery("ReservationPayments", time.Now(), &err)
+	return s.next.ReservationPayments(ctx, reservationID)
+}
+
+func (s *Storage) Payment(ctx context.Context, paymentID int) (result *storage.Payment, err error) {
+	defer s.metrics.observeQuery("Payment", time.Now(), &err)
+	return s.next.Payment(ctx, paymentID)
+}
+
+func (s *Storage) InsertPayment(ctx context.Context, payment *storage.Payment) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertPayment", time.Now(), &err)
+	return s.next.InsertPayment(ctx, payment)
+}
+
+func (s *Storage) LockPayment(ctx context.Context, paymentID int) (err error) {
+	defer s.metrics.observeQuery("LockPayment", time.Now(), &err)
+	return s.next.LockPayment(ctx, paymentID)
+}
+
+func (s *Storage) UpdatePayment(ctx context.Context, payment *storage.Payment) (result *storage.Payment, err error) {
+	defer s.metrics.observeQuery("UpdatePayment", time.Now(), &err)
+	return s.next.UpdatePayment(ctx, payment)
+}
+
+func (s *Storage) Agency(ctx context.Context, agencyID int) (result *storage.Agency, err error) {
+	defer s.metrics.observeQuery("Agency", time.Now(), &err)
+	return s.next.Agency(ctx, agencyID)
+}
+
+func (s *Storage) ReservationInvoices(ctx context.Context, reservationID int) (result []storage.Invoice, err error) {
+	defer s.metrics.observeQuery("ReservationInvoices", time.Now(), &err)
+	return s.next.ReservationInvoices(ctx, reservationID)
+}
+
+func (s *Storage) Invoice(ctx context.Context, invoiceID int) (result *storage.Invoice, err error) {
+	defer s.metrics.observeQuery("Invoice", time.Now(), &err)
+	return s.next.Invoice(ctx, invoiceID)
+}
+
+func (s *Storage) NextInvoiceSequence(ctx context.Context, kind string) (result int, err error) {
+	defer s.metrics.observeQuery("NextInvoiceSequence", time.Now(), &err)
+	return s.next.NextInvoiceSequence(ctx, kind)
+}
+
+func (s *Storage) InsertInvoice(ctx context.Context, invoice *storage.Invoice) (result int64, err error) {
+	defer s.metrics.observeQuery("InsertInvoice", time.Now(), &err)
+	return s.next.InsertInvoice(ctx, invoice)
+}
+
+func (s *Storage) InTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer s.metrics.observeQuery("InTx", time.Now(), &err)
+	return s.next.InTx(ctx, fn)
+}
+
+func (s *Storage) InsertAuditEntry(ctx context.Context, entry *storage.AuditEntry) (err error) {
+	defer s.metrics.observeQuery("InsertAuditEntry", time.Now(), &err)
+	return s.next.InsertAuditEntry(ctx, entry)
+}
+
+func (s *Storage) AuditEntries(ctx context.Context, resource string, resourceID int) (result []storage.AuditEntry, err error) {
+	defer s.metrics.observeQuery("AuditEntries", time.Now(), &err)
+	return s.next.AuditEntries(ctx, resource, resourceID)
+}
@@ -0,0 +1,153 @@
+// Package money holds amounts of money exactly, as a whole number of the minor
+// unit (cents) of an ISO 4217 currency, so prices never pick up the rounding
+// errors of floating point numbers.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrInvalidAmount   = errors.New("invalid amount")
+)
+
+// Money is Amount minor units of Currency, 49999 EUR is 499.99 EUR.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// digits are the decimal places of the minor unit of the currencies we sell
+// in, all others are unknown.
+var digits = map[string]int{
+	"AUD": 2, "BGN": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2,
+	"CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2,
+	"ILS": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3,
+	"MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2, "PLN": 2,
+	"RON": 2, "RSD": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2,
+	"USD": 2, "ZAR": 2,
+}
+
+// Digits returns the decimal places of the minor unit of currency.
+func Digits(currency string) (int, error) {
+	d, ok := digits[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+
+	return d, nil
+}
+
+// Parse reads a decimal amount like "499.99" or "-5" of currency. The amount
+// may not have more decimals than the minor unit of the currency.
+func Parse(amount, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	d, err := Digits(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	value := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, amount)
+	}
+	if len(fraction) > d {
+		return Money{}, fmt.Errorf("%w %q: %s has %d decimals", ErrInvalidAmount, amount, currency, d)
+	}
+
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", d-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate checks that m is in a known currency.
+func (m Money) Validate() error {
+	_, err := Digits(m.Currency)
+	return err
+}
+
+// Decimal formats the amount with the decimals of its currency, e.g. "499.99".
+func (m Money) Decimal() string {
+	d, err := Digits(m.Currency)
+	if err != nil {
+		d = 2
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	value := strconv.FormatInt(amount, 10)
+	if d == 0 {
+		return sign + value
+	}
+	if len(value) <= d {
+		value = strings.Repeat("0", d-len(value)+1) + value
+	}
+
+	return sign + value[:len(value)-d] + "." + value[len(value)-d:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Percent returns percent of m, rounded half away from zero to the minor unit.
+func (m Money) Percent(percent float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * percent / 100)), Currency: m.Currency}
+}
+
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON writes m as {"amount": "499.99", "currency": "EUR"}, the amount
+// is a string so no client reads it into a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var value jsonMoney
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%w: expected {\"amount\": \"0.00\", \"currency\": \"EUR\"}", ErrInvalidAmount)
+	}
+
+	parsed, err := Parse(value.Amount, value.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package money_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"travel/internal/money"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		want             money.Money
+		decimal          string
+	}{
+		{"499.99", "EUR", money.Money{Amount: 49999, Currency: "EUR"}, "499.99"},
+		{"499.9", "eur", money.Money{Amount: 49990, Currency: "EUR"}, "499.90"},
+		{"650", "EUR", money.Money{Amount: 65000, Currency: "EUR"}, "650.00"},
+		{"0.05", "USD", money.Money{Amount: 5, Currency: "USD"}, "0.05"},
+		{"-1.5", "EUR", money.Money{Amount: -150, Currency: "EUR"}, "-1.50"},
+		{"12000", "JPY", money.Money{Amount: 12000, Currency: "JPY"}, "12000"},
+		{"1.234", "KWD", money.Money{Amount: 1234, Currency: "KWD"}, "1.234"},
+	}
+
+	for _, test := range tests {
+		got, err := money.Parse(test.amount, test.currency)
+		if err != nil {
+			t.Errorf("%s %s: %v", test.amount, test.currency, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s %s: got %+v, want %+v", test.amount, test.currency, got, test.want)
+		}
+		if got.Decimal() != test.decimal {
+			t.Errorf("%s %s: decimal %q, want %q", test.amount, test.currency, got.Decimal(), test.decimal)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		err              error
+	}{
+		{"499.999", "EUR", money.ErrInvalidAmount},
+		{"1.5", "JPY", money.ErrInvalidAmount},
+		{"cheap", "EUR", money.ErrInvalidAmount},
+		{"1e3", "EUR", money.ErrInvalidAmount},
+		{".5", "EUR", money.ErrInvalidAmount},
+		{"", "EUR", money.ErrInvalidAmount},
+		{"99999999999999999999", "EUR", money.ErrInvalidAmount},
+		{"10", "XXX", money.ErrUnknownCurrency},
+		{"10", "", money.ErrUnknownCurrency},
+	}
+
+	for _, test := range tests {
+		if _, err := money.Parse(test.amount, test.currency); !errors.Is(err, test.err) {
+			t.Errorf("%q %q: got %v, want %v", test.amount, test.currency, err, test.err)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	price := money.Money{Amount: 9999, Currency: "EUR"}
+
+	if got := price.Percent(-20); got.Amount != -2000 {
+		t.Errorf("-20%% of %s: got %s", price, got)
+	}
+	if got := price.Percent(5); got.Amount != 500 {
+		t.Errorf("5%% of %s: got %s", price, got)
+	}
+	// 10% of 0.05 is half a cent, which rounds away from zero
+	if got := (money.Money{Amount: 5, Currency: "EUR"}).Percent(-10); got.Amount != -1 {
+		t.Errorf("-10%% of 0.05: got %s", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	price := money.Money{Amount: 49999, Currency: "EUR"}
+
+	data, err := json.Marshal(price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":"499.99","currency":"EUR"}` {
+		t.Errorf("got %s", data)
+	}
+
+	var got money.Money
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != price {
+		t.Errorf("got %+v, want %+v", got, price)
+	}
+
+	for _, invalid := range []string{`499.99`, `"499.99"`, `{"amount": 499.99, "currency": "EUR"}`, `{"amount": "499.99"}`} {
+		if err := json.Unmarshal([]byte(invalid), &got); err == nil {
+			t.Errorf("%s: no error", invalid)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"travel/internal/money"
+)
+
+// Fake is a gateway that moves no money, so the payment flow runs locally and
+// in tests. Its intents stay pending until Complete settles them the way a
+// customer paying at a real gateway would.
+type Fake struct {
+	secret []byte
+	now    func() time.Time
+
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+	next    int
+	fail    error
+}
+
+type fakeIntent struct {
+	request  IntentRequest
+	status   string
+	refunded int64
+}
+
+// NewFake signs its webhooks with secret. Without a secret a random one is
+// used, the webhooks then only verify in this process.
+func NewFake(secret []byte) *Fake {
+	if len(secret) == 0 {
+		secret = randomBytes(32)
+	}
+
+	return &Fake{secret: secret, now: time.Now, intents: map[string]*fakeIntent{}}
+}
+
+// FailNext makes the next intent, refund or cancellation fail with err, the way a gateway
+// that is down would.
+func (f *Fake) FailNext(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fail = err
+}
+
+// failure returns and clears the error set by FailNext, f.mu is held.
+func (f *Fake) failure() error {
+	err := f.fail
+	f.fail = nil
+	return err
+}
+
+func (f *Fake) CreateIntent(ctx context.Context, request IntentRequest) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failure(); err != nil {
+		return nil, err
+	}
+
+	f.next++
+	id := fmt.Sprintf("pi_fake_%d", f.next)
+	f.intents[id] = &fakeIntent{request: request, status: StatusPending}
+
+	return &Intent{ID: id, ClientSecret: id + "_secret_" + hex.EncodeToString(randomBytes(8))}, nil
+}
+
+// Refund pays amount of a succeeded intent back, up to what is left of it.
+func (f *Fake) Refund(ctx context.Context, intentID string, amount money.Money) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failure(); err != nil {
+		return "", err
+	}
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownIntent, intentID)
+	}
+	if intent.status != StatusSucceeded {
+		return "", fmt.Errorf("%w: the payment is %s", ErrRefund, intent.status)
+	}
+	if amount.Currency != intent.request.Amount.Currency || amount.Amount <= 0 || intent.refunded+amount.Amount > intent.request.Amount.Amount {
+		return "", fmt.Errorf("%w: %s of %s", ErrRefund, amount, intent.request.Amount)
+	}
+
+	intent.refunded += amount.Amount
+	f.next++
+
+	return fmt.Sprintf("re_fake_%d", f.next), nil
+}
+
+// CancelIntent cancels a pending intent, which then can not be paid. An
+// intent that was paid can not be cancelled, one that failed already is left
+// as it is.
+func (f *Fake) CancelIntent(ctx context.Context, intentID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failure(); err != nil {
+		return err
+	}
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownIntent, intentID)
+	}
+	if intent.status == StatusSucceeded {
+		return fmt.Errorf("%w: intent %s was paid", ErrCancel, intentID)
+	}
+
+	intent.status = StatusFailed
+
+	return nil
+}
+
+// ParseEvent verifies the signature of a webhook and reads its event.
+func (f *Fake) ParseEvent(body []byte, signature string) (*Event, error) {
+	if err := Verify(f.secret, signature, body, f.now()); err != nil {
+		return nil, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+	}
+
+	return &event, nil
+}
+
+// Complete settles a pending intent, paid when succeed is true, and returns
+// the signed webhook the gateway sends about it.
+func (f *Fake) Complete(intentID string, succeed bool) (body []byte, signature string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, "", fmt.Errorf("%w %q", ErrUnknownIntent, intentID)
+	}
+	if intent.status != StatusPending {
+		return nil, "", fmt.Errorf("intent %s is %s already", intentID, intent.status)
+	}
+
+	event := Event{
+		Type:     EventFailed,
+		IntentID: intentID,
+		Amount:   intent.request.Amount,
+		Metadata: intent.request.Metadata,
+	}
+	intent.status = StatusFailed
+	if succeed {
+		event.Type = EventSucceeded
+		intent.status = StatusSucceeded
+	}
+
+	f.next++
+	event.ID = fmt.Sprintf("evt_fake_%d", f.next)
+
+	body, err = json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, Sign(f.secret, f.now(), body), nil
+}
+
+// Handler stands in for the checkout page of a real gateway: POST
+// /<intent>/succeed or /<intent>/fail settles the intent and delivers its
+// webhook to webhooks, answering with what webhooks answered.
+func (f *Fake) Handler(webhooks http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		intentID, outcome, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+		if r.Method != http.MethodPost || (outcome != "succeed" && outcome != "fail") {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, signature, err := f.Complete(intentID, outcome == "succeed")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		webhook, err := http.NewRequestWithContext(r.Context(), http.MethodPost, "/webhooks/payments", bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		webhook.Header.Set("Content-Type", "application/json")
+		webhook.Header.Set(SignatureHeader, signature)
+
+		webhooks.ServeHTTP(w, webhook)
+	})
+}
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	// crypto/rand does not fail on the platforms we run on
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+
+	return data
+}
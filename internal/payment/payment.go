@@ -0,0 +1,119 @@
+// Package payment collects the price of reservations through a payment
+// gateway. A reservation is paid in installments: a deposit now and the
+// balance some days before the holiday starts, or in full when the holiday
+// starts too soon for that. Every installment is a payment intent at the
+// gateway, which confirms or fails it later with a signed webhook.
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"travel/internal/money"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("invalid payment schedule")
+	ErrUnknownIntent   = errors.New("unknown payment intent")
+	ErrMalformedEvent  = errors.New("malformed payment event")
+	ErrRefund          = errors.New("refund not possible")
+	ErrCancel          = errors.New("intent can not be cancelled")
+)
+
+// DefaultIntentTTL is how long a customer has to pay an intent before it may
+// be cancelled in favour of a new payment or the cancellation of the
+// reservation.
+const DefaultIntentTTL = time.Hour
+
+// Statuses of a payment.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusRefunded  = "refunded"
+)
+
+// Kinds of installments.
+const (
+	KindDeposit = "deposit"
+	KindBalance = "balance"
+	KindFull    = "full"
+)
+
+// Types of the events gateways send.
+const (
+	EventSucceeded = "payment.succeeded"
+	EventFailed    = "payment.failed"
+)
+
+// Schedule decides how a reservation is paid. A holiday starting more than
+// BalanceDays from now is paid with a deposit of DepositPercent of the price
+// now and the balance BalanceDays before it starts, others in full now.
+type Schedule struct {
+	DepositPercent float64
+	BalanceDays    int
+}
+
+// DefaultSchedule takes a 30% deposit and the balance 30 days before the
+// holiday starts.
+var DefaultSchedule = Schedule{DepositPercent: 30, BalanceDays: 30}
+
+func (s Schedule) Validate() error {
+	if s.DepositPercent <= 0 || s.DepositPercent >= 100 {
+		return fmt.Errorf("%w: depositPercent %v is not between 0 and 100", ErrInvalidSchedule, s.DepositPercent)
+	}
+	if s.BalanceDays < 0 {
+		return fmt.Errorf("%w: balanceDays %d is negative", ErrInvalidSchedule, s.BalanceDays)
+	}
+
+	return nil
+}
+
+// Installment is one amount a reservation is paid with and when it is due.
+type Installment struct {
+	Kind    string      `json:"kind"`
+	Amount  money.Money `json:"amount"`
+	DueDate time.Time   `json:"dueDate"`
+}
+
+// Plan splits total into the installments of a holiday starting on
+// startDate, booked now.
+func (s Schedule) Plan(total money.Money, startDate time.Time, now time.Time) []Installment {
+	today := now.UTC().Truncate(24 * time.Hour)
+	balanceDue := startDate.UTC().Truncate(24*time.Hour).AddDate(0, 0, -s.BalanceDays)
+
+	if !balanceDue.After(today) {
+		return []Installment{{Kind: KindFull, Amount: total, DueDate: today}}
+	}
+
+	deposit := total.Percent(s.DepositPercent)
+
+	return []Installment{
+		{Kind: KindDeposit, Amount: deposit, DueDate: today},
+		{Kind: KindBalance, Amount: money.Money{Amount: total.Amount - deposit.Amount, Currency: total.Currency}, DueDate: balanceDue},
+	}
+}
+
+// IntentRequest asks a gateway to collect Amount. Metadata comes back with
+// the events of the intent.
+type IntentRequest struct {
+	Amount      money.Money
+	Description string
+	Metadata    map[string]string
+}
+
+// Intent is a payment intent as created at a gateway. The client confirms it
+// at the gateway with ClientSecret.
+type Intent struct {
+	ID           string
+	ClientSecret string
+}
+
+// Event is a webhook of a gateway about one of its intents.
+type Event struct {
+	ID       string            `json:"id"`
+	Type     string            `json:"type"`
+	IntentID string            `json:"intent"`
+	Amount   money.Money       `json:"amount"`
+	Metadata map[string]string `json:"metadata"`
+}
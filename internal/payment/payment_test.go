@@ -0,0 +1,188 @@
+package payment_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"travel/internal/money"
+	"travel/internal/payment"
+)
+
+func eur(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "EUR"}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2030, time.January, 10, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		startDate time.Time
+		want      []payment.Installment
+	}{
+		{
+			name:      "deposit",
+			startDate: date(2030, time.July, 1),
+			want: []payment.Installment{
+				{Kind: payment.KindDeposit, Amount: eur(90015), DueDate: date(2030, time.January, 10)},
+				{Kind: payment.KindBalance, Amount: eur(210035), DueDate: date(2030, time.June, 1)},
+			},
+		},
+		{
+			name:      "balance due tomorrow",
+			startDate: date(2030, time.February, 10),
+			want: []payment.Installment{
+				{Kind: payment.KindDeposit, Amount: eur(90015), DueDate: date(2030, time.January, 10)},
+				{Kind: payment.KindBalance, Amount: eur(210035), DueDate: date(2030, time.January, 11)},
+			},
+		},
+		{
+			name:      "balance due today",
+			startDate: date(2030, time.February, 9),
+			want:      []payment.Installment{{Kind: payment.KindFull, Amount: eur(300050), DueDate: date(2030, time.January, 10)}},
+		},
+		{
+			name:      "started",
+			startDate: date(2030, time.January, 1),
+			want:      []payment.Installment{{Kind: payment.KindFull, Amount: eur(300050), DueDate: date(2030, time.January, 10)}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := payment.DefaultSchedule.Plan(eur(300050), test.startDate, now)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	if err := payment.DefaultSchedule.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	for _, schedule := range []payment.Schedule{{DepositPercent: 0, BalanceDays: 30}, {DepositPercent: 100, BalanceDays: 30}, {DepositPercent: 30, BalanceDays: -1}} {
+		if err := schedule.Validate(); !errors.Is(err, payment.ErrInvalidSchedule) {
+			t.Errorf("%+v: got %v, want %v", schedule, err, payment.ErrInvalidSchedule)
+		}
+	}
+}
+
+func TestSignature(t *testing.T) {
+	secret, body := []byte("secret"), []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1893456000, 0)
+	signature := payment.Sign(secret, now, body)
+
+	if err := payment.Verify(secret, signature, body, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		signature string
+		body      []byte
+		now       time.Time
+	}{
+		{name: "changed body", secret: secret, signature: signature, body: []byte(`{"id":"evt_2"}`), now: now},
+		{name: "other secret", secret: []byte("other"), signature: signature, body: body, now: now},
+		{name: "replayed", secret: secret, signature: signature, body: body, now: now.Add(time.Hour)},
+		{name: "missing", secret: secret, signature: "", body: body, now: now},
+		{name: "malformed", secret: secret, signature: "t=now,v1=zz", body: body, now: now},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := payment.Verify(test.secret, test.signature, test.body, test.now); !errors.Is(err, payment.ErrInvalidSignature) {
+				t.Errorf("got %v, want %v", err, payment.ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+	gateway := payment.NewFake([]byte("secret"))
+
+	intent, err := gateway.CreateIntent(ctx, payment.IntentRequest{Amount: eur(90015), Metadata: map[string]string{"payment": "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intent.ID == "" || intent.ClientSecret == "" {
+		t.Fatalf("got %+v", intent)
+	}
+
+	// nothing is refunded before it is paid
+	if _, err := gateway.Refund(ctx, intent.ID, eur(100)); !errors.Is(err, payment.ErrRefund) {
+		t.Errorf("got %v, want %v", err, payment.ErrRefund)
+	}
+
+	body, signature, err := gateway.Complete(intent.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := gateway.ParseEvent(body, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := payment.Event{ID: event.ID, Type: payment.EventSucceeded, IntentID: intent.ID, Amount: eur(90015), Metadata: map[string]string{"payment": "1"}}
+	if !reflect.DeepEqual(*event, want) {
+		t.Errorf("got %+v, want %+v", *event, want)
+	}
+
+	if _, _, err := gateway.Complete(intent.ID, false); err == nil {
+		t.Error("a paid intent failed")
+	}
+	if _, err := payment.NewFake([]byte("other")).ParseEvent(body, signature); !errors.Is(err, payment.ErrInvalidSignature) {
+		t.Errorf("got %v, want %v", err, payment.ErrInvalidSignature)
+	}
+
+	if _, err := gateway.Refund(ctx, intent.ID, eur(90000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gateway.Refund(ctx, intent.ID, eur(16)); !errors.Is(err, payment.ErrRefund) {
+		t.Errorf("got %v, want %v", err, payment.ErrRefund)
+	}
+	if _, err := gateway.Refund(ctx, "pi_missing", eur(1)); !errors.Is(err, payment.ErrUnknownIntent) {
+		t.Errorf("got %v, want %v", err, payment.ErrUnknownIntent)
+	}
+}
+
+func TestFakeCancelIntent(t *testing.T) {
+	ctx := context.Background()
+	gateway := payment.NewFake([]byte("secret"))
+
+	unpaid, err := gateway.CreateIntent(ctx, payment.IntentRequest{Amount: eur(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.CancelIntent(ctx, unpaid.ID); err != nil {
+		t.Fatal(err)
+	}
+	// a cancelled intent can not be paid any more
+	if _, _, err := gateway.Complete(unpaid.ID, true); err == nil {
+		t.Error("a cancelled intent was paid")
+	}
+
+	paid, err := gateway.CreateIntent(ctx, payment.IntentRequest{Amount: eur(100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := gateway.Complete(paid.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.CancelIntent(ctx, paid.ID); !errors.Is(err, payment.ErrCancel) {
+		t.Errorf("got %v, want %v", err, payment.ErrCancel)
+	}
+	if err := gateway.CancelIntent(ctx, "pi_missing"); !errors.Is(err, payment.ErrUnknownIntent) {
+		t.Errorf("got %v, want %v", err, payment.ErrUnknownIntent)
+	}
+}
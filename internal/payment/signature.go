@@ -0,0 +1,66 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// SignatureHeader carries the signature of a webhook.
+const SignatureHeader = "Payment-Signature"
+
+// SignatureTolerance is how old a webhook may be, older ones are replays.
+const SignatureTolerance = 5 * time.Minute
+
+// Sign returns the signature of a webhook body sent at t, "t=<unix>,v1=<hex>"
+// with an HMAC-SHA256 of "<unix>.<body>".
+func Sign(secret []byte, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac(secret, timestamp, body))
+}
+
+// Verify checks that signature was made by Sign with secret for body, no
+// longer than SignatureTolerance before now.
+func Verify(secret []byte, signature string, body []byte, now time.Time) error {
+	var timestamp, v1 string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			v1 = value
+		}
+	}
+
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(v1)
+	if err != nil || !hmac.Equal(got, mac(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(sent, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return fmt.Errorf("%w: sent %s ago", ErrInvalidSignature, age.Round(time.Second))
+	}
+
+	return nil
+}
+
+func mac(secret []byte, timestamp string, body []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(timestamp + "."))
+	m.Write(body)
+
+	return m.Sum(nil)
+}
@@ -0,0 +1,238 @@
+// Package phone parses, validates and formats phone numbers against the
+// numbering plans bundled in plans.json.
+package phone
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrEmpty          = errors.New("phone number is empty")
+	ErrInvalidChars   = errors.New("phone number contains invalid characters")
+	ErrUnknownRegion  = errors.New("unknown phone region")
+	ErrUnknownCountry = errors.New("unknown country calling code")
+	ErrInvalidNumber  = errors.New("phone number is not valid for its region")
+)
+
+//go:embed plans.json
+var plansJSON []byte
+
+type format struct {
+	LeadingDigits       string `json:"leadingDigits"`
+	Pattern             string `json:"pattern"`
+	Format              string `json:"format"`
+	InternationalFormat string `json:"internationalFormat"`
+
+	leading *regexp.Regexp
+	pattern *regexp.Regexp
+}
+
+type plan struct {
+	CountryCode    string `json:"countryCode"`
+	NationalPrefix string `json:"nationalPrefix"`
+	// FormatWithoutPrefix leaves the national prefix out of the national
+	// format, as NANP numbers are written.
+	FormatWithoutPrefix bool     `json:"formatWithoutPrefix"`
+	MainCountry         bool     `json:"mainCountry"`
+	Pattern             string   `json:"pattern"`
+	Formats             []format `json:"formats"`
+
+	region  string
+	pattern *regexp.Regexp
+}
+
+var (
+	plans = map[string]*plan{}
+	// regions sharing a calling code, most specific first
+	countryRegions = map[string][]*plan{}
+)
+
+func init() {
+	if err := json.Unmarshal(plansJSON, &plans); err != nil {
+		panic(fmt.Sprintf("phone: bad plans.json: %v", err))
+	}
+
+	for region, p := range plans {
+		p.region = region
+		p.pattern = regexp.MustCompile("^(?:" + p.Pattern + ")$")
+		for i := range p.Formats {
+			f := &p.Formats[i]
+			f.pattern = regexp.MustCompile("^(?:" + f.Pattern + ")$")
+			if f.LeadingDigits != "" {
+				f.leading = regexp.MustCompile("^(?:" + f.LeadingDigits + ")")
+			}
+		}
+		countryRegions[p.CountryCode] = append(countryRegions[p.CountryCode], p)
+	}
+
+	for _, regions := range countryRegions {
+		sort.Slice(regions, func(i, j int) bool {
+			if regions[i].MainCountry != regions[j].MainCountry {
+				return !regions[i].MainCountry
+			}
+			return regions[i].region < regions[j].region
+		})
+	}
+}
+
+// Number is a validated phone number split into its country calling code and
+// national significant number.
+type Number struct {
+	Region         string
+	CountryCode    string
+	NationalNumber string
+}
+
+// Parse reads a phone number written in any of the usual ways ("+359 88
+// 123 4567", "0035988-123-4567", "088 123 4567"). Numbers without an
+// international prefix are read in defaultRegion.
+func Parse(input string, defaultRegion string) (*Number, error) {
+	digits, international, err := clean(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if international {
+		return parseInternational(digits)
+	}
+
+	p, ok := plans[strings.ToUpper(defaultRegion)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRegion, defaultRegion)
+	}
+
+	if p.NationalPrefix != "" && strings.HasPrefix(digits, p.NationalPrefix) && !p.pattern.MatchString(digits) {
+		digits = strings.TrimPrefix(digits, p.NationalPrefix)
+	}
+
+	// a number read in the default region may still belong to another region
+	// sharing the calling code (US and CA)
+	for _, candidate := range countryRegions[p.CountryCode] {
+		if candidate.pattern.MatchString(digits) {
+			return &Number{Region: candidate.region, CountryCode: candidate.CountryCode, NationalNumber: digits}, nil
+		}
+	}
+
+	return nil, ErrInvalidNumber
+}
+
+// Valid reports whether input parses in defaultRegion.
+func Valid(input string, defaultRegion string) bool {
+	_, err := Parse(input, defaultRegion)
+	return err == nil
+}
+
+func parseInternational(digits string) (*Number, error) {
+	// calling codes are prefix free and at most three digits long
+	for length := 1; length <= 3 && length < len(digits); length++ {
+		regions, ok := countryRegions[digits[:length]]
+		if !ok {
+			continue
+		}
+
+		national := digits[length:]
+		for _, p := range regions {
+			if p.pattern.MatchString(national) {
+				return &Number{Region: p.region, CountryCode: p.CountryCode, NationalNumber: national}, nil
+			}
+		}
+
+		return nil, ErrInvalidNumber
+	}
+
+	return nil, ErrUnknownCountry
+}
+
+// clean drops the usual separators and reports whether the number starts
+// with an international prefix ("+" or "00").
+func clean(input string) (string, bool, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", false, ErrEmpty
+	}
+
+	international := strings.HasPrefix(input, "+")
+	if international {
+		input = input[1:]
+	}
+
+	var b strings.Builder
+	for _, r := range input {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ', r == '-', r == '.', r == '(', r == ')', r == '/':
+		default:
+			return "", false, ErrInvalidChars
+		}
+	}
+
+	digits := b.String()
+	if !international && strings.HasPrefix(digits, "00") {
+		digits = digits[2:]
+		international = true
+	}
+
+	if digits == "" {
+		return "", false, ErrEmpty
+	}
+
+	return digits, international, nil
+}
+
+// E164 returns the canonical form, e.g. "+359881234567".
+func (n *Number) E164() string {
+	return "+" + n.CountryCode + n.NationalNumber
+}
+
+// National returns the number the way it is dialled inside its region,
+// e.g. "088 123 4567".
+func (n *Number) National() string {
+	p := plans[n.Region]
+	formatted := n.NationalNumber
+	if f := p.format(n.NationalNumber); f != nil {
+		formatted = f.pattern.ReplaceAllString(n.NationalNumber, f.Format)
+	}
+
+	if p.FormatWithoutPrefix {
+		return formatted
+	}
+
+	return p.NationalPrefix + formatted
+}
+
+// International returns the number for dialling from abroad, e.g.
+// "+359 88 123 4567".
+func (n *Number) International() string {
+	f := plans[n.Region].format(n.NationalNumber)
+	if f == nil {
+		return n.E164()
+	}
+
+	layout := f.Format
+	if f.InternationalFormat != "" {
+		layout = f.InternationalFormat
+	}
+
+	return "+" + n.CountryCode + " " + f.pattern.ReplaceAllString(n.NationalNumber, layout)
+}
+
+func (p *plan) format(national string) *format {
+	for i := range p.Formats {
+		f := &p.Formats[i]
+		if f.leading != nil && !f.leading.MatchString(national) {
+			continue
+		}
+		if f.pattern.MatchString(national) {
+			return f
+		}
+	}
+
+	return nil
+}
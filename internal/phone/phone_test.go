@@ -0,0 +1,86 @@
+package phone_test
+
+import (
+	"errors"
+	"testing"
+	"travel/internal/phone"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input, region                 string
+		want                          phone.Number
+		e164, national, international string
+	}{
+		// national numbers are read in the default region
+		{"088 123 4567", "BG", phone.Number{Region: "BG", CountryCode: "359", NationalNumber: "881234567"}, "+359881234567", "088 123 4567", "+359 88 123 4567"},
+		{"02 123 4567", "BG", phone.Number{Region: "BG", CountryCode: "359", NationalNumber: "21234567"}, "+35921234567", "02 123 4567", "+359 2 123 4567"},
+		{"0888123456", "bg", phone.Number{Region: "BG", CountryCode: "359", NationalNumber: "888123456"}, "+359888123456", "088 812 3456", "+359 88 812 3456"},
+		{"020 7946 0958", "GB", phone.Number{Region: "GB", CountryCode: "44", NationalNumber: "2079460958"}, "+442079460958", "020 7946 0958", "+44 20 7946 0958"},
+		{"(212) 555-0123", "US", phone.Number{Region: "US", CountryCode: "1", NationalNumber: "2125550123"}, "+12125550123", "(212) 555-0123", "+1 212-555-0123"},
+		{"1 212 555 0123", "US", phone.Number{Region: "US", CountryCode: "1", NationalNumber: "2125550123"}, "+12125550123", "(212) 555-0123", "+1 212-555-0123"},
+		// regions sharing a calling code are told apart by the number
+		{"416-555-0123", "US", phone.Number{Region: "CA", CountryCode: "1", NationalNumber: "4165550123"}, "+14165550123", "(416) 555-0123", "+1 416-555-0123"},
+		// international numbers ignore the default region
+		{"+359 88 123 4567", "GB", phone.Number{Region: "BG", CountryCode: "359", NationalNumber: "881234567"}, "+359881234567", "088 123 4567", "+359 88 123 4567"},
+		{"0035988-123-4567", "US", phone.Number{Region: "BG", CountryCode: "359", NationalNumber: "881234567"}, "+359881234567", "088 123 4567", "+359 88 123 4567"},
+		{"+44 (20) 7946.0958", "BG", phone.Number{Region: "GB", CountryCode: "44", NationalNumber: "2079460958"}, "+442079460958", "020 7946 0958", "+44 20 7946 0958"},
+		{"+1 416 555 0123", "BG", phone.Number{Region: "CA", CountryCode: "1", NationalNumber: "4165550123"}, "+14165550123", "(416) 555-0123", "+1 416-555-0123"},
+		{"+49 30 123456", "BG", phone.Number{Region: "DE", CountryCode: "49", NationalNumber: "30123456"}, "+4930123456", "", ""},
+		{"+39 06 1234 5678", "BG", phone.Number{Region: "IT", CountryCode: "39", NationalNumber: "0612345678"}, "+390612345678", "", ""},
+	}
+
+	for _, test := range tests {
+		got, err := phone.Parse(test.input, test.region)
+		if err != nil {
+			t.Errorf("%q in %s: %v", test.input, test.region, err)
+			continue
+		}
+		if *got != test.want {
+			t.Errorf("%q in %s: got %+v, want %+v", test.input, test.region, *got, test.want)
+		}
+		if got.E164() != test.e164 {
+			t.Errorf("%q in %s: E.164 %q, want %q", test.input, test.region, got.E164(), test.e164)
+		}
+		if test.national != "" && got.National() != test.national {
+			t.Errorf("%q in %s: national %q, want %q", test.input, test.region, got.National(), test.national)
+		}
+		if test.international != "" && got.International() != test.international {
+			t.Errorf("%q in %s: international %q, want %q", test.input, test.region, got.International(), test.international)
+		}
+
+		// the canonical form reads back as the same number
+		if again, err := phone.Parse(got.E164(), "GB"); err != nil || *again != *got {
+			t.Errorf("%q in %s: %s reads back as %+v, %v", test.input, test.region, got.E164(), again, err)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		input, region string
+		err           error
+	}{
+		{"", "BG", phone.ErrEmpty},
+		{"   ", "BG", phone.ErrEmpty},
+		{"+", "BG", phone.ErrEmpty},
+		{"00", "BG", phone.ErrEmpty},
+		{"+359 abc", "BG", phone.ErrInvalidChars},
+		{"088 123 4567 ext. 5", "BG", phone.ErrInvalidChars},
+		{"088 12", "BG", phone.ErrInvalidNumber},
+		{"088 123 4567 890", "BG", phone.ErrInvalidNumber},
+		{"+359 12", "BG", phone.ErrInvalidNumber},
+		{"+999 123 456", "BG", phone.ErrUnknownCountry},
+		{"0888123456", "XX", phone.ErrUnknownRegion},
+		{"0888123456", "", phone.ErrUnknownRegion},
+	}
+
+	for _, test := range tests {
+		if _, err := phone.Parse(test.input, test.region); !errors.Is(err, test.err) {
+			t.Errorf("%q in %q: got %v, want %v", test.input, test.region, err, test.err)
+		}
+		if phone.Valid(test.input, test.region) {
+			t.Errorf("%q in %q is valid", test.input, test.region)
+		}
+	}
+}
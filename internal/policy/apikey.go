@@ -0,0 +1,38 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+)
+
+func (p *Service) APIKeyGetAll(ctx context.Context) ([]service.APIKeyDTO, error) {
+	if _, err := p.require(ctx, "apikey:manage"); err != nil {
+		return nil, err
+	}
+
+	return p.next.APIKeyGetAll(ctx)
+}
+
+func (p *Service) CreateAPIKey(ctx context.Context, name string, role string) (*service.APIKeyDTO, error) {
+	if _, err := p.require(ctx, "apikey:manage"); err != nil {
+		return nil, err
+	}
+
+	return p.next.CreateAPIKey(ctx, name, role)
+}
+
+func (p *Service) RevokeAPIKey(ctx context.Context, keyID int) (*service.APIKeyDTO, error) {
+	if _, err := p.require(ctx, "apikey:manage"); err != nil {
+		return nil, err
+	}
+
+	return p.next.RevokeAPIKey(ctx, keyID)
+}
+
+func (p *Service) RotateAPIKey(ctx context.Context, keyID int) (*service.APIKeyDTO, error) {
+	if _, err := p.require(ctx, "apikey:manage"); err != nil {
+		return nil, err
+	}
+
+	return p.next.RotateAPIKey(ctx, keyID)
+}
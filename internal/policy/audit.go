@@ -0,0 +1,14 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+)
+
+func (p *Service) AuditLog(ctx context.Context, resource string, resourceID int) ([]service.AuditEntryDTO, error) {
+	if _, err := p.require(ctx, "audit:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.AuditLog(ctx, resource, resourceID)
+}
@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+	"travel/internal/storage"
+)
+
+func (p *Service) CustomerGetAll(ctx context.Context) ([]service.CustomerDTO, error) {
+	if _, err := p.require(ctx, "customer:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.CustomerGetAll(ctx)
+}
+
+func (p *Service) Customer(ctx context.Context, customerID int) (*service.CustomerDTO, error) {
+	if err := p.readCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+
+	return p.next.Customer(ctx, customerID)
+}
+
+func (p *Service) InsertCustomer(ctx context.Context, customer service.CustomerDTO) (int64, error) {
+	if _, err := p.require(ctx, "customer:write"); err != nil {
+		return 0, err
+	}
+
+	return p.next.InsertCustomer(ctx, customer)
+}
+
+func (p *Service) UpdateCustomer(ctx context.Context, customer service.CustomerDTO) (*service.CustomerDTO, error) {
+	if _, err := p.require(ctx, "customer:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.UpdateCustomer(ctx, customer)
+}
+
+func (p *Service) DeleteCustomer(ctx context.Context, customerID int) (*service.CustomerDTO, error) {
+	if _, err := p.require(ctx, "customer:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.DeleteCustomer(ctx, customerID)
+}
+
+func (p *Service) CustomerReservations(ctx context.Context, customerID int, currency string) ([]storage.ReservationResult, error) {
+	if err := p.readCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+
+	return p.next.CustomerReservations(ctx, customerID, currency)
+}
+
+func (p *Service) readCustomer(ctx context.Context, customerID int) error {
+	principal, own, err := p.scope(ctx, "customer:read", "customer:read:own")
+	if err != nil {
+		return err
+	}
+
+	if own && customerID != principal.CustomerID {
+		return denied("customer:read")
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package policy
+
+import (
+	"context"
+	"io"
+	"travel/internal/service"
+)
+
+func (p *Service) ExchangeRateGetAll(ctx context.Context) ([]service.ExchangeRateDTO, error) {
+	if _, err := p.require(ctx, "pricing:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.ExchangeRateGetAll(ctx)
+}
+
+func (p *Service) SaveExchangeRate(ctx context.Context, rate service.ExchangeRateDTO) (*service.ExchangeRateDTO, error) {
+	if _, err := p.require(ctx, "pricing:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.SaveExchangeRate(ctx, rate)
+}
+
+func (p *Service) ImportExchangeRates(ctx context.Context, r io.Reader) ([]service.ExchangeRateDTO, error) {
+	if _, err := p.require(ctx, "pricing:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.ImportExchangeRates(ctx, r)
+}
+
+func (p *Service) DeleteExchangeRate(ctx context.Context, currency string) (*service.ExchangeRateDTO, error) {
+	if _, err := p.require(ctx, "pricing:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.DeleteExchangeRate(ctx, currency)
+}
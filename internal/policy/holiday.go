@@ -0,0 +1,63 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+	"travel/internal/storage"
+)
+
+func (p *Service) HolidayGetAll(ctx context.Context, filterDTO service.FilterHolidays) (interface{}, error) {
+	if _, err := p.require(ctx, "holiday:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.HolidayGetAll(ctx, filterDTO)
+}
+
+func (p *Service) ExportHolidays(ctx context.Context, filterDTO service.FilterHolidays, fn func(storage.HolidayWithLocation) error) error {
+	if _, err := p.require(ctx, "holiday:read"); err != nil {
+		return err
+	}
+
+	return p.next.ExportHolidays(ctx, filterDTO, fn)
+}
+
+func (p *Service) Holiday(ctx context.Context, holidayID int, currency string) (*service.HolidayDTO, error) {
+	if _, err := p.require(ctx, "holiday:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.Holiday(ctx, holidayID, currency)
+}
+
+func (p *Service) Quote(ctx context.Context, holidayID int, request service.QuoteRequestDTO) (*service.QuoteDTO, error) {
+	if _, err := p.require(ctx, "holiday:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.Quote(ctx, holidayID, request)
+}
+
+func (p *Service) InsertHoliday(ctx context.Context, holiday service.HolidayDTO) (int64, error) {
+	if _, err := p.require(ctx, "holiday:write"); err != nil {
+		return 0, err
+	}
+
+	return p.next.InsertHoliday(ctx, holiday)
+}
+
+func (p *Service) UpdateHoliday(ctx context.Context, holiday service.HolidayDTO) (*service.HolidayDTO, error) {
+	if _, err := p.require(ctx, "holiday:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.UpdateHoliday(ctx, holiday)
+}
+
+func (p *Service) DeleteHoliday(ctx context.Context, holidayID int) (*service.HolidayDTO, error) {
+	if _, err := p.require(ctx, "holiday:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.DeleteHoliday(ctx, holidayID)
+}
@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+)
+
+// IssueInvoice lets customers have the invoices of their own reservations
+// issued.
+func (p *Service) IssueInvoice(ctx context.Context, reservationID int) (*service.InvoiceDTO, error) {
+	if err := p.reservationScope(ctx, reservationID, "invoice:write", "invoice:create:own"); err != nil {
+		return nil, err
+	}
+
+	return p.next.IssueInvoice(ctx, reservationID)
+}
+
+// ReservationInvoice shows customers the invoices of their own
+// reservations.
+func (p *Service) ReservationInvoice(ctx context.Context, reservationID int) (*service.InvoiceDTO, error) {
+	if err := p.reservationScope(ctx, reservationID, "invoice:read", "invoice:read:own"); err != nil {
+		return nil, err
+	}
+
+	return p.next.ReservationInvoice(ctx, reservationID)
+}
+
+// ReservationInvoices shows customers the invoices issued to them. The
+// reservation may be deleted, so they are told apart by their customer.
+func (p *Service) ReservationInvoices(ctx context.Context, reservationID int) ([]service.InvoiceDTO, error) {
+	principal, own, err := p.scope(ctx, "invoice:read", "invoice:read:own")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.next.ReservationInvoices(ctx, reservationID)
+	if err != nil || !own {
+		return result, err
+	}
+
+	owned := []service.InvoiceDTO{}
+	for _, invoice := range result {
+		if invoice.CustomerID == principal.CustomerID {
+			owned = append(owned, invoice)
+		}
+	}
+
+	return owned, nil
+}
+
+func (p *Service) Invoice(ctx context.Context, invoiceID int) (*service.InvoiceDTO, error) {
+	principal, own, err := p.scope(ctx, "invoice:read", "invoice:read:own")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.next.Invoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if own && result.CustomerID != principal.CustomerID {
+		return nil, denied("invoice:read")
+	}
+
+	return result, nil
+}
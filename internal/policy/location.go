@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+)
+
+func (p *Service) LocationGetAll(ctx context.Context) ([]service.LocationDTO, error) {
+	if _, err := p.require(ctx, "location:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.LocationGetAll(ctx)
+}
+
+func (p *Service) Location(ctx context.Context, locationID int) (*service.LocationDTO, error) {
+	if _, err := p.require(ctx, "location:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.Location(ctx, locationID)
+}
+
+func (p *Service) InsertLocation(ctx context.Context, location service.LocationDTO) (int64, error) {
+	if _, err := p.require(ctx, "location:write"); err != nil {
+		return 0, err
+	}
+
+	return p.next.InsertLocation(ctx, location)
+}
+
+func (p *Service) UpdateLocation(ctx context.Context, location service.LocationDTO) (*service.LocationDTO, error) {
+	if _, err := p.require(ctx, "location:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.UpdateLocation(ctx, location)
+}
+
+func (p *Service) DeleteLocation(ctx context.Context, locationID int) (*service.LocationDTO, error) {
+	if _, err := p.require(ctx, "location:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.DeleteLocation(ctx, locationID)
+}
@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+)
+
+// ReservationPayments lets customers see how their own reservations are paid.
+func (p *Service) ReservationPayments(ctx context.Context, reservationID int) (*service.PaymentsDTO, error) {
+	if err := p.reservationScope(ctx, reservationID, "payment:read", "payment:read:own"); err != nil {
+		return nil, err
+	}
+
+	return p.next.ReservationPayments(ctx, reservationID)
+}
+
+func (p *Service) Payment(ctx context.Context, paymentID int) (*service.PaymentDTO, error) {
+	principal, own, err := p.scope(ctx, "payment:read", "payment:read:own")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.next.Payment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if own {
+		reservation, err := p.next.Reservation(ctx, result.ReservationID, "")
+		if err != nil {
+			return nil, err
+		}
+		if reservation.CustomerID != principal.CustomerID {
+			return nil, denied("payment:read")
+		}
+	}
+
+	return result, nil
+}
+
+// CreatePayment lets customers pay their own reservations.
+func (p *Service) CreatePayment(ctx context.Context, reservationID int, request service.PaymentRequestDTO) (*service.PaymentDTO, error) {
+	if err := p.reservationScope(ctx, reservationID, "payment:write", "payment:create:own"); err != nil {
+		return nil, err
+	}
+
+	return p.next.CreatePayment(ctx, reservationID, request)
+}
+
+func (p *Service) RefundPayment(ctx context.Context, paymentID int, request service.RefundRequestDTO) (*service.PaymentDTO, error) {
+	if _, err := p.require(ctx, "payment:refund"); err != nil {
+		return nil, err
+	}
+
+	return p.next.RefundPayment(ctx, paymentID, request)
+}
+
+// HandlePaymentEvent needs no permission, the signature of the webhook
+// authenticates the gateway.
+func (p *Service) HandlePaymentEvent(ctx context.Context, body []byte, signature string) (*service.PaymentDTO, error) {
+	return p.next.HandlePaymentEvent(ctx, body, signature)
+}
+
+// reservationScope checks permission for what belongs to a reservation,
+// or ownPermission when the reservation is the caller's.
+func (p *Service) reservationScope(ctx context.Context, reservationID int, permission string, ownPermission string) error {
+	principal, own, err := p.scope(ctx, permission, ownPermission)
+	if err != nil {
+		return err
+	}
+
+	if !own {
+		return nil
+	}
+
+	reservation, err := p.next.Reservation(ctx, reservationID, "")
+	if err != nil {
+		return err
+	}
+	if reservation.CustomerID != principal.CustomerID {
+		return denied(permission)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+// Package policy decides who may call which service method. It wraps the
+// service so the handler cannot reach an operation without passing a check.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"travel/internal/auth"
+	"travel/internal/service"
+)
+
+// cacheTTL is how long the permissions of a role are kept before they are
+// read from the database again.
+const cacheTTL = time.Minute
+
+type PermissionStore interface {
+	RolePermissions(ctx context.Context, role string) ([]string, error)
+}
+
+type Service struct {
+	next        *service.Service
+	permissions PermissionStore
+
+	mu    sync.Mutex
+	cache map[string]cachedRole
+}
+
+type cachedRole struct {
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+func New(next *service.Service, permissions PermissionStore) *Service {
+	return &Service{
+		next:        next,
+		permissions: permissions,
+		cache:       map[string]cachedRole{},
+	}
+}
+
+// require fails unless the caller holds permission.
+func (p *Service) require(ctx context.Context, permission string) (*auth.Principal, error) {
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return nil, fmt.Errorf("%w: not authenticated", auth.ErrForbidden)
+	}
+
+	granted, err := p.rolePermissions(ctx, principal.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	if !granted[permission] {
+		return nil, denied(permission)
+	}
+
+	return principal, nil
+}
+
+// scope checks a permission that may also be granted for the caller's own
+// records only. own is true when only ownPermission matched.
+func (p *Service) scope(ctx context.Context, permission string, ownPermission string) (principal *auth.Principal, own bool, err error) {
+	principal = auth.FromContext(ctx)
+	if principal == nil {
+		return nil, false, fmt.Errorf("%w: not authenticated", auth.ErrForbidden)
+	}
+
+	granted, err := p.rolePermissions(ctx, principal.Role)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case granted[permission]:
+		return principal, false, nil
+	case granted[ownPermission] && principal.CustomerID != 0:
+		return principal, true, nil
+	}
+
+	return nil, false, denied(permission)
+}
+
+func (p *Service) rolePermissions(ctx context.Context, role string) (map[string]bool, error) {
+	p.mu.Lock()
+	cached, ok := p.cache[role]
+	p.mu.Unlock()
+
+	if ok && time.Since(cached.loadedAt) < cacheTTL {
+		return cached.permissions, nil
+	}
+
+	names, err := p.permissions.RolePermissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+
+	p.mu.Lock()
+	p.cache[role] = cachedRole{permissions: permissions, loadedAt: time.Now()}
+	p.mu.Unlock()
+
+	return permissions, nil
+}
+
+func denied(permission string) error {
+	return fmt.Errorf("%w: missing permission %q", auth.ErrForbidden, permission)
+}
@@ -0,0 +1,151 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+	"travel/internal/auth"
+	"travel/internal/policy"
+	"travel/internal/service"
+	"travel/internal/storage"
+	"travel/internal/storage/memory"
+	"travel/internal/tenant"
+)
+
+// roles grants permissions like the seeded roles do.
+type roles map[string][]string
+
+func (r roles) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	return r[role], nil
+}
+
+var permissions = roles{
+	"agent":    {"reservation:read", "reservation:write", "customer:read"},
+	"customer": {"reservation:read:own", "reservation:create:own", "customer:read:own"},
+}
+
+type fixture struct {
+	policy                    *policy.Service
+	ctx                       context.Context
+	maria, ivan               int
+	mariaBooking, ivanBooking int
+	holidayID                 int
+}
+
+func setup(t *testing.T) fixture {
+	t.Helper()
+
+	store := memory.New()
+	ctx := tenant.WithID(context.Background(), 1)
+
+	must := func(id int64, err error) int {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+
+	f := fixture{ctx: ctx}
+	locationID := must(store.InsertLocation(ctx, &storage.Location{City: "Sofia", Country: "BG"}))
+	f.holidayID = must(store.InsertHolidays(ctx, &storage.Holiday{Title: "Ski week", Duration: 7, StartDate: time.Now().AddDate(0, 1, 0), FreeSlots: 10, LocationID: locationID, PriceMinor: 50000, Currency: "EUR"}))
+	f.maria = must(store.InsertCustomer(ctx, &storage.Customer{Name: "Maria", PhoneNumber: "0888123456", NormalizedPhone: "+359888123456"}))
+	f.ivan = must(store.InsertCustomer(ctx, &storage.Customer{Name: "Ivan", PhoneNumber: "0888654321", NormalizedPhone: "+359888654321"}))
+	f.mariaBooking = must(store.InsertReservation(ctx, &storage.Reservation{ContactName: "Maria", HolidayID: f.holidayID, CustomerID: &f.maria}))
+	f.ivanBooking = must(store.InsertReservation(ctx, &storage.Reservation{ContactName: "Ivan", HolidayID: f.holidayID, CustomerID: &f.ivan}))
+
+	f.policy = policy.New(service.New(store, slog.New(slog.NewTextHandler(io.Discard, nil))), permissions)
+
+	return f
+}
+
+func (f fixture) as(principal *auth.Principal) context.Context {
+	return auth.WithPrincipal(f.ctx, principal)
+}
+
+func TestOwnRecords(t *testing.T) {
+	f := setup(t)
+	maria := f.as(&auth.Principal{Type: auth.PrincipalUser, ID: "maria", Role: "customer", CustomerID: f.maria})
+	agent := f.as(&auth.Principal{Type: auth.PrincipalUser, ID: "agent", Role: "agent"})
+
+	// customers list only their own reservations, agents all of them
+	all, err := f.policy.ReservationGetAll(maria, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if own := all.([]storage.ReservationResult); len(own) != 1 || own[0].ID != f.mariaBooking {
+		t.Errorf("maria sees %+v", own)
+	}
+	if _, err := f.policy.ReservationGetAll(agent, ""); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := f.policy.Reservation(maria, f.mariaBooking, ""); err != nil {
+		t.Errorf("own reservation: %v", err)
+	}
+	if _, err := f.policy.Reservation(maria, f.ivanBooking, ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("reservation of another customer: got %v", err)
+	}
+	if _, err := f.policy.Reservation(agent, f.ivanBooking, ""); err != nil {
+		t.Errorf("agent: %v", err)
+	}
+
+	if _, err := f.policy.Customer(maria, f.maria); err != nil {
+		t.Errorf("own customer: %v", err)
+	}
+	if _, err := f.policy.Customer(maria, f.ivan); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("another customer: got %v", err)
+	}
+	if _, err := f.policy.CustomerReservations(maria, f.ivan, ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("reservations of another customer: got %v", err)
+	}
+
+	// own permissions stop at reading and booking
+	if _, err := f.policy.DeleteReservation(maria, f.mariaBooking); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("delete: got %v", err)
+	}
+	if _, err := f.policy.CustomerGetAll(maria); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("all customers: got %v", err)
+	}
+}
+
+func TestBookForSelf(t *testing.T) {
+	f := setup(t)
+	maria := f.as(&auth.Principal{Type: auth.PrincipalUser, ID: "maria", Role: "customer", CustomerID: f.maria})
+
+	// the body names ivan, the reservation is maria's anyway
+	id, err := f.policy.InsertReservation(maria, service.ReservationDTO{ContactName: "Maria", PhoneNumber: "0888123456", HolidayID: f.holidayID, CustomerID: f.ivan})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reservation, err := f.policy.Reservation(maria, int(id), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reservation.CustomerID != f.maria {
+		t.Errorf("booked for customer %d, want %d", reservation.CustomerID, f.maria)
+	}
+}
+
+func TestNoOwner(t *testing.T) {
+	f := setup(t)
+
+	// a customer role without a customer owns nothing
+	keyless := f.as(&auth.Principal{Type: auth.PrincipalAPIKey, ID: "1", Role: "customer"})
+	if _, err := f.policy.ReservationGetAll(keyless, ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("got %v", err)
+	}
+
+	if _, err := f.policy.Reservation(f.ctx, f.mariaBooking, ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("anonymous: got %v", err)
+	}
+
+	unknown := f.as(&auth.Principal{Type: auth.PrincipalUser, ID: "x", Role: "guest", CustomerID: f.maria})
+	if _, err := f.policy.Reservation(unknown, f.mariaBooking, ""); !errors.Is(err, auth.ErrForbidden) {
+		t.Errorf("unknown role: got %v", err)
+	}
+}
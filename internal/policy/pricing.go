@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+)
+
+func (p *Service) PricingRuleSetGetAll(ctx context.Context) ([]service.PricingRuleSetDTO, error) {
+	if _, err := p.require(ctx, "pricing:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.PricingRuleSetGetAll(ctx)
+}
+
+func (p *Service) PricingRuleSet(ctx context.Context, ruleSetID int) (*service.PricingRuleSetDTO, error) {
+	if _, err := p.require(ctx, "pricing:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.PricingRuleSet(ctx, ruleSetID)
+}
+
+func (p *Service) InsertPricingRuleSet(ctx context.Context, ruleSet service.PricingRuleSetDTO) (int64, error) {
+	if _, err := p.require(ctx, "pricing:write"); err != nil {
+		return 0, err
+	}
+
+	return p.next.InsertPricingRuleSet(ctx, ruleSet)
+}
+
+func (p *Service) UpdatePricingRuleSet(ctx context.Context, ruleSet service.PricingRuleSetDTO) (*service.PricingRuleSetDTO, error) {
+	if _, err := p.require(ctx, "pricing:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.UpdatePricingRuleSet(ctx, ruleSet)
+}
+
+func (p *Service) DeletePricingRuleSet(ctx context.Context, ruleSetID int) (*service.PricingRuleSetDTO, error) {
+	if _, err := p.require(ctx, "pricing:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.DeletePricingRuleSet(ctx, ruleSetID)
+}
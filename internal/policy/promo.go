@@ -0,0 +1,54 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+)
+
+func (p *Service) PromoCodeGetAll(ctx context.Context) ([]service.PromoCodeDTO, error) {
+	if _, err := p.require(ctx, "promo:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.PromoCodeGetAll(ctx)
+}
+
+func (p *Service) PromoCode(ctx context.Context, promoCodeID int) (*service.PromoCodeDTO, error) {
+	if _, err := p.require(ctx, "promo:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.PromoCode(ctx, promoCodeID)
+}
+
+func (p *Service) PromoCodeRedemptions(ctx context.Context, promoCodeID int) (*service.PromoRedemptionsDTO, error) {
+	if _, err := p.require(ctx, "promo:read"); err != nil {
+		return nil, err
+	}
+
+	return p.next.PromoCodeRedemptions(ctx, promoCodeID)
+}
+
+func (p *Service) InsertPromoCode(ctx context.Context, code service.PromoCodeDTO) (int64, error) {
+	if _, err := p.require(ctx, "promo:write"); err != nil {
+		return 0, err
+	}
+
+	return p.next.InsertPromoCode(ctx, code)
+}
+
+func (p *Service) UpdatePromoCode(ctx context.Context, code service.PromoCodeDTO) (*service.PromoCodeDTO, error) {
+	if _, err := p.require(ctx, "promo:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.UpdatePromoCode(ctx, code)
+}
+
+func (p *Service) DeletePromoCode(ctx context.Context, promoCodeID int) (*service.PromoCodeDTO, error) {
+	if _, err := p.require(ctx, "promo:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.DeletePromoCode(ctx, promoCodeID)
+}
@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"context"
+	"travel/internal/service"
+	"travel/internal/storage"
+)
+
+func (p *Service) ReservationGetAll(ctx context.Context, currency string) (interface{}, error) {
+	principal, own, err := p.scope(ctx, "reservation:read", "reservation:read:own")
+	if err != nil {
+		return nil, err
+	}
+
+	if own {
+		return p.next.CustomerReservations(ctx, principal.CustomerID, currency)
+	}
+
+	return p.next.ReservationGetAll(ctx, currency)
+}
+
+func (p *Service) ExportReservations(ctx context.Context, fn func(storage.ReservationResult) error) error {
+	if _, err := p.require(ctx, "reservation:read"); err != nil {
+		return err
+	}
+
+	return p.next.ExportReservations(ctx, fn)
+}
+
+func (p *Service) Reservation(ctx context.Context, reservationID int, currency string) (*service.ReservationDTO, error) {
+	principal, own, err := p.scope(ctx, "reservation:read", "reservation:read:own")
+	if err != nil {
+		return nil, err
+	}
+
+	reservation, err := p.next.Reservation(ctx, reservationID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if own && reservation.CustomerID != principal.CustomerID {
+		return nil, denied("reservation:read")
+	}
+
+	return reservation, nil
+}
+
+// InsertReservation lets customers book only for themselves, whatever
+// customer the request body names.
+func (p *Service) InsertReservation(ctx context.Context, reservation service.ReservationDTO) (int64, error) {
+	principal, own, err := p.scope(ctx, "reservation:write", "reservation:create:own")
+	if err != nil {
+		return 0, err
+	}
+
+	if own {
+		reservation.CustomerID = principal.CustomerID
+	}
+
+	return p.next.InsertReservation(ctx, reservation)
+}
+
+func (p *Service) UpdateReservation(ctx context.Context, reservation service.ReservationDTO) (*service.ReservationDTO, error) {
+	if _, err := p.require(ctx, "reservation:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.UpdateReservation(ctx, reservation)
+}
+
+func (p *Service) DeleteReservation(ctx context.Context, reservationID int) (*service.ReservationDTO, error) {
+	if _, err := p.require(ctx, "reservation:write"); err != nil {
+		return nil, err
+	}
+
+	return p.next.DeleteReservation(ctx, reservationID)
+}
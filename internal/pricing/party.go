@@ -0,0 +1,198 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"travel/internal/money"
+)
+
+var (
+	ErrInvalidParty     = errors.New("invalid party")
+	ErrUnknownExtra     = errors.New("unknown extra")
+	ErrCurrencyMismatch = errors.New("currency of the rule does not match the price")
+)
+
+// AdultAge is the age from which travellers pay as adults.
+const AdultAge = 18
+
+// Kinds of the items that are no rules: the travellers themselves and the
+// discount of a promo code.
+const (
+	KindTraveller = "traveller"
+	KindPromo     = "promo"
+)
+
+// Party is who travels on one booking.
+type Party struct {
+	Adults   int     `json:"adults"`
+	Children []Child `json:"children"`
+}
+
+type Child struct {
+	Age int `json:"age"`
+}
+
+// Travellers is the number of adults and children.
+func (p Party) Travellers() int {
+	return p.Adults + len(p.Children)
+}
+
+// Validate checks that an adult travels and every child is younger than
+// AdultAge.
+func (p Party) Validate() error {
+	if p.Adults < 1 {
+		return fmt.Errorf("%w: at least one adult has to travel", ErrInvalidParty)
+	}
+	for _, child := range p.Children {
+		if child.Age < 0 || child.Age >= AdultAge {
+			return fmt.Errorf("%w: a child of %d is no child", ErrInvalidParty, child.Age)
+		}
+	}
+
+	return nil
+}
+
+// Item is one line of a Breakdown, Quantity times UnitPrice. The amounts of
+// discounts are negative.
+type Item struct {
+	Kind      string      `json:"kind"`
+	Name      string      `json:"name"`
+	Quantity  int         `json:"quantity"`
+	Percent   float64     `json:"percent,omitempty"`
+	UnitPrice money.Money `json:"unitPrice"`
+	Amount    money.Money `json:"amount"`
+}
+
+// Breakdown is the price of a booking, itemized.
+type Breakdown struct {
+	Items []Item      `json:"items"`
+	Total money.Money `json:"total"`
+}
+
+// Discount is taken off a booking before taxes, e.g. by a promo code. Amount
+// is negative, Percent is what it is of the booking when it is one.
+type Discount struct {
+	Name    string
+	Percent float64
+	Amount  money.Money
+}
+
+// Subtotal is the total before taxes.
+func (b Breakdown) Subtotal() money.Money {
+	subtotal := b.Total
+	for _, item := range b.Items {
+		if item.Kind == KindTax {
+			subtotal.Amount -= item.Amount.Amount
+		}
+	}
+
+	return subtotal
+}
+
+func (b *Breakdown) add(item Item) {
+	item.Amount = money.Money{Amount: item.UnitPrice.Amount * int64(item.Quantity), Currency: item.UnitPrice.Currency}
+	b.Items = append(b.Items, item)
+	b.Total.Amount += item.Amount.Amount
+}
+
+// Itemize prices a booking of party at price per traveller, the price of
+// Evaluate, with the extras named by extras and discount unless it is nil.
+// The items come in the order travellers, child discounts, extras, fees,
+// discount and taxes; of the child rules the first one a child is younger
+// than applies, and taxes are worked out on the total of the items before
+// them.
+func Itemize(rules []Rule, price money.Money, party Party, extras []string, discount *Discount) (Breakdown, error) {
+	if err := party.Validate(); err != nil {
+		return Breakdown{}, err
+	}
+
+	breakdown := Breakdown{Items: []Item{}, Total: money.Money{Currency: price.Currency}}
+
+	breakdown.add(Item{Kind: KindTraveller, Name: "Adult", Quantity: party.Adults, UnitPrice: price})
+	if len(party.Children) > 0 {
+		breakdown.add(Item{Kind: KindTraveller, Name: "Child", Quantity: len(party.Children), UnitPrice: price})
+	}
+
+	children := map[int]int{}
+	for _, child := range party.Children {
+		for i, rule := range rules {
+			if rule.Kind == KindChild && child.Age < rule.Age {
+				children[i]++
+				break
+			}
+		}
+	}
+	for i, rule := range rules {
+		if children[i] > 0 {
+			breakdown.add(Item{Kind: KindChild, Name: rule.Name, Quantity: children[i], Percent: rule.Percent, UnitPrice: price.Percent(rule.Percent)})
+		}
+	}
+
+	booked := map[string]bool{}
+	for _, name := range extras {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if booked[key] {
+			continue
+		}
+		booked[key] = true
+
+		rule, ok := extra(rules, key)
+		if !ok {
+			return Breakdown{}, fmt.Errorf("%w %q", ErrUnknownExtra, name)
+		}
+		if err := breakdown.charge(rule, party); err != nil {
+			return Breakdown{}, err
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.Kind != KindFee {
+			continue
+		}
+		if err := breakdown.charge(rule, party); err != nil {
+			return Breakdown{}, err
+		}
+	}
+
+	if discount != nil {
+		if discount.Amount.Currency != price.Currency {
+			return Breakdown{}, fmt.Errorf("%w: %s is not taken off in %s", ErrCurrencyMismatch, discount.Name, price.Currency)
+		}
+		breakdown.add(Item{Kind: KindPromo, Name: discount.Name, Quantity: 1, Percent: discount.Percent, UnitPrice: discount.Amount})
+	}
+
+	subtotal := breakdown.Total
+	for _, rule := range rules {
+		if rule.Kind == KindTax {
+			breakdown.add(Item{Kind: KindTax, Name: rule.Name, Quantity: 1, Percent: rule.Percent, UnitPrice: subtotal.Percent(rule.Percent)})
+		}
+	}
+
+	return breakdown, nil
+}
+
+// charge adds the amount of an extra or fee.
+func (b *Breakdown) charge(rule Rule, party Party) error {
+	if rule.Amount == nil || rule.Amount.Currency != b.Total.Currency {
+		return fmt.Errorf("%w: %s is not charged in %s", ErrCurrencyMismatch, rule.Name, b.Total.Currency)
+	}
+
+	quantity := 1
+	if rule.PerPerson {
+		quantity = party.Travellers()
+	}
+	b.add(Item{Kind: rule.Kind, Name: rule.Name, Quantity: quantity, UnitPrice: *rule.Amount})
+
+	return nil
+}
+
+func extra(rules []Rule, name string) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.Kind == KindExtra && strings.EqualFold(strings.TrimSpace(rule.Name), name) {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
@@ -0,0 +1,283 @@
+// Package pricing turns the base price of a holiday into the price a customer
+// pays, by the rules of the holiday's rule set: seasonal multipliers, the
+// weekday of departure, early-bird and last-minute deals and surcharges when
+// the holiday fills up. The price is per traveller, Itemize prices a whole
+// party with the child discounts, extras, fees and taxes of the rule set.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"travel/internal/money"
+)
+
+// Kinds of rules, in the order they are applied.
+const (
+	KindSeason     = "season"
+	KindWeekday    = "weekday"
+	KindEarlyBird  = "earlyBird"
+	KindLastMinute = "lastMinute"
+	KindOccupancy  = "occupancy"
+)
+
+var kinds = []string{KindSeason, KindWeekday, KindEarlyBird, KindLastMinute, KindOccupancy}
+
+// Kinds of rules that price a party rather than a traveller, see Itemize.
+const (
+	KindChild = "child"
+	KindExtra = "extra"
+	KindFee   = "fee"
+	KindTax   = "tax"
+)
+
+var partyKinds = []string{KindChild, KindExtra, KindFee, KindTax}
+
+var ErrInvalidRule = errors.New("invalid pricing rule")
+
+// Rule changes the price by Percent, negative for a discount, when it matches
+// a booking. Which of the other fields it reads depends on Kind:
+//
+//   - season matches departures from From to To, both "MM-DD" and inclusive;
+//     a season may run over the new year, e.g. 12-20 to 01-06
+//   - weekday matches departures on one of Weekdays, e.g. "saturday"
+//   - earlyBird matches bookings made at least Days before departure
+//   - lastMinute matches bookings made at most Days before departure
+//   - occupancy matches while the holiday has at most FreeSlots free slots
+//
+// The rules of a party read:
+//
+//   - child changes the price of children younger than Age by Percent, down
+//     to -100 for children who travel free
+//   - extra is an optional Amount the customer can book by Name
+//   - fee is an Amount charged on every booking
+//   - tax is Percent of the total of everything else
+//
+// Extras and fees are charged once per traveller with PerPerson, once per
+// booking without.
+type Rule struct {
+	Name      string       `json:"name"`
+	Kind      string       `json:"kind"`
+	Percent   float64      `json:"percent"`
+	From      string       `json:"from,omitempty"`
+	To        string       `json:"to,omitempty"`
+	Weekdays  []string     `json:"weekdays,omitempty"`
+	Days      int          `json:"days,omitempty"`
+	FreeSlots int          `json:"freeSlots,omitempty"`
+	Age       int          `json:"age,omitempty"`
+	Amount    *money.Money `json:"amount,omitempty"`
+	PerPerson bool         `json:"perPerson,omitempty"`
+}
+
+// Input is what a price is worked out for.
+type Input struct {
+	BasePrice money.Money
+	StartDate time.Time
+	FreeSlots int
+	// BookingDate is the day the customer books, today for a quote.
+	BookingDate time.Time
+}
+
+// Quote is a price with the rules that made it.
+type Quote struct {
+	BasePrice   money.Money  `json:"basePrice"`
+	Adjustments []Adjustment `json:"adjustments"`
+	Price       money.Money  `json:"price"`
+}
+
+// Adjustment is one applied rule. Amount is what it added to the price, it is
+// negative for a discount.
+type Adjustment struct {
+	Rule    string      `json:"rule"`
+	Kind    string      `json:"kind"`
+	Percent float64     `json:"percent"`
+	Amount  money.Money `json:"amount"`
+}
+
+// Validate checks every rule of a rule set.
+func Validate(rules []Rule) error {
+	extras := map[string]bool{}
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("%w %d (%s): %v", ErrInvalidRule, i+1, rule.Name, err)
+		}
+
+		// extras are booked by name
+		if rule.Kind == KindExtra {
+			name := strings.ToLower(strings.TrimSpace(rule.Name))
+			if extras[name] {
+				return fmt.Errorf("%w %d (%s): another extra has the name", ErrInvalidRule, i+1, rule.Name)
+			}
+			extras[name] = true
+		}
+	}
+
+	return nil
+}
+
+func (r Rule) validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is missing")
+	}
+
+	switch r.Kind {
+	case KindChild:
+		if r.Percent < -100 || r.Percent > 0 {
+			return errors.New("percent has to be from -100 to 0")
+		}
+	case KindTax:
+		if r.Percent <= 0 {
+			return errors.New("percent has to be above 0")
+		}
+	case KindExtra, KindFee:
+	default:
+		if r.Percent <= -100 {
+			return errors.New("percent has to be above -100")
+		}
+	}
+
+	switch r.Kind {
+	case KindSeason:
+		if _, err := monthDay(r.From); err != nil {
+			return fmt.Errorf("from: %v", err)
+		}
+		if _, err := monthDay(r.To); err != nil {
+			return fmt.Errorf("to: %v", err)
+		}
+	case KindWeekday:
+		if len(r.Weekdays) == 0 {
+			return errors.New("weekdays are missing")
+		}
+		for _, name := range r.Weekdays {
+			if _, ok := weekday(name); !ok {
+				return fmt.Errorf("unknown weekday %q", name)
+			}
+		}
+	case KindEarlyBird, KindLastMinute:
+		if r.Days < 0 {
+			return errors.New("days can not be negative")
+		}
+	case KindOccupancy:
+		if r.FreeSlots < 0 {
+			return errors.New("freeSlots can not be negative")
+		}
+	case KindChild:
+		if r.Age <= 0 || r.Age > AdultAge {
+			return fmt.Errorf("age has to be from 1 to %d", AdultAge)
+		}
+	case KindExtra, KindFee:
+		if r.Amount == nil {
+			return errors.New("amount is missing")
+		}
+		if err := r.Amount.Validate(); err != nil {
+			return fmt.Errorf("amount: %v", err)
+		}
+		if r.Amount.Amount < 0 {
+			return errors.New("amount can not be negative")
+		}
+	case KindTax:
+	default:
+		return fmt.Errorf("unknown kind %q, expected one of %s", r.Kind, strings.Join(append(kinds, partyKinds...), ", "))
+	}
+
+	return nil
+}
+
+// Evaluate prices in by rules. Of the rules of one kind only the first that
+// matches applies, so tiers are listed from the strongest down. The kinds are
+// applied one after the other in the order of the Kind constants, each on the
+// price the ones before left, and every amount is rounded to the minor unit of
+// the currency.
+func Evaluate(rules []Rule, in Input) Quote {
+	quote := Quote{
+		BasePrice:   in.BasePrice,
+		Adjustments: []Adjustment{},
+		Price:       in.BasePrice,
+	}
+
+	for _, kind := range kinds {
+		for _, rule := range rules {
+			if rule.Kind != kind || !rule.matches(in) {
+				continue
+			}
+
+			amount := quote.Price.Percent(rule.Percent)
+			quote.Adjustments = append(quote.Adjustments, Adjustment{
+				Rule:    rule.Name,
+				Kind:    rule.Kind,
+				Percent: rule.Percent,
+				Amount:  amount,
+			})
+			quote.Price.Amount += amount.Amount
+			break
+		}
+	}
+
+	return quote
+}
+
+func (r Rule) matches(in Input) bool {
+	switch r.Kind {
+	case KindSeason:
+		from, err := monthDay(r.From)
+		if err != nil {
+			return false
+		}
+		to, err := monthDay(r.To)
+		if err != nil {
+			return false
+		}
+
+		day := int(in.StartDate.Month())*100 + in.StartDate.Day()
+		if from <= to {
+			return from <= day && day <= to
+		}
+		return day >= from || day <= to
+	case KindWeekday:
+		for _, name := range r.Weekdays {
+			if day, ok := weekday(name); ok && day == in.StartDate.Weekday() {
+				return true
+			}
+		}
+		return false
+	case KindEarlyBird:
+		return daysAhead(in) >= r.Days
+	case KindLastMinute:
+		ahead := daysAhead(in)
+		return ahead >= 0 && ahead <= r.Days
+	case KindOccupancy:
+		return in.FreeSlots <= r.FreeSlots
+	}
+
+	return false
+}
+
+// daysAhead is the number of days from booking to departure, in whole
+// calendar days.
+func daysAhead(in Input) int {
+	start := time.Date(in.StartDate.Year(), in.StartDate.Month(), in.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	booked := time.Date(in.BookingDate.Year(), in.BookingDate.Month(), in.BookingDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	return int(start.Sub(booked).Hours() / 24)
+}
+
+// monthDay parses "MM-DD" into MM*100+DD, which orders like the dates.
+func monthDay(value string) (int, error) {
+	date, err := time.Parse("01-02", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is no MM-DD date", value)
+	}
+
+	return int(date.Month())*100 + date.Day(), nil
+}
+
+func weekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,218 @@
+package pricing_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"travel/internal/money"
+	"travel/internal/pricing"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func eur(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "EUR"}
+}
+
+var rules = []pricing.Rule{
+	{Name: "High season", Kind: pricing.KindSeason, Percent: 20, From: "07-01", To: "08-31"},
+	{Name: "Holidays", Kind: pricing.KindSeason, Percent: 30, From: "12-20", To: "01-06"},
+	{Name: "Weekend departure", Kind: pricing.KindWeekday, Percent: 5, Weekdays: []string{"Friday", "saturday"}},
+	{Name: "Super early bird", Kind: pricing.KindEarlyBird, Percent: -15, Days: 180},
+	{Name: "Early bird", Kind: pricing.KindEarlyBird, Percent: -10, Days: 90},
+	{Name: "Last minute", Kind: pricing.KindLastMinute, Percent: -20, Days: 7},
+	{Name: "Almost full", Kind: pricing.KindOccupancy, Percent: 15, FreeSlots: 2},
+	{Name: "Filling up", Kind: pricing.KindOccupancy, Percent: 5, FreeSlots: 10},
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    pricing.Input
+		want  []string
+		price money.Money
+	}{
+		{
+			name:  "nothing matches",
+			in:    pricing.Input{BasePrice: eur(50000), StartDate: date(2030, time.May, 15), FreeSlots: 30, BookingDate: date(2030, time.April, 1)},
+			want:  []string{},
+			price: eur(50000),
+		},
+		{
+			name: "season, weekday and early bird compound",
+			// a Saturday in August, booked 200 days ahead
+			in:    pricing.Input{BasePrice: eur(50000), StartDate: date(2030, time.August, 3), FreeSlots: 30, BookingDate: date(2030, time.January, 15)},
+			want:  []string{"High season", "Weekend departure", "Super early bird"},
+			price: eur(53550),
+		},
+		{
+			name:  "season over the new year",
+			in:    pricing.Input{BasePrice: eur(10000), StartDate: date(2031, time.January, 2), FreeSlots: 30, BookingDate: date(2030, time.December, 1)},
+			want:  []string{"Holidays"},
+			price: eur(13000),
+		},
+		{
+			name:  "only the first matching tier applies",
+			in:    pricing.Input{BasePrice: eur(10000), StartDate: date(2030, time.May, 15), FreeSlots: 1, BookingDate: date(2030, time.February, 1)},
+			want:  []string{"Early bird", "Almost full"},
+			price: eur(10350),
+		},
+		{
+			name:  "last minute",
+			in:    pricing.Input{BasePrice: eur(9999), StartDate: date(2030, time.May, 15), FreeSlots: 5, BookingDate: date(2030, time.May, 10)},
+			want:  []string{"Last minute", "Filling up"},
+			price: eur(8399),
+		},
+		{
+			name:  "no last minute deal after departure",
+			in:    pricing.Input{BasePrice: eur(10000), StartDate: date(2030, time.May, 15), FreeSlots: 30, BookingDate: date(2030, time.May, 16)},
+			want:  []string{},
+			price: eur(10000),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quote := pricing.Evaluate(rules, test.in)
+
+			applied := []string{}
+			sum := quote.BasePrice.Amount
+			for _, adjustment := range quote.Adjustments {
+				applied = append(applied, adjustment.Rule)
+				sum += adjustment.Amount.Amount
+			}
+
+			if !reflect.DeepEqual(applied, test.want) {
+				t.Errorf("applied %v, want %v", applied, test.want)
+			}
+			if quote.Price != test.price {
+				t.Errorf("price %v, want %v", quote.Price, test.price)
+			}
+			// the itemized amounts add up to the price to the cent
+			if sum != quote.Price.Amount {
+				t.Errorf("adjustments add up to %v, price is %v", sum, quote.Price)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := pricing.Validate(rules); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []pricing.Rule{
+		{Kind: pricing.KindOccupancy, Percent: 5},
+		{Name: "Free", Kind: pricing.KindEarlyBird, Percent: -100},
+		{Name: "Summer", Kind: pricing.KindSeason, Percent: 10, From: "06-01", To: "31-08"},
+		{Name: "Weekend", Kind: pricing.KindWeekday, Percent: 10},
+		{Name: "Weekend", Kind: pricing.KindWeekday, Percent: 10, Weekdays: []string{"sat"}},
+		{Name: "Late", Kind: pricing.KindLastMinute, Percent: -10, Days: -1},
+		{Name: "Loyalty", Kind: "loyalty", Percent: -5},
+		{Name: "Adult child", Kind: pricing.KindChild, Percent: -50, Age: 19},
+		{Name: "Child surcharge", Kind: pricing.KindChild, Percent: 10, Age: 12},
+		{Name: "Transfer", Kind: pricing.KindExtra},
+		{Name: "Refund", Kind: pricing.KindFee, Amount: &money.Money{Amount: -100, Currency: "EUR"}},
+		{Name: "Fee", Kind: pricing.KindFee, Amount: &money.Money{Amount: 100, Currency: "XYZ"}},
+		{Name: "VAT", Kind: pricing.KindTax},
+	}
+
+	for _, rule := range invalid {
+		if err := pricing.Validate([]pricing.Rule{rule}); !errors.Is(err, pricing.ErrInvalidRule) {
+			t.Errorf("%+v: got %v, want %v", rule, err, pricing.ErrInvalidRule)
+		}
+	}
+}
+
+var partyRules = []pricing.Rule{
+	{Name: "Infant", Kind: pricing.KindChild, Percent: -100, Age: 2},
+	{Name: "Child", Kind: pricing.KindChild, Percent: -50, Age: 12},
+	{Name: "Transfer", Kind: pricing.KindExtra, Amount: &money.Money{Amount: 2500, Currency: "EUR"}, PerPerson: true},
+	{Name: "Insurance", Kind: pricing.KindExtra, Amount: &money.Money{Amount: 4000, Currency: "EUR"}},
+	{Name: "Booking fee", Kind: pricing.KindFee, Amount: &money.Money{Amount: 1500, Currency: "EUR"}},
+	{Name: "VAT", Kind: pricing.KindTax, Percent: 9},
+}
+
+func TestItemize(t *testing.T) {
+	if err := pricing.Validate(partyRules); err != nil {
+		t.Fatal(err)
+	}
+
+	party := pricing.Party{Adults: 2, Children: []pricing.Child{{Age: 1}, {Age: 8}, {Age: 14}}}
+	got, err := pricing.Itemize(partyRules, eur(10000), party, []string{"transfer", "Transfer "}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := pricing.Breakdown{
+		Items: []pricing.Item{
+			{Kind: pricing.KindTraveller, Name: "Adult", Quantity: 2, UnitPrice: eur(10000), Amount: eur(20000)},
+			{Kind: pricing.KindTraveller, Name: "Child", Quantity: 3, UnitPrice: eur(10000), Amount: eur(30000)},
+			{Kind: pricing.KindChild, Name: "Infant", Quantity: 1, Percent: -100, UnitPrice: eur(-10000), Amount: eur(-10000)},
+			{Kind: pricing.KindChild, Name: "Child", Quantity: 1, Percent: -50, UnitPrice: eur(-5000), Amount: eur(-5000)},
+			{Kind: pricing.KindExtra, Name: "Transfer", Quantity: 5, UnitPrice: eur(2500), Amount: eur(12500)},
+			{Kind: pricing.KindFee, Name: "Booking fee", Quantity: 1, UnitPrice: eur(1500), Amount: eur(1500)},
+			{Kind: pricing.KindTax, Name: "VAT", Quantity: 1, Percent: 9, UnitPrice: eur(4410), Amount: eur(4410)},
+		},
+		Total: eur(53410),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestItemizeDiscount(t *testing.T) {
+	party := pricing.Party{Adults: 2, Children: []pricing.Child{{Age: 8}}}
+	discount := &pricing.Discount{Name: "SUMMER10", Percent: -10, Amount: eur(-2650)}
+	got, err := pricing.Itemize(partyRules, eur(10000), party, nil, discount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the discount is taken off before taxes
+	want := []pricing.Item{
+		{Kind: pricing.KindTraveller, Name: "Adult", Quantity: 2, UnitPrice: eur(10000), Amount: eur(20000)},
+		{Kind: pricing.KindTraveller, Name: "Child", Quantity: 1, UnitPrice: eur(10000), Amount: eur(10000)},
+		{Kind: pricing.KindChild, Name: "Child", Quantity: 1, Percent: -50, UnitPrice: eur(-5000), Amount: eur(-5000)},
+		{Kind: pricing.KindFee, Name: "Booking fee", Quantity: 1, UnitPrice: eur(1500), Amount: eur(1500)},
+		{Kind: pricing.KindPromo, Name: "SUMMER10", Quantity: 1, Percent: -10, UnitPrice: eur(-2650), Amount: eur(-2650)},
+		{Kind: pricing.KindTax, Name: "VAT", Quantity: 1, Percent: 9, UnitPrice: eur(2147), Amount: eur(2147)},
+	}
+	if !reflect.DeepEqual(got.Items, want) {
+		t.Errorf("got %+v\nwant %+v", got.Items, want)
+	}
+	if got.Total != eur(25997) || got.Subtotal() != eur(23850) {
+		t.Errorf("got total %v and subtotal %v", got.Total, got.Subtotal())
+	}
+
+	discount.Amount = money.Money{Amount: -2650, Currency: "GBP"}
+	if _, err := pricing.Itemize(partyRules, eur(10000), party, nil, discount); !errors.Is(err, pricing.ErrCurrencyMismatch) {
+		t.Errorf("got %v, want %v", err, pricing.ErrCurrencyMismatch)
+	}
+}
+
+func TestItemizeInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		price  money.Money
+		party  pricing.Party
+		extras []string
+		want   error
+	}{
+		{name: "no adult", price: eur(10000), party: pricing.Party{Children: []pricing.Child{{Age: 10}}}, want: pricing.ErrInvalidParty},
+		{name: "grown up child", price: eur(10000), party: pricing.Party{Adults: 1, Children: []pricing.Child{{Age: 18}}}, want: pricing.ErrInvalidParty},
+		{name: "unknown extra", price: eur(10000), party: pricing.Party{Adults: 1}, extras: []string{"Spa"}, want: pricing.ErrUnknownExtra},
+		{name: "fee in another currency", price: money.Money{Amount: 10000, Currency: "GBP"}, party: pricing.Party{Adults: 1}, want: pricing.ErrCurrencyMismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := pricing.Itemize(partyRules, test.price, test.party, test.extras, nil); !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package promo decides whether the promo code of a marketing campaign, e.g.
+// "SUMMER10", can be redeemed on a booking and what it takes off: a percent
+// of what the booking costs before taxes or a fixed amount, never more than
+// that.
+package promo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"travel/internal/money"
+)
+
+var (
+	ErrInvalidCode   = errors.New("invalid promo code")
+	ErrNotValid      = errors.New("the promo code is not valid at this time")
+	ErrUsedUp        = errors.New("the promo code is used up")
+	ErrNotApplicable = errors.New("the promo code does not apply to the booking")
+)
+
+// Code is a promo code and the terms it is redeemed on. It takes Percent off
+// a booking, or the fixed Amount when Percent is 0. Zero values leave a term
+// out: a code without ValidFrom is valid from the start, one without
+// MaxRedemptions can be redeemed any number of times and one without Holidays
+// and Locations applies to every holiday.
+type Code struct {
+	Code        string       `json:"code"`
+	Description string       `json:"description"`
+	Percent     float64      `json:"percent,omitempty"`
+	Amount      *money.Money `json:"amount,omitempty"`
+	ValidFrom   *time.Time   `json:"validFrom,omitempty"`
+	ValidTo     *time.Time   `json:"validTo,omitempty"`
+	// MaxRedemptions caps the redemptions of all customers together,
+	// MaxPerCustomer the ones of every single customer.
+	MaxRedemptions int `json:"maxRedemptions,omitempty"`
+	MaxPerCustomer int `json:"maxPerCustomer,omitempty"`
+	// Holidays and Locations restrict the code to bookings of these
+	// holidays and of holidays in these locations, by id.
+	Holidays  []int `json:"holidays"`
+	Locations []int `json:"locations"`
+	// MinSpend is what a booking has to cost before taxes at least.
+	MinSpend *money.Money `json:"minSpend,omitempty"`
+}
+
+// Booking is what a code is redeemed on.
+type Booking struct {
+	HolidayID  int
+	LocationID int
+	// Spend is what the booking costs before taxes.
+	Spend money.Money
+	Date  time.Time
+}
+
+// Usage is how often a code was redeemed before, by all customers and by the
+// customer who books.
+type Usage struct {
+	Redemptions         int
+	CustomerRedemptions int
+}
+
+var codePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{0,31}$`)
+
+// Normalize is the form codes are stored and looked up in, customers may type
+// them in any case.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate checks the terms of c, its code has to be normalized.
+func (c Code) Validate() error {
+	if !codePattern.MatchString(c.Code) {
+		return fmt.Errorf("%w %q: up to 32 letters, digits, - and _", ErrInvalidCode, c.Code)
+	}
+
+	switch {
+	case c.Percent != 0 && c.Amount != nil:
+		return fmt.Errorf("%w %s: either percent or amount", ErrInvalidCode, c.Code)
+	case c.Percent != 0:
+		if c.Percent < 0 || c.Percent > 100 {
+			return fmt.Errorf("%w %s: percent has to be above 0 and at most 100", ErrInvalidCode, c.Code)
+		}
+	case c.Amount != nil:
+		if err := c.Amount.Validate(); err != nil {
+			return fmt.Errorf("%w %s: amount: %v", ErrInvalidCode, c.Code, err)
+		}
+		if c.Amount.Amount <= 0 {
+			return fmt.Errorf("%w %s: amount has to be above 0", ErrInvalidCode, c.Code)
+		}
+	default:
+		return fmt.Errorf("%w %s: percent or amount is missing", ErrInvalidCode, c.Code)
+	}
+
+	if c.MinSpend != nil {
+		if err := c.MinSpend.Validate(); err != nil {
+			return fmt.Errorf("%w %s: minSpend: %v", ErrInvalidCode, c.Code, err)
+		}
+		if c.MinSpend.Amount < 0 {
+			return fmt.Errorf("%w %s: minSpend can not be negative", ErrInvalidCode, c.Code)
+		}
+		// both are stored in one currency
+		if c.Amount != nil && c.Amount.Currency != c.MinSpend.Currency {
+			return fmt.Errorf("%w %s: amount and minSpend are in different currencies", ErrInvalidCode, c.Code)
+		}
+	}
+
+	if c.ValidFrom != nil && c.ValidTo != nil && !c.ValidTo.After(*c.ValidFrom) {
+		return fmt.Errorf("%w %s: validTo has to be after validFrom", ErrInvalidCode, c.Code)
+	}
+	if c.MaxRedemptions < 0 || c.MaxPerCustomer < 0 {
+		return fmt.Errorf("%w %s: redemption limits can not be negative", ErrInvalidCode, c.Code)
+	}
+	for _, id := range append(append([]int{}, c.Holidays...), c.Locations...) {
+		if id <= 0 {
+			return fmt.Errorf("%w %s: unknown holiday or location %d", ErrInvalidCode, c.Code, id)
+		}
+	}
+
+	return nil
+}
+
+// Redeemable checks the redemption limits of c. The redemptions of a
+// customer are only known once the booking is made, 0 checks the limit of all
+// customers alone.
+func (c Code) Redeemable(usage Usage) error {
+	if c.MaxRedemptions > 0 && usage.Redemptions >= c.MaxRedemptions {
+		return fmt.Errorf("%w: redeemed %d times", ErrUsedUp, usage.Redemptions)
+	}
+	if c.MaxPerCustomer > 0 && usage.CustomerRedemptions >= c.MaxPerCustomer {
+		return fmt.Errorf("%w: redeemed %d times by the customer", ErrUsedUp, usage.CustomerRedemptions)
+	}
+
+	return nil
+}
+
+// Discount checks that c applies to booking and returns what it takes off,
+// a negative amount in the currency of the booking.
+func (c Code) Discount(booking Booking) (money.Money, error) {
+	if c.ValidFrom != nil && booking.Date.Before(*c.ValidFrom) {
+		return money.Money{}, fmt.Errorf("%w: valid from %s", ErrNotValid, c.ValidFrom.Format(time.RFC3339))
+	}
+	if c.ValidTo != nil && !booking.Date.Before(*c.ValidTo) {
+		return money.Money{}, fmt.Errorf("%w: valid until %s", ErrNotValid, c.ValidTo.Format(time.RFC3339))
+	}
+
+	if len(c.Holidays) > 0 && !slices.Contains(c.Holidays, booking.HolidayID) {
+		return money.Money{}, fmt.Errorf("%w: not valid for the holiday", ErrNotApplicable)
+	}
+	if len(c.Locations) > 0 && !slices.Contains(c.Locations, booking.LocationID) {
+		return money.Money{}, fmt.Errorf("%w: not valid for the location", ErrNotApplicable)
+	}
+
+	spend := booking.Spend
+	if c.MinSpend != nil {
+		if c.MinSpend.Currency != spend.Currency {
+			return money.Money{}, fmt.Errorf("%w: the minimum spend is in %s", ErrNotApplicable, c.MinSpend.Currency)
+		}
+		if spend.Amount < c.MinSpend.Amount {
+			return money.Money{}, fmt.Errorf("%w: the booking costs less than %s", ErrNotApplicable, c.MinSpend)
+		}
+	}
+
+	if c.Amount == nil {
+		return spend.Percent(-c.Percent), nil
+	}
+	if c.Amount.Currency != spend.Currency {
+		return money.Money{}, fmt.Errorf("%w: the discount is in %s", ErrNotApplicable, c.Amount.Currency)
+	}
+
+	return money.Money{Amount: -min(c.Amount.Amount, spend.Amount), Currency: spend.Currency}, nil
+}
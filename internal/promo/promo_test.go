@@ -0,0 +1,115 @@
+package promo_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"travel/internal/money"
+	"travel/internal/promo"
+)
+
+func eur(amount int64) *money.Money {
+	return &money.Money{Amount: amount, Currency: "EUR"}
+}
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestNormalize(t *testing.T) {
+	if got := promo.Normalize(" summer10 "); got != "SUMMER10" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []promo.Code{
+		{Code: "SUMMER10", Percent: 10},
+		{Code: "WELCOME-50", Amount: eur(5000), MinSpend: eur(50000), MaxPerCustomer: 1},
+		{Code: "SEA_2030", Percent: 100, ValidFrom: date(2030, time.June, 1), ValidTo: date(2030, time.September, 1), Holidays: []int{3}, Locations: []int{2}},
+	}
+	for _, code := range valid {
+		if err := code.Validate(); err != nil {
+			t.Errorf("%+v: %v", code, err)
+		}
+	}
+
+	invalid := []promo.Code{
+		{Code: "summer10", Percent: 10},
+		{Code: "SUMMER 10", Percent: 10},
+		{Code: "", Percent: 10},
+		{Code: "NOTHING"},
+		{Code: "BOTH", Percent: 10, Amount: eur(500)},
+		{Code: "MORE", Percent: 110},
+		{Code: "SURCHARGE", Percent: -10},
+		{Code: "FREE", Amount: eur(0)},
+		{Code: "XYZ", Amount: &money.Money{Amount: 500, Currency: "XYZ"}},
+		{Code: "MIXED", Amount: eur(500), MinSpend: &money.Money{Amount: 500, Currency: "GBP"}},
+		{Code: "BACKWARDS", Percent: 10, ValidFrom: date(2030, time.June, 1), ValidTo: date(2030, time.May, 1)},
+		{Code: "LIMIT", Percent: 10, MaxRedemptions: -1},
+		{Code: "HOLIDAY", Percent: 10, Holidays: []int{0}},
+	}
+	for _, code := range invalid {
+		if err := code.Validate(); !errors.Is(err, promo.ErrInvalidCode) {
+			t.Errorf("%+v: got %v, want %v", code, err, promo.ErrInvalidCode)
+		}
+	}
+}
+
+func TestRedeemable(t *testing.T) {
+	code := promo.Code{Code: "SUMMER10", Percent: 10, MaxRedemptions: 100, MaxPerCustomer: 1}
+
+	if err := code.Redeemable(promo.Usage{Redemptions: 99}); err != nil {
+		t.Error(err)
+	}
+	if err := code.Redeemable(promo.Usage{Redemptions: 100}); !errors.Is(err, promo.ErrUsedUp) {
+		t.Errorf("got %v, want %v", err, promo.ErrUsedUp)
+	}
+	if err := code.Redeemable(promo.Usage{Redemptions: 5, CustomerRedemptions: 1}); !errors.Is(err, promo.ErrUsedUp) {
+		t.Errorf("got %v, want %v", err, promo.ErrUsedUp)
+	}
+
+	unlimited := promo.Code{Code: "ALWAYS", Percent: 5}
+	if err := unlimited.Redeemable(promo.Usage{Redemptions: 10000, CustomerRedemptions: 100}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	booking := promo.Booking{HolidayID: 3, LocationID: 2, Spend: *eur(90050), Date: *date(2030, time.June, 15)}
+
+	tests := []struct {
+		name string
+		code promo.Code
+		want money.Money
+		err  error
+	}{
+		{name: "percent", code: promo.Code{Percent: 10}, want: *eur(-9005)},
+		{name: "amount", code: promo.Code{Amount: eur(5000)}, want: *eur(-5000)},
+		{name: "amount above the spend", code: promo.Code{Amount: eur(100000)}, want: *eur(-90050)},
+		{name: "in the window", code: promo.Code{Percent: 10, ValidFrom: date(2030, time.June, 1), ValidTo: date(2030, time.July, 1)}, want: *eur(-9005)},
+		{name: "before the window", code: promo.Code{Percent: 10, ValidFrom: date(2030, time.July, 1)}, err: promo.ErrNotValid},
+		{name: "after the window", code: promo.Code{Percent: 10, ValidTo: date(2030, time.June, 15)}, err: promo.ErrNotValid},
+		{name: "holiday", code: promo.Code{Percent: 10, Holidays: []int{1, 3}}, want: *eur(-9005)},
+		{name: "other holiday", code: promo.Code{Percent: 10, Holidays: []int{1}}, err: promo.ErrNotApplicable},
+		{name: "location", code: promo.Code{Percent: 10, Locations: []int{2}}, want: *eur(-9005)},
+		{name: "other location", code: promo.Code{Percent: 10, Locations: []int{4}}, err: promo.ErrNotApplicable},
+		{name: "minimum spend", code: promo.Code{Percent: 10, MinSpend: eur(90050)}, want: *eur(-9005)},
+		{name: "below the minimum spend", code: promo.Code{Percent: 10, MinSpend: eur(100000)}, err: promo.ErrNotApplicable},
+		{name: "minimum spend in another currency", code: promo.Code{Percent: 10, MinSpend: &money.Money{Amount: 100, Currency: "GBP"}}, err: promo.ErrNotApplicable},
+		{name: "amount in another currency", code: promo.Code{Amount: &money.Money{Amount: 500, Currency: "GBP"}}, err: promo.ErrNotApplicable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.code.Discount(booking)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+			if err == nil && got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
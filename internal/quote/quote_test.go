@@ -0,0 +1,133 @@
+package quote_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"travel/internal/money"
+	"travel/internal/pricing"
+	"travel/internal/quote"
+)
+
+var secret = []byte("secret")
+
+func eur(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "EUR"}
+}
+
+func sample() quote.Quote {
+	return quote.Quote{
+		TenantID:  1,
+		HolidayID: 7,
+		Party:     pricing.Party{Adults: 2, Children: []pricing.Child{{Age: 4}}},
+		Extras:    []string{"Transfer"},
+		Pricing:   pricing.Quote{BasePrice: eur(50000), Adjustments: []pricing.Adjustment{}, Price: eur(50000)},
+		Breakdown: pricing.Breakdown{Items: []pricing.Item{}, Total: eur(150000)},
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := quote.NewSigner(secret, time.Hour)
+
+	signed, token, err := signer.Sign(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(signed.ExpiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("expires in %s, want an hour", until)
+	}
+
+	got, err := signer.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.HolidayID != 7 || got.Total != signed.Total || len(got.Party.Children) != 1 || !got.ExpiresAt.Equal(signed.ExpiresAt) || got.ID != signed.ID {
+		t.Errorf("got %+v, want %+v", got, signed)
+	}
+
+	// every quote gets its own ID, even for the same booking
+	again, _, err := signer.Sign(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.ID == "" || again.ID == signed.ID {
+		t.Errorf("IDs %q and %q", signed.ID, again.ID)
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	signer := quote.NewSigner(secret, time.Hour)
+	_, token, err := signer.Sign(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a quote signed with another secret
+	_, foreign, err := quote.NewSigner([]byte("other"), time.Hour).Sign(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a cheaper quote with the signature of the original
+	cheaper := sample()
+	cheaper.Total.Amount = 100
+	_, forged, err := quote.NewSigner(secret, time.Hour).Sign(cheaper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _, _ := strings.Cut(forged, ".")
+	_, signature, _ := strings.Cut(token, ".")
+
+	_, expired, err := quote.NewSigner(secret, -time.Minute).Sign(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a quote signed before quotes had IDs
+	old := sample()
+	old.ExpiresAt = time.Now().Add(time.Hour)
+	data, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPayload := base64.RawURLEncoding.EncodeToString(data)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(oldPayload))
+	withoutID := oldPayload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "no signature", token: "abc", want: quote.ErrMalformed},
+		{name: "invalid signature encoding", token: "abc.!!", want: quote.ErrMalformed},
+		{name: "other secret", token: foreign, want: quote.ErrInvalidSignature},
+		{name: "changed", token: payload + "." + signature, want: quote.ErrInvalidSignature},
+		{name: "expired", token: expired, want: quote.ErrExpired},
+		{name: "without an ID", token: withoutID, want: quote.ErrMalformed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := signer.Verify(test.token); !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestRandomSecret(t *testing.T) {
+	_, token, err := quote.NewSigner(nil, time.Hour).Sign(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := quote.NewSigner(nil, time.Hour).Verify(token); !errors.Is(err, quote.ErrInvalidSignature) {
+		t.Errorf("got %v, want %v", err, quote.ErrInvalidSignature)
+	}
+}
@@ -0,0 +1,104 @@
+package seed
+
+// kind is what a destination is visited for, it decides when its holidays
+// start, how long they last and what they cost.
+type kind int
+
+const (
+	seaside kind = iota
+	mountain
+	city
+)
+
+type destination struct {
+	city    string
+	country string
+	kind    kind
+	// price is the typical price of a night in the main season
+	price float64
+}
+
+var destinations = []destination{
+	{"Varna", "Bulgaria", seaside, 45},
+	{"Burgas", "Bulgaria", seaside, 40},
+	{"Sozopol", "Bulgaria", seaside, 50},
+	{"Nessebar", "Bulgaria", seaside, 55},
+	{"Bansko", "Bulgaria", mountain, 60},
+	{"Borovets", "Bulgaria", mountain, 55},
+	{"Pamporovo", "Bulgaria", mountain, 50},
+	{"Sofia", "Bulgaria", city, 40},
+	{"Plovdiv", "Bulgaria", city, 35},
+	{"Veliko Tarnovo", "Bulgaria", city, 30},
+	{"Thessaloniki", "Greece", city, 60},
+	{"Halkidiki", "Greece", seaside, 75},
+	{"Corfu", "Greece", seaside, 85},
+	{"Santorini", "Greece", seaside, 140},
+	{"Antalya", "Turkey", seaside, 70},
+	{"Istanbul", "Turkey", city, 65},
+	{"Dubrovnik", "Croatia", seaside, 110},
+	{"Split", "Croatia", seaside, 90},
+	{"Budva", "Montenegro", seaside, 65},
+	{"Ohrid", "North Macedonia", seaside, 40},
+	{"Rome", "Italy", city, 110},
+	{"Florence", "Italy", city, 105},
+	{"Venice", "Italy", city, 130},
+	{"Dolomites", "Italy", mountain, 120},
+	{"Barcelona", "Spain", city, 100},
+	{"Mallorca", "Spain", seaside, 95},
+	{"Lisbon", "Portugal", city, 85},
+	{"Algarve", "Portugal", seaside, 80},
+	{"Paris", "France", city, 140},
+	{"Chamonix", "France", mountain, 150},
+	{"Vienna", "Austria", city, 100},
+	{"Innsbruck", "Austria", mountain, 115},
+	{"Prague", "Czech Republic", city, 75},
+	{"Budapest", "Hungary", city, 70},
+	{"Zermatt", "Switzerland", mountain, 190},
+}
+
+var streets = []string{
+	"Primorska", "Vitosha", "Tsar Simeon", "Knyaz Boris I", "Rakovski",
+	"Main Street", "Harbour Road", "Old Town Square", "Seaside Promenade",
+	"Via Roma", "Rue de la Paix", "Hauptstrasse", "Rua Augusta", "Marine Drive",
+	"Pine Alley", "Church Street", "Station Road", "Market Lane",
+}
+
+var titles = map[kind][]string{
+	seaside: {
+		"Sunny beach week", "Seaside escape", "All-inclusive by the sea",
+		"Family beach holiday", "Coastal retreat", "Island hopping", "Summer on the shore",
+	},
+	mountain: {
+		"Ski week", "Snow and spa", "Alpine adventure", "Mountain lodge break",
+		"Ski school for beginners", "Winter wonderland", "Freeride camp",
+	},
+	city: {
+		"City break", "Weekend of culture", "Food and wine tour", "Old town walks",
+		"Museums and galleries", "Romantic getaway", "Art and architecture tour",
+	},
+}
+
+var firstNames = []string{
+	"Maria", "Ivan", "Georgi", "Elena", "Petar", "Nikolay", "Desislava", "Dimitar",
+	"Yana", "Stefan", "Anna", "Martin", "Viktoria", "Aleksandar", "Teodora",
+	"Hristo", "Gergana", "Kristian", "Radostina", "Todor", "Sofia", "Boris",
+}
+
+var lastNames = []string{
+	"Ivanov", "Petrov", "Georgiev", "Dimitrov", "Nikolov", "Todorov", "Stoyanov",
+	"Kolev", "Angelov", "Marinov", "Popov", "Hristov", "Yordanov", "Vasilev",
+}
+
+// seasons weight the months a holiday of a kind starts in, January first.
+var seasons = map[kind][12]int{
+	seaside:  {0, 0, 0, 1, 3, 8, 10, 10, 6, 2, 0, 0},
+	mountain: {10, 9, 6, 1, 0, 0, 0, 0, 0, 0, 1, 8},
+	city:     {2, 2, 4, 6, 7, 5, 4, 4, 6, 6, 4, 6},
+}
+
+// durations are the lengths in nights holidays of a kind are sold with.
+var durations = map[kind][]int{
+	seaside:  {5, 7, 7, 10, 14},
+	mountain: {4, 5, 7, 7},
+	city:     {2, 3, 3, 4, 5},
+}
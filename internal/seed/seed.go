@@ -0,0 +1,332 @@
+// Package seed fills a storage with generated but plausible locations,
+// holidays, customers and reservations for demos and load tests. The same
+// options always generate the same data.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+	"travel/internal/phone"
+	"travel/internal/service"
+	"travel/internal/storage"
+)
+
+type Options struct {
+	// Seed drives every random choice.
+	Seed int64
+	// Locations, Holidays and Reservations are how many of each are made.
+	// Customers are made along with the reservations, some of them book
+	// more than once.
+	Locations    int
+	Holidays     int
+	Reservations int
+	// Start is the first day a holiday may start on, they are spread over
+	// the year that follows.
+	Start time.Time
+}
+
+// Result counts what was stored.
+type Result struct {
+	Locations    int
+	Holidays     int
+	Customers    int
+	Reservations int
+}
+
+var (
+	ErrNoLocations = errors.New("seed: holidays need at least one location")
+	ErrNoHolidays  = errors.New("seed: reservations need at least one holiday")
+)
+
+// phoneRegion is the region of the generated phone numbers.
+const phoneRegion = "BG"
+
+// returningShare is the share of reservations made by a customer who booked
+// before.
+const returningShare = 0.25
+
+type holiday struct {
+	storage.Holiday
+	// demand weighs how likely a reservation is for the holiday
+	demand float64
+	booked int
+}
+
+type generator struct {
+	rng   *rand.Rand
+	store service.Storage
+	start time.Time
+
+	locations []storage.Location
+	// sites are the destinations of the locations
+	sites     []destination
+	holidays  []*holiday
+	customers []storage.Customer
+	phones    map[string]bool
+}
+
+// Run stores the generated records in one transaction. ctx has to carry the
+// tenant they are made for.
+func Run(ctx context.Context, store service.Storage, options Options) (*Result, error) {
+	if options.Holidays > 0 && options.Locations == 0 {
+		return nil, ErrNoLocations
+	}
+	if options.Reservations > 0 && options.Holidays == 0 {
+		return nil, ErrNoHolidays
+	}
+
+	g := &generator{
+		rng:    rand.New(rand.NewSource(options.Seed)),
+		store:  store,
+		start:  time.Date(options.Start.Year(), options.Start.Month(), options.Start.Day(), 0, 0, 0, 0, time.UTC),
+		phones: map[string]bool{},
+	}
+
+	result := &Result{}
+	err := store.InTx(ctx, func(ctx context.Context) error {
+		if err := g.insertLocations(ctx, options.Locations); err != nil {
+			return err
+		}
+
+		// the free slots of a holiday depend on its reservations, so they
+		// are booked before anything is stored
+		g.makeHolidays(options.Holidays)
+		bookings := g.book(options.Reservations)
+
+		if err := g.insertHolidays(ctx); err != nil {
+			return err
+		}
+
+		reservations, err := g.insertReservations(ctx, bookings)
+		if err != nil {
+			return err
+		}
+
+		*result = Result{
+			Locations:    len(g.locations),
+			Holidays:     len(g.holidays),
+			Customers:    len(g.customers),
+			Reservations: reservations,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// insertLocations visits every destination once, in random order, before
+// any is visited again.
+func (g *generator) insertLocations(ctx context.Context, count int) error {
+	var order []int
+	for i := 0; i < count; i++ {
+		if len(order) == 0 {
+			order = g.rng.Perm(len(destinations))
+		}
+		d := destinations[order[0]]
+		order = order[1:]
+
+		location := storage.Location{
+			Street:  streets[g.rng.Intn(len(streets))],
+			Number:  fmt.Sprint(1 + g.rng.Intn(150)),
+			City:    d.city,
+			Country: d.country,
+		}
+
+		id, err := g.store.InsertLocation(ctx, &location)
+		if err != nil {
+			return err
+		}
+		location.ID = int(id)
+
+		g.locations = append(g.locations, location)
+		g.sites = append(g.sites, d)
+	}
+
+	return nil
+}
+
+func (g *generator) makeHolidays(count int) {
+	for i := 0; i < count; i++ {
+		n := g.rng.Intn(len(g.locations))
+		location, d := g.locations[n], g.sites[n]
+		kind := d.kind
+
+		startDate, season := g.startDate(kind)
+		nights := durations[kind][g.rng.Intn(len(durations[kind]))]
+
+		// main season holidays cost more and sell better
+		price := float64(nights) * d.price * (0.7 + 0.6*season) * (0.85 + 0.3*g.rng.Float64())
+		price = math.Max(math.Round(price/10)*10-1, 9)
+
+		g.holidays = append(g.holidays, &holiday{
+			Holiday: storage.Holiday{
+				Title:      titles[kind][g.rng.Intn(len(titles[kind]))] + " in " + location.City,
+				Duration:   nights,
+				StartDate:  startDate,
+				FreeSlots:  10 + g.rng.Intn(31),
+				LocationID: location.ID,
+				PriceMinor: int64(price) * 100,
+				Currency:   service.DefaultCurrency,
+			},
+			demand: (0.3 + 0.7*season) * (0.5 + g.rng.Float64()),
+		})
+	}
+}
+
+// startDate picks a day in the year after start, in a month weighted by the
+// season of kind. Beach and ski holidays start on Saturdays. season is the
+// weight of the month relative to the busiest one, from 0 to 1.
+func (g *generator) startDate(kind kind) (date time.Time, season float64) {
+	weights := seasons[kind]
+
+	total, busiest := 0, 0
+	for _, w := range weights {
+		total += w
+		busiest = max(busiest, w)
+	}
+
+	month, pick := 0, g.rng.Intn(total)
+	for pick >= weights[month] {
+		pick -= weights[month]
+		month++
+	}
+
+	date = time.Date(g.start.Year(), time.Month(month+1), 1+g.rng.Intn(28), 0, 0, 0, 0, time.UTC)
+	if date.Before(g.start) {
+		date = date.AddDate(1, 0, 0)
+	}
+
+	if kind != city {
+		date = date.AddDate(0, 0, int(time.Saturday-date.Weekday()+7)%7)
+	}
+
+	return date, float64(weights[month]) / float64(busiest)
+}
+
+// book spreads count reservations over the holidays by demand and returns
+// the holiday of each. Holidays booked beyond their slots get more slots,
+// the free slots are what is left.
+func (g *generator) book(count int) []*holiday {
+	if count == 0 {
+		return nil
+	}
+
+	cumulative := make([]float64, len(g.holidays))
+	var total float64
+	for i, h := range g.holidays {
+		total += float64(h.FreeSlots) * h.demand
+		cumulative[i] = total
+	}
+
+	bookings := make([]*holiday, count)
+	for i := range bookings {
+		n := sort.SearchFloat64s(cumulative, g.rng.Float64()*total)
+		h := g.holidays[min(n, len(g.holidays)-1)]
+		h.booked++
+		bookings[i] = h
+	}
+
+	for _, h := range g.holidays {
+		h.FreeSlots = max(h.FreeSlots, h.booked) - h.booked
+	}
+
+	return bookings
+}
+
+func (g *generator) insertHolidays(ctx context.Context) error {
+	for _, h := range g.holidays {
+		id, err := g.store.InsertHolidays(ctx, &h.Holiday)
+		if err != nil {
+			return err
+		}
+		h.ID = int(id)
+	}
+
+	return nil
+}
+
+func (g *generator) insertReservations(ctx context.Context, bookings []*holiday) (int, error) {
+	for _, h := range bookings {
+		var customer storage.Customer
+		if len(g.customers) > 0 && g.rng.Float64() < returningShare {
+			customer = g.customers[g.rng.Intn(len(g.customers))]
+		} else {
+			var err error
+			customer, err = g.insertCustomer(ctx)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		reservation := storage.Reservation{
+			ContactName: customer.Name,
+			PhoneNumber: customer.PhoneNumber,
+			HolidayID:   h.ID,
+			CustomerID:  &customer.ID,
+			PhoneE164:   customer.NormalizedPhone,
+		}
+
+		if _, err := g.store.InsertReservation(ctx, &reservation); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(bookings), nil
+}
+
+func (g *generator) insertCustomer(ctx context.Context) (storage.Customer, error) {
+	first := firstNames[g.rng.Intn(len(firstNames))]
+	last := lastNames[g.rng.Intn(len(lastNames))]
+	if strings.HasSuffix(first, "a") {
+		last += "a"
+	}
+
+	number, err := g.phone()
+	if err != nil {
+		return storage.Customer{}, err
+	}
+
+	// every email is unique, not every customer leaves one
+	var email string
+	if g.rng.Float64() < 0.9 {
+		email = fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), len(g.customers)+1)
+	}
+
+	customer := storage.Customer{
+		Name:            first + " " + last,
+		PhoneNumber:     number.National(),
+		Email:           email,
+		NormalizedPhone: number.E164(),
+	}
+
+	id, err := g.store.InsertCustomer(ctx, &customer)
+	if err != nil {
+		return storage.Customer{}, err
+	}
+	customer.ID = int(id)
+
+	g.customers = append(g.customers, customer)
+	return customer, nil
+}
+
+// phone returns a mobile number no other customer has.
+func (g *generator) phone() (*phone.Number, error) {
+	for {
+		digits := fmt.Sprintf("08%d%07d", 7+g.rng.Intn(3), g.rng.Intn(10_000_000))
+		if g.phones[digits] {
+			continue
+		}
+		g.phones[digits] = true
+
+		return phone.Parse(digits, phoneRegion)
+	}
+}
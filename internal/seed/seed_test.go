@@ -0,0 +1,129 @@
+package seed_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+	"travel/internal/seed"
+	"travel/internal/storage"
+	"travel/internal/storage/memory"
+	"travel/internal/tenant"
+)
+
+var options = seed.Options{
+	Seed:         7,
+	Locations:    12,
+	Holidays:     60,
+	Reservations: 400,
+	Start:        time.Date(2030, time.March, 15, 0, 0, 0, 0, time.UTC),
+}
+
+type dump struct {
+	Locations    []storage.Location
+	Holidays     []storage.HolidayWithLocation
+	Customers    []storage.Customer
+	Reservations []storage.ReservationResult
+}
+
+func run(t *testing.T, options seed.Options) (*seed.Result, dump) {
+	t.Helper()
+
+	ctx := tenant.WithID(context.Background(), 1)
+	store := memory.New()
+
+	result, err := seed.Run(ctx, store, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d dump
+	if d.Locations, err = store.LocationGetAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d.Holidays, err = store.HolidaysGetAll(ctx, "", 0, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if d.Customers, err = store.CustomerGetAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	err = store.ReservationEach(ctx, func(reservation storage.ReservationResult) error {
+		d.Reservations = append(d.Reservations, reservation)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result, d
+}
+
+func TestReproducible(t *testing.T) {
+	_, first := run(t, options)
+	_, second := run(t, options)
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("the same options generated different data")
+	}
+
+	other := options
+	other.Seed++
+	if _, third := run(t, other); reflect.DeepEqual(first.Holidays, third.Holidays) {
+		t.Fatal("another seed generated the same holidays")
+	}
+}
+
+func TestVolumes(t *testing.T) {
+	result, d := run(t, options)
+
+	if result.Locations != options.Locations || len(d.Locations) != options.Locations {
+		t.Errorf("%d locations, want %d", len(d.Locations), options.Locations)
+	}
+	if result.Holidays != options.Holidays || len(d.Holidays) != options.Holidays {
+		t.Errorf("%d holidays, want %d", len(d.Holidays), options.Holidays)
+	}
+	if result.Reservations != options.Reservations || len(d.Reservations) != options.Reservations {
+		t.Errorf("%d reservations, want %d", len(d.Reservations), options.Reservations)
+	}
+	if result.Customers != len(d.Customers) || len(d.Customers) >= options.Reservations {
+		t.Errorf("%d customers for %d reservations, some should book twice", len(d.Customers), options.Reservations)
+	}
+}
+
+func TestPlausible(t *testing.T) {
+	_, d := run(t, options)
+
+	end := options.Start.AddDate(1, 0, 7)
+	for _, holiday := range d.Holidays {
+		if holiday.StartDate.Before(options.Start) || holiday.StartDate.After(end) {
+			t.Errorf("%q starts on %s, outside of the year after %s", holiday.Title, holiday.StartDate, options.Start)
+		}
+		if holiday.Price.Amount <= 0 || holiday.Duration <= 0 || holiday.FreeSlots < 0 {
+			t.Errorf("%q: price %v, duration %d, free slots %d", holiday.Title, holiday.Price, holiday.Duration, holiday.FreeSlots)
+		}
+	}
+
+	customers := map[int]storage.Customer{}
+	for _, customer := range d.Customers {
+		if customer.NormalizedPhone == "" || customer.Name == "" {
+			t.Errorf("incomplete customer %+v", customer)
+		}
+		customers[customer.ID] = customer
+	}
+
+	for _, reservation := range d.Reservations {
+		customer, ok := customers[*reservation.CustomerID]
+		if !ok || customer.NormalizedPhone != reservation.PhoneE164 {
+			t.Errorf("reservation %d does not match its customer %+v", reservation.ID, customer)
+		}
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	if _, err := seed.Run(context.Background(), memory.New(), seed.Options{Holidays: 1}); err != seed.ErrNoLocations {
+		t.Errorf("holidays without locations: %v", err)
+	}
+	if _, err := seed.Run(context.Background(), memory.New(), seed.Options{Locations: 1, Reservations: 1}); err != seed.ErrNoHolidays {
+		t.Errorf("reservations without holidays: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"travel/internal/audit"
+	"travel/internal/auth"
+	"travel/internal/storage"
+)
+
+var (
+	ErrAPIKeyInvalid     = errors.New("invalid api key")
+	ErrAPIKeyRevoked     = errors.New("api key is revoked")
+	ErrAPIKeyNameMissing = errors.New("api key needs a name")
+	ErrAPIKeyRoleUnknown = errors.New("unknown api key role")
+)
+
+// defaultAPIKeyRole is given to partner keys created without a role.
+const defaultAPIKeyRole = "agent"
+
+// apiKeyTouchInterval limits how often lastUsedAt is written for a busy key.
+const apiKeyTouchInterval = time.Minute
+
+func (s *Service) APIKeyGetAll(ctx context.Context) ([]APIKeyDTO, error) {
+	keys, err := s.storage.APIKeyGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []APIKeyDTO{}
+	for _, key := range keys {
+		result = append(result, *apiKeyToDTO(&key))
+	}
+
+	return result, nil
+}
+
+// CreateAPIKey stores a new key for the principal in ctx and returns it with
+// the plain key, which is not retrievable afterwards.
+func (s *Service) CreateAPIKey(ctx context.Context, name string, role string) (*APIKeyDTO, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrAPIKeyNameMissing
+	}
+
+	if role == "" {
+		role = defaultAPIKeyRole
+	}
+	exists, err := s.storage.RoleExists(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w %q", ErrAPIKeyRoleUnknown, role)
+	}
+
+	var createdBy string
+	if principal := auth.FromContext(ctx); principal != nil {
+		createdBy = principal.ID
+	}
+
+	plain, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &storage.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Role:      role,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	var result *APIKeyDTO
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		id, err := s.storage.InsertAPIKey(ctx, key)
+		if err != nil {
+			return err
+		}
+		key.ID = int(id)
+
+		// recorded before the plain key is added so it never reaches the log
+		result = apiKeyToDTO(key)
+
+		return s.record(ctx, auditAPIKey, key.ID, audit.ActionCreate, nil, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Key = plain
+
+	return result, nil
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, keyID int) (*APIKeyDTO, error) {
+	var result *APIKeyDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		key, err := s.storage.APIKey(ctx, keyID)
+		if err != nil {
+			return err
+		}
+
+		result = apiKeyToDTO(key)
+		if key.RevokedAt != nil {
+			return nil
+		}
+
+		before := *result
+		revokedAt := time.Now().UTC().Truncate(time.Second)
+		if err := s.storage.RevokeAPIKey(ctx, keyID, revokedAt); err != nil {
+			return err
+		}
+		result.RevokedAt = &revokedAt
+
+		return s.record(ctx, auditAPIKey, keyID, audit.ActionUpdate, before, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RotateAPIKey revokes a key and issues a replacement with the same name and
+// role.
+func (s *Service) RotateAPIKey(ctx context.Context, keyID int) (*APIKeyDTO, error) {
+	var result *APIKeyDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		key, err := s.RevokeAPIKey(ctx, keyID)
+		if err != nil {
+			return err
+		}
+
+		result, err = s.CreateAPIKey(ctx, key.Name, key.Role)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// AuthenticateAPIKey implements auth.APIKeyAuthenticator.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, plain string) (*auth.Principal, error) {
+	prefix, err := auth.APIKeyPrefix(plain)
+	if err != nil {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	key, err := s.storage.APIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(auth.HashAPIKey(plain))) != 1 {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	if key.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	now := time.Now().UTC()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.storage.TouchAPIKey(ctx, key.ID, now.Truncate(time.Second)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &auth.Principal{
+		Type:     auth.PrincipalAPIKey,
+		ID:       strconv.Itoa(key.ID),
+		Name:     key.Name,
+		Role:     key.Role,
+		TenantID: key.TenantID,
+	}, nil
+}
+
+func apiKeyToDTO(key *storage.APIKey) *APIKeyDTO {
+	return &APIKeyDTO{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Role:       key.Role,
+		CreatedBy:  key.CreatedBy,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"travel/internal/audit"
+	"travel/internal/auth"
+	"travel/internal/storage"
+)
+
+// Resources recorded in the audit log.
+const (
+	auditHoliday        = "holiday"
+	auditLocation       = "location"
+	auditReservation    = "reservation"
+	auditCustomer       = "customer"
+	auditAPIKey         = "apikey"
+	auditPricingRuleSet = "pricingruleset"
+	auditExchangeRate   = "exchangerate"
+	auditPromoCode      = "promocode"
+	auditPayment        = "payment"
+	auditInvoice        = "invoice"
+)
+
+var ErrAuditResourceUnknown = errors.New("unknown audit resource")
+
+// AuditLog returns the recorded changes of a resource, oldest first. A
+// resourceID of 0 returns the changes of every record of the resource.
+func (s *Service) AuditLog(ctx context.Context, resource string, resourceID int) ([]AuditEntryDTO, error) {
+	switch resource {
+	case auditHoliday, auditLocation, auditReservation, auditCustomer, auditAPIKey, auditPricingRuleSet, auditExchangeRate, auditPromoCode, auditPayment, auditInvoice:
+	default:
+		return nil, ErrAuditResourceUnknown
+	}
+
+	entries, err := s.storage.AuditEntries(ctx, resource, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []AuditEntryDTO{}
+	for _, entry := range entries {
+		result = append(result, AuditEntryDTO{
+			ID: entry.ID,
+			Actor: AuditActorDTO{
+				Type: entry.ActorType,
+				ID:   entry.ActorID,
+				Name: entry.ActorName,
+			},
+			OccurredAt: entry.OccurredAt,
+			Resource:   entry.Resource,
+			ResourceID: entry.ResourceID,
+			Action:     entry.Action,
+			Diff:       json.RawMessage(entry.Diff),
+		})
+	}
+
+	return result, nil
+}
+
+// record writes an audit entry for a change of a resource. It must be called
+// with the context of the transaction that made the change so that both are
+// committed or rolled back together.
+func (s *Service) record(ctx context.Context, resource string, resourceID int, action string, before interface{}, after interface{}) error {
+	diff, err := audit.Diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	entry := &storage.AuditEntry{
+		OccurredAt: time.Now().UTC(),
+		Resource:   resource,
+		ResourceID: resourceID,
+		Action:     action,
+		Diff:       diff,
+	}
+
+	if principal := auth.FromContext(ctx); principal != nil {
+		entry.ActorType = principal.Type
+		entry.ActorID = principal.ID
+		entry.ActorName = principal.Name
+	}
+
+	return s.storage.InsertAuditEntry(ctx, entry)
+}
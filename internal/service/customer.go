@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"travel/internal/audit"
+	"travel/internal/phone"
+	"travel/internal/storage"
+)
+
+var ErrCustomerContactMissing = errors.New("customer needs a phone number or an email")
+
+func (s *Service) CustomerGetAll(ctx context.Context) ([]CustomerDTO, error) {
+	customers, err := s.storage.CustomerGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []CustomerDTO{}
+	for _, value := range customers {
+		result = append(result, *customerToDTO(&value))
+	}
+
+	return result, nil
+}
+
+func (s *Service) Customer(ctx context.Context, customerID int) (*CustomerDTO, error) {
+	customer, err := s.storage.Customer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return customerToDTO(customer), nil
+}
+
+func (s *Service) InsertCustomer(ctx context.Context, customer CustomerDTO) (int64, error) {
+	customerData, err := s.customerFromDTO(customer)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		id, err = s.insertCustomer(ctx, customerData)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Service) UpdateCustomer(ctx context.Context, customer CustomerDTO) (*CustomerDTO, error) {
+	customerData, err := s.customerFromDTO(customer)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *CustomerDTO
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		before, err := s.Customer(ctx, customer.ID)
+		if err != nil {
+			return err
+		}
+
+		updatedCustomer, err := s.storage.UpdateCustomer(ctx, customerData)
+		if err != nil {
+			return err
+		}
+
+		result = customerToDTO(updatedCustomer)
+
+		return s.record(ctx, auditCustomer, result.ID, audit.ActionUpdate, before, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Service) DeleteCustomer(ctx context.Context, customerID int) (*CustomerDTO, error) {
+	var result *CustomerDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		customer, err := s.storage.DeleteCustomer(ctx, customerID)
+		if err != nil {
+			return err
+		}
+
+		result = customerToDTO(customer)
+
+		return s.record(ctx, auditCustomer, result.ID, audit.ActionDelete, result, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CustomerReservations returns the reservations of a customer like
+// ReservationGetAll.
+func (s *Service) CustomerReservations(ctx context.Context, customerID int, currency string) ([]storage.ReservationResult, error) {
+	if _, err := s.storage.Customer(ctx, customerID); err != nil {
+		return nil, err
+	}
+
+	reservations, err := s.storage.CustomerReservations(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	convert, err := s.currentConverter(ctx, currency)
+	if err != nil || convert == nil {
+		return reservations, err
+	}
+	if err := convertReservations(reservations, convert); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// resolveCustomer returns the customer a reservation belongs to. An explicit
+// customerID wins, otherwise the customer is matched by phone number or email
+// and created from the contact details when nobody matches.
+func (s *Service) resolveCustomer(ctx context.Context, reservation ReservationDTO, number *phone.Number) (int, error) {
+	if reservation.CustomerID != 0 {
+		customer, err := s.storage.Customer(ctx, reservation.CustomerID)
+		if err != nil {
+			return 0, err
+		}
+		return customer.ID, nil
+	}
+
+	email := normalizeEmail(reservation.Email)
+
+	// customers created before phone numbers were parsed are stored with
+	// the digits only form
+	for _, normalized := range []string{number.E164(), normalizePhone(reservation.PhoneNumber)} {
+		customer, err := s.storage.CustomerByContact(ctx, normalized, email)
+		if err != nil {
+			return 0, err
+		}
+		if customer != nil {
+			return customer.ID, nil
+		}
+	}
+
+	customerData, err := s.customerFromDTO(CustomerDTO{
+		Name:        reservation.ContactName,
+		PhoneNumber: reservation.PhoneNumber,
+		Email:       reservation.Email,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := s.insertCustomer(ctx, customerData)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// insertCustomer stores a customer and records its creation. ctx must carry a
+// transaction.
+func (s *Service) insertCustomer(ctx context.Context, customer *storage.Customer) (int64, error) {
+	id, err := s.storage.InsertCustomer(ctx, customer)
+	if err != nil {
+		return 0, err
+	}
+	customer.ID = int(id)
+
+	if err := s.record(ctx, auditCustomer, customer.ID, audit.ActionCreate, nil, customerToDTO(customer)); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func customerToDTO(customer *storage.Customer) *CustomerDTO {
+	return &CustomerDTO{
+		ID:          customer.ID,
+		Name:        customer.Name,
+		PhoneNumber: customer.PhoneNumber,
+		Email:       customer.Email,
+	}
+}
+
+func (s *Service) customerFromDTO(customer CustomerDTO) (*storage.Customer, error) {
+	email := normalizeEmail(customer.Email)
+
+	var normalizedPhone string
+	if strings.TrimSpace(customer.PhoneNumber) != "" {
+		number, err := phone.Parse(customer.PhoneNumber, s.phoneRegion)
+		if err != nil {
+			return nil, err
+		}
+		normalizedPhone = number.E164()
+	}
+
+	if normalizedPhone == "" && email == "" {
+		return nil, ErrCustomerContactMissing
+	}
+
+	return &storage.Customer{
+		ID:              customer.ID,
+		Name:            strings.TrimSpace(customer.Name),
+		PhoneNumber:     strings.TrimSpace(customer.PhoneNumber),
+		Email:           email,
+		NormalizedPhone: normalizedPhone,
+	}, nil
+}
+
+// normalizePhone keeps only the digits of a phone number and a leading "+"
+// ("00" international prefixes are turned into "+"). It is the form customers
+// migrated from old reservations are stored with.
+func normalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+
+	var b strings.Builder
+	for i, r := range phone {
+		if r == '+' && i == 0 {
+			b.WriteRune(r)
+			continue
+		}
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	normalized := b.String()
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + normalized[2:]
+	}
+
+	return normalized
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
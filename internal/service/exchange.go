@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"travel/internal/audit"
+	"travel/internal/exchange"
+	"travel/internal/money"
+	"travel/internal/pricing"
+	"travel/internal/storage"
+)
+
+var (
+	ErrExchangeRateInvalid = errors.New("exchange rate has to be above 0")
+	ErrExchangeRateBase    = errors.New("the default currency has no exchange rate")
+)
+
+// SetRounding sets how prices converted into another currency are rounded,
+// exchange.DefaultRounding unless set.
+func (s *Service) SetRounding(rounding exchange.Rounding) {
+	s.rounding = rounding
+}
+
+func (s *Service) ExchangeRateGetAll(ctx context.Context) ([]ExchangeRateDTO, error) {
+	rates, err := s.storage.ExchangeRateGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ExchangeRateDTO{}
+	for _, rate := range rates {
+		result = append(result, *exchangeRateToDTO(&rate))
+	}
+
+	return result, nil
+}
+
+// SaveExchangeRate sets the rate of a currency, a rate without a date is
+// dated today.
+func (s *Service) SaveExchangeRate(ctx context.Context, rate ExchangeRateDTO) (*ExchangeRateDTO, error) {
+	var result *ExchangeRateDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.saveExchangeRate(ctx, rate)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ImportExchangeRates sets the rates of every currency in ECB reference rates
+// to the ones of their newest day. Currencies prices can not be in are left
+// out.
+func (s *Service) ImportExchangeRates(ctx context.Context, r io.Reader) ([]ExchangeRateDTO, error) {
+	rates, err := exchange.ParseECB(r)
+	if err != nil {
+		return nil, err
+	}
+	if rates.Base != DefaultCurrency {
+		return nil, fmt.Errorf("%w: rates of %s, prices are in %s", exchange.ErrInvalidECB, rates.Base, DefaultCurrency)
+	}
+
+	currencies := []string{}
+	for currency := range rates.Rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	result := []ExchangeRateDTO{}
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		for _, currency := range currencies {
+			if _, err := money.Digits(currency); err != nil {
+				continue
+			}
+
+			saved, err := s.saveExchangeRate(ctx, ExchangeRateDTO{Currency: currency, Rate: rates.Rates[currency], Date: rates.Date})
+			if err != nil {
+				return err
+			}
+			result = append(result, *saved)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "exchange rates imported", "date", rates.Date.Format(time.DateOnly), "rates", len(result))
+
+	return result, nil
+}
+
+// saveExchangeRate validates and saves rate, it must run in a transaction.
+func (s *Service) saveExchangeRate(ctx context.Context, rate ExchangeRateDTO) (*ExchangeRateDTO, error) {
+	rate.Currency = strings.ToUpper(strings.TrimSpace(rate.Currency))
+	if _, err := money.Digits(rate.Currency); err != nil {
+		return nil, err
+	}
+	if rate.Currency == DefaultCurrency {
+		return nil, ErrExchangeRateBase
+	}
+	if rate.Rate <= 0 {
+		return nil, ErrExchangeRateInvalid
+	}
+	if rate.Date.IsZero() {
+		rate.Date = time.Now().UTC()
+	}
+	rate.Date = rate.Date.UTC().Truncate(24 * time.Hour)
+
+	action := audit.ActionUpdate
+	var before interface{}
+	stored, err := s.storage.ExchangeRate(ctx, rate.Currency)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		action = audit.ActionCreate
+	case err != nil:
+		return nil, err
+	default:
+		before = exchangeRateToDTO(stored)
+	}
+
+	saved, err := s.storage.SaveExchangeRate(ctx, &storage.ExchangeRate{
+		Currency: rate.Currency,
+		Rate:     rate.Rate,
+		Date:     rate.Date,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := exchangeRateToDTO(saved)
+
+	return result, s.record(ctx, auditExchangeRate, saved.ID, action, before, result)
+}
+
+func (s *Service) DeleteExchangeRate(ctx context.Context, currency string) (*ExchangeRateDTO, error) {
+	var result *ExchangeRateDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		rate, err := s.storage.DeleteExchangeRate(ctx, strings.ToUpper(strings.TrimSpace(currency)))
+		if err != nil {
+			return err
+		}
+
+		result = exchangeRateToDTO(rate)
+
+		return s.record(ctx, auditExchangeRate, rate.ID, audit.ActionDelete, result, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// exchangeRates returns the current rates of the agency.
+func (s *Service) exchangeRates(ctx context.Context) (*exchange.Rates, error) {
+	stored, err := s.storage.ExchangeRateGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := &exchange.Rates{Base: DefaultCurrency, Rates: map[string]float64{}}
+	for _, rate := range stored {
+		rates.Rates[rate.Currency] = rate.Rate
+		if rate.Date.After(rates.Date) {
+			rates.Date = rate.Date
+		}
+	}
+
+	return rates, nil
+}
+
+// reservationExchangeRates returns the current rates of the agency as the
+// JSON stored with a reservation, nil when the agency has none.
+func (s *Service) reservationExchangeRates(ctx context.Context) (*string, error) {
+	rates, err := s.exchangeRates(ctx)
+	if err != nil || len(rates.Rates) == 0 {
+		return nil, err
+	}
+
+	data, err := json.Marshal(rates)
+	if err != nil {
+		return nil, err
+	}
+
+	result := string(data)
+	return &result, nil
+}
+
+// converter returns a function converting amounts into currency with rates,
+// or nil when currency is empty and amounts are shown as they are.
+func (s *Service) converter(rates *exchange.Rates, currency string) func(money.Money) (money.Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return nil
+	}
+
+	return func(amount money.Money) (money.Money, error) {
+		return rates.Convert(amount, currency, s.rounding)
+	}
+}
+
+// currentConverter is converter with the current rates of the agency.
+func (s *Service) currentConverter(ctx context.Context, currency string) (func(money.Money) (money.Money, error), error) {
+	if strings.TrimSpace(currency) == "" {
+		return nil, nil
+	}
+
+	rates, err := s.exchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.converter(rates, currency), nil
+}
+
+// convertQuote converts every amount of quote. The amounts are rounded one by
+// one, so the converted adjustments may not add up to the converted price to
+// the last minor unit.
+func convertQuote(quote *pricing.Quote, convert func(money.Money) (money.Money, error)) error {
+	var err error
+	if quote.BasePrice, err = convert(quote.BasePrice); err != nil {
+		return err
+	}
+	for i := range quote.Adjustments {
+		if quote.Adjustments[i].Amount, err = convert(quote.Adjustments[i].Amount); err != nil {
+			return err
+		}
+	}
+	quote.Price, err = convert(quote.Price)
+
+	return err
+}
+
+// convertReservations converts the holiday prices of reservation results.
+func convertReservations(reservations []storage.ReservationResult, convert func(money.Money) (money.Money, error)) error {
+	for i := range reservations {
+		price, err := convert(reservations[i].Holiday.Price)
+		if err != nil {
+			return err
+		}
+		reservations[i].Holiday.Price = price
+	}
+
+	return nil
+}
+
+func exchangeRateToDTO(rate *storage.ExchangeRate) *ExchangeRateDTO {
+	return &ExchangeRateDTO{
+		Currency: rate.Currency,
+		Rate:     rate.Rate,
+		Date:     rate.Date,
+	}
+}
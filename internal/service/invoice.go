@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"travel/internal/audit"
+	"travel/internal/invoice"
+	"travel/internal/money"
+	"travel/internal/pricing"
+	"travel/internal/storage"
+	"travel/internal/tenant"
+)
+
+// IssueInvoice issues the invoice of a reservation, from the price it was
+// booked at. A reservation has one invoice at a time, issuing it again
+// returns the current one until a credit note cancels it.
+func (s *Service) IssueInvoice(ctx context.Context, reservationID int) (*InvoiceDTO, error) {
+	var result *InvoiceDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		// two requests of one reservation must not both issue
+		if err := s.storage.LockReservation(ctx, reservationID); err != nil {
+			return err
+		}
+
+		reservation, err := s.storage.Reservation(ctx, reservationID)
+		if err != nil {
+			return err
+		}
+
+		stored, err := s.storage.ReservationInvoices(ctx, reservationID)
+		if err != nil {
+			return err
+		}
+
+		if current := currentInvoice(stored); current != nil {
+			result, err = invoiceToDTO(current)
+			return err
+		}
+
+		result, err = s.issueInvoice(ctx, reservation)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ReservationInvoice returns the current invoice of a reservation, it fails
+// with sql.ErrNoRows when none is issued.
+func (s *Service) ReservationInvoice(ctx context.Context, reservationID int) (*InvoiceDTO, error) {
+	if _, err := s.storage.Reservation(ctx, reservationID); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.storage.ReservationInvoices(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := currentInvoice(stored)
+	if current == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return invoiceToDTO(current)
+}
+
+// ReservationInvoices returns the invoices and credit notes of a reservation,
+// also once it is deleted.
+func (s *Service) ReservationInvoices(ctx context.Context, reservationID int) ([]InvoiceDTO, error) {
+	stored, err := s.storage.ReservationInvoices(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []InvoiceDTO{}
+	for _, i := range stored {
+		dto, err := invoiceToDTO(&i)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *dto)
+	}
+
+	return result, nil
+}
+
+func (s *Service) Invoice(ctx context.Context, invoiceID int) (*InvoiceDTO, error) {
+	stored, err := s.storage.Invoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoiceToDTO(stored)
+}
+
+// issueInvoice issues the next invoice of the tenant for reservation, from
+// the price it was booked at. It must be called inside a transaction.
+func (s *Service) issueInvoice(ctx context.Context, reservation *storage.Reservation) (*InvoiceDTO, error) {
+	holiday, err := s.storage.Holiday(ctx, reservation.HolidayID)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	agency, err := s.storage.Agency(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	customer := invoice.Party{Name: reservation.ContactName, Phone: reservation.PhoneNumber}
+	if reservation.CustomerID != nil {
+		stored, err := s.storage.Customer(ctx, *reservation.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+		customer.Email = stored.Email
+	}
+
+	lines, taxes, currency, err := invoiceLines(reservation, holiday)
+	if err != nil {
+		return nil, err
+	}
+	// prices booked without taxes are invoiced at a rate of 0
+	if len(taxes) == 0 {
+		taxes = []invoice.Line{invoice.ZeroTax(currency)}
+	}
+
+	sequence, err := s.storage.NextInvoiceSequence(ctx, invoice.KindInvoice)
+	if err != nil {
+		return nil, err
+	}
+
+	document := invoice.Invoice{
+		Kind:        invoice.KindInvoice,
+		Number:      invoice.Number(invoice.KindInvoice, sequence),
+		IssuedAt:    time.Now().UTC().Truncate(time.Second),
+		Issuer:      agencyParty(agency),
+		Customer:    customer,
+		Reservation: reservation.ID,
+		Holiday:     fmt.Sprintf("%s, %d days from %s", holiday.Title, holiday.Duration, holiday.StartDate.Format("2 January 2006")),
+		Lines:       lines,
+		Taxes:       taxes,
+	}
+	document.Sum(currency)
+
+	return s.storeInvoice(ctx, reservation.ID, reservation.CustomerID, sequence, nil, document)
+}
+
+// creditInvoice issues a credit note for the current invoice of a
+// reservation, when it has one. It must be called inside a transaction.
+func (s *Service) creditInvoice(ctx context.Context, reservationID int) error {
+	stored, err := s.storage.ReservationInvoices(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+
+	current := currentInvoice(stored)
+	if current == nil {
+		return nil
+	}
+
+	var issued invoice.Invoice
+	if err := json.Unmarshal([]byte(current.Data), &issued); err != nil {
+		return err
+	}
+
+	sequence, err := s.storage.NextInvoiceSequence(ctx, invoice.KindCreditNote)
+	if err != nil {
+		return err
+	}
+
+	credit := issued.Credit(invoice.Number(invoice.KindCreditNote, sequence), time.Now().UTC().Truncate(time.Second))
+	_, err = s.storeInvoice(ctx, reservationID, current.CustomerID, sequence, &current.ID, credit)
+
+	return err
+}
+
+// storeInvoice renders document and stores it with the audit entry of its
+// issue.
+func (s *Service) storeInvoice(ctx context.Context, reservationID int, customerID *int, sequence int, creditedID *int, document invoice.Invoice) (*InvoiceDTO, error) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &storage.Invoice{
+		ReservationID:     reservationID,
+		CustomerID:        customerID,
+		Kind:              document.Kind,
+		Sequence:          sequence,
+		Number:            document.Number,
+		IssuedAt:          document.IssuedAt,
+		TotalMinor:        document.Total.Amount,
+		Currency:          document.Total.Currency,
+		CreditedInvoiceID: creditedID,
+		Data:              string(data),
+		Document:          string(invoice.Render(document)),
+	}
+
+	id, err := s.storage.InsertInvoice(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	record.ID = int(id)
+
+	result, err := invoiceToDTO(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, s.record(ctx, auditInvoice, result.ID, audit.ActionCreate, nil, result)
+}
+
+// currentInvoice is the last invoice of stored no credit note cancels.
+func currentInvoice(stored []storage.Invoice) *storage.Invoice {
+	credited := map[int]bool{}
+	for _, i := range stored {
+		if i.CreditedInvoiceID != nil {
+			credited[*i.CreditedInvoiceID] = true
+		}
+	}
+
+	for n := len(stored) - 1; n >= 0; n-- {
+		if stored[n].Kind == invoice.KindInvoice && !credited[stored[n].ID] {
+			return &stored[n]
+		}
+	}
+
+	return nil
+}
+
+// invoiceLines itemizes what a reservation costs like reservationTotal: the
+// items of the quote it was booked with, taxes apart. A reservation booked
+// without a quote is for one traveller, it is itemized like a quote for one
+// adult at the price it was booked at or the price of its holiday.
+func invoiceLines(reservation *storage.Reservation, holiday *storage.Holiday) (lines []invoice.Line, taxes []invoice.Line, currency string, err error) {
+	if reservation.Quote != nil {
+		var booked QuoteDTO
+		if err := json.Unmarshal([]byte(*reservation.Quote), &booked); err != nil {
+			return nil, nil, "", err
+		}
+
+		lines, taxes = itemLines(booked.Items)
+		return lines, taxes, booked.Total.Currency, nil
+	}
+
+	price, discount := holiday.Price(), (*pricing.Discount)(nil)
+	if reservation.Pricing != nil {
+		var priced pricing.Quote
+		// quotes stored before prices had currencies hold plain numbers
+		if err := json.Unmarshal([]byte(*reservation.Pricing), &priced); err == nil && priced.Price.Currency != "" {
+			// the promo code is taken off the price per traveller, it is
+			// itemized apart like on quotes
+			price = priced.Price
+			for _, adjustment := range priced.Adjustments {
+				if adjustment.Kind == pricing.KindPromo {
+					price.Amount -= adjustment.Amount.Amount
+					discount = &pricing.Discount{Name: adjustment.Rule, Percent: adjustment.Percent, Amount: adjustment.Amount}
+				}
+			}
+		}
+	}
+
+	breakdown, err := pricing.Itemize(nil, price, pricing.Party{Adults: 1, Children: []pricing.Child{}}, nil, discount)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	lines, taxes = itemLines(breakdown.Items)
+	return lines, taxes, breakdown.Total.Currency, nil
+}
+
+// itemLines turns the items of a price breakdown into invoice lines and tax
+// lines.
+func itemLines(items []pricing.Item) (lines []invoice.Line, taxes []invoice.Line) {
+	for _, item := range items {
+		line := invoice.Line{Description: item.Name, Quantity: item.Quantity, Percent: item.Percent, UnitPrice: item.UnitPrice, Amount: item.Amount}
+		if item.Kind == pricing.KindTax {
+			taxes = append(taxes, line)
+		} else {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, taxes
+}
+
+// agencyParty is the agency as it is printed on invoices, its address one
+// line per line.
+func agencyParty(agency *storage.Agency) invoice.Party {
+	party := invoice.Party{Name: agency.Name, Email: agency.Email, TaxNumber: agency.TaxNumber}
+	for _, line := range strings.Split(agency.Address, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			party.Address = append(party.Address, line)
+		}
+	}
+
+	return party
+}
+
+func invoiceToDTO(i *storage.Invoice) (*InvoiceDTO, error) {
+	result := &InvoiceDTO{
+		ID:            i.ID,
+		ReservationID: i.ReservationID,
+		Kind:          i.Kind,
+		Number:        i.Number,
+		IssuedAt:      i.IssuedAt,
+		Total:         money.Money{Amount: i.TotalMinor, Currency: i.Currency},
+		PDF:           []byte(i.Document),
+	}
+
+	if i.CustomerID != nil {
+		result.CustomerID = *i.CustomerID
+	}
+	if i.CreditedInvoiceID != nil {
+		result.CreditedInvoiceID = *i.CreditedInvoiceID
+	}
+	if err := json.Unmarshal([]byte(i.Data), &result.Document); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
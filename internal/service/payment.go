@@ -0,0 +1,612 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"travel/internal/audit"
+	"travel/internal/auth"
+	"travel/internal/money"
+	"travel/internal/payment"
+	"travel/internal/pricing"
+	"travel/internal/storage"
+	"travel/internal/tenant"
+)
+
+var (
+	ErrNoPaymentGateway = errors.New("no payment gateway is configured")
+	ErrPaymentPending   = errors.New("a payment of the reservation is pending")
+	ErrPaidInFull       = errors.New("the reservation is paid in full")
+	ErrPaymentMismatch  = errors.New("the payment event does not match the payment")
+	ErrReservationPaid  = errors.New("the reservation is paid, refund its payments first")
+	ErrRefundPending    = errors.New("a refund of the payment is on its way")
+	ErrPaymentAbandoned = errors.New("the payment was given up before the gateway answered")
+)
+
+// PaymentGateway collects payments. Intents are confirmed by the client at
+// the gateway, which reports the outcome with a signed webhook.
+type PaymentGateway interface {
+	CreateIntent(ctx context.Context, request payment.IntentRequest) (*payment.Intent, error)
+	// Refund pays amount of a succeeded intent back and returns the id of
+	// the refund.
+	Refund(ctx context.Context, intentID string, amount money.Money) (string, error)
+	// CancelIntent cancels an intent the client did not pay, it fails with
+	// payment.ErrCancel for one that was paid.
+	CancelIntent(ctx context.Context, intentID string) error
+	// ParseEvent verifies the signature of a webhook and reads its event.
+	ParseEvent(body []byte, signature string) (*payment.Event, error)
+}
+
+// Metadata keys of the intents, the signed events of a gateway find the
+// payment with them.
+const (
+	metadataTenant  = "tenant"
+	metadataPayment = "payment"
+)
+
+type payments struct {
+	name      string
+	gateway   PaymentGateway
+	schedule  payment.Schedule
+	intentTTL time.Duration
+}
+
+// SetPayments collects payments through gateway, known as name, on schedule.
+// Intents not paid within intentTTL are cancelled once they are in the way.
+// Without a gateway reservations can not be paid.
+func (s *Service) SetPayments(name string, gateway PaymentGateway, schedule payment.Schedule, intentTTL time.Duration) {
+	s.payments = payments{name: name, gateway: gateway, schedule: schedule, intentTTL: intentTTL}
+}
+
+// ReservationPayments returns the payments of a reservation, what is paid and
+// outstanding, and the installments it is paid in.
+func (s *Service) ReservationPayments(ctx context.Context, reservationID int) (*PaymentsDTO, error) {
+	state, err := s.loadPaymentState(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PaymentsDTO{
+		ReservationID: reservationID,
+		Total:         state.total,
+		Paid:          state.paid,
+		Outstanding:   state.outstanding(),
+		Schedule:      state.plan,
+		Payments:      []PaymentDTO{},
+	}
+	for _, stored := range state.payments {
+		result.Payments = append(result.Payments, *paymentToDTO(&stored))
+	}
+
+	return result, nil
+}
+
+func (s *Service) Payment(ctx context.Context, paymentID int) (*PaymentDTO, error) {
+	stored, err := s.storage.Payment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return paymentToDTO(stored), nil
+}
+
+// CreatePayment starts paying the next installment of a reservation, or all
+// that is outstanding, with an intent at the gateway. The payment is stored
+// before the gateway is called and stays pending until the gateway reports
+// on it, or until it expires.
+func (s *Service) CreatePayment(ctx context.Context, reservationID int, request PaymentRequestDTO) (*PaymentDTO, error) {
+	if s.payments.gateway == nil {
+		return nil, ErrNoPaymentGateway
+	}
+
+	if err := s.expireIntents(ctx, reservationID); err != nil {
+		return nil, err
+	}
+
+	var paymentData *storage.Payment
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		if err := s.storage.LockReservation(ctx, reservationID); err != nil {
+			return err
+		}
+
+		state, err := s.loadPaymentState(ctx, reservationID)
+		if err != nil {
+			return err
+		}
+
+		for _, stored := range state.payments {
+			if stored.Status != payment.StatusPending {
+				continue
+			}
+			if !abandoned(stored) {
+				return fmt.Errorf("%w: payment %d", ErrPaymentPending, stored.ID)
+			}
+			if err := s.failPayment(ctx, &stored); err != nil {
+				return err
+			}
+		}
+
+		installment, err := state.next(request.Full)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		paymentData = &storage.Payment{
+			ReservationID: reservationID,
+			Kind:          installment.Kind,
+			AmountMinor:   installment.Amount.Amount,
+			Currency:      installment.Amount.Currency,
+			DueDate:       installment.DueDate,
+			Status:        payment.StatusPending,
+			Gateway:       s.payments.name,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+
+		id, err := s.storage.InsertPayment(ctx, paymentData)
+		if err != nil {
+			return err
+		}
+		paymentData.ID = int(id)
+
+		return s.record(ctx, auditPayment, paymentData.ID, audit.ActionCreate, nil, paymentToDTO(paymentData))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the gateway is called once the payment is stored, a payment it never
+	// heard of can be given up, money it collected for nothing can not
+	tenantID, _ := tenant.FromContext(ctx)
+	intent, err := s.payments.gateway.CreateIntent(ctx, payment.IntentRequest{
+		Amount:      money.Money{Amount: paymentData.AmountMinor, Currency: paymentData.Currency},
+		Description: fmt.Sprintf("Reservation %d, %s", reservationID, paymentData.Kind),
+		Metadata: map[string]string{
+			metadataTenant:  strconv.Itoa(tenantID),
+			metadataPayment: strconv.Itoa(paymentData.ID),
+		},
+	})
+	if err != nil {
+		failErr := s.storage.InTx(ctx, func(ctx context.Context) error {
+			return s.failPayment(ctx, paymentData)
+		})
+		return nil, errors.Join(err, failErr)
+	}
+
+	var result *PaymentDTO
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		stored, err := s.lockPayment(ctx, paymentData.ID)
+		if err != nil {
+			return err
+		}
+		before := paymentToDTO(stored)
+		if stored.Status != payment.StatusPending {
+			return fmt.Errorf("%w: payment %d", ErrPaymentAbandoned, stored.ID)
+		}
+
+		stored.IntentID = &intent.ID
+		updated, err := s.storage.UpdatePayment(ctx, stored)
+		if err != nil {
+			return err
+		}
+
+		result = paymentToDTO(updated)
+		return s.record(ctx, auditPayment, result.ID, audit.ActionUpdate, before, result)
+	})
+	if err != nil {
+		// without its intent the payment is given up after intentTimeout
+		s.logger.ErrorContext(ctx, "payment intent not stored", "id", paymentData.ID, "intent", intent.ID, "error", err)
+		return nil, err
+	}
+	result.ClientSecret = intent.ClientSecret
+
+	s.logger.InfoContext(ctx, "payment created", "id", result.ID, "reservation", reservationID, "kind", result.Kind, "amount", result.Amount.String())
+
+	return result, nil
+}
+
+// intentTimeout is how long a payment waits for the intent of the gateway
+// before it is given up, e.g. because the process stopped while it called
+// the gateway.
+const intentTimeout = 10 * time.Minute
+
+// abandoned tells a pending payment that never got its intent. Its customer
+// never got a client secret either, so nothing can be collected for it.
+func abandoned(p storage.Payment) bool {
+	return p.Status == payment.StatusPending && p.IntentID == nil && time.Since(p.CreatedAt) > intentTimeout
+}
+
+// expired tells a pending payment whose intent was not paid within the
+// intent TTL.
+func (s *Service) expired(p storage.Payment) bool {
+	return p.Status == payment.StatusPending && p.IntentID != nil && time.Since(p.CreatedAt) > s.payments.intentTTL
+}
+
+// expireIntents cancels the expired intents of a reservation at the gateway
+// and fails their payments, so they no longer hold up a new payment or the
+// cancellation of the reservation. The gateway is called outside of any
+// transaction. An intent it refuses to cancel was paid meanwhile, its payment
+// stays pending until the webhook settles it.
+func (s *Service) expireIntents(ctx context.Context, reservationID int) error {
+	stored, err := s.storage.ReservationPayments(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range stored {
+		if !s.expired(p) {
+			continue
+		}
+
+		if err := s.payments.gateway.CancelIntent(ctx, *p.IntentID); err != nil {
+			if errors.Is(err, payment.ErrCancel) {
+				continue
+			}
+			return err
+		}
+
+		err := s.storage.InTx(ctx, func(ctx context.Context) error {
+			locked, err := s.lockPayment(ctx, p.ID)
+			if err != nil {
+				return err
+			}
+			// a webhook may have settled it meanwhile
+			if locked.Status != payment.StatusPending {
+				return nil
+			}
+
+			return s.failPayment(ctx, locked)
+		})
+		if err != nil {
+			return err
+		}
+
+		s.logger.InfoContext(ctx, "payment expired", "id", p.ID, "intent", *p.IntentID)
+	}
+
+	return nil
+}
+
+// failPayment marks a pending payment as failed.
+func (s *Service) failPayment(ctx context.Context, p *storage.Payment) error {
+	before := paymentToDTO(p)
+
+	p.Status = payment.StatusFailed
+	p.UpdatedAt = time.Now().UTC()
+	updated, err := s.storage.UpdatePayment(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	return s.record(ctx, auditPayment, updated.ID, audit.ActionUpdate, before, paymentToDTO(updated))
+}
+
+// lockPayment locks a payment for the rest of the transaction of ctx and
+// reads it.
+func (s *Service) lockPayment(ctx context.Context, paymentID int) (*storage.Payment, error) {
+	if err := s.storage.LockPayment(ctx, paymentID); err != nil {
+		return nil, err
+	}
+
+	return s.storage.Payment(ctx, paymentID)
+}
+
+// RefundPayment pays back part of a succeeded payment, or all that is left
+// of it. The refund is stored as on its way before the gateway is called, so
+// a second refund can not pay the same money back, and settled when the
+// gateway confirms it. A refund the gateway refuses is dropped.
+func (s *Service) RefundPayment(ctx context.Context, paymentID int, request RefundRequestDTO) (*PaymentDTO, error) {
+	if s.payments.gateway == nil {
+		return nil, ErrNoPaymentGateway
+	}
+
+	var intentID string
+	var amount money.Money
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		stored, err := s.lockPayment(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+
+		if stored.Status != payment.StatusSucceeded || stored.IntentID == nil {
+			return fmt.Errorf("%w: the payment is %s", payment.ErrRefund, stored.Status)
+		}
+		if stored.RefundingMinor != 0 {
+			return fmt.Errorf("%w: payment %d", ErrRefundPending, stored.ID)
+		}
+
+		left := money.Money{Amount: stored.AmountMinor - stored.RefundedMinor, Currency: stored.Currency}
+		amount = left
+		if request.Amount != nil {
+			amount = *request.Amount
+		}
+		if amount.Currency != left.Currency || amount.Amount <= 0 || amount.Amount > left.Amount {
+			return fmt.Errorf("%w: %s of the %s left", payment.ErrRefund, amount, left)
+		}
+
+		intentID = *stored.IntentID
+		stored.RefundingMinor = amount.Amount
+		stored.UpdatedAt = time.Now().UTC()
+		_, err = s.storage.UpdatePayment(ctx, stored)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refundID, err := s.payments.gateway.Refund(ctx, intentID, amount)
+	if err != nil {
+		dropErr := s.storage.InTx(ctx, func(ctx context.Context) error {
+			stored, err := s.lockPayment(ctx, paymentID)
+			if err != nil {
+				return err
+			}
+
+			stored.RefundingMinor = 0
+			stored.UpdatedAt = time.Now().UTC()
+			_, err = s.storage.UpdatePayment(ctx, stored)
+			return err
+		})
+		return nil, errors.Join(err, dropErr)
+	}
+
+	var result *PaymentDTO
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		stored, err := s.lockPayment(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		before := paymentToDTO(stored)
+
+		stored.RefundedMinor += stored.RefundingMinor
+		stored.RefundingMinor = 0
+		if stored.RefundedMinor == stored.AmountMinor {
+			stored.Status = payment.StatusRefunded
+		}
+		stored.UpdatedAt = time.Now().UTC()
+
+		updated, err := s.storage.UpdatePayment(ctx, stored)
+		if err != nil {
+			return err
+		}
+
+		result = paymentToDTO(updated)
+		return s.record(ctx, auditPayment, result.ID, audit.ActionUpdate, before, result)
+	})
+	if err != nil {
+		// the money is back with the customer, the payment shows the refund
+		// as on its way until it is settled by hand
+		s.logger.ErrorContext(ctx, "refund not stored", "id", paymentID, "refund", refundID, "amount", amount.String(), "error", err)
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "payment refunded", "id", result.ID, "refund", refundID, "amount", amount.String())
+
+	return result, nil
+}
+
+// HandlePaymentEvent applies a webhook of the gateway to its payment. The
+// signature authenticates the webhook, the tenant and payment are read from
+// the metadata of its intent. Events about settled payments are replays and
+// change nothing, as do events of types the service does not know.
+func (s *Service) HandlePaymentEvent(ctx context.Context, body []byte, signature string) (*PaymentDTO, error) {
+	if s.payments.gateway == nil {
+		return nil, ErrNoPaymentGateway
+	}
+
+	event, err := s.payments.gateway.ParseEvent(body, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID, err := strconv.Atoi(event.Metadata[metadataTenant])
+	if err != nil {
+		return nil, fmt.Errorf("%w: tenant %q", payment.ErrMalformedEvent, event.Metadata[metadataTenant])
+	}
+	paymentID, err := strconv.Atoi(event.Metadata[metadataPayment])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payment %q", payment.ErrMalformedEvent, event.Metadata[metadataPayment])
+	}
+
+	ctx = tenant.WithID(ctx, tenantID)
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{
+		Type:     auth.PrincipalGateway,
+		ID:       event.ID,
+		Name:     s.payments.name,
+		TenantID: tenantID,
+	})
+
+	var result *PaymentDTO
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		stored, err := s.lockPayment(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		before := paymentToDTO(stored)
+
+		if stored.IntentID == nil || *stored.IntentID != event.IntentID || event.Amount != before.Amount {
+			return fmt.Errorf("%w: payment %d, intent %s of %s", ErrPaymentMismatch, paymentID, event.IntentID, event.Amount)
+		}
+
+		result = before
+		if stored.Status != payment.StatusPending {
+			return nil
+		}
+
+		switch event.Type {
+		case payment.EventSucceeded:
+			stored.Status = payment.StatusSucceeded
+		case payment.EventFailed:
+			stored.Status = payment.StatusFailed
+		default:
+			return nil
+		}
+		stored.UpdatedAt = time.Now().UTC()
+
+		updated, err := s.storage.UpdatePayment(ctx, stored)
+		if err != nil {
+			return err
+		}
+
+		result = paymentToDTO(updated)
+
+		return s.record(ctx, auditPayment, result.ID, audit.ActionUpdate, before, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "payment event", "event", event.ID, "type", event.Type, "payment", result.ID, "status", result.Status)
+
+	return result, nil
+}
+
+// checkCancellable fails while a payment of a reservation is pending or has
+// money left that was not refunded. Failed and refunded payments are kept
+// when the reservation is cancelled. Expired intents are cancelled before, see
+// expireIntents.
+func (s *Service) checkCancellable(ctx context.Context, reservationID int) error {
+	stored, err := s.storage.ReservationPayments(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range stored {
+		switch {
+		case p.Status == payment.StatusPending && !abandoned(p):
+			return fmt.Errorf("%w: payment %d", ErrPaymentPending, p.ID)
+		case p.Status == payment.StatusSucceeded && p.RefundedMinor < p.AmountMinor:
+			return fmt.Errorf("%w: payment %d", ErrReservationPaid, p.ID)
+		}
+	}
+
+	return nil
+}
+
+// paymentState is a reservation as far as paying it goes.
+type paymentState struct {
+	total    money.Money
+	paid     money.Money
+	plan     []payment.Installment
+	payments []storage.Payment
+}
+
+func (p paymentState) outstanding() money.Money {
+	return money.Money{Amount: p.total.Amount - p.paid.Amount, Currency: p.total.Currency}
+}
+
+// next is the installment to pay now: the deposit of an unpaid reservation
+// paid in installments, otherwise all that is outstanding.
+func (p paymentState) next(full bool) (payment.Installment, error) {
+	outstanding := p.outstanding()
+	if outstanding.Amount <= 0 {
+		return payment.Installment{}, ErrPaidInFull
+	}
+
+	if len(p.plan) == 1 {
+		return payment.Installment{Kind: payment.KindFull, Amount: outstanding, DueDate: p.plan[0].DueDate}, nil
+	}
+	if p.paid.Amount == 0 && !full {
+		return p.plan[0], nil
+	}
+
+	kind := payment.KindBalance
+	if p.paid.Amount == 0 {
+		kind = payment.KindFull
+	}
+
+	return payment.Installment{Kind: kind, Amount: outstanding, DueDate: p.plan[1].DueDate}, nil
+}
+
+// loadPaymentState reads the price of a reservation and its payments. The plan
+// is made as of the first payment, so it does not change as the holiday
+// comes closer.
+func (s *Service) loadPaymentState(ctx context.Context, reservationID int) (*paymentState, error) {
+	reservation, err := s.storage.Reservation(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	holiday, err := s.storage.Holiday(ctx, reservation.HolidayID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.storage.ReservationPayments(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := reservationTotal(reservation, holiday)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &paymentState{total: total, paid: money.Money{Currency: total.Currency}, payments: stored}
+
+	planned := time.Now()
+	if len(stored) > 0 {
+		planned = stored[0].CreatedAt
+	}
+	state.plan = s.payments.schedule.Plan(total, holiday.StartDate, planned)
+
+	for _, p := range stored {
+		if p.Status == payment.StatusSucceeded || p.Status == payment.StatusRefunded {
+			state.paid.Amount += p.AmountMinor - p.RefundedMinor
+		}
+	}
+
+	return state, nil
+}
+
+// reservationTotal is what a reservation costs: the total of the quote it
+// was booked with, the price it was booked at or, for reservations booked
+// before either was stored, the price of its holiday.
+func reservationTotal(reservation *storage.Reservation, holiday *storage.Holiday) (money.Money, error) {
+	if reservation.Quote != nil {
+		var booked QuoteDTO
+		if err := json.Unmarshal([]byte(*reservation.Quote), &booked); err != nil {
+			return money.Money{}, err
+		}
+
+		return booked.Total, nil
+	}
+
+	if reservation.Pricing != nil {
+		var priced pricing.Quote
+		// quotes stored before prices had currencies hold plain numbers
+		if err := json.Unmarshal([]byte(*reservation.Pricing), &priced); err == nil && priced.Price.Currency != "" {
+			return priced.Price, nil
+		}
+	}
+
+	return holiday.Price(), nil
+}
+
+func paymentToDTO(p *storage.Payment) *PaymentDTO {
+	result := &PaymentDTO{
+		ID:            p.ID,
+		ReservationID: p.ReservationID,
+		Kind:          p.Kind,
+		Amount:        money.Money{Amount: p.AmountMinor, Currency: p.Currency},
+		DueDate:       p.DueDate,
+		Status:        p.Status,
+		Gateway:       p.Gateway,
+		Refunded:      money.Money{Amount: p.RefundedMinor, Currency: p.Currency},
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+
+	if p.IntentID != nil {
+		result.IntentID = *p.IntentID
+	}
+	if p.RefundingMinor != 0 {
+		result.Refunding = &money.Money{Amount: p.RefundingMinor, Currency: p.Currency}
+	}
+
+	return result
+}
@@ -0,0 +1,28 @@
+package service
+
+import "travel/internal/phone"
+
+// defaultPhoneRegion is the region numbers without an international prefix
+// are read in.
+const defaultPhoneRegion = "BG"
+
+// phoneToDTO formats a stored phone number. Rows saved before the E.164
+// column existed only have the original input, which is parsed again.
+func (s *Service) phoneToDTO(original string, e164 string) *PhoneDTO {
+	input := e164
+	if input == "" {
+		input = original
+	}
+
+	number, err := phone.Parse(input, s.phoneRegion)
+	if err != nil {
+		return nil
+	}
+
+	return &PhoneDTO{
+		E164:          number.E164(),
+		National:      number.National(),
+		International: number.International(),
+		Region:        number.Region,
+	}
+}
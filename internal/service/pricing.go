@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"travel/internal/audit"
+	"travel/internal/pricing"
+	"travel/internal/storage"
+)
+
+var ErrPricingRuleSetNameMissing = errors.New("pricing rule set needs a name")
+
+func (s *Service) PricingRuleSetGetAll(ctx context.Context) ([]PricingRuleSetDTO, error) {
+	ruleSets, err := s.storage.PricingRuleSetGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []PricingRuleSetDTO{}
+	for _, ruleSet := range ruleSets {
+		dto, err := pricingRuleSetToDTO(&ruleSet)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *dto)
+	}
+
+	return result, nil
+}
+
+func (s *Service) PricingRuleSet(ctx context.Context, ruleSetID int) (*PricingRuleSetDTO, error) {
+	ruleSet, err := s.storage.PricingRuleSet(ctx, ruleSetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pricingRuleSetToDTO(ruleSet)
+}
+
+func (s *Service) InsertPricingRuleSet(ctx context.Context, ruleSet PricingRuleSetDTO) (int64, error) {
+	ruleSetData, err := pricingRuleSetFromDTO(ruleSet)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.storage.InsertPricingRuleSet(ctx, ruleSetData)
+		if err != nil {
+			return err
+		}
+		ruleSetData.ID = int(id)
+
+		result, err := pricingRuleSetToDTO(ruleSetData)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, auditPricingRuleSet, result.ID, audit.ActionCreate, nil, result)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Service) UpdatePricingRuleSet(ctx context.Context, ruleSet PricingRuleSetDTO) (*PricingRuleSetDTO, error) {
+	ruleSetData, err := pricingRuleSetFromDTO(ruleSet)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *PricingRuleSetDTO
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		before, err := s.PricingRuleSet(ctx, ruleSet.ID)
+		if err != nil {
+			return err
+		}
+
+		updated, err := s.storage.UpdatePricingRuleSet(ctx, ruleSetData)
+		if err != nil {
+			return err
+		}
+
+		result, err = pricingRuleSetToDTO(updated)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, auditPricingRuleSet, result.ID, audit.ActionUpdate, before, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Service) DeletePricingRuleSet(ctx context.Context, ruleSetID int) (*PricingRuleSetDTO, error) {
+	var result *PricingRuleSetDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		ruleSet, err := s.storage.DeletePricingRuleSet(ctx, ruleSetID)
+		if err != nil {
+			return err
+		}
+
+		result, err = pricingRuleSetToDTO(ruleSet)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, auditPricingRuleSet, result.ID, audit.ActionDelete, result, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// quote prices holiday for a booking made on bookingDate with the rules of
+// its rule set, a holiday without one is sold at its price.
+func (s *Service) quote(ctx context.Context, holiday *storage.Holiday, bookingDate time.Time) (*pricing.Quote, error) {
+	rules, err := s.pricingRules(ctx, holiday)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := evaluate(rules, holiday, bookingDate)
+
+	return &quote, nil
+}
+
+// pricingRules returns the rules of the rule set of holiday, none for a
+// holiday without one.
+func (s *Service) pricingRules(ctx context.Context, holiday *storage.Holiday) ([]pricing.Rule, error) {
+	if holiday.PricingRuleSetID == nil {
+		return nil, nil
+	}
+
+	ruleSet, err := s.PricingRuleSet(ctx, *holiday.PricingRuleSetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ruleSet.Rules, nil
+}
+
+func evaluate(rules []pricing.Rule, holiday *storage.Holiday, bookingDate time.Time) pricing.Quote {
+	return pricing.Evaluate(rules, pricing.Input{
+		BasePrice:   holiday.Price(),
+		StartDate:   holiday.StartDate,
+		FreeSlots:   holiday.FreeSlots,
+		BookingDate: bookingDate,
+	})
+}
+
+func pricingRuleSetToDTO(ruleSet *storage.PricingRuleSet) (*PricingRuleSetDTO, error) {
+	result := &PricingRuleSetDTO{
+		ID:    ruleSet.ID,
+		Name:  ruleSet.Name,
+		Rules: []pricing.Rule{},
+	}
+
+	if err := json.Unmarshal([]byte(ruleSet.Rules), &result.Rules); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// pricingRuleSetFromDTO validates ruleSet before it is stored.
+func pricingRuleSetFromDTO(ruleSet PricingRuleSetDTO) (*storage.PricingRuleSet, error) {
+	name := strings.TrimSpace(ruleSet.Name)
+	if name == "" {
+		return nil, ErrPricingRuleSetNameMissing
+	}
+
+	if ruleSet.Rules == nil {
+		ruleSet.Rules = []pricing.Rule{}
+	}
+	if err := pricing.Validate(ruleSet.Rules); err != nil {
+		return nil, err
+	}
+
+	rules, err := json.Marshal(ruleSet.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &storage.PricingRuleSet{
+		ID:    ruleSet.ID,
+		Name:  name,
+		Rules: string(rules),
+	}, nil
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"travel/internal/audit"
+	"travel/internal/money"
+	"travel/internal/promo"
+	"travel/internal/storage"
+)
+
+var ErrPromoCodeUnknown = errors.New("unknown promo code")
+
+func (s *Service) PromoCodeGetAll(ctx context.Context) ([]PromoCodeDTO, error) {
+	codes, err := s.storage.PromoCodeGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []PromoCodeDTO{}
+	for _, code := range codes {
+		dto, err := promoCodeToDTO(&code)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *dto)
+	}
+
+	return result, nil
+}
+
+func (s *Service) PromoCode(ctx context.Context, promoCodeID int) (*PromoCodeDTO, error) {
+	code, err := s.storage.PromoCode(ctx, promoCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return promoCodeToDTO(code)
+}
+
+func (s *Service) InsertPromoCode(ctx context.Context, code PromoCodeDTO) (int64, error) {
+	codeData, err := promoCodeFromDTO(code)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		if err := s.checkPromoRestrictions(ctx, code.Code); err != nil {
+			return err
+		}
+
+		var err error
+		id, err = s.storage.InsertPromoCode(ctx, codeData)
+		if err != nil {
+			return err
+		}
+		codeData.ID = int(id)
+
+		result, err := promoCodeToDTO(codeData)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, auditPromoCode, result.ID, audit.ActionCreate, nil, result)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Service) UpdatePromoCode(ctx context.Context, code PromoCodeDTO) (*PromoCodeDTO, error) {
+	codeData, err := promoCodeFromDTO(code)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *PromoCodeDTO
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		before, err := s.PromoCode(ctx, code.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.checkPromoRestrictions(ctx, code.Code); err != nil {
+			return err
+		}
+
+		updated, err := s.storage.UpdatePromoCode(ctx, codeData)
+		if err != nil {
+			return err
+		}
+
+		result, err = promoCodeToDTO(updated)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, auditPromoCode, result.ID, audit.ActionUpdate, before, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeletePromoCode deletes a code that was never redeemed, the reservations
+// booked with one keep it.
+func (s *Service) DeletePromoCode(ctx context.Context, promoCodeID int) (*PromoCodeDTO, error) {
+	var result *PromoCodeDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		code, err := s.storage.DeletePromoCode(ctx, promoCodeID)
+		if err != nil {
+			return err
+		}
+
+		result, err = promoCodeToDTO(code)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, auditPromoCode, result.ID, audit.ActionDelete, result, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// PromoCodeRedemptions reports the reservations a promo code was redeemed on.
+func (s *Service) PromoCodeRedemptions(ctx context.Context, promoCodeID int) (*PromoRedemptionsDTO, error) {
+	code, err := s.storage.PromoCode(ctx, promoCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations, err := s.storage.PromoCodeRedemptions(ctx, promoCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PromoRedemptionsDTO{
+		PromoCodeID: code.ID,
+		Code:        code.Code,
+		Count:       len(reservations),
+		Discounts:   []money.Money{},
+		Redemptions: []PromoRedemptionDTO{},
+	}
+
+	totals := map[string]int64{}
+	for _, reservation := range reservations {
+		redemption := PromoRedemptionDTO{
+			ReservationID: reservation.ID,
+			HolidayID:     reservation.HolidayID,
+		}
+		if reservation.CustomerID != nil {
+			redemption.CustomerID = *reservation.CustomerID
+		}
+
+		if reservation.Promo != nil {
+			var redeemed PromoDTO
+			if err := json.Unmarshal([]byte(*reservation.Promo), &redeemed); err != nil {
+				return nil, err
+			}
+			redemption.Discount = redeemed.Discount
+			totals[redeemed.Discount.Currency] += redeemed.Discount.Amount
+		}
+
+		result.Redemptions = append(result.Redemptions, redemption)
+	}
+
+	for currency, amount := range totals {
+		result.Discounts = append(result.Discounts, money.Money{Amount: amount, Currency: currency})
+	}
+	sort.Slice(result.Discounts, func(i, j int) bool { return result.Discounts[i].Currency < result.Discounts[j].Currency })
+
+	return result, nil
+}
+
+// redeemablePromoCode looks up code as entered by a customer and checks its
+// redemption limits, the one per customer only when customerID is known.
+// Bookings call it in their transaction: the code stays locked until the
+// reservation redeeming it is stored, so concurrent bookings can not all
+// take its last redemption.
+func (s *Service) redeemablePromoCode(ctx context.Context, code string, customerID *int) (*PromoCodeDTO, error) {
+	return s.promoCodeUsage(ctx, code, customerID, true)
+}
+
+// checkPromoCode checks code like redeemablePromoCode without locking it, for
+// quotes that only show what it takes off.
+func (s *Service) checkPromoCode(ctx context.Context, code string, customerID *int) (*PromoCodeDTO, error) {
+	return s.promoCodeUsage(ctx, code, customerID, false)
+}
+
+func (s *Service) promoCodeUsage(ctx context.Context, code string, customerID *int, lock bool) (*PromoCodeDTO, error) {
+	stored, err := s.storage.PromoCodeByCode(ctx, promo.Normalize(code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w %q", ErrPromoCodeUnknown, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := promoCodeToDTO(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	if lock {
+		if err := s.storage.LockPromoCode(ctx, stored.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var usage promo.Usage
+	if usage.Redemptions, err = s.storage.PromoCodeRedemptionCount(ctx, stored.ID, nil); err != nil {
+		return nil, err
+	}
+	if customerID != nil {
+		if usage.CustomerRedemptions, err = s.storage.PromoCodeRedemptionCount(ctx, stored.ID, customerID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := result.Redeemable(usage); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// redeemPromoCode records code and what it took off on a reservation.
+func redeemPromoCode(reservation *storage.Reservation, code *PromoCodeDTO, discount money.Money) error {
+	data, err := json.Marshal(PromoDTO{Code: code.Code.Code, Discount: discount})
+	if err != nil {
+		return err
+	}
+
+	promoCodeID, redeemed := code.ID, string(data)
+	reservation.PromoCodeID = &promoCodeID
+	reservation.Promo = &redeemed
+
+	return nil
+}
+
+// convertPromo converts the discount of the promo code stored with a
+// reservation, like convertQuote.
+func convertPromo(data string, convert func(money.Money) (money.Money, error)) (json.RawMessage, error) {
+	var redeemed PromoDTO
+	if err := json.Unmarshal([]byte(data), &redeemed); err != nil {
+		return nil, err
+	}
+
+	discount, err := convert(redeemed.Discount)
+	if err != nil {
+		return nil, err
+	}
+	redeemed.Discount = discount
+
+	return json.Marshal(redeemed)
+}
+
+// checkPromoRestrictions makes sure the holidays and locations a code is
+// restricted to exist.
+func (s *Service) checkPromoRestrictions(ctx context.Context, code promo.Code) error {
+	for _, holidayID := range code.Holidays {
+		if _, err := s.storage.Holiday(ctx, holidayID); err != nil {
+			return fmt.Errorf("%w %s: holiday %d: %v", promo.ErrInvalidCode, promo.Normalize(code.Code), holidayID, err)
+		}
+	}
+	for _, locationID := range code.Locations {
+		if _, err := s.storage.Location(ctx, locationID); err != nil {
+			return fmt.Errorf("%w %s: location %d: %v", promo.ErrInvalidCode, promo.Normalize(code.Code), locationID, err)
+		}
+	}
+
+	return nil
+}
+
+func promoCodeToDTO(code *storage.PromoCode) (*PromoCodeDTO, error) {
+	result := &PromoCodeDTO{
+		ID: code.ID,
+		Code: promo.Code{
+			Code:           code.Code,
+			Description:    code.Description,
+			Percent:        code.Percent,
+			ValidFrom:      code.ValidFrom,
+			ValidTo:        code.ValidTo,
+			MaxRedemptions: code.MaxRedemptions,
+			MaxPerCustomer: code.MaxPerCustomer,
+			Holidays:       []int{},
+			Locations:      []int{},
+		},
+	}
+
+	if code.Currency != nil {
+		if code.AmountMinor != nil {
+			result.Amount = &money.Money{Amount: *code.AmountMinor, Currency: *code.Currency}
+		}
+		if code.MinSpendMinor != nil {
+			result.MinSpend = &money.Money{Amount: *code.MinSpendMinor, Currency: *code.Currency}
+		}
+	}
+
+	if err := json.Unmarshal([]byte(code.Holidays), &result.Holidays); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(code.Locations), &result.Locations); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// promoCodeFromDTO normalizes and validates code before it is stored.
+func promoCodeFromDTO(code PromoCodeDTO) (*storage.PromoCode, error) {
+	code.Code.Code = promo.Normalize(code.Code.Code)
+	code.Description = strings.TrimSpace(code.Description)
+	if code.Holidays == nil {
+		code.Holidays = []int{}
+	}
+	if code.Locations == nil {
+		code.Locations = []int{}
+	}
+	if err := code.Validate(); err != nil {
+		return nil, err
+	}
+
+	holidays, err := json.Marshal(code.Holidays)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := json.Marshal(code.Locations)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &storage.PromoCode{
+		ID:             code.ID,
+		Code:           code.Code.Code,
+		Description:    code.Description,
+		Percent:        code.Percent,
+		ValidFrom:      utc(code.ValidFrom),
+		ValidTo:        utc(code.ValidTo),
+		MaxRedemptions: code.MaxRedemptions,
+		MaxPerCustomer: code.MaxPerCustomer,
+		Holidays:       string(holidays),
+		Locations:      string(locations),
+	}
+
+	if code.Amount != nil {
+		result.AmountMinor = &code.Amount.Amount
+		result.Currency = &code.Amount.Currency
+	}
+	if code.MinSpend != nil {
+		result.MinSpendMinor = &code.MinSpend.Amount
+		result.Currency = &code.MinSpend.Currency
+	}
+
+	return result, nil
+}
+
+// utc stores the validity of codes as UTC to the second, a DATETIME keeps no
+// zone.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	value := t.UTC().Truncate(time.Second)
+	return &value
+}
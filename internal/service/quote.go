@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"travel/internal/money"
+	"travel/internal/pricing"
+	"travel/internal/promo"
+	"travel/internal/quote"
+	"travel/internal/storage"
+	"travel/internal/tenant"
+)
+
+var (
+	ErrQuoteNotEnoughSlots = errors.New("the holiday has not enough free slots for the party")
+	ErrQuoteMismatch       = errors.New("the quote is for another holiday")
+	ErrQuoteBooked         = errors.New("the quote is booked already")
+	ErrQuotePromoCode      = errors.New("the promo code of the quote takes nothing off")
+)
+
+// SetQuotes sets the signer of quotes, one with a random secret and
+// quote.DefaultTTL unless set.
+func (s *Service) SetQuotes(signer *quote.Signer) {
+	s.quotes = signer
+}
+
+// Quote prices a booking of a holiday made now by the party of request, less
+// its promo code. The token of the quote books the holiday at the quoted
+// price until the quote expires.
+func (s *Service) Quote(ctx context.Context, holidayID int, request QuoteRequestDTO) (*QuoteDTO, error) {
+	holiday, err := s.storage.Holiday(ctx, holidayID)
+	if err != nil {
+		return nil, err
+	}
+
+	party := pricing.Party{Adults: request.Adults, Children: request.Children}
+	if party.Children == nil {
+		party.Children = []pricing.Child{}
+	}
+	if err := party.Validate(); err != nil {
+		return nil, err
+	}
+	if party.Travellers() > holiday.FreeSlots {
+		return nil, fmt.Errorf("%w: %d travel, %d slots are free", ErrQuoteNotEnoughSlots, party.Travellers(), holiday.FreeSlots)
+	}
+
+	extras := []string{}
+	for _, extra := range request.Extras {
+		extras = append(extras, strings.TrimSpace(extra))
+	}
+
+	rules, err := s.pricingRules(ctx, holiday)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	perTraveller := evaluate(rules, holiday, now)
+	breakdown, err := pricing.Itemize(rules, perTraveller.Price, party, extras, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the redemptions of the customer are checked once the quote is booked,
+	// the code is locked then
+	var code string
+	if strings.TrimSpace(request.PromoCode) != "" {
+		promoCode, err := s.checkPromoCode(ctx, request.PromoCode, nil)
+		if err != nil {
+			return nil, err
+		}
+		code = promoCode.Code.Code
+
+		discount, err := promoCode.Discount(promo.Booking{
+			HolidayID:  holiday.ID,
+			LocationID: holiday.LocationID,
+			Spend:      breakdown.Subtotal(),
+			Date:       now,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		breakdown, err = pricing.Itemize(rules, perTraveller.Price, party, extras, &pricing.Discount{
+			Name:    code,
+			Percent: -promoCode.Percent,
+			Amount:  discount,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	signed, token, err := s.quotes.Sign(quote.Quote{
+		TenantID:  tenantID,
+		HolidayID: holiday.ID,
+		Party:     party,
+		Extras:    extras,
+		PromoCode: code,
+		Pricing:   perTraveller,
+		Breakdown: breakdown,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := quoteToDTO(&signed)
+	result.Token = token
+
+	return result, nil
+}
+
+// verifyQuote verifies the quote token of reservation. A reservation without
+// a holiday is booked for the one of the quote.
+func (s *Service) verifyQuote(ctx context.Context, reservation *ReservationDTO) (*quote.Quote, error) {
+	q, err := s.quotes.Verify(reservation.QuoteToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID, _ := tenant.FromContext(ctx)
+	if q.TenantID != tenantID {
+		return nil, quote.ErrInvalidSignature
+	}
+	if reservation.HolidayID == 0 {
+		reservation.HolidayID = q.HolidayID
+	}
+	if reservation.HolidayID != q.HolidayID {
+		return nil, ErrQuoteMismatch
+	}
+
+	return q, nil
+}
+
+// bookQuote books a reservation at the price of q and redeems the promo code
+// of q on it, with the discount it was quoted with. A quote books one
+// reservation.
+func (s *Service) bookQuote(ctx context.Context, reservation *storage.Reservation, q *quote.Quote) error {
+	booked, err := s.storage.QuoteBooked(ctx, q.ID)
+	if err != nil {
+		return err
+	}
+	if booked {
+		return ErrQuoteBooked
+	}
+
+	pricingData, err := json.Marshal(q.Pricing)
+	if err != nil {
+		return err
+	}
+	quoteData, err := json.Marshal(quoteToDTO(q))
+	if err != nil {
+		return err
+	}
+
+	pricingJSON, quoteJSON, quoteID := string(pricingData), string(quoteData), q.ID
+	reservation.Pricing, reservation.Quote, reservation.QuoteID = &pricingJSON, &quoteJSON, &quoteID
+
+	if q.PromoCode == "" {
+		return nil
+	}
+
+	promoCode, err := s.redeemablePromoCode(ctx, q.PromoCode, reservation.CustomerID)
+	if err != nil {
+		return err
+	}
+	for _, item := range q.Items {
+		if item.Kind == pricing.KindPromo {
+			return redeemPromoCode(reservation, promoCode, item.Amount)
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrQuotePromoCode, q.PromoCode)
+}
+
+// convertBookedQuote converts the amounts of the quote stored with a
+// reservation, like convertQuote.
+func convertBookedQuote(data string, convert func(money.Money) (money.Money, error)) (json.RawMessage, error) {
+	var booked QuoteDTO
+	if err := json.Unmarshal([]byte(data), &booked); err != nil {
+		return nil, err
+	}
+
+	if err := convertQuote(&booked.Pricing, convert); err != nil {
+		return nil, err
+	}
+	for i := range booked.Items {
+		item := &booked.Items[i]
+		var err error
+		if item.UnitPrice, err = convert(item.UnitPrice); err != nil {
+			return nil, err
+		}
+		if item.Amount, err = convert(item.Amount); err != nil {
+			return nil, err
+		}
+	}
+	total, err := convert(booked.Total)
+	if err != nil {
+		return nil, err
+	}
+	booked.Total = total
+
+	return json.Marshal(booked)
+}
+
+func quoteToDTO(q *quote.Quote) *QuoteDTO {
+	return &QuoteDTO{
+		ID:        q.ID,
+		HolidayID: q.HolidayID,
+		Party:     q.Party,
+		Extras:    q.Extras,
+		PromoCode: q.PromoCode,
+		Pricing:   q.Pricing,
+		Items:     q.Items,
+		Total:     q.Total,
+		ExpiresAt: q.ExpiresAt,
+	}
+}
@@ -1,109 +1,379 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
+	"travel/internal/audit"
+	"travel/internal/exchange"
+	"travel/internal/money"
+	"travel/internal/payment"
+	"travel/internal/phone"
+	"travel/internal/pricing"
+	"travel/internal/promo"
+	"travel/internal/quote"
 	"travel/internal/storage"
 )
 
 type Storage interface {
 	//reservation
-	ReservationGetAll() (interface{}, error)
-	Reservation(reservationID int) (*storage.Reservation, error)
-	InsertReservation(reservation *storage.Reservation) (int64, error)
-	UpdateReservation(reservation *storage.Reservation) (*storage.Reservation, error)
-	DeleteReservation(reservationID int) (*storage.Reservation, error)
+	ReservationGetAll(ctx context.Context) (interface{}, error)
+	ReservationEach(ctx context.Context, fn func(storage.ReservationResult) error) error
+	Reservation(ctx context.Context, reservationID int) (*storage.Reservation, error)
+	InsertReservation(ctx context.Context, reservation *storage.Reservation) (int64, error)
+	UpdateReservation(ctx context.Context, reservation *storage.Reservation) (*storage.Reservation, error)
+	DeleteReservation(ctx context.Context, reservationID int) (*storage.Reservation, error)
+	LockReservation(ctx context.Context, reservationID int) error
+	QuoteBooked(ctx context.Context, quoteID string) (bool, error)
+
+	//customer
+	CustomerGetAll(ctx context.Context) ([]storage.Customer, error)
+	Customer(ctx context.Context, customerID int) (*storage.Customer, error)
+	CustomerByContact(ctx context.Context, normalizedPhone string, email string) (*storage.Customer, error)
+	InsertCustomer(ctx context.Context, customer *storage.Customer) (int64, error)
+	UpdateCustomer(ctx context.Context, customer *storage.Customer) (*storage.Customer, error)
+	DeleteCustomer(ctx context.Context, customerID int) (*storage.Customer, error)
+	CustomerReservations(ctx context.Context, customerID int) ([]storage.ReservationResult, error)
+
+	//api key
+	APIKeyGetAll(ctx context.Context) ([]storage.APIKey, error)
+	APIKey(ctx context.Context, keyID int) (*storage.APIKey, error)
+	APIKeyByPrefix(ctx context.Context, prefix string) (*storage.APIKey, error)
+	InsertAPIKey(ctx context.Context, key *storage.APIKey) (int64, error)
+	RevokeAPIKey(ctx context.Context, keyID int, revokedAt time.Time) error
+	TouchAPIKey(ctx context.Context, keyID int, usedAt time.Time) error
+	RoleExists(ctx context.Context, role string) (bool, error)
 
 	//location
-	LocationGetAll() ([]storage.Location, error)
-	Location(locationID int) (*storage.Location, error)
-	InsertLocation(location *storage.Location) (int64, error)
-	UpdateLocation(location *storage.Location) (*storage.Location, error)
-	DeleteLocation(locationID int) (*storage.Location, error)
+	LocationGetAll(ctx context.Context) ([]storage.Location, error)
+	Location(ctx context.Context, locationID int) (*storage.Location, error)
+	InsertLocation(ctx context.Context, location *storage.Location) (int64, error)
+	UpdateLocation(ctx context.Context, location *storage.Location) (*storage.Location, error)
+	DeleteLocation(ctx context.Context, locationID int) (*storage.Location, error)
 
 	//holiday
-	HolidaysGetAll(location string, duration int, startDate time.Time) ([]storage.HolidayWithLocation, error)
-	Holiday(holidaysID int) (*storage.Holiday, error)
-	InsertHolidays(holidays *storage.Holiday) (int64, error)
-	UpdateHolidays(holidays *storage.Holiday) (*storage.Holiday, error)
-	DeleteHolidays(holidaysID int) (*storage.Holiday, error)
+	HolidaysGetAll(ctx context.Context, location string, duration int, startDate time.Time) ([]storage.HolidayWithLocation, error)
+	HolidaysEach(ctx context.Context, location string, duration int, startDate time.Time, fn func(storage.HolidayWithLocation) error) error
+	Holiday(ctx context.Context, holidaysID int) (*storage.Holiday, error)
+	InsertHolidays(ctx context.Context, holidays *storage.Holiday) (int64, error)
+	UpdateHolidays(ctx context.Context, holidays *storage.Holiday) (*storage.Holiday, error)
+	DeleteHolidays(ctx context.Context, holidaysID int) (*storage.Holiday, error)
+
+	//pricing
+	PricingRuleSetGetAll(ctx context.Context) ([]storage.PricingRuleSet, error)
+	PricingRuleSet(ctx context.Context, ruleSetID int) (*storage.PricingRuleSet, error)
+	InsertPricingRuleSet(ctx context.Context, ruleSet *storage.PricingRuleSet) (int64, error)
+	UpdatePricingRuleSet(ctx context.Context, ruleSet *storage.PricingRuleSet) (*storage.PricingRuleSet, error)
+	DeletePricingRuleSet(ctx context.Context, ruleSetID int) (*storage.PricingRuleSet, error)
+
+	//exchange rate
+	ExchangeRateGetAll(ctx context.Context) ([]storage.ExchangeRate, error)
+	ExchangeRate(ctx context.Context, currency string) (*storage.ExchangeRate, error)
+	SaveExchangeRate(ctx context.Context, rate *storage.ExchangeRate) (*storage.ExchangeRate, error)
+	DeleteExchangeRate(ctx context.Context, currency string) (*storage.ExchangeRate, error)
+
+	//promo code
+	PromoCodeGetAll(ctx context.Context) ([]storage.PromoCode, error)
+	PromoCode(ctx context.Context, promoCodeID int) (*storage.PromoCode, error)
+	PromoCodeByCode(ctx context.Context, code string) (*storage.PromoCode, error)
+	InsertPromoCode(ctx context.Context, code *storage.PromoCode) (int64, error)
+	UpdatePromoCode(ctx context.Context, code *storage.PromoCode) (*storage.PromoCode, error)
+	DeletePromoCode(ctx context.Context, promoCodeID int) (*storage.PromoCode, error)
+	PromoCodeRedemptions(ctx context.Context, promoCodeID int) ([]storage.Reservation, error)
+	LockPromoCode(ctx context.Context, promoCodeID int) error
+	PromoCodeRedemptionCount(ctx context.Context, promoCodeID int, customerID *int) (int, error)
+
+	//payment
+	ReservationPayments(ctx context.Context, reservationID int) ([]storage.Payment, error)
+	Payment(ctx context.Context, paymentID int) (*storage.Payment, error)
+	InsertPayment(ctx context.Context, payment *storage.Payment) (int64, error)
+	UpdatePayment(ctx context.Context, payment *storage.Payment) (*storage.Payment, error)
+	LockPayment(ctx context.Context, paymentID int) error
+
+	//invoice
+	Agency(ctx context.Context, agencyID int) (*storage.Agency, error)
+	ReservationInvoices(ctx context.Context, reservationID int) ([]storage.Invoice, error)
+	Invoice(ctx context.Context, invoiceID int) (*storage.Invoice, error)
+	NextInvoiceSequence(ctx context.Context, kind string) (int, error)
+	InsertInvoice(ctx context.Context, invoice *storage.Invoice) (int64, error)
+
+	//audit
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+	InsertAuditEntry(ctx context.Context, entry *storage.AuditEntry) error
+	AuditEntries(ctx context.Context, resource string, resourceID int) ([]storage.AuditEntry, error)
 }
 
 type Service struct {
-	storage Storage
+	storage     Storage
+	phoneRegion string
+	rounding    exchange.Rounding
+	quotes      *quote.Signer
+	payments    payments
+	logger      *slog.Logger
 }
 
-func New(storage Storage) *Service {
-	return &Service{storage: storage}
+func New(storage Storage, logger *slog.Logger) *Service {
+	return &Service{
+		storage:     storage,
+		phoneRegion: defaultPhoneRegion,
+		rounding:    exchange.DefaultRounding,
+		quotes:      quote.NewSigner(nil, quote.DefaultTTL),
+		payments:    payments{schedule: payment.DefaultSchedule, intentTTL: payment.DefaultIntentTTL},
+		logger:      logger,
+	}
 }
 
-func (s *Service) ReservationGetAll() (interface{}, error) {
-	reservations, err := s.storage.ReservationGetAll()
+// ReservationGetAll returns every reservation, with the prices of the
+// holidays in currency at the current exchange rates unless it is empty.
+func (s *Service) ReservationGetAll(ctx context.Context, currency string) (interface{}, error) {
+	reservations, err := s.storage.ReservationGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	convert, err := s.currentConverter(ctx, currency)
 	if err != nil {
 		return nil, err
 	}
+	if results, ok := reservations.([]storage.ReservationResult); ok && convert != nil {
+		if err := convertReservations(results, convert); err != nil {
+			return nil, err
+		}
+	}
 
 	return reservations, nil
 }
 
-func (s *Service) Reservation(reservationID int) (*ReservationDTO, error) {
-	reservation, err := s.storage.Reservation(reservationID)
+// ExportReservations streams every reservation with its holiday and location to fn.
+func (s *Service) ExportReservations(ctx context.Context, fn func(storage.ReservationResult) error) error {
+	return s.storage.ReservationEach(ctx, fn)
+}
+
+var ErrPricingNotConvertible = errors.New("the pricing of the reservation can not be converted")
+
+// Reservation returns a reservation with the quote it was booked at, in
+// currency unless it is empty. The quote is converted with the exchange
+// rates of the day of booking, the current ones for reservations booked
+// while the agency had none.
+func (s *Service) Reservation(ctx context.Context, reservationID int, currency string) (*ReservationDTO, error) {
+	reservation, err := s.storage.Reservation(ctx, reservationID)
 	if err != nil {
 		return nil, err
 	}
 
-	result := &ReservationDTO{
-		ID:          reservation.ID,
-		ContactName: reservation.ContactName,
-		PhoneNumber: reservation.PhoneNumber,
-		HolidayID:   reservation.HolidayID,
+	result := s.reservationToDTO(reservation)
+	if strings.TrimSpace(currency) == "" || reservation.Pricing == nil {
+		return result, nil
 	}
 
-	return result, nil
+	var rates *exchange.Rates
+	if reservation.ExchangeRates != nil {
+		if err := json.Unmarshal([]byte(*reservation.ExchangeRates), &rates); err != nil {
+			return nil, err
+		}
+	} else if rates, err = s.exchangeRates(ctx); err != nil {
+		return nil, err
+	}
 
-}
+	// quotes stored before prices had currencies hold plain numbers
+	var quote pricing.Quote
+	if err := json.Unmarshal([]byte(*reservation.Pricing), &quote); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPricingNotConvertible, err)
+	}
+	if err := convertQuote(&quote, s.converter(rates, currency)); err != nil {
+		return nil, err
+	}
+	if result.Pricing, err = json.Marshal(quote); err != nil {
+		return nil, err
+	}
+	if reservation.Quote != nil {
+		if result.Quote, err = convertBookedQuote(*reservation.Quote, s.converter(rates, currency)); err != nil {
+			return nil, err
+		}
+	}
+	if reservation.Promo != nil {
+		if result.Promo, err = convertPromo(*reservation.Promo, s.converter(rates, currency)); err != nil {
+			return nil, err
+		}
+	}
 
-func (s *Service) InsertReservation(reservation ReservationDTO) (int64, error) {
+	return result, nil
+}
 
-	reservationData := &storage.Reservation{
-		ID:          reservation.ID,
-		ContactName: reservation.ContactName,
-		PhoneNumber: reservation.PhoneNumber,
-		HolidayID:   reservation.HolidayID,
+func (s *Service) InsertReservation(ctx context.Context, reservation ReservationDTO) (int64, error) {
+	number, err := phone.Parse(reservation.PhoneNumber, s.phoneRegion)
+	if err != nil {
+		return 0, err
 	}
 
-	return s.storage.InsertReservation(reservationData)
-}
+	// a quote locks in the price and the promo code it was quoted with,
+	// without one the holiday is priced as booked now
+	var quoted *quote.Quote
+	if reservation.QuoteToken != "" {
+		if quoted, err = s.verifyQuote(ctx, &reservation); err != nil {
+			return 0, err
+		}
+	}
 
-func (s *Service) UpdateReservation(reservation ReservationDTO) (*ReservationDTO, error) {
-	reservationData := &storage.Reservation{
-		ID:          reservation.ID,
-		ContactName: reservation.ContactName,
-		PhoneNumber: reservation.PhoneNumber,
-		HolidayID:   reservation.HolidayID,
+	var id int64
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		customerID, err := s.resolveCustomer(ctx, reservation, number)
+		if err != nil {
+			return err
+		}
+
+		reservationData := &storage.Reservation{
+			ID:          reservation.ID,
+			ContactName: reservation.ContactName,
+			PhoneNumber: reservation.PhoneNumber,
+			HolidayID:   reservation.HolidayID,
+			CustomerID:  &customerID,
+			PhoneE164:   number.E164(),
+		}
+
+		if quoted != nil {
+			err = s.bookQuote(ctx, reservationData, quoted)
+		} else {
+			err = s.bookNow(ctx, reservationData, reservation.PromoCode)
+		}
+		if err != nil {
+			return err
+		}
+		if reservationData.ExchangeRates, err = s.reservationExchangeRates(ctx); err != nil {
+			return err
+		}
+
+		id, err = s.storage.InsertReservation(ctx, reservationData)
+		if err != nil {
+			return err
+		}
+		reservationData.ID = int(id)
+
+		return s.record(ctx, auditReservation, reservationData.ID, audit.ActionCreate, nil, s.reservationToDTO(reservationData))
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	updatedReservation, err := s.storage.UpdateReservation(reservationData)
+	s.logger.InfoContext(ctx, "reservation created", "id", id, "holiday", reservation.HolidayID, "phone", number.E164())
+
+	return id, nil
+}
+
+func (s *Service) UpdateReservation(ctx context.Context, reservation ReservationDTO) (*ReservationDTO, error) {
+	number, err := phone.Parse(reservation.PhoneNumber, s.phoneRegion)
 	if err != nil {
 		return nil, err
 	}
 
-	reservation = ReservationDTO{
-		ID:          updatedReservation.ID,
-		ContactName: updatedReservation.ContactName,
-		PhoneNumber: updatedReservation.PhoneNumber,
-		HolidayID:   updatedReservation.HolidayID,
+	var result *ReservationDTO
+	err = s.storage.InTx(ctx, func(ctx context.Context) error {
+		stored, err := s.storage.Reservation(ctx, reservation.ID)
+		if err != nil {
+			return err
+		}
+		before := s.reservationToDTO(stored)
+
+		customerID, err := s.resolveCustomer(ctx, reservation, number)
+		if err != nil {
+			return err
+		}
+
+		reservationData := &storage.Reservation{
+			ID:            reservation.ID,
+			ContactName:   reservation.ContactName,
+			PhoneNumber:   reservation.PhoneNumber,
+			HolidayID:     reservation.HolidayID,
+			CustomerID:    &customerID,
+			PhoneE164:     number.E164(),
+			Pricing:       stored.Pricing,
+			ExchangeRates: stored.ExchangeRates,
+			Quote:         stored.Quote,
+			PromoCodeID:   stored.PromoCodeID,
+			Promo:         stored.Promo,
+			QuoteID:       stored.QuoteID,
+		}
+
+		// the booked price, quote, promo code and exchange rates stay unless
+		// the reservation moves to another holiday, which is priced as
+		// booked today without them. The quote ID stays: the quote was used.
+		if reservation.HolidayID != stored.HolidayID {
+			reservationData.Quote, reservationData.PromoCodeID, reservationData.Promo = nil, nil, nil
+			// the invoice of the old holiday is cancelled, the next one is
+			// issued at the new price
+			if err := s.creditInvoice(ctx, reservation.ID); err != nil {
+				return err
+			}
+			if err := s.bookNow(ctx, reservationData, ""); err != nil {
+				return err
+			}
+			if reservationData.ExchangeRates, err = s.reservationExchangeRates(ctx); err != nil {
+				return err
+			}
+		}
+
+		updatedReservation, err := s.storage.UpdateReservation(ctx, reservationData)
+		if err != nil {
+			return err
+		}
+
+		result = s.reservationToDTO(updatedReservation)
+
+		return s.record(ctx, auditReservation, result.ID, audit.ActionUpdate, before, result)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return &reservation, nil
+	return result, nil
 }
 
-func (s *Service) DeleteReservation(reservationID int) (*ReservationDTO, error) {
-	reservation, err := s.storage.DeleteReservation(reservationID)
+// DeleteReservation cancels a reservation. Reservations are cancelled once
+// nothing is collected for them: while a payment is pending or not refunded
+// the agency still holds, or may still get, the customer's money.
+func (s *Service) DeleteReservation(ctx context.Context, reservationID int) (*ReservationDTO, error) {
+	if s.payments.gateway != nil {
+		if err := s.expireIntents(ctx, reservationID); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *ReservationDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		if err := s.storage.LockReservation(ctx, reservationID); err != nil {
+			return err
+		}
+		if err := s.checkCancellable(ctx, reservationID); err != nil {
+			return err
+		}
+
+		// a cancelled reservation keeps its invoices, the current one with
+		// the credit note cancelling it
+		if err := s.creditInvoice(ctx, reservationID); err != nil {
+			return err
+		}
+
+		reservation, err := s.storage.DeleteReservation(ctx, reservationID)
+		if err != nil {
+			return err
+		}
+
+		result = s.reservationToDTO(reservation)
+
+		return s.record(ctx, auditReservation, result.ID, audit.ActionDelete, result, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return result, nil
+}
+
+func (s *Service) reservationToDTO(reservation *storage.Reservation) *ReservationDTO {
 	result := &ReservationDTO{
 		ID:          reservation.ID,
 		ContactName: reservation.ContactName,
@@ -111,11 +381,84 @@ func (s *Service) DeleteReservation(reservationID int) (*ReservationDTO, error)
 		HolidayID:   reservation.HolidayID,
 	}
 
-	return result, nil
+	if reservation.CustomerID != nil {
+		result.CustomerID = *reservation.CustomerID
+	}
+
+	result.Phone = s.phoneToDTO(reservation.PhoneNumber, reservation.PhoneE164)
+
+	if reservation.Pricing != nil {
+		result.Pricing = json.RawMessage(*reservation.Pricing)
+	}
+	if reservation.ExchangeRates != nil {
+		result.ExchangeRates = json.RawMessage(*reservation.ExchangeRates)
+	}
+	if reservation.Quote != nil {
+		result.Quote = json.RawMessage(*reservation.Quote)
+	}
+	if reservation.Promo != nil {
+		result.Promo = json.RawMessage(*reservation.Promo)
+	}
+
+	return result
 }
 
-func (s *Service) LocationGetAll() ([]LocationDTO, error) {
-	locations, err := s.storage.LocationGetAll()
+// bookNow prices a reservation as booked now and redeems code on it unless
+// it is empty. The discount of the code is an adjustment of the price.
+func (s *Service) bookNow(ctx context.Context, reservation *storage.Reservation, code string) error {
+	holiday, err := s.storage.Holiday(ctx, reservation.HolidayID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	priced, err := s.quote(ctx, holiday, now)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(code) != "" {
+		promoCode, err := s.redeemablePromoCode(ctx, code, reservation.CustomerID)
+		if err != nil {
+			return err
+		}
+
+		discount, err := promoCode.Discount(promo.Booking{
+			HolidayID:  holiday.ID,
+			LocationID: holiday.LocationID,
+			Spend:      priced.Price,
+			Date:       now,
+		})
+		if err != nil {
+			return err
+		}
+
+		priced.Adjustments = append(priced.Adjustments, pricing.Adjustment{
+			Rule:    promoCode.Code.Code,
+			Kind:    pricing.KindPromo,
+			Percent: -promoCode.Percent,
+			Amount:  discount,
+		})
+		priced.Price.Amount += discount.Amount
+
+		if err := redeemPromoCode(reservation, promoCode, discount); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(priced)
+	if err != nil {
+		return err
+	}
+
+	pricingJSON := string(data)
+	reservation.Pricing = &pricingJSON
+
+	return nil
+}
+
+func (s *Service) LocationGetAll(ctx context.Context) ([]LocationDTO, error) {
+	locations, err := s.storage.LocationGetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -134,8 +477,8 @@ func (s *Service) LocationGetAll() ([]LocationDTO, error) {
 	return result, nil
 }
 
-func (s *Service) Location(locationID int) (*LocationDTO, error) {
-	location, err := s.storage.Location(locationID)
+func (s *Service) Location(ctx context.Context, locationID int) (*LocationDTO, error) {
+	location, err := s.storage.Location(ctx, locationID)
 	if err != nil {
 		return nil, err
 	}
@@ -152,7 +495,7 @@ func (s *Service) Location(locationID int) (*LocationDTO, error) {
 
 }
 
-func (s *Service) InsertLocation(location LocationDTO) (int64, error) {
+func (s *Service) InsertLocation(ctx context.Context, location LocationDTO) (int64, error) {
 
 	locationData := &storage.Location{
 		ID:      location.ID,
@@ -162,10 +505,25 @@ func (s *Service) InsertLocation(location LocationDTO) (int64, error) {
 		Country: location.Country,
 	}
 
-	return s.storage.InsertLocation(locationData)
+	var id int64
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.storage.InsertLocation(ctx, locationData)
+		if err != nil {
+			return err
+		}
+		location.ID = int(id)
+
+		return s.record(ctx, auditLocation, location.ID, audit.ActionCreate, nil, location)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
 }
 
-func (s *Service) UpdateLocation(location LocationDTO) (*LocationDTO, error) {
+func (s *Service) UpdateLocation(ctx context.Context, location LocationDTO) (*LocationDTO, error) {
 	reservationData := &storage.Location{
 		ID:      location.ID,
 		Street:  location.Street,
@@ -174,128 +532,256 @@ func (s *Service) UpdateLocation(location LocationDTO) (*LocationDTO, error) {
 		Country: location.Country,
 	}
 
-	updatedReservation, err := s.storage.UpdateLocation(reservationData)
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		before, err := s.Location(ctx, location.ID)
+		if err != nil {
+			return err
+		}
+
+		updatedReservation, err := s.storage.UpdateLocation(ctx, reservationData)
+		if err != nil {
+			return err
+		}
+
+		location = LocationDTO{
+			ID:      updatedReservation.ID,
+			Street:  updatedReservation.Street,
+			Number:  updatedReservation.Number,
+			City:    updatedReservation.City,
+			Country: updatedReservation.Country,
+		}
+
+		return s.record(ctx, auditLocation, location.ID, audit.ActionUpdate, before, location)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	location = LocationDTO{
-		ID:      updatedReservation.ID,
-		Street:  updatedReservation.Street,
-		Number:  updatedReservation.Number,
-		City:    updatedReservation.City,
-		Country: updatedReservation.Country,
-	}
-
 	return &location, nil
 }
 
-func (s *Service) DeleteLocation(locationID int) (*LocationDTO, error) {
-	location, err := s.storage.DeleteLocation(locationID)
+func (s *Service) DeleteLocation(ctx context.Context, locationID int) (*LocationDTO, error) {
+	var result *LocationDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		location, err := s.storage.DeleteLocation(ctx, locationID)
+		if err != nil {
+			return err
+		}
+
+		result = &LocationDTO{
+			ID:      location.ID,
+			Street:  location.Street,
+			Number:  location.Number,
+			City:    location.City,
+			Country: location.Country,
+		}
+
+		return s.record(ctx, auditLocation, result.ID, audit.ActionDelete, result, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	result := &LocationDTO{
-		ID:      location.ID,
-		Street:  location.Street,
-		Number:  location.Number,
-		City:    location.City,
-		Country: location.Country,
-	}
-
 	return result, nil
 }
 
-func (s *Service) HolidayGetAll(filterHolidays FilterHolidays) (interface{}, error) {
-	holidays, err := s.storage.HolidaysGetAll(filterHolidays.Location, filterHolidays.Duration, filterHolidays.StartDate)
+func (s *Service) HolidayGetAll(ctx context.Context, filterHolidays FilterHolidays) (interface{}, error) {
+	holidays, err := s.storage.HolidaysGetAll(ctx, filterHolidays.Location, filterHolidays.Duration, filterHolidays.StartDate)
+	if err != nil {
+		return nil, err
+	}
+
+	convert, err := s.currentConverter(ctx, filterHolidays.Currency)
 	if err != nil {
 		return nil, err
 	}
+	for i := 0; convert != nil && i < len(holidays); i++ {
+		if holidays[i].Price, err = convert(holidays[i].Price); err != nil {
+			return nil, err
+		}
+	}
 
 	return holidays, nil
 }
 
-func (s *Service) Holiday(holidayID int) (*HolidayDTO, error) {
-	holiday, err := s.storage.Holiday(holidayID)
+// ExportHolidays streams the holidays matching filterHolidays to fn.
+func (s *Service) ExportHolidays(ctx context.Context, filterHolidays FilterHolidays, fn func(storage.HolidayWithLocation) error) error {
+	return s.storage.HolidaysEach(ctx, filterHolidays.Location, filterHolidays.Duration, filterHolidays.StartDate, fn)
+}
+
+// Holiday returns a holiday with its price for a booking made today, in
+// currency at the current exchange rates unless it is empty.
+func (s *Service) Holiday(ctx context.Context, holidayID int, currency string) (*HolidayDTO, error) {
+	holiday, err := s.storage.Holiday(ctx, holidayID)
 	if err != nil {
 		return nil, err
 	}
 
-	result := &HolidayDTO{
-		ID:         holiday.ID,
-		Title:      holiday.Title,
-		StartDate:  holiday.StartDate,
-		Duration:   holiday.Duration,
-		Price:      holiday.Price,
-		FreeSlots:  holiday.FreeSlots,
-		LocationID: holiday.LocationID,
+	result := holidayToDTO(holiday)
+
+	result.Pricing, err = s.quote(ctx, holiday, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	convert, err := s.currentConverter(ctx, currency)
+	if err != nil || convert == nil {
+		return result, err
+	}
+	if result.Price, err = convert(result.Price); err != nil {
+		return nil, err
+	}
+	if err := convertQuote(result.Pricing, convert); err != nil {
+		return nil, err
 	}
 
 	return result, nil
+}
 
+// DefaultCurrency is the currency of prices given without one.
+const DefaultCurrency = "EUR"
+
+var ErrHolidayPriceNegative = errors.New("holiday price can not be negative")
+
+// validatePrice checks the price of a holiday before it is stored.
+func validatePrice(price money.Money) error {
+	if err := price.Validate(); err != nil {
+		return err
+	}
+	if price.Amount < 0 {
+		return ErrHolidayPriceNegative
+	}
+
+	return nil
 }
 
-func (s *Service) InsertHoliday(holiday HolidayDTO) (int64, error) {
+func (s *Service) InsertHoliday(ctx context.Context, holiday HolidayDTO) (int64, error) {
+	if err := validatePrice(holiday.Price); err != nil {
+		return 0, err
+	}
 
 	holidayData := &storage.Holiday{
-		Title:      holiday.Title,
-		StartDate:  holiday.StartDate,
-		Duration:   holiday.Duration,
-		Price:      holiday.Price,
-		FreeSlots:  holiday.FreeSlots,
-		LocationID: holiday.LocationID,
+		Title:            holiday.Title,
+		StartDate:        holiday.StartDate,
+		Duration:         holiday.Duration,
+		PriceMinor:       holiday.Price.Amount,
+		Currency:         holiday.Price.Currency,
+		FreeSlots:        holiday.FreeSlots,
+		LocationID:       holiday.LocationID,
+		PricingRuleSetID: optionalID(holiday.PricingRuleSetID),
+	}
+
+	var id int64
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.storage.InsertHolidays(ctx, holidayData)
+		if err != nil {
+			return err
+		}
+		holiday.ID = int(id)
+
+		return s.record(ctx, auditHoliday, holiday.ID, audit.ActionCreate, nil, holiday)
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	fmt.Printf("holidayData: %v\n", holidayData)
+	s.logger.InfoContext(ctx, "holiday created", "id", id, "location", holiday.LocationID)
 
-	return s.storage.InsertHolidays(holidayData)
+	return id, nil
 }
 
-func (s *Service) UpdateHoliday(holiday HolidayDTO) (*HolidayDTO, error) {
+func (s *Service) UpdateHoliday(ctx context.Context, holiday HolidayDTO) (*HolidayDTO, error) {
+	if err := validatePrice(holiday.Price); err != nil {
+		return nil, err
+	}
+
 	reservationData := &storage.Holiday{
-		ID:         holiday.ID,
-		Title:      holiday.Title,
-		StartDate:  holiday.StartDate,
-		Duration:   holiday.Duration,
-		Price:      holiday.Price,
-		FreeSlots:  holiday.FreeSlots,
-		LocationID: holiday.LocationID,
+		ID:               holiday.ID,
+		Title:            holiday.Title,
+		StartDate:        holiday.StartDate,
+		Duration:         holiday.Duration,
+		PriceMinor:       holiday.Price.Amount,
+		Currency:         holiday.Price.Currency,
+		FreeSlots:        holiday.FreeSlots,
+		LocationID:       holiday.LocationID,
+		PricingRuleSetID: optionalID(holiday.PricingRuleSetID),
 	}
 
-	updatedReservation, err := s.storage.UpdateHolidays(reservationData)
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		stored, err := s.storage.Holiday(ctx, holiday.ID)
+		if err != nil {
+			return err
+		}
+		before := holidayToDTO(stored)
+
+		updatedReservation, err := s.storage.UpdateHolidays(ctx, reservationData)
+		if err != nil {
+			return err
+		}
+
+		holiday = HolidayDTO{
+			ID:               updatedReservation.ID,
+			Title:            holiday.Title,
+			StartDate:        holiday.StartDate,
+			Duration:         holiday.Duration,
+			Price:            holiday.Price,
+			FreeSlots:        holiday.FreeSlots,
+			LocationID:       holiday.LocationID,
+			PricingRuleSetID: holiday.PricingRuleSetID,
+		}
+
+		return s.record(ctx, auditHoliday, holiday.ID, audit.ActionUpdate, before, holiday)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	holiday = HolidayDTO{
-		ID:         updatedReservation.ID,
-		Title:      holiday.Title,
-		StartDate:  holiday.StartDate,
-		Duration:   holiday.Duration,
-		Price:      holiday.Price,
-		FreeSlots:  holiday.FreeSlots,
-		LocationID: holiday.LocationID,
-	}
-
 	return &holiday, nil
 }
 
-func (s *Service) DeleteHoliday(holidayID int) (*HolidayDTO, error) {
-	holiday, err := s.storage.DeleteHolidays(holidayID)
+func (s *Service) DeleteHoliday(ctx context.Context, holidayID int) (*HolidayDTO, error) {
+	var result *HolidayDTO
+	err := s.storage.InTx(ctx, func(ctx context.Context) error {
+		holiday, err := s.storage.DeleteHolidays(ctx, holidayID)
+		if err != nil {
+			return err
+		}
+
+		result = holidayToDTO(holiday)
+
+		return s.record(ctx, auditHoliday, result.ID, audit.ActionDelete, result, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return result, nil
+}
+
+func holidayToDTO(holiday *storage.Holiday) *HolidayDTO {
 	result := &HolidayDTO{
 		ID:         holiday.ID,
 		Title:      holiday.Title,
 		StartDate:  holiday.StartDate,
 		Duration:   holiday.Duration,
-		Price:      holiday.Price,
+		Price:      holiday.Price(),
 		FreeSlots:  holiday.FreeSlots,
 		LocationID: holiday.LocationID,
 	}
 
-	return result, nil
+	if holiday.PricingRuleSetID != nil {
+		result.PricingRuleSetID = *holiday.PricingRuleSetID
+	}
+
+	return result
+}
+
+// optionalID maps the 0 of an unset reference to NULL.
+func optionalID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+
+	return &id
 }
@@ -1,15 +1,27 @@
 package service
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+	"travel/internal/invoice"
+	"travel/internal/money"
+	"travel/internal/payment"
+	"travel/internal/pricing"
+	"travel/internal/promo"
+)
 
 type HolidayDTO struct {
-	ID         int       `json:"id"`
-	Title      string    `json:"title"`
-	StartDate  time.Time `json:"startDate"`
-	Duration   int       `json:"duration"`
-	Price      float64   `json:"price"`
-	FreeSlots  int       `json:"freeSlots"`
-	LocationID int       `json:"location"`
+	ID         int         `json:"id"`
+	Title      string      `json:"title"`
+	StartDate  time.Time   `json:"startDate"`
+	Duration   int         `json:"duration"`
+	Price      money.Money `json:"price"`
+	FreeSlots  int         `json:"freeSlots"`
+	LocationID int         `json:"location"`
+	// PricingRuleSetID is 0 for a holiday sold at its price.
+	PricingRuleSetID int `json:"pricingRuleSet,omitempty"`
+	// Pricing is filled in responses only, it prices a booking made today.
+	Pricing *pricing.Quote `json:"pricing,omitempty"`
 }
 
 type LocationDTO struct {
@@ -24,6 +36,8 @@ type FilterHolidays struct {
 	Location  string
 	StartDate time.Time
 	Duration  int
+	// Currency the prices are shown in, as they are when empty.
+	Currency string
 }
 
 type ReservationDTO struct {
@@ -31,4 +45,197 @@ type ReservationDTO struct {
 	ContactName string `json:"contactName"`
 	PhoneNumber string `json:"phoneNumber"`
 	HolidayID   int    `json:"holiday"`
+	CustomerID  int    `json:"customerID"`
+	// Phone is filled in responses only, PhoneNumber is what is read on input.
+	Phone *PhoneDTO `json:"phone,omitempty"`
+	// Email is only used to match or create the customer, it is not stored
+	// on the reservation itself.
+	Email string `json:"email,omitempty"`
+	// Pricing is filled in responses only, it is the quote the reservation
+	// was booked at.
+	Pricing json.RawMessage `json:"pricing,omitempty"`
+	// ExchangeRates is filled in responses only, they are the exchange rates
+	// of the day the reservation was booked.
+	ExchangeRates json.RawMessage `json:"exchangeRates,omitempty"`
+	// QuoteToken is read on input only, the token of a quote the reservation
+	// is booked at instead of today's price.
+	QuoteToken string `json:"quoteToken,omitempty"`
+	// Quote is filled in responses only, the quote the reservation was
+	// booked at when it was booked with one.
+	Quote json.RawMessage `json:"quote,omitempty"`
+	// PromoCode is read on input only, a promo code redeemed on the
+	// reservation. A reservation booked with a quote redeems the code of
+	// the quote instead.
+	PromoCode string `json:"promoCode,omitempty"`
+	// Promo is filled in responses only, the PromoDTO of the code the
+	// reservation was booked with.
+	Promo json.RawMessage `json:"promo,omitempty"`
+}
+
+type CustomerDTO struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	PhoneNumber string `json:"phoneNumber"`
+	Email       string `json:"email"`
+}
+
+type PhoneDTO struct {
+	E164          string `json:"e164"`
+	National      string `json:"national"`
+	International string `json:"international"`
+	Region        string `json:"region"`
+}
+
+type APIKeyDTO struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Role       string     `json:"role"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	// Key is only set right after the key is created or rotated.
+	Key string `json:"key,omitempty"`
+}
+
+type PricingRuleSetDTO struct {
+	ID    int            `json:"id"`
+	Name  string         `json:"name"`
+	Rules []pricing.Rule `json:"rules"`
+}
+
+type AuditEntryDTO struct {
+	ID         int64           `json:"id"`
+	Actor      AuditActorDTO   `json:"actor"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Resource   string          `json:"resource"`
+	ResourceID int             `json:"resourceID"`
+	Action     string          `json:"action"`
+	Diff       json.RawMessage `json:"diff"`
+}
+
+type AuditActorDTO struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ExchangeRateDTO is Rate units of Currency for one unit of the default
+// currency, published for Date.
+type ExchangeRateDTO struct {
+	Currency string    `json:"currency"`
+	Rate     float64   `json:"rate"`
+	Date     time.Time `json:"date"`
+}
+
+// PromoCodeDTO is a promo code with the terms it is redeemed on.
+type PromoCodeDTO struct {
+	ID int `json:"id"`
+	promo.Code
+}
+
+// PromoDTO is a promo code redeemed on a reservation and what it took off,
+// a negative amount.
+type PromoDTO struct {
+	Code     string      `json:"code"`
+	Discount money.Money `json:"discount"`
+}
+
+// PromoRedemptionsDTO reports the redemptions of a promo code: how often it
+// was redeemed, what it took off in total, one amount per currency, and the
+// reservations it was redeemed on.
+type PromoRedemptionsDTO struct {
+	PromoCodeID int                  `json:"promoCode"`
+	Code        string               `json:"code"`
+	Count       int                  `json:"count"`
+	Discounts   []money.Money        `json:"discounts"`
+	Redemptions []PromoRedemptionDTO `json:"redemptions"`
+}
+
+type PromoRedemptionDTO struct {
+	ReservationID int         `json:"reservation"`
+	HolidayID     int         `json:"holiday"`
+	CustomerID    int         `json:"customerID"`
+	Discount      money.Money `json:"discount"`
+}
+
+// QuoteRequestDTO is who wants to book a holiday, with which extras.
+type QuoteRequestDTO struct {
+	Adults    int             `json:"adults"`
+	Children  []pricing.Child `json:"children"`
+	Extras    []string        `json:"extras"`
+	PromoCode string          `json:"promoCode"`
+}
+
+// QuoteDTO is the price of a booking, itemized. Token books it at that price
+// until ExpiresAt, it is left out of the quotes stored with reservations.
+type QuoteDTO struct {
+	ID        string         `json:"id"`
+	HolidayID int            `json:"holiday"`
+	Party     pricing.Party  `json:"party"`
+	Extras    []string       `json:"extras"`
+	PromoCode string         `json:"promoCode,omitempty"`
+	Pricing   pricing.Quote  `json:"pricing"`
+	Items     []pricing.Item `json:"items"`
+	Total     money.Money    `json:"total"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+	Token     string         `json:"token,omitempty"`
+}
+
+// PaymentDTO is one installment of a reservation. ClientSecret confirms a
+// new payment at the gateway, it is only returned when the payment is made.
+type PaymentDTO struct {
+	ID            int         `json:"id"`
+	ReservationID int         `json:"reservation"`
+	Kind          string      `json:"kind"`
+	Amount        money.Money `json:"amount"`
+	DueDate       time.Time   `json:"dueDate"`
+	Status        string      `json:"status"`
+	Gateway       string      `json:"gateway"`
+	IntentID      string      `json:"intent,omitempty"`
+	Refunded      money.Money `json:"refunded"`
+	// Refunding is a refund sent to the gateway and not confirmed yet.
+	Refunding    *money.Money `json:"refunding,omitempty"`
+	ClientSecret string       `json:"clientSecret,omitempty"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
+}
+
+// PaymentsDTO is where the payment of a reservation stands: its price, what
+// was paid of it less refunds, what is left and when it is due.
+type PaymentsDTO struct {
+	ReservationID int                   `json:"reservation"`
+	Total         money.Money           `json:"total"`
+	Paid          money.Money           `json:"paid"`
+	Outstanding   money.Money           `json:"outstanding"`
+	Schedule      []payment.Installment `json:"schedule"`
+	Payments      []PaymentDTO          `json:"payments"`
+}
+
+// PaymentRequestDTO pays the next installment of a reservation, or all that
+// is outstanding when Full is set.
+type PaymentRequestDTO struct {
+	Full bool `json:"full"`
+}
+
+// RefundRequestDTO refunds Amount of a payment, all that is left of it when
+// Amount is nil.
+type RefundRequestDTO struct {
+	Amount *money.Money `json:"amount"`
+}
+
+// InvoiceDTO is an invoice or credit note of a reservation. Document is what
+// was issued and PDF its rendering, which is served on its own.
+type InvoiceDTO struct {
+	ID                int             `json:"id"`
+	ReservationID     int             `json:"reservation"`
+	CustomerID        int             `json:"customerID,omitempty"`
+	Kind              string          `json:"kind"`
+	Number            string          `json:"number"`
+	IssuedAt          time.Time       `json:"issuedAt"`
+	Total             money.Money     `json:"total"`
+	CreditedInvoiceID int             `json:"creditedInvoice,omitempty"`
+	Document          invoice.Invoice `json:"document"`
+	PDF               []byte          `json:"-"`
 }
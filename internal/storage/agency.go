@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// Agency is a tenant of the platform. Agencies are not tenant scoped
+// themselves. Address, Email and TaxNumber are printed on its invoices.
+type Agency struct {
+	ID        int    `db:"id" json:"id" goqu:"skipinsert"`
+	Slug      string `db:"slug" json:"slug"`
+	Name      string `db:"name" json:"name"`
+	Address   string `db:"address" json:"address"`
+	Email     string `db:"email" json:"email"`
+	TaxNumber string `db:"taxNumber" json:"taxNumber"`
+}
+
+const agencyTable = "agency"
+
+// AgencyIDBySlug returns 0 without an error for unknown slugs.
+func (s *Storage) AgencyIDBySlug(ctx context.Context, slug string) (int, error) {
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(agencyTable).
+		Select("id").
+		Where(goqu.C("slug").Eq(slug)).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return id, err
+}
+
+func (s *Storage) Agency(ctx context.Context, agencyID int) (*Agency, error) {
+	var agency = &Agency{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(agencyTable).
+		Select("*").
+		Where(goqu.C("id").Eq(agencyID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(getColumnsForStruct(agency)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return agency, nil
+}
+
+func (s *Storage) InsertAgency(ctx context.Context, agency *Agency) (int64, error) {
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(agencyTable).
+		Insert().
+		Rows(agency))
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type APIKey struct {
+	ID         int        `db:"id" goqu:"skipinsert"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	Hash       string     `db:"hash"`
+	CreatedBy  string     `db:"createdBy"`
+	CreatedAt  time.Time  `db:"createdAt"`
+	LastUsedAt *time.Time `db:"lastUsedAt"`
+	RevokedAt  *time.Time `db:"revokedAt"`
+	Role       string     `db:"role"`
+	TenantID   int        `db:"tenantID"`
+}
+
+const apiKeyTable = "api_key"
+
+func (s *Storage) APIKeyGetAll(ctx context.Context) ([]APIKey, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys = []APIKey{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(apiKeyTable).
+		Where(goqu.C(tenantColumn).Eq(tenantID)).
+		Order(goqu.C("id").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var key APIKey
+		columns := getColumnsForStruct(&key)
+		if err := rows.Scan(columns...); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *Storage) APIKey(ctx context.Context, keyID int) (*APIKey, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.apiKeyWhere(ctx, goqu.Ex{"id": keyID, tenantColumn: tenantID})
+}
+
+// APIKeyByPrefix returns nil without an error when no key has the prefix.
+// It runs before the tenant is known, so it is the one lookup that is not
+// tenant scoped.
+func (s *Storage) APIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	key, err := s.apiKeyWhere(ctx, goqu.Ex{"prefix": prefix})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return key, err
+}
+
+func (s *Storage) apiKeyWhere(ctx context.Context, where goqu.Ex) (*APIKey, error) {
+	var key = &APIKey{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(apiKeyTable).
+		Select("*").
+		Where(where).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(getColumnsForStruct(key)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (s *Storage) InsertAPIKey(ctx context.Context, key *APIKey) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	key.TenantID = tenantID
+
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(apiKeyTable).
+		Insert().
+		Rows(key))
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, keyID int, revokedAt time.Time) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.updateAPIKey(ctx, goqu.Ex{"id": keyID, tenantColumn: tenantID}, goqu.Record{"revokedAt": revokedAt})
+}
+
+// TouchAPIKey records the use of a key while authenticating, before the
+// tenant is known.
+func (s *Storage) TouchAPIKey(ctx context.Context, keyID int, usedAt time.Time) error {
+	return s.updateAPIKey(ctx, goqu.Ex{"id": keyID}, goqu.Record{"lastUsedAt": usedAt})
+}
+
+func (s *Storage) updateAPIKey(ctx context.Context, where goqu.Ex, record goqu.Record) error {
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Update(apiKeyTable).
+		Set(record).
+		Where(where).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	return err
+}
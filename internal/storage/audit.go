@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type AuditEntry struct {
+	ID         int64     `db:"id" goqu:"skipinsert"`
+	TenantID   int       `db:"tenantID"`
+	ActorType  string    `db:"actorType"`
+	ActorID    string    `db:"actorID"`
+	ActorName  string    `db:"actorName"`
+	OccurredAt time.Time `db:"occurredAt"`
+	Resource   string    `db:"resource"`
+	ResourceID int       `db:"resourceID"`
+	Action     string    `db:"action"`
+	Diff       []byte    `db:"diff"`
+}
+
+const auditTable = "audit_log"
+
+// InsertAuditEntry appends to the audit log. There is deliberately no way to
+// update or delete entries.
+func (s *Storage) InsertAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	entry.TenantID = tenantID
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(auditTable).
+		Insert().
+		Rows(goqu.Record{
+			"tenantID":   entry.TenantID,
+			"actorType":  entry.ActorType,
+			"actorID":    entry.ActorID,
+			"actorName":  entry.ActorName,
+			"occurredAt": entry.OccurredAt,
+			"resource":   entry.Resource,
+			"resourceID": entry.ResourceID,
+			"action":     entry.Action,
+			"diff":       string(entry.Diff),
+		}).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	return err
+}
+
+// AuditEntries returns the history of a resource, oldest first. A resourceID
+// of 0 returns the history of every record of the resource.
+func (s *Storage) AuditEntries(ctx context.Context, resource string, resourceID int) ([]AuditEntry, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	where := goqu.Ex{tenantColumn: tenantID, "resource": resource}
+	if resourceID != 0 {
+		where["resourceID"] = resourceID
+	}
+
+	entries := []AuditEntry{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(auditTable).
+		Where(where).
+		Order(goqu.C("id").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(getColumnsForStruct(&entry)...); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type Customer struct {
+	ID              int    `db:"id" json:"id" goqu:"skipinsert"`
+	Name            string `db:"name" json:"name"`
+	PhoneNumber     string `db:"phoneNumber" json:"phoneNumber"`
+	Email           string `db:"email" json:"email"`
+	NormalizedPhone string `db:"normalizedPhone" json:"-"`
+	TenantID        int    `db:"tenantID" json:"-"`
+}
+
+const customerTable = "customer"
+
+func (s *Storage) CustomerGetAll(ctx context.Context) ([]Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var customers = []Customer{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(customerTable).
+		Where(goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var customer Customer
+		columns := getColumnsForStruct(&customer)
+		if err := rows.Scan(columns...); err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, rows.Err()
+}
+
+func (s *Storage) Customer(ctx context.Context, customerID int) (*Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var customer = &Customer{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(customerTable).
+		Select("*").
+		Where(goqu.C("id").Eq(customerID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.conn(ctx).QueryRowContext(ctx, sqlStr)
+
+	columns := getColumnsForStruct(customer)
+	err = row.Scan(columns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+// CustomerByContact looks a customer up by normalized phone number or, when
+// that does not match, by email. It returns nil without an error when nobody
+// matches.
+func (s *Storage) CustomerByContact(ctx context.Context, normalizedPhone string, email string) (*Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lookups := []goqu.Ex{}
+	if normalizedPhone != "" {
+		lookups = append(lookups, goqu.Ex{"normalizedPhone": normalizedPhone})
+	}
+	if email != "" {
+		lookups = append(lookups, goqu.Ex{"email": email})
+	}
+
+	for _, where := range lookups {
+		var customer = &Customer{}
+		sqlStr, _, err := goqu.Dialect(s.dialect).
+			From(customerTable).
+			Select("*").
+			Where(where, goqu.C(tenantColumn).Eq(tenantID)).
+			Order(goqu.C("id").Asc()).
+			Limit(1).ToSQL()
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(getColumnsForStruct(customer)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return customer, nil
+	}
+
+	return nil, nil
+}
+
+func (s *Storage) InsertCustomer(ctx context.Context, customer *Customer) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	customer.TenantID = tenantID
+
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(customerTable).
+		Insert().
+		Rows(customer))
+}
+
+func (s *Storage) UpdateCustomer(ctx context.Context, customer *Customer) (*Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customer.TenantID = tenantID
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(customerTable).
+		Update().
+		Set(customer).
+		Where(goqu.C("id").Eq(customer.ID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+func (s *Storage) DeleteCustomer(ctx context.Context, customerID int) (*Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(customerTable).
+		Delete().
+		Where(goqu.C("id").Eq(customerID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := s.Customer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+// CustomerReservations returns the booking history of a customer with the
+// holiday and location of every reservation.
+func (s *Storage) CustomerReservations(ctx context.Context, customerID int) ([]ReservationResult, error) {
+	reservations := []ReservationResult{}
+
+	err := s.reservationsEach(ctx, goqu.Ex{reservationTable + ".customerID": customerID}, func(reservation ReservationResult) error {
+		reservations = append(reservations, reservation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// ExchangeRate is Rate units of Currency for one unit of the base currency.
+type ExchangeRate struct {
+	ID       int       `db:"id" goqu:"skipinsert"`
+	Currency string    `db:"currency"`
+	Rate     float64   `db:"rate"`
+	Date     time.Time `db:"date"`
+	TenantID int       `db:"tenantID"`
+}
+
+const exchangeRateTable = "exchange_rate"
+
+func (s *Storage) ExchangeRateGetAll(ctx context.Context) ([]ExchangeRate, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates = []ExchangeRate{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(exchangeRateTable).
+		Where(goqu.C(tenantColumn).Eq(tenantID)).
+		Order(goqu.C("currency").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var rate ExchangeRate
+		if err := rows.Scan(getColumnsForStruct(&rate)...); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+func (s *Storage) ExchangeRate(ctx context.Context, currency string) (*ExchangeRate, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rate = &ExchangeRate{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(exchangeRateTable).
+		Select("*").
+		Where(goqu.C("currency").Eq(currency), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(getColumnsForStruct(rate)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+// SaveExchangeRate inserts the rate of a currency or replaces the one the
+// agency has.
+func (s *Storage) SaveExchangeRate(ctx context.Context, rate *ExchangeRate) (*ExchangeRate, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rate.TenantID = tenantID
+
+	stored, err := s.ExchangeRate(ctx, rate.Currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		id, err := s.insert(ctx, goqu.Dialect(s.dialect).
+			From(exchangeRateTable).
+			Insert().
+			Rows(rate))
+		if err != nil {
+			return nil, err
+		}
+		rate.ID = int(id)
+
+		return rate, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rate.ID = stored.ID
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(exchangeRateTable).
+		Update().
+		Set(rate).
+		Where(goqu.C("id").Eq(rate.ID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, sqlStr); err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+func (s *Storage) DeleteExchangeRate(ctx context.Context, currency string) (*ExchangeRate, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(exchangeRateTable).
+		Delete().
+		Where(goqu.C("currency").Eq(currency), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := s.ExchangeRate(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
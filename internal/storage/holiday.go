@@ -1,52 +1,85 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"time"
+	"travel/internal/money"
 
 	"github.com/doug-martin/goqu/v9"
 )
 
 type Holiday struct {
-	ID         int       `db:"id"`
+	ID         int       `db:"id" goqu:"skipinsert"`
 	Title      string    `db:"title"`
 	Duration   int       `db:"duration"`
 	StartDate  time.Time `db:"startDate"`
-	Price      float64   `db:"price"`
 	FreeSlots  int       `db:"freeSlots"`
 	LocationID int       `db:"locationID"`
+	TenantID   int       `db:"tenantID"`
+	// PricingRuleSetID is nil for a holiday sold at its price.
+	PricingRuleSetID *int `db:"pricingRuleSetID"`
+	// PriceMinor is the price in minor units of Currency, see Price.
+	PriceMinor int64  `db:"priceMinor"`
+	Currency   string `db:"currency"`
+}
+
+func (h Holiday) Price() money.Money {
+	return money.Money{Amount: h.PriceMinor, Currency: h.Currency}
 }
 
 type HolidayWithLocation struct {
-	ID        int       `db:"id" json:"id"`
-	Title     string    `db:"title" json:"title"`
-	Duration  int       `db:"duration" json:"duration"`
-	StartDate time.Time `db:"startDate" json:"startDate"`
-	Price     float64   `db:"price" json:"price"`
-	FreeSlots int       `db:"freeSlots" json:"freeSlots"`
-	Location  Location  `json:"location"`
+	ID        int         `db:"id" json:"id"`
+	Title     string      `db:"title" json:"title"`
+	Duration  int         `db:"duration" json:"duration"`
+	StartDate time.Time   `db:"startDate" json:"startDate"`
+	Price     money.Money `json:"price"`
+	FreeSlots int         `db:"freeSlots" json:"freeSlots"`
+	Location  Location    `json:"location"`
 }
 
 const holidaysTable = "holiday"
 
-func (s *Storage) HolidaysGetAll(location string, duration int, startDate time.Time) ([]HolidayWithLocation, error) {
+func (s *Storage) HolidaysGetAll(ctx context.Context, location string, duration int, startDate time.Time) ([]HolidayWithLocation, error) {
 	var holidays = []HolidayWithLocation{}
-	sql := goqu.Dialect("mysql").
+
+	err := s.HolidaysEach(ctx, location, duration, startDate, func(holiday HolidayWithLocation) error {
+		holidays = append(holidays, holiday)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return holidays, nil
+}
+
+// HolidaysEach runs the HolidaysGetAll query and hands every row to fn as soon
+// as it is scanned, so callers can stream big results without buffering them.
+func (s *Storage) HolidaysEach(ctx context.Context, location string, duration int, startDate time.Time, fn func(HolidayWithLocation) error) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	sql := goqu.Dialect(s.dialect).
 		Select(goqu.T(holidaysTable).All(), goqu.T(locationTable).All()).
 		From(holidaysTable)
 
 	sql = sql.InnerJoin(
 		goqu.T(locationTable),
-		goqu.On(goqu.Ex{holidaysTable + ".locationID": goqu.I(locationTable + ".id")}),
-	)
+		goqu.On(goqu.Ex{
+			holidaysTable + ".locationID": goqu.I(locationTable + ".id"),
+			locationTable + ".tenantID":   goqu.I(holidaysTable + ".tenantID"),
+		}),
+	).Where(goqu.Ex{holidaysTable + ".tenantID": tenantID})
 
 	if location != "" || duration > 0 || !startDate.IsZero() {
 		if location != "" {
 			sql = sql.Where(goqu.ExOr{
-				locationTable + ".country": goqu.Op{"like": "%" + location + "%"},
-				locationTable + ".city":    goqu.Op{"like": "%" + location + "%"},
+				locationTable + ".country": goqu.Op{"ilike": "%" + location + "%"},
+				locationTable + ".city":    goqu.Op{"ilike": "%" + location + "%"},
 			})
 		}
 
@@ -64,12 +97,12 @@ func (s *Storage) HolidaysGetAll(location string, duration int, startDate time.T
 	}
 	sqlStr, _, err := sql.ToSQL()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	rows, err := s.db.Query(sqlStr)
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	defer rows.Close()
@@ -80,35 +113,42 @@ func (s *Storage) HolidaysGetAll(location string, duration int, startDate time.T
 		var location Location
 		columns = append(columns, getColumnsForStruct(&location)...)
 		if err := rows.Scan(columns...); err != nil {
-			fmt.Printf("holidays: %v\n", holidays)
-			fmt.Printf("err: %v\n", err.Error())
-			return nil, err
+			return err
 		}
-		holidays = append(holidays, HolidayWithLocation{
+
+		err := fn(HolidayWithLocation{
 			ID:        holiday.ID,
 			Title:     holiday.Title,
 			Duration:  holiday.Duration,
 			StartDate: holiday.StartDate,
-			Price:     holiday.Price,
+			Price:     holiday.Price(),
 			FreeSlots: holiday.FreeSlots,
 			Location:  location,
 		})
+		if err != nil {
+			return err
+		}
 	}
 
-	return holidays, nil
+	return rows.Err()
 }
 
-func (s *Storage) Holiday(holidaysID int) (*Holiday, error) {
+func (s *Storage) Holiday(ctx context.Context, holidaysID int) (*Holiday, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var holidays = &Holiday{}
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		From(holidaysTable).
 		Select("*").
-		Where(goqu.C("id").Eq(holidaysID)).ToSQL()
+		Where(goqu.C("id").Eq(holidaysID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	row := s.db.QueryRow(sqlStr)
+	row := s.conn(ctx).QueryRowContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -122,40 +162,61 @@ func (s *Storage) Holiday(holidaysID int) (*Holiday, error) {
 	return holidays, nil
 }
 
-func (s *Storage) InsertHolidays(holidays *Holiday) (int64, error) {
-	sqlStr, _, err := goqu.Dialect(s.dialect).
-		From(holidaysTable).
-		Insert().
-		Rows(holidays).ToSQL()
+func (s *Storage) InsertHolidays(ctx context.Context, holidays *Holiday) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
 	if err != nil {
 		return 0, err
 	}
+	holidays.TenantID = tenantID
 
-	result, err := s.db.Exec(sqlStr)
-	if err != nil {
+	// the location has to belong to the same tenant
+	if _, err := s.Location(ctx, holidays.LocationID); err != nil {
 		return 0, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+	// and so does the rule set, SQLite has no foreign key checking that
+	if holidays.PricingRuleSetID != nil {
+		if _, err := s.PricingRuleSet(ctx, *holidays.PricingRuleSetID); err != nil {
+			return 0, err
+		}
 	}
 
-	return id, nil
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(holidaysTable).
+		Insert().
+		Rows(holidays))
 }
 
-func (s *Storage) UpdateHolidays(holidays *Holiday) (*Holiday, error) {
+func (s *Storage) UpdateHolidays(ctx context.Context, holidays *Holiday) (*Holiday, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	holidays.TenantID = tenantID
+
+	// the location has to belong to the same tenant
+	if _, err := s.Location(ctx, holidays.LocationID); err != nil {
+		return nil, err
+	}
+
+	// and so does the rule set, SQLite has no foreign key checking that
+	if holidays.PricingRuleSetID != nil {
+		if _, err := s.PricingRuleSet(ctx, *holidays.PricingRuleSetID); err != nil {
+			return nil, err
+		}
+	}
+
 	updateHolidays := &Holiday{}
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		From(holidaysTable).
 		Update().
 		Set(holidays).
-		Where(goqu.C("id").Eq(holidays.ID)).ToSQL()
+		Where(goqu.C("id").Eq(holidays.ID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	row := s.db.QueryRow(sqlStr)
+	row := s.conn(ctx).QueryRowContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -176,20 +237,25 @@ func (s *Storage) UpdateHolidays(holidays *Holiday) (*Holiday, error) {
 	return holidays, nil
 }
 
-func (s *Storage) DeleteHolidays(holidaysID int) (*Holiday, error) {
+func (s *Storage) DeleteHolidays(ctx context.Context, holidaysID int) (*Holiday, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		Delete(holidaysTable).
-		Where(goqu.C("id").Eq(holidaysID)).ToSQL()
+		Where(goqu.C("id").Eq(holidaysID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	holiday, err := s.Holiday(holidaysID)
+	holiday, err := s.Holiday(ctx, holidaysID)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = s.db.Exec(sqlStr)
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
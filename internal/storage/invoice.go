@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// Invoice is an invoice or credit note as issued. Data is the JSON of the
+// document and Document its PDF. CreditedInvoiceID is the invoice a credit
+// note cancels.
+type Invoice struct {
+	ID                int       `db:"id" goqu:"skipinsert"`
+	ReservationID     int       `db:"reservationID"`
+	CustomerID        *int      `db:"customerID"`
+	Kind              string    `db:"kind"`
+	Sequence          int       `db:"sequence"`
+	Number            string    `db:"number"`
+	IssuedAt          time.Time `db:"issuedAt"`
+	TotalMinor        int64     `db:"totalMinor"`
+	Currency          string    `db:"currency"`
+	CreditedInvoiceID *int      `db:"creditedInvoiceID"`
+	Data              string    `db:"data"`
+	Document          string    `db:"document"`
+	TenantID          int       `db:"tenantID"`
+}
+
+const (
+	invoiceTable         = "invoice"
+	invoiceSequenceTable = "invoice_sequence"
+)
+
+// ReservationInvoices returns the invoices and credit notes of a reservation
+// in the order they were issued.
+func (s *Storage) ReservationInvoices(ctx context.Context, reservationID int) ([]Invoice, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoices = []Invoice{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(invoiceTable).
+		Where(goqu.C("reservationID").Eq(reservationID), goqu.C(tenantColumn).Eq(tenantID)).
+		Order(goqu.C("id").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var invoice Invoice
+		if err := rows.Scan(getColumnsForStruct(&invoice)...); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+
+	return invoices, rows.Err()
+}
+
+func (s *Storage) Invoice(ctx context.Context, invoiceID int) (*Invoice, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice = &Invoice{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(invoiceTable).
+		Select("*").
+		Where(goqu.C("id").Eq(invoiceID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(getColumnsForStruct(invoice)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// NextInvoiceSequence takes the number the next document of kind gets in
+// the sequence of the tenant, 1 for the first. The counter stays locked until
+// the transaction ends, so concurrent issuers wait for each other and a
+// rolled back issue hands its number back. It must be called inside a
+// transaction.
+func (s *Storage) NextInvoiceSequence(ctx context.Context, kind string) (int, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	where := goqu.Ex{tenantColumn: tenantID, "kind": kind}
+	sqlStr, _, err := s.forUpdate(goqu.Dialect(s.dialect).
+		Select("lastSequence").
+		From(invoiceSequenceTable).
+		Where(where)).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	var last int
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the first document of a kind starts the counter. It is read before,
+		// an insert that finds the row would only take a shared lock on it
+		err = s.startInvoiceSequence(ctx, tenantID, kind)
+		if err == nil {
+			err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&last)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	sqlStr, _, err = goqu.Dialect(s.dialect).
+		Update(invoiceSequenceTable).
+		Set(goqu.Record{"lastSequence": last + 1}).
+		Where(where).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := s.conn(ctx).ExecContext(ctx, sqlStr); err != nil {
+		return 0, err
+	}
+
+	return last + 1, nil
+}
+
+func (s *Storage) startInvoiceSequence(ctx context.Context, tenantID int, kind string) error {
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Insert(invoiceSequenceTable).
+		Rows(goqu.Record{tenantColumn: tenantID, "kind": kind, "lastSequence": 0}).
+		OnConflict(goqu.DoNothing()).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	return err
+}
+
+// InsertInvoice stamps the tenant on the invoice and makes sure the invoice a
+// credit note cancels belongs to that tenant too. Invoices are never updated
+// or deleted.
+func (s *Storage) InsertInvoice(ctx context.Context, invoice *Invoice) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	invoice.TenantID = tenantID
+
+	if invoice.CreditedInvoiceID != nil {
+		if _, err := s.Invoice(ctx, *invoice.CreditedInvoiceID); err != nil {
+			return 0, err
+		}
+	}
+
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(invoiceTable).
+		Insert().
+		Rows(invoice))
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -8,25 +9,32 @@ import (
 )
 
 type Location struct {
-	ID      int    `db:"id" json:"id"`
-	Street  string `db:"street" json:"street"`
-	Number  string `db:"number" json:"number"`
-	City    string `db:"city" json:"city"`
-	Country string `db:"country" json:"country"`
+	ID       int    `db:"id" json:"id" goqu:"skipinsert"`
+	Street   string `db:"street" json:"street"`
+	Number   string `db:"number" json:"number"`
+	City     string `db:"city" json:"city"`
+	Country  string `db:"country" json:"country"`
+	TenantID int    `db:"tenantID" json:"-"`
 }
 
 const locationTable = "location"
 
-func (s *Storage) LocationGetAll() ([]Location, error) {
+func (s *Storage) LocationGetAll(ctx context.Context) ([]Location, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var locations = []Location{}
-	sqlStr, _, err := goqu.Dialect("mysql").
+	sqlStr, _, err := goqu.Dialect(s.dialect).
 		Select("*").
-		From(locationTable).ToSQL()
+		From(locationTable).
+		Where(goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := s.db.Query(sqlStr)
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -45,17 +53,22 @@ func (s *Storage) LocationGetAll() ([]Location, error) {
 	return locations, nil
 }
 
-func (s *Storage) Location(locationID int) (*Location, error) {
+func (s *Storage) Location(ctx context.Context, locationID int) (*Location, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var location = &Location{}
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		From(locationTable).
 		Select("*").
-		Where(goqu.C("id").Eq(locationID)).ToSQL()
+		Where(goqu.C("id").Eq(locationID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	row := s.db.QueryRow(sqlStr)
+	row := s.conn(ctx).QueryRowContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -69,40 +82,37 @@ func (s *Storage) Location(locationID int) (*Location, error) {
 	return location, nil
 }
 
-func (s *Storage) InsertLocation(location *Location) (int64, error) {
-	sqlStr, _, err := goqu.Dialect(s.dialect).
-		From(locationTable).
-		Insert().
-		Rows(location).ToSQL()
+func (s *Storage) InsertLocation(ctx context.Context, location *Location) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
 	if err != nil {
 		return 0, err
 	}
+	location.TenantID = tenantID
 
-	result, err := s.db.Exec(sqlStr)
-	if err != nil {
-		return 0, err
-	}
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(locationTable).
+		Insert().
+		Rows(location))
+}
 
-	id, err := result.LastInsertId()
+func (s *Storage) UpdateLocation(ctx context.Context, location *Location) (*Location, error) {
+	tenantID, err := tenantFrom(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	location.TenantID = tenantID
 
-	return id, nil
-}
-
-func (s *Storage) UpdateLocation(location *Location) (*Location, error) {
 	updatelocation := &Location{}
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		From(locationTable).
 		Update().
 		Set(location).
-		Where(goqu.C("id").Eq(location.ID)).ToSQL()
+		Where(goqu.C("id").Eq(location.ID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	row := s.db.QueryRow(sqlStr)
+	row := s.conn(ctx).QueryRowContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -123,21 +133,26 @@ func (s *Storage) UpdateLocation(location *Location) (*Location, error) {
 	return location, nil
 }
 
-func (s *Storage) DeleteLocation(locationID int) (*Location, error) {
+func (s *Storage) DeleteLocation(ctx context.Context, locationID int) (*Location, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		From(locationTable).
 		Delete().
-		Where(goqu.C("id").Eq(locationID)).ToSQL()
+		Where(goqu.C("id").Eq(locationID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	location, err := s.Location(locationID)
+	location, err := s.Location(ctx, locationID)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = s.db.Exec(sqlStr)
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
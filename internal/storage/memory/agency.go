@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"travel/internal/storage"
+)
+
+const (
+	agencyTable         = "agency"
+	locationTable       = "location"
+	holidaysTable       = "holiday"
+	customerTable       = "customer"
+	reservationTable    = "reservation"
+	apiKeyTable         = "api_key"
+	pricingRuleSetTable = "pricing_rule_set"
+	exchangeRateTable   = "exchange_rate"
+	promoCodeTable      = "promo_code"
+	paymentTable        = "payment"
+	invoiceTable        = "invoice"
+)
+
+// AgencyIDBySlug returns 0 without an error for unknown slugs.
+func (s *Storage) AgencyIDBySlug(ctx context.Context, slug string) (int, error) {
+	var id int
+	err := s.read(ctx, func(d *data) error {
+		for _, agency := range d.agencies {
+			if agency.Slug == slug {
+				id = agency.ID
+			}
+		}
+		return nil
+	})
+
+	return id, err
+}
+
+func (s *Storage) Agency(ctx context.Context, agencyID int) (*storage.Agency, error) {
+	var agency storage.Agency
+	err := s.read(ctx, func(d *data) error {
+		var ok bool
+		if agency, ok = d.agencies[agencyID]; !ok {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &agency, nil
+}
+
+func (s *Storage) InsertAgency(ctx context.Context, agency *storage.Agency) (int64, error) {
+	var id int
+	err := s.write(ctx, func(d *data) error {
+		for _, other := range d.agencies {
+			if other.Slug == agency.Slug {
+				return fmt.Errorf("agency %q: %w", agency.Slug, ErrDuplicate)
+			}
+		}
+
+		id = d.nextID(agencyTable)
+		record := *agency
+		record.ID = id
+		d.agencies[id] = record
+		return nil
+	})
+
+	return int64(id), err
+}
+
+// RolePermissions returns the names of the permissions granted to role.
+func (s *Storage) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	permissions := []string{}
+	err := s.read(ctx, func(d *data) error {
+		permissions = append(permissions, d.permissions[role]...)
+		return nil
+	})
+
+	return permissions, err
+}
+
+func (s *Storage) RoleExists(ctx context.Context, role string) (bool, error) {
+	exists := false
+	err := s.read(ctx, func(d *data) error {
+		_, exists = d.permissions[role]
+		return nil
+	})
+
+	return exists, err
+}
+
+// rolePermissions are the grants the migrations seed.
+func rolePermissions() map[string][]string {
+	// permissions ending in :own only cover records of the caller's customer
+	all := []string{
+		"holiday:read", "holiday:write",
+		"location:read", "location:write",
+		"reservation:read", "reservation:write",
+		"reservation:read:own", "reservation:create:own",
+		"customer:read", "customer:write", "customer:read:own",
+		"apikey:manage", "audit:read",
+		"pricing:read", "pricing:write",
+		"promo:read", "promo:write",
+		"payment:read", "payment:write", "payment:refund",
+		"payment:read:own", "payment:create:own",
+		"invoice:read", "invoice:write",
+		"invoice:read:own", "invoice:create:own",
+	}
+
+	admin := []string{}
+	for _, permission := range all {
+		if !strings.HasSuffix(permission, ":own") {
+			admin = append(admin, permission)
+		}
+	}
+
+	return map[string][]string{
+		"admin": admin,
+		"agent": {
+			"holiday:read", "location:read",
+			"reservation:read", "reservation:write",
+			"customer:read", "customer:write",
+			"pricing:read", "promo:read",
+			"payment:read", "payment:write",
+			"invoice:read", "invoice:write",
+		},
+		"customer": {
+			"holiday:read", "location:read",
+			"reservation:read:own", "reservation:create:own",
+			"customer:read:own",
+			"payment:read:own", "payment:create:own",
+			"invoice:read:own", "invoice:create:own",
+		},
+	}
+}
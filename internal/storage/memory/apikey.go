@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"travel/internal/storage"
+)
+
+func (s *Storage) APIKeyGetAll(ctx context.Context) ([]storage.APIKey, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []storage.APIKey{}
+	err = s.read(ctx, func(d *data) error {
+		for _, key := range sorted(d.apiKeys) {
+			if key.TenantID == tenantID {
+				keys = append(keys, copyAPIKey(key))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) APIKey(ctx context.Context, keyID int) (*storage.APIKey, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.apiKeyWhere(ctx, func(key storage.APIKey) bool {
+		return key.ID == keyID && key.TenantID == tenantID
+	})
+}
+
+// APIKeyByPrefix returns nil without an error when no key has the prefix.
+// It runs before the tenant is known, so it is the one lookup that is not
+// tenant scoped.
+func (s *Storage) APIKeyByPrefix(ctx context.Context, prefix string) (*storage.APIKey, error) {
+	key, err := s.apiKeyWhere(ctx, func(key storage.APIKey) bool {
+		return key.Prefix == prefix
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return key, err
+}
+
+func (s *Storage) apiKeyWhere(ctx context.Context, where func(storage.APIKey) bool) (*storage.APIKey, error) {
+	var match *storage.APIKey
+	err := s.read(ctx, func(d *data) error {
+		for _, key := range sorted(d.apiKeys) {
+			if where(key) {
+				key = copyAPIKey(key)
+				match = &key
+				return nil
+			}
+		}
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+func (s *Storage) InsertAPIKey(ctx context.Context, key *storage.APIKey) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	key.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		for _, other := range d.apiKeys {
+			if other.Prefix == key.Prefix {
+				return fmt.Errorf("api key prefix %q: %w", key.Prefix, ErrDuplicate)
+			}
+		}
+
+		id = d.nextID(apiKeyTable)
+		record := copyAPIKey(*key)
+		record.ID = id
+		d.apiKeys[id] = record
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, keyID int, revokedAt time.Time) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.updateAPIKey(ctx, keyID, func(key *storage.APIKey) bool {
+		if key.TenantID != tenantID {
+			return false
+		}
+		key.RevokedAt = &revokedAt
+		return true
+	})
+}
+
+// TouchAPIKey records the use of a key while authenticating, before the
+// tenant is known.
+func (s *Storage) TouchAPIKey(ctx context.Context, keyID int, usedAt time.Time) error {
+	return s.updateAPIKey(ctx, keyID, func(key *storage.APIKey) bool {
+		key.LastUsedAt = &usedAt
+		return true
+	})
+}
+
+// updateAPIKey stores the key when update reports a change.
+func (s *Storage) updateAPIKey(ctx context.Context, keyID int, update func(key *storage.APIKey) bool) error {
+	return s.write(ctx, func(d *data) error {
+		key, ok := d.apiKeys[keyID]
+		if ok && update(&key) {
+			d.apiKeys[keyID] = key
+		}
+		return nil
+	})
+}
+
+// copyAPIKey does not share the times between the stored record and the
+// caller, who may change them.
+func copyAPIKey(key storage.APIKey) storage.APIKey {
+	if key.LastUsedAt != nil {
+		lastUsedAt := *key.LastUsedAt
+		key.LastUsedAt = &lastUsedAt
+	}
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		key.RevokedAt = &revokedAt
+	}
+
+	return key
+}
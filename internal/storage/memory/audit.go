@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"travel/internal/storage"
+)
+
+// InsertAuditEntry appends to the audit log. There is deliberately no way to
+// update or delete entries.
+func (s *Storage) InsertAuditEntry(ctx context.Context, entry *storage.AuditEntry) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	entry.TenantID = tenantID
+
+	return s.write(ctx, func(d *data) error {
+		record := *entry
+		record.ID = int64(len(d.audit) + 1)
+		record.Diff = append([]byte(nil), entry.Diff...)
+		d.audit = append(d.audit, record)
+		return nil
+	})
+}
+
+// AuditEntries returns the history of a resource, oldest first. A resourceID
+// of 0 returns the history of every record of the resource.
+func (s *Storage) AuditEntries(ctx context.Context, resource string, resourceID int) ([]storage.AuditEntry, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []storage.AuditEntry{}
+	err = s.read(ctx, func(d *data) error {
+		for _, entry := range d.audit {
+			if entry.TenantID != tenantID || entry.Resource != resource {
+				continue
+			}
+			if resourceID != 0 && entry.ResourceID != resourceID {
+				continue
+			}
+
+			entry.Diff = append([]byte(nil), entry.Diff...)
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"travel/internal/storage"
+)
+
+func customerTenant(customer storage.Customer) int { return customer.TenantID }
+
+func (s *Storage) CustomerGetAll(ctx context.Context) ([]storage.Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	customers := []storage.Customer{}
+	err = s.read(ctx, func(d *data) error {
+		for _, customer := range sorted(d.customers) {
+			if customer.TenantID == tenantID {
+				customers = append(customers, customer)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return customers, nil
+}
+
+func (s *Storage) Customer(ctx context.Context, customerID int) (*storage.Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var customer storage.Customer
+	err = s.read(ctx, func(d *data) error {
+		customer, err = owned(d.customers, customerID, tenantID, customerTenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &customer, nil
+}
+
+// CustomerByContact looks a customer up by normalized phone number or, when
+// that does not match, by email. It returns nil without an error when nobody
+// matches.
+func (s *Storage) CustomerByContact(ctx context.Context, normalizedPhone string, email string) (*storage.Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lookups := []func(storage.Customer) bool{}
+	if normalizedPhone != "" {
+		lookups = append(lookups, func(customer storage.Customer) bool { return customer.NormalizedPhone == normalizedPhone })
+	}
+	if email != "" {
+		lookups = append(lookups, func(customer storage.Customer) bool { return customer.Email == email })
+	}
+
+	var match *storage.Customer
+	err = s.read(ctx, func(d *data) error {
+		customers := sorted(d.customers)
+		for _, matches := range lookups {
+			for _, customer := range customers {
+				if customer.TenantID == tenantID && matches(customer) {
+					match = &customer
+					return nil
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+func (s *Storage) InsertCustomer(ctx context.Context, customer *storage.Customer) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	customer.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		id = d.nextID(customerTable)
+		record := *customer
+		record.ID = id
+		d.customers[id] = record
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// UpdateCustomer changes nothing and returns no error for a customer that
+// does not exist, like an UPDATE matching no row.
+func (s *Storage) UpdateCustomer(ctx context.Context, customer *storage.Customer) (*storage.Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customer.TenantID = tenantID
+
+	err = s.write(ctx, func(d *data) error {
+		if _, err := owned(d.customers, customer.ID, tenantID, customerTenant); err == nil {
+			d.customers[customer.ID] = *customer
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+func (s *Storage) DeleteCustomer(ctx context.Context, customerID int) (*storage.Customer, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var customer storage.Customer
+	err = s.write(ctx, func(d *data) error {
+		customer, err = owned(d.customers, customerID, tenantID, customerTenant)
+		if err != nil {
+			return err
+		}
+
+		for _, reservation := range d.reservations {
+			if reservation.CustomerID != nil && *reservation.CustomerID == customerID {
+				return ErrReferenced
+			}
+		}
+
+		delete(d.customers, customerID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &customer, nil
+}
+
+func (s *Storage) CustomerReservations(ctx context.Context, customerID int) ([]storage.ReservationResult, error) {
+	reservations := []storage.ReservationResult{}
+
+	err := s.reservationsEach(ctx, func(reservation storage.Reservation) bool {
+		return reservation.CustomerID != nil && *reservation.CustomerID == customerID
+	}, func(reservation storage.ReservationResult) error {
+		reservations = append(reservations, reservation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
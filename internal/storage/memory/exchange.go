@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"travel/internal/storage"
+)
+
+// exchangeRate finds the rate of currency of the tenant, ok is false when it
+// has none.
+func (d *data) exchangeRate(currency string, tenantID int) (storage.ExchangeRate, bool) {
+	for _, rate := range d.exchangeRates {
+		if rate.Currency == currency && rate.TenantID == tenantID {
+			return rate, true
+		}
+	}
+
+	return storage.ExchangeRate{}, false
+}
+
+func (s *Storage) ExchangeRateGetAll(ctx context.Context) ([]storage.ExchangeRate, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := []storage.ExchangeRate{}
+	err = s.read(ctx, func(d *data) error {
+		for _, rate := range d.exchangeRates {
+			if rate.TenantID == tenantID {
+				rates = append(rates, rate)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Currency < rates[j].Currency })
+
+	return rates, nil
+}
+
+func (s *Storage) ExchangeRate(ctx context.Context, currency string) (*storage.ExchangeRate, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rate storage.ExchangeRate
+	err = s.read(ctx, func(d *data) error {
+		var ok bool
+		if rate, ok = d.exchangeRate(currency, tenantID); !ok {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rate, nil
+}
+
+func (s *Storage) SaveExchangeRate(ctx context.Context, rate *storage.ExchangeRate) (*storage.ExchangeRate, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rate.TenantID = tenantID
+
+	err = s.write(ctx, func(d *data) error {
+		if stored, ok := d.exchangeRate(rate.Currency, tenantID); ok {
+			rate.ID = stored.ID
+		} else {
+			rate.ID = d.nextID(exchangeRateTable)
+		}
+		d.exchangeRates[rate.ID] = *rate
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rate, nil
+}
+
+func (s *Storage) DeleteExchangeRate(ctx context.Context, currency string) (*storage.ExchangeRate, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rate storage.ExchangeRate
+	err = s.write(ctx, func(d *data) error {
+		var ok bool
+		if rate, ok = d.exchangeRate(currency, tenantID); !ok {
+			return sql.ErrNoRows
+		}
+
+		delete(d.exchangeRates, rate.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rate, nil
+}
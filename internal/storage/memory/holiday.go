@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"travel/internal/storage"
+)
+
+func holidayTenant(holiday storage.Holiday) int { return holiday.TenantID }
+
+func (s *Storage) HolidaysGetAll(ctx context.Context, location string, duration int, startDate time.Time) ([]storage.HolidayWithLocation, error) {
+	holidays := []storage.HolidayWithLocation{}
+
+	err := s.HolidaysEach(ctx, location, duration, startDate, func(holiday storage.HolidayWithLocation) error {
+		holidays = append(holidays, holiday)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return holidays, nil
+}
+
+// HolidaysEach hands the holidays matching the filters to fn. The location
+// filter is a case-insensitive substring of the city or the country.
+func (s *Storage) HolidaysEach(ctx context.Context, location string, duration int, startDate time.Time, fn func(storage.HolidayWithLocation) error) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	// collected first, fn must not run under the lock
+	var holidays []storage.HolidayWithLocation
+	err = s.read(ctx, func(d *data) error {
+		for _, holiday := range sorted(d.holidays) {
+			if holiday.TenantID != tenantID {
+				continue
+			}
+
+			place, err := owned(d.locations, holiday.LocationID, tenantID, locationTenant)
+			if err != nil {
+				continue
+			}
+
+			if location != "" && !containsFold(place.Country, location) && !containsFold(place.City, location) {
+				continue
+			}
+			if duration > 0 && holiday.Duration != duration {
+				continue
+			}
+			if !startDate.IsZero() && !holiday.StartDate.Equal(startDate) {
+				continue
+			}
+
+			holidays = append(holidays, withLocation(holiday, place))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, holiday := range holidays {
+		if err := fn(holiday); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func withLocation(holiday storage.Holiday, location storage.Location) storage.HolidayWithLocation {
+	return storage.HolidayWithLocation{
+		ID:        holiday.ID,
+		Title:     holiday.Title,
+		Duration:  holiday.Duration,
+		StartDate: holiday.StartDate,
+		Price:     holiday.Price(),
+		FreeSlots: holiday.FreeSlots,
+		Location:  location,
+	}
+}
+
+func (s *Storage) Holiday(ctx context.Context, holidaysID int) (*storage.Holiday, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var holiday storage.Holiday
+	err = s.read(ctx, func(d *data) error {
+		holiday, err = owned(d.holidays, holidaysID, tenantID, holidayTenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &holiday, nil
+}
+
+func (s *Storage) InsertHolidays(ctx context.Context, holidays *storage.Holiday) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	holidays.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		// the location has to belong to the same tenant
+		if _, err := owned(d.locations, holidays.LocationID, tenantID, locationTenant); err != nil {
+			return err
+		}
+		// and so does the rule set
+		if holidays.PricingRuleSetID != nil {
+			if _, err := owned(d.pricingRuleSets, *holidays.PricingRuleSetID, tenantID, pricingRuleSetTenant); err != nil {
+				return err
+			}
+		}
+
+		id = d.nextID(holidaysTable)
+		d.holidays[id] = copyHoliday(holidays, id)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// UpdateHolidays changes nothing and returns no error for a holiday that does
+// not exist, like an UPDATE matching no row.
+func (s *Storage) UpdateHolidays(ctx context.Context, holidays *storage.Holiday) (*storage.Holiday, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	holidays.TenantID = tenantID
+
+	err = s.write(ctx, func(d *data) error {
+		// the location has to belong to the same tenant
+		if _, err := owned(d.locations, holidays.LocationID, tenantID, locationTenant); err != nil {
+			return err
+		}
+		// and so does the rule set
+		if holidays.PricingRuleSetID != nil {
+			if _, err := owned(d.pricingRuleSets, *holidays.PricingRuleSetID, tenantID, pricingRuleSetTenant); err != nil {
+				return err
+			}
+		}
+
+		if _, err := owned(d.holidays, holidays.ID, tenantID, holidayTenant); err == nil {
+			d.holidays[holidays.ID] = copyHoliday(holidays, holidays.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return holidays, nil
+}
+
+func (s *Storage) DeleteHolidays(ctx context.Context, holidaysID int) (*storage.Holiday, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var holiday storage.Holiday
+	err = s.write(ctx, func(d *data) error {
+		holiday, err = owned(d.holidays, holidaysID, tenantID, holidayTenant)
+		if err != nil {
+			return err
+		}
+
+		for _, reservation := range d.reservations {
+			if reservation.HolidayID == holidaysID {
+				return ErrReferenced
+			}
+		}
+
+		delete(d.holidays, holidaysID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &holiday, nil
+}
+
+// copyHoliday does not share the rule set id between the stored record and
+// the caller, who may change it.
+func copyHoliday(holiday *storage.Holiday, id int) storage.Holiday {
+	record := *holiday
+	record.ID = id
+	if holiday.PricingRuleSetID != nil {
+		ruleSetID := *holiday.PricingRuleSetID
+		record.PricingRuleSetID = &ruleSetID
+	}
+
+	return record
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"travel/internal/storage"
+)
+
+func invoiceTenant(invoice storage.Invoice) int { return invoice.TenantID }
+
+// invoiceSequence is a counter of the invoice numbers of a tenant, one per
+// kind of document.
+type invoiceSequence struct {
+	tenantID int
+	kind     string
+}
+
+// checkInvoice keeps sequences and credits unique and credits within the
+// tenant, like the keys of the SQL storage. The reservation is not checked,
+// invoices outlive it.
+func (d *data) checkInvoice(invoice *storage.Invoice) error {
+	if invoice.CreditedInvoiceID != nil {
+		if _, err := owned(d.invoices, *invoice.CreditedInvoiceID, invoice.TenantID, invoiceTenant); err != nil {
+			return err
+		}
+	}
+
+	for _, other := range d.invoices {
+		if other.TenantID == invoice.TenantID && other.Kind == invoice.Kind && other.Sequence == invoice.Sequence {
+			return fmt.Errorf("invoice %q: %w", invoice.Number, ErrDuplicate)
+		}
+		if invoice.CreditedInvoiceID != nil && other.CreditedInvoiceID != nil && *other.CreditedInvoiceID == *invoice.CreditedInvoiceID {
+			return fmt.Errorf("credit of invoice %d: %w", *invoice.CreditedInvoiceID, ErrDuplicate)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) ReservationInvoices(ctx context.Context, reservationID int) ([]storage.Invoice, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invoices := []storage.Invoice{}
+	err = s.read(ctx, func(d *data) error {
+		for _, invoice := range sorted(d.invoices) {
+			if invoice.TenantID == tenantID && invoice.ReservationID == reservationID {
+				invoices = append(invoices, copyInvoice(&invoice, invoice.ID))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invoices, nil
+}
+
+func (s *Storage) Invoice(ctx context.Context, invoiceID int) (*storage.Invoice, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice storage.Invoice
+	err = s.read(ctx, func(d *data) error {
+		invoice, err = owned(d.invoices, invoiceID, tenantID, invoiceTenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invoice = copyInvoice(&invoice, invoice.ID)
+	return &invoice, nil
+}
+
+// NextInvoiceSequence counts like the SQL storage, the write lock held by a
+// transaction keeps others from taking a number meanwhile.
+func (s *Storage) NextInvoiceSequence(ctx context.Context, kind string) (int, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var next int
+	err = s.write(ctx, func(d *data) error {
+		key := invoiceSequence{tenantID: tenantID, kind: kind}
+		d.invoiceCounter[key]++
+		next = d.invoiceCounter[key]
+		return nil
+	})
+
+	return next, err
+}
+
+func (s *Storage) InsertInvoice(ctx context.Context, invoice *storage.Invoice) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	invoice.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		if err := d.checkInvoice(invoice); err != nil {
+			return err
+		}
+
+		id = d.nextID(invoiceTable)
+		d.invoices[id] = copyInvoice(invoice, id)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// copyInvoice does not share the customer and credited ids between the
+// stored record and the caller, who may change them.
+func copyInvoice(invoice *storage.Invoice, id int) storage.Invoice {
+	record := *invoice
+	record.ID = id
+	if invoice.CustomerID != nil {
+		customerID := *invoice.CustomerID
+		record.CustomerID = &customerID
+	}
+	if invoice.CreditedInvoiceID != nil {
+		creditedID := *invoice.CreditedInvoiceID
+		record.CreditedInvoiceID = &creditedID
+	}
+
+	return record
+}
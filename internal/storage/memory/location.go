@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"travel/internal/storage"
+)
+
+func locationTenant(location storage.Location) int { return location.TenantID }
+
+func (s *Storage) LocationGetAll(ctx context.Context) ([]storage.Location, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []storage.Location{}
+	err = s.read(ctx, func(d *data) error {
+		for _, location := range sorted(d.locations) {
+			if location.TenantID == tenantID {
+				locations = append(locations, location)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func (s *Storage) Location(ctx context.Context, locationID int) (*storage.Location, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var location storage.Location
+	err = s.read(ctx, func(d *data) error {
+		location, err = owned(d.locations, locationID, tenantID, locationTenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &location, nil
+}
+
+func (s *Storage) InsertLocation(ctx context.Context, location *storage.Location) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	location.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		id = d.nextID(locationTable)
+		record := *location
+		record.ID = id
+		d.locations[id] = record
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// UpdateLocation changes nothing and returns no error for a location that
+// does not exist, like an UPDATE matching no row.
+func (s *Storage) UpdateLocation(ctx context.Context, location *storage.Location) (*storage.Location, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	location.TenantID = tenantID
+
+	err = s.write(ctx, func(d *data) error {
+		if _, err := owned(d.locations, location.ID, tenantID, locationTenant); err == nil {
+			d.locations[location.ID] = *location
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return location, nil
+}
+
+func (s *Storage) DeleteLocation(ctx context.Context, locationID int) (*storage.Location, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var location storage.Location
+	err = s.write(ctx, func(d *data) error {
+		location, err = owned(d.locations, locationID, tenantID, locationTenant)
+		if err != nil {
+			return err
+		}
+
+		for _, holiday := range d.holidays {
+			if holiday.LocationID == locationID {
+				return ErrReferenced
+			}
+		}
+
+		delete(d.locations, locationID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &location, nil
+}
@@ -0,0 +1,216 @@
+// Package memory is a storage kept in process memory. It behaves like the SQL
+// storage, ids, tenant scoping, foreign keys and transactions included, and
+// lets the API and its tests run without a database. Everything is lost when
+// the process exits.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"travel/internal/storage"
+	"travel/internal/tenant"
+)
+
+// ErrReferenced is returned when a record is deleted while others still
+// reference it, where the SQL storage fails on a foreign key.
+var ErrReferenced = errors.New("record is still referenced")
+
+// ErrDuplicate is returned for a value that has to be unique and is not.
+var ErrDuplicate = errors.New("duplicate value")
+
+// Storage is safe for concurrent use. Transactions run one at a time on a
+// copy of the data that replaces it on commit, so readers outside a
+// transaction never see its writes before that.
+type Storage struct {
+	// mu guards data, writers hold it only to swap in or change data
+	mu   sync.RWMutex
+	data *data
+
+	// writing serializes transactions and writes outside of them, so no
+	// commit overwrites a write made after its copy was taken
+	writing sync.Mutex
+}
+
+type data struct {
+	agencies        map[int]storage.Agency
+	locations       map[int]storage.Location
+	holidays        map[int]storage.Holiday
+	customers       map[int]storage.Customer
+	reservations    map[int]storage.Reservation
+	apiKeys         map[int]storage.APIKey
+	pricingRuleSets map[int]storage.PricingRuleSet
+	exchangeRates   map[int]storage.ExchangeRate
+	promoCodes      map[int]storage.PromoCode
+	payments        map[int]storage.Payment
+	invoices        map[int]storage.Invoice
+	invoiceCounter  map[invoiceSequence]int
+	audit           []storage.AuditEntry
+	permissions     map[string][]string
+
+	// last ids handed out per table, ids are never reused
+	sequences map[string]int
+}
+
+// New returns a storage holding what the migrations seed: the default agency
+// and the roles with their permissions.
+func New() *Storage {
+	d := &data{
+		agencies:        map[int]storage.Agency{},
+		locations:       map[int]storage.Location{},
+		holidays:        map[int]storage.Holiday{},
+		customers:       map[int]storage.Customer{},
+		reservations:    map[int]storage.Reservation{},
+		apiKeys:         map[int]storage.APIKey{},
+		pricingRuleSets: map[int]storage.PricingRuleSet{},
+		exchangeRates:   map[int]storage.ExchangeRate{},
+		promoCodes:      map[int]storage.PromoCode{},
+		payments:        map[int]storage.Payment{},
+		invoices:        map[int]storage.Invoice{},
+		invoiceCounter:  map[invoiceSequence]int{},
+		audit:           []storage.AuditEntry{},
+		permissions:     rolePermissions(),
+		sequences:       map[string]int{},
+	}
+
+	id := d.nextID(agencyTable)
+	d.agencies[id] = storage.Agency{ID: id, Slug: "default", Name: "Default agency"}
+
+	return &Storage{data: d}
+}
+
+func (d *data) clone() *data {
+	permissions := make(map[string][]string, len(d.permissions))
+	for role, names := range d.permissions {
+		permissions[role] = append([]string(nil), names...)
+	}
+
+	return &data{
+		agencies:        maps.Clone(d.agencies),
+		locations:       maps.Clone(d.locations),
+		holidays:        maps.Clone(d.holidays),
+		customers:       maps.Clone(d.customers),
+		reservations:    maps.Clone(d.reservations),
+		apiKeys:         maps.Clone(d.apiKeys),
+		pricingRuleSets: maps.Clone(d.pricingRuleSets),
+		exchangeRates:   maps.Clone(d.exchangeRates),
+		promoCodes:      maps.Clone(d.promoCodes),
+		payments:        maps.Clone(d.payments),
+		invoices:        maps.Clone(d.invoices),
+		invoiceCounter:  maps.Clone(d.invoiceCounter),
+		audit:           append([]storage.AuditEntry(nil), d.audit...),
+		permissions:     permissions,
+		sequences:       maps.Clone(d.sequences),
+	}
+}
+
+func (d *data) nextID(table string) int {
+	d.sequences[table]++
+	return d.sequences[table]
+}
+
+type txKey struct{}
+
+// InTx runs fn in a transaction: every storage call made with the context fn
+// receives joins it. The transaction commits when fn returns nil and rolls
+// back otherwise. Nested calls join the outer transaction.
+func (s *Storage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*data); ok {
+		return fn(ctx)
+	}
+
+	s.writing.Lock()
+	defer s.writing.Unlock()
+
+	s.mu.RLock()
+	tx := s.data.clone()
+	s.mu.RUnlock()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.data = tx
+	s.mu.Unlock()
+
+	return nil
+}
+
+// read runs fn on the data of the transaction in ctx or on the committed
+// data.
+func (s *Storage) read(ctx context.Context, fn func(d *data) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*data); ok {
+		return fn(tx)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return fn(s.data)
+}
+
+// write runs fn on the data of the transaction in ctx or, outside of one, on
+// the committed data. fn has to check everything before it changes anything,
+// there is nothing to roll back to outside a transaction.
+func (s *Storage) write(ctx context.Context, fn func(d *data) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*data); ok {
+		return fn(tx)
+	}
+
+	s.writing.Lock()
+	defer s.writing.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fn(s.data)
+}
+
+func tenantFrom(ctx context.Context) (int, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return 0, storage.ErrNoTenant
+	}
+
+	return tenantID, nil
+}
+
+// sorted returns the records of table in id order, the order the SQL storage
+// returns rows in.
+func sorted[T any](table map[int]T) []T {
+	ids := make([]int, 0, len(table))
+	for id := range table {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	result := make([]T, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, table[id])
+	}
+
+	return result
+}
+
+// owned returns the record of table with id when it belongs to tenantID, and
+// sql.ErrNoRows otherwise, like a query scoped to the tenant.
+func owned[T any](table map[int]T, id int, tenantID int, tenantOf func(T) int) (T, error) {
+	record, ok := table[id]
+	if !ok || tenantOf(record) != tenantID {
+		var zero T
+		return zero, sql.ErrNoRows
+	}
+
+	return record, nil
+}
+
+// containsFold matches like the case-insensitive LIKE '%substr%' of the SQL
+// storage.
+func containsFold(s string, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,13 @@
+package memory_test
+
+import (
+	"testing"
+	"travel/internal/storage/memory"
+	"travel/internal/storage/storagetest"
+)
+
+func TestMemory(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		return memory.New()
+	})
+}
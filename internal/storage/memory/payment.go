@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"travel/internal/storage"
+)
+
+func paymentTenant(payment storage.Payment) int { return payment.TenantID }
+
+// checkPayment makes sure the reservation belongs to the tenant of the
+// payment and no other payment has its intent, like the keys of the SQL
+// storage.
+func (d *data) checkPayment(payment *storage.Payment) error {
+	if _, err := owned(d.reservations, payment.ReservationID, payment.TenantID, reservationTenant); err != nil {
+		return err
+	}
+
+	if payment.IntentID == nil {
+		return nil
+	}
+	for _, other := range d.payments {
+		if other.ID != payment.ID && other.Gateway == payment.Gateway && other.IntentID != nil && *other.IntentID == *payment.IntentID {
+			return fmt.Errorf("payment intent %q: %w", *payment.IntentID, ErrDuplicate)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) ReservationPayments(ctx context.Context, reservationID int) ([]storage.Payment, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payments := []storage.Payment{}
+	err = s.read(ctx, func(d *data) error {
+		for _, payment := range sorted(d.payments) {
+			if payment.TenantID == tenantID && payment.ReservationID == reservationID {
+				payments = append(payments, copyPayment(&payment, payment.ID))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+func (s *Storage) Payment(ctx context.Context, paymentID int) (*storage.Payment, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment storage.Payment
+	err = s.read(ctx, func(d *data) error {
+		payment, err = owned(d.payments, paymentID, tenantID, paymentTenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payment = copyPayment(&payment, payment.ID)
+	return &payment, nil
+}
+
+// LockPayment only checks that the payment exists, transactions run one at
+// a time.
+func (s *Storage) LockPayment(ctx context.Context, paymentID int) error {
+	_, err := s.Payment(ctx, paymentID)
+	return err
+}
+
+func (s *Storage) InsertPayment(ctx context.Context, payment *storage.Payment) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	payment.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		if err := d.checkPayment(payment); err != nil {
+			return err
+		}
+
+		id = d.nextID(paymentTable)
+		d.payments[id] = copyPayment(payment, id)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// UpdatePayment changes nothing and returns no error for a payment that does
+// not exist, like an UPDATE matching no row.
+func (s *Storage) UpdatePayment(ctx context.Context, payment *storage.Payment) (*storage.Payment, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payment.TenantID = tenantID
+
+	err = s.write(ctx, func(d *data) error {
+		if err := d.checkPayment(payment); err != nil {
+			return err
+		}
+
+		if _, err := owned(d.payments, payment.ID, tenantID, paymentTenant); err == nil {
+			d.payments[payment.ID] = copyPayment(payment, payment.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// copyPayment does not share the intent id between the stored record and
+// the caller, who may change it.
+func copyPayment(payment *storage.Payment, id int) storage.Payment {
+	record := *payment
+	record.ID = id
+	if payment.IntentID != nil {
+		intentID := *payment.IntentID
+		record.IntentID = &intentID
+	}
+
+	return record
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"travel/internal/storage"
+)
+
+func pricingRuleSetTenant(ruleSet storage.PricingRuleSet) int { return ruleSet.TenantID }
+
+func (s *Storage) PricingRuleSetGetAll(ctx context.Context) ([]storage.PricingRuleSet, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSets := []storage.PricingRuleSet{}
+	err = s.read(ctx, func(d *data) error {
+		for _, ruleSet := range sorted(d.pricingRuleSets) {
+			if ruleSet.TenantID == tenantID {
+				ruleSets = append(ruleSets, ruleSet)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ruleSets, nil
+}
+
+func (s *Storage) PricingRuleSet(ctx context.Context, ruleSetID int) (*storage.PricingRuleSet, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleSet storage.PricingRuleSet
+	err = s.read(ctx, func(d *data) error {
+		ruleSet, err = owned(d.pricingRuleSets, ruleSetID, tenantID, pricingRuleSetTenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ruleSet, nil
+}
+
+func (s *Storage) InsertPricingRuleSet(ctx context.Context, ruleSet *storage.PricingRuleSet) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ruleSet.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		id = d.nextID(pricingRuleSetTable)
+		record := *ruleSet
+		record.ID = id
+		d.pricingRuleSets[id] = record
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// UpdatePricingRuleSet changes nothing and returns no error for a rule set
+// that does not exist, like an UPDATE matching no row.
+func (s *Storage) UpdatePricingRuleSet(ctx context.Context, ruleSet *storage.PricingRuleSet) (*storage.PricingRuleSet, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ruleSet.TenantID = tenantID
+
+	err = s.write(ctx, func(d *data) error {
+		if _, err := owned(d.pricingRuleSets, ruleSet.ID, tenantID, pricingRuleSetTenant); err == nil {
+			d.pricingRuleSets[ruleSet.ID] = *ruleSet
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ruleSet, nil
+}
+
+func (s *Storage) DeletePricingRuleSet(ctx context.Context, ruleSetID int) (*storage.PricingRuleSet, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleSet storage.PricingRuleSet
+	err = s.write(ctx, func(d *data) error {
+		ruleSet, err = owned(d.pricingRuleSets, ruleSetID, tenantID, pricingRuleSetTenant)
+		if err != nil {
+			return err
+		}
+
+		for _, holiday := range d.holidays {
+			if holiday.PricingRuleSetID != nil && *holiday.PricingRuleSetID == ruleSetID {
+				return ErrReferenced
+			}
+		}
+
+		delete(d.pricingRuleSets, ruleSetID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ruleSet, nil
+}
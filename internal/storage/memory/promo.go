@@ -0,0 +1,249 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"travel/internal/storage"
+)
+
+func promoCodeTenant(code storage.PromoCode) int { return code.TenantID }
+
+// promoCode finds a code of the tenant, ok is false when it has none.
+func (d *data) promoCode(code string, tenantID int) (storage.PromoCode, bool) {
+	for _, promoCode := range d.promoCodes {
+		if promoCode.Code == code && promoCode.TenantID == tenantID {
+			return promoCode, true
+		}
+	}
+
+	return storage.PromoCode{}, false
+}
+
+// checkPromoCode makes sure no other code of the tenant has the code of
+// promoCode, like the unique key of the SQL storage.
+func (d *data) checkPromoCode(promoCode *storage.PromoCode) error {
+	if other, ok := d.promoCode(promoCode.Code, promoCode.TenantID); ok && other.ID != promoCode.ID {
+		return fmt.Errorf("promo code %q: %w", promoCode.Code, ErrDuplicate)
+	}
+
+	return nil
+}
+
+func (s *Storage) PromoCodeGetAll(ctx context.Context) ([]storage.PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := []storage.PromoCode{}
+	err = s.read(ctx, func(d *data) error {
+		for _, code := range sorted(d.promoCodes) {
+			if code.TenantID == tenantID {
+				codes = append(codes, copyPromoCode(&code, code.ID))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *Storage) PromoCode(ctx context.Context, promoCodeID int) (*storage.PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var code storage.PromoCode
+	err = s.read(ctx, func(d *data) error {
+		code, err = owned(d.promoCodes, promoCodeID, tenantID, promoCodeTenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	code = copyPromoCode(&code, code.ID)
+	return &code, nil
+}
+
+func (s *Storage) PromoCodeByCode(ctx context.Context, code string) (*storage.PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var promoCode storage.PromoCode
+	err = s.read(ctx, func(d *data) error {
+		var ok bool
+		if promoCode, ok = d.promoCode(code, tenantID); !ok {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	promoCode = copyPromoCode(&promoCode, promoCode.ID)
+	return &promoCode, nil
+}
+
+func (s *Storage) InsertPromoCode(ctx context.Context, code *storage.PromoCode) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	code.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		if err := d.checkPromoCode(code); err != nil {
+			return err
+		}
+
+		id = d.nextID(promoCodeTable)
+		d.promoCodes[id] = copyPromoCode(code, id)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// UpdatePromoCode changes nothing and returns no error for a code that does
+// not exist, like an UPDATE matching no row.
+func (s *Storage) UpdatePromoCode(ctx context.Context, code *storage.PromoCode) (*storage.PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	code.TenantID = tenantID
+
+	err = s.write(ctx, func(d *data) error {
+		if err := d.checkPromoCode(code); err != nil {
+			return err
+		}
+
+		if _, err := owned(d.promoCodes, code.ID, tenantID, promoCodeTenant); err == nil {
+			d.promoCodes[code.ID] = copyPromoCode(code, code.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return code, nil
+}
+
+func (s *Storage) DeletePromoCode(ctx context.Context, promoCodeID int) (*storage.PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var code storage.PromoCode
+	err = s.write(ctx, func(d *data) error {
+		code, err = owned(d.promoCodes, promoCodeID, tenantID, promoCodeTenant)
+		if err != nil {
+			return err
+		}
+
+		for _, reservation := range d.reservations {
+			if reservation.PromoCodeID != nil && *reservation.PromoCodeID == promoCodeID {
+				return ErrReferenced
+			}
+		}
+
+		delete(d.promoCodes, promoCodeID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// PromoCodeRedemptions returns the reservations booked with a promo code in
+// id order.
+func (s *Storage) PromoCodeRedemptions(ctx context.Context, promoCodeID int) ([]storage.Reservation, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations := []storage.Reservation{}
+	err = s.read(ctx, func(d *data) error {
+		for _, reservation := range sorted(d.reservations) {
+			if reservation.TenantID == tenantID && reservation.PromoCodeID != nil && *reservation.PromoCodeID == promoCodeID {
+				reservations = append(reservations, copyReservation(&reservation, reservation.ID))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// LockPromoCode only checks that the code exists, transactions run one at a
+// time.
+func (s *Storage) LockPromoCode(ctx context.Context, promoCodeID int) error {
+	_, err := s.PromoCode(ctx, promoCodeID)
+	return err
+}
+
+func (s *Storage) PromoCodeRedemptionCount(ctx context.Context, promoCodeID int, customerID *int) (int, error) {
+	redemptions, err := s.PromoCodeRedemptions(ctx, promoCodeID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, reservation := range redemptions {
+		if customerID == nil || (reservation.CustomerID != nil && *reservation.CustomerID == *customerID) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// copyPromoCode does not share the amounts, currency and dates between the
+// stored record and the caller, who may change them.
+func copyPromoCode(code *storage.PromoCode, id int) storage.PromoCode {
+	record := *code
+	record.ID = id
+	if code.AmountMinor != nil {
+		amount := *code.AmountMinor
+		record.AmountMinor = &amount
+	}
+	if code.MinSpendMinor != nil {
+		minSpend := *code.MinSpendMinor
+		record.MinSpendMinor = &minSpend
+	}
+	if code.Currency != nil {
+		currency := *code.Currency
+		record.Currency = &currency
+	}
+	if code.ValidFrom != nil {
+		validFrom := *code.ValidFrom
+		record.ValidFrom = &validFrom
+	}
+	if code.ValidTo != nil {
+		validTo := *code.ValidTo
+		record.ValidTo = &validTo
+	}
+
+	return record
+}
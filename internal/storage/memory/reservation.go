@@ -0,0 +1,263 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"travel/internal/storage"
+)
+
+func reservationTenant(reservation storage.Reservation) int { return reservation.TenantID }
+
+func (s *Storage) ReservationGetAll(ctx context.Context) (interface{}, error) {
+	reservations := []storage.ReservationResult{}
+
+	err := s.ReservationEach(ctx, func(reservation storage.ReservationResult) error {
+		reservations = append(reservations, reservation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// ReservationEach hands every reservation joined with its holiday and
+// location to fn.
+func (s *Storage) ReservationEach(ctx context.Context, fn func(storage.ReservationResult) error) error {
+	return s.reservationsEach(ctx, nil, fn)
+}
+
+func (s *Storage) reservationsEach(ctx context.Context, where func(storage.Reservation) bool, fn func(storage.ReservationResult) error) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	// collected first, fn must not run under the lock
+	var reservations []storage.ReservationResult
+	err = s.read(ctx, func(d *data) error {
+		for _, reservation := range sorted(d.reservations) {
+			if reservation.TenantID != tenantID || (where != nil && !where(reservation)) {
+				continue
+			}
+
+			holiday, err := owned(d.holidays, reservation.HolidayID, tenantID, holidayTenant)
+			if err != nil {
+				continue
+			}
+			location, err := owned(d.locations, holiday.LocationID, tenantID, locationTenant)
+			if err != nil {
+				continue
+			}
+
+			reservations = append(reservations, storage.ReservationResult{
+				ID:          reservation.ID,
+				ContactName: reservation.ContactName,
+				PhoneNumber: reservation.PhoneNumber,
+				CustomerID:  copyReservation(&reservation, reservation.ID).CustomerID,
+				PhoneE164:   reservation.PhoneE164,
+				Holiday:     withLocation(holiday, location),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, reservation := range reservations {
+		if err := fn(reservation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) Reservation(ctx context.Context, reservationID int) (*storage.Reservation, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservation storage.Reservation
+	err = s.read(ctx, func(d *data) error {
+		reservation, err = owned(d.reservations, reservationID, tenantID, reservationTenant)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reservation = copyReservation(&reservation, reservation.ID)
+	return &reservation, nil
+}
+
+func (s *Storage) InsertReservation(ctx context.Context, reservation *storage.Reservation) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	reservation.TenantID = tenantID
+
+	var id int
+	err = s.write(ctx, func(d *data) error {
+		if err := d.checkReservation(reservation); err != nil {
+			return err
+		}
+
+		id = d.nextID(reservationTable)
+		d.reservations[id] = copyReservation(reservation, id)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(id), nil
+}
+
+// UpdateReservation changes nothing and returns no error for a reservation
+// that does not exist, like an UPDATE matching no row.
+func (s *Storage) UpdateReservation(ctx context.Context, reservation *storage.Reservation) (*storage.Reservation, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reservation.TenantID = tenantID
+
+	err = s.write(ctx, func(d *data) error {
+		if err := d.checkReservation(reservation); err != nil {
+			return err
+		}
+
+		if _, err := owned(d.reservations, reservation.ID, tenantID, reservationTenant); err == nil {
+			d.reservations[reservation.ID] = copyReservation(reservation, reservation.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+func (s *Storage) DeleteReservation(ctx context.Context, reservationID int) (*storage.Reservation, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservation storage.Reservation
+	err = s.write(ctx, func(d *data) error {
+		reservation, err = owned(d.reservations, reservationID, tenantID, reservationTenant)
+		if err != nil {
+			return err
+		}
+
+		delete(d.reservations, reservationID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// LockReservation only checks that the reservation exists, transactions run
+// one at a time.
+func (s *Storage) LockReservation(ctx context.Context, reservationID int) error {
+	_, err := s.Reservation(ctx, reservationID)
+	return err
+}
+
+func (s *Storage) QuoteBooked(ctx context.Context, quoteID string) (bool, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	booked := false
+	err = s.read(ctx, func(d *data) error {
+		for _, reservation := range d.reservations {
+			if reservation.TenantID == tenantID && reservation.QuoteID != nil && *reservation.QuoteID == quoteID {
+				booked = true
+				break
+			}
+		}
+		return nil
+	})
+
+	return booked, err
+}
+
+// checkReservation makes sure the holiday, the customer and the promo code
+// belong to the tenant of the reservation and keeps quotes to one
+// reservation, like the unique key of the SQL storage.
+func (d *data) checkReservation(reservation *storage.Reservation) error {
+	if _, err := owned(d.holidays, reservation.HolidayID, reservation.TenantID, holidayTenant); err != nil {
+		return err
+	}
+
+	if reservation.CustomerID != nil {
+		if _, err := owned(d.customers, *reservation.CustomerID, reservation.TenantID, customerTenant); err != nil {
+			return err
+		}
+	}
+
+	if reservation.PromoCodeID != nil {
+		if _, err := owned(d.promoCodes, *reservation.PromoCodeID, reservation.TenantID, promoCodeTenant); err != nil {
+			return err
+		}
+	}
+
+	if reservation.QuoteID != nil {
+		for id, other := range d.reservations {
+			if id != reservation.ID && other.TenantID == reservation.TenantID && other.QuoteID != nil && *other.QuoteID == *reservation.QuoteID {
+				return fmt.Errorf("reservation quote %q: %w", *reservation.QuoteID, ErrDuplicate)
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyReservation does not share the customer and promo code ids and the JSON
+// columns between the stored record and the caller, who may change them.
+func copyReservation(reservation *storage.Reservation, id int) storage.Reservation {
+	record := *reservation
+	record.ID = id
+	if reservation.CustomerID != nil {
+		customerID := *reservation.CustomerID
+		record.CustomerID = &customerID
+	}
+	if reservation.Pricing != nil {
+		pricing := *reservation.Pricing
+		record.Pricing = &pricing
+	}
+	if reservation.ExchangeRates != nil {
+		rates := *reservation.ExchangeRates
+		record.ExchangeRates = &rates
+	}
+	if reservation.Quote != nil {
+		quote := *reservation.Quote
+		record.Quote = &quote
+	}
+	if reservation.PromoCodeID != nil {
+		promoCodeID := *reservation.PromoCodeID
+		record.PromoCodeID = &promoCodeID
+	}
+	if reservation.Promo != nil {
+		promo := *reservation.Promo
+		record.Promo = &promo
+	}
+	if reservation.QuoteID != nil {
+		quoteID := *reservation.QuoteID
+		record.QuoteID = &quoteID
+	}
+
+	return record
+}
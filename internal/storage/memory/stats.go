@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"travel/internal/storage"
+)
+
+// HolidayStats returns the figures of every holiday of every agency. Like
+// the other statistics it is not tenant scoped, it feeds /metrics only.
+func (s *Storage) HolidayStats(ctx context.Context) ([]storage.HolidayStats, error) {
+	stats := []storage.HolidayStats{}
+	err := s.read(ctx, func(d *data) error {
+		sold := map[int]int{}
+		for _, reservation := range d.reservations {
+			sold[reservation.HolidayID]++
+		}
+
+		for _, holiday := range sorted(d.holidays) {
+			stats = append(stats, storage.HolidayStats{
+				HolidayID: holiday.ID,
+				Agency:    d.agencies[holiday.TenantID].Slug,
+				FreeSlots: holiday.FreeSlots,
+				SeatsSold: sold[holiday.ID],
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ReservationsCreated counts the reservations ever created per agency. It is
+// read from the audit log, which is append-only, so deleting a reservation
+// does not lower the count.
+func (s *Storage) ReservationsCreated(ctx context.Context) (map[string]int, error) {
+	created := map[string]int{}
+	err := s.read(ctx, func(d *data) error {
+		for _, entry := range d.audit {
+			if entry.Resource == "reservation" && entry.Action == "create" {
+				created[d.agencies[entry.TenantID].Slug]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
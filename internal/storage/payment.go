@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// Payment is one installment of a reservation, collected through the intent
+// IntentID at Gateway. AmountMinor, RefundedMinor and RefundingMinor are in
+// Currency. RefundingMinor is a refund sent to the gateway that is not
+// confirmed yet.
+type Payment struct {
+	ID            int       `db:"id" goqu:"skipinsert"`
+	ReservationID int       `db:"reservationID"`
+	Kind          string    `db:"kind"`
+	AmountMinor   int64     `db:"amountMinor"`
+	Currency      string    `db:"currency"`
+	DueDate       time.Time `db:"dueDate"`
+	Status        string    `db:"status"`
+	Gateway       string    `db:"gateway"`
+	IntentID      *string   `db:"intentID"`
+	RefundedMinor int64     `db:"refundedMinor"`
+	CreatedAt     time.Time `db:"createdAt"`
+	UpdatedAt     time.Time `db:"updatedAt"`
+	TenantID      int       `db:"tenantID"`
+	// RefundingMinor is 0 unless a refund is on its way.
+	RefundingMinor int64 `db:"refundingMinor"`
+}
+
+const paymentTable = "payment"
+
+// ReservationPayments returns the payments of a reservation in the order
+// they were made.
+func (s *Storage) ReservationPayments(ctx context.Context, reservationID int) ([]Payment, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var payments = []Payment{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(paymentTable).
+		Where(goqu.C("reservationID").Eq(reservationID), goqu.C(tenantColumn).Eq(tenantID)).
+		Order(goqu.C("id").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var payment Payment
+		if err := rows.Scan(getColumnsForStruct(&payment)...); err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
+
+func (s *Storage) Payment(ctx context.Context, paymentID int) (*Payment, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment = &Payment{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(paymentTable).
+		Select("*").
+		Where(goqu.C("id").Eq(paymentID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(getColumnsForStruct(payment)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// LockPayment locks a payment until the transaction of ctx ends, so that
+// refunds and events of the gateway change it one after the other.
+func (s *Storage) LockPayment(ctx context.Context, paymentID int) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	sqlStr, _, err := s.forUpdate(goqu.Dialect(s.dialect).
+		From(paymentTable).
+		Select("id").
+		Where(goqu.C("id").Eq(paymentID), goqu.C(tenantColumn).Eq(tenantID))).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	var id int
+	return s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&id)
+}
+
+func (s *Storage) InsertPayment(ctx context.Context, payment *Payment) (int64, error) {
+	if err := s.scopePayment(ctx, payment); err != nil {
+		return 0, err
+	}
+
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(paymentTable).
+		Insert().
+		Rows(payment))
+}
+
+func (s *Storage) UpdatePayment(ctx context.Context, payment *Payment) (*Payment, error) {
+	if err := s.scopePayment(ctx, payment); err != nil {
+		return nil, err
+	}
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(paymentTable).
+		Update().
+		Set(payment).
+		Where(goqu.C("id").Eq(payment.ID), goqu.C(tenantColumn).Eq(payment.TenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// scopePayment stamps the tenant on a payment and makes sure its reservation
+// belongs to that tenant too.
+func (s *Storage) scopePayment(ctx context.Context, payment *Payment) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	payment.TenantID = tenantID
+
+	_, err = s.Reservation(ctx, payment.ReservationID)
+	return err
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+type PricingRuleSet struct {
+	ID   int    `db:"id" goqu:"skipinsert"`
+	Name string `db:"name"`
+	// Rules is the JSON array of the rules, the storage does not look into it.
+	Rules    string `db:"rules"`
+	TenantID int    `db:"tenantID"`
+}
+
+const pricingRuleSetTable = "pricing_rule_set"
+
+func (s *Storage) PricingRuleSetGetAll(ctx context.Context) ([]PricingRuleSet, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleSets = []PricingRuleSet{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(pricingRuleSetTable).
+		Where(goqu.C(tenantColumn).Eq(tenantID)).
+		Order(goqu.C("id").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ruleSet PricingRuleSet
+		if err := rows.Scan(getColumnsForStruct(&ruleSet)...); err != nil {
+			return nil, err
+		}
+		ruleSets = append(ruleSets, ruleSet)
+	}
+
+	return ruleSets, rows.Err()
+}
+
+func (s *Storage) PricingRuleSet(ctx context.Context, ruleSetID int) (*PricingRuleSet, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleSet = &PricingRuleSet{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(pricingRuleSetTable).
+		Select("*").
+		Where(goqu.C("id").Eq(ruleSetID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(getColumnsForStruct(ruleSet)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ruleSet, nil
+}
+
+func (s *Storage) InsertPricingRuleSet(ctx context.Context, ruleSet *PricingRuleSet) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ruleSet.TenantID = tenantID
+
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(pricingRuleSetTable).
+		Insert().
+		Rows(ruleSet))
+}
+
+func (s *Storage) UpdatePricingRuleSet(ctx context.Context, ruleSet *PricingRuleSet) (*PricingRuleSet, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ruleSet.TenantID = tenantID
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(pricingRuleSetTable).
+		Update().
+		Set(ruleSet).
+		Where(goqu.C("id").Eq(ruleSet.ID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return ruleSet, nil
+}
+
+func (s *Storage) DeletePricingRuleSet(ctx context.Context, ruleSetID int) (*PricingRuleSet, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(pricingRuleSetTable).
+		Delete().
+		Where(goqu.C("id").Eq(ruleSetID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSet, err := s.PricingRuleSet(ctx, ruleSetID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return ruleSet, nil
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// PromoCode is a promo code with the terms it is redeemed on, see
+// promo.Code. AmountMinor and MinSpendMinor are in Currency.
+type PromoCode struct {
+	ID             int        `db:"id" goqu:"skipinsert"`
+	Code           string     `db:"code"`
+	Description    string     `db:"description"`
+	Percent        float64    `db:"percent"`
+	AmountMinor    *int64     `db:"amountMinor"`
+	MinSpendMinor  *int64     `db:"minSpendMinor"`
+	Currency       *string    `db:"currency"`
+	ValidFrom      *time.Time `db:"validFrom"`
+	ValidTo        *time.Time `db:"validTo"`
+	MaxRedemptions int        `db:"maxRedemptions"`
+	MaxPerCustomer int        `db:"maxPerCustomer"`
+	// Holidays and Locations are the JSON arrays of the ids the code is
+	// restricted to, the storage does not look into them.
+	Holidays  string `db:"holidays"`
+	Locations string `db:"locations"`
+	TenantID  int    `db:"tenantID"`
+}
+
+const promoCodeTable = "promo_code"
+
+func (s *Storage) PromoCodeGetAll(ctx context.Context) ([]PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes = []PromoCode{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(promoCodeTable).
+		Where(goqu.C(tenantColumn).Eq(tenantID)).
+		Order(goqu.C("id").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var code PromoCode
+		if err := rows.Scan(getColumnsForStruct(&code)...); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+func (s *Storage) PromoCode(ctx context.Context, promoCodeID int) (*PromoCode, error) {
+	return s.promoCodeWhere(ctx, goqu.C("id").Eq(promoCodeID))
+}
+
+// PromoCodeByCode looks a code up as customers enter it, codes are stored
+// normalized.
+func (s *Storage) PromoCodeByCode(ctx context.Context, code string) (*PromoCode, error) {
+	return s.promoCodeWhere(ctx, goqu.C("code").Eq(code))
+}
+
+func (s *Storage) promoCodeWhere(ctx context.Context, where goqu.Expression) (*PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var code = &PromoCode{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(promoCodeTable).
+		Select("*").
+		Where(where, goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(getColumnsForStruct(code)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return code, nil
+}
+
+func (s *Storage) InsertPromoCode(ctx context.Context, code *PromoCode) (int64, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	code.TenantID = tenantID
+
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(promoCodeTable).
+		Insert().
+		Rows(code))
+}
+
+func (s *Storage) UpdatePromoCode(ctx context.Context, code *PromoCode) (*PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	code.TenantID = tenantID
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(promoCodeTable).
+		Update().
+		Set(code).
+		Where(goqu.C("id").Eq(code.ID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return code, nil
+}
+
+func (s *Storage) DeletePromoCode(ctx context.Context, promoCodeID int) (*PromoCode, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		From(promoCodeTable).
+		Delete().
+		Where(goqu.C("id").Eq(promoCodeID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.PromoCode(ctx, promoCodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return code, nil
+}
+
+// PromoCodeRedemptions returns the reservations booked with a promo code, in
+// the order they were made.
+func (s *Storage) PromoCodeRedemptions(ctx context.Context, promoCodeID int) ([]Reservation, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservations = []Reservation{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select("*").
+		From(reservationTable).
+		Where(goqu.C("promoCodeID").Eq(promoCodeID), goqu.C(tenantColumn).Eq(tenantID)).
+		Order(goqu.C("id").Asc()).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var reservation Reservation
+		if err := rows.Scan(getColumnsForStruct(&reservation)...); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, rows.Err()
+}
+
+// LockPromoCode locks a promo code until the transaction of ctx ends, so that
+// bookings redeeming it count its redemptions one after the other.
+func (s *Storage) LockPromoCode(ctx context.Context, promoCodeID int) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	sqlStr, _, err := s.forUpdate(goqu.Dialect(s.dialect).
+		From(promoCodeTable).
+		Select("id").
+		Where(goqu.C("id").Eq(promoCodeID), goqu.C(tenantColumn).Eq(tenantID))).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	var id int
+	return s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&id)
+}
+
+// PromoCodeRedemptionCount counts the reservations booked with a promo code,
+// of one customer unless customerID is nil.
+func (s *Storage) PromoCodeRedemptionCount(ctx context.Context, promoCodeID int, customerID *int) (int, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	where := goqu.Ex{"promoCodeID": promoCodeID, tenantColumn: tenantID}
+	if customerID != nil {
+		where["customerID"] = *customerID
+	}
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select(goqu.COUNT("*")).
+		From(reservationTable).
+		Where(where).ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&count)
+
+	return count, err
+}
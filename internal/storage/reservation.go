@@ -1,9 +1,9 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 
 	"github.com/doug-martin/goqu/v9"
 )
@@ -11,44 +11,100 @@ import (
 var reservationTable = "reservation"
 
 type Reservation struct {
-	ID          int    `db:"id"`
+	ID          int    `db:"id" goqu:"skipinsert"`
 	ContactName string `db:"contactName"`
 	PhoneNumber string `db:"phoneNumber"`
 	HolidayID   int    `db:"holidayID"`
+	CustomerID  *int   `db:"customerID"`
+	PhoneE164   string `db:"phoneE164"`
+	TenantID    int    `db:"tenantID"`
+	// Pricing is the JSON of the quote the reservation was booked at, nil
+	// for reservations made before prices were quoted.
+	Pricing *string `db:"pricing"`
+	// ExchangeRates is the JSON of the exchange rates of the agency when the
+	// reservation was booked, nil for reservations made before.
+	ExchangeRates *string `db:"exchangeRates"`
+	// Quote is the JSON of the signed quote the reservation was booked at,
+	// with its party and items, nil when it was booked without one.
+	Quote *string `db:"quote"`
+	// PromoCodeID is the promo code the reservation was booked with, Promo
+	// the JSON of the code and what it took off.
+	PromoCodeID *int    `db:"promoCodeID"`
+	Promo       *string `db:"promo"`
+	// QuoteID is the ID of the quote the reservation was booked at, unique
+	// per tenant so that a quote books one reservation.
+	QuoteID *string `db:"quoteID"`
 }
 
 type ReservationResult struct {
 	ID          int                 `db:"id" json:"id"`
 	ContactName string              `db:"contactName" json:"contactName"`
 	PhoneNumber string              `db:"phoneNumber" json:"phoneNumber"`
+	CustomerID  *int                `db:"customerID" json:"customerID"`
+	PhoneE164   string              `db:"phoneE164" json:"phoneE164"`
 	Holiday     HolidayWithLocation `db:"holiday" json:"holiday"`
 }
 
-func (s *Storage) ReservationGetAll() (interface{}, error) {
-	sqlStr, _, err := goqu.Dialect("mysql").
+func (s *Storage) ReservationGetAll(ctx context.Context) (interface{}, error) {
+	resultStruct := []ReservationResult{}
+
+	err := s.ReservationEach(ctx, func(reservation ReservationResult) error {
+		resultStruct = append(resultStruct, reservation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resultStruct, nil
+}
+
+// ReservationEach runs the ReservationGetAll query and hands every joined row
+// to fn as soon as it is scanned.
+func (s *Storage) ReservationEach(ctx context.Context, fn func(ReservationResult) error) error {
+	return s.reservationsEach(ctx, nil, fn)
+}
+
+func (s *Storage) reservationsEach(ctx context.Context, where goqu.Ex, fn func(ReservationResult) error) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	sql := goqu.Dialect(s.dialect).
 		Select(goqu.T(reservationTable).All(), goqu.T(holidaysTable).All(), goqu.T(locationTable).All()).
 		From(reservationTable).InnerJoin(
 		goqu.T(holidaysTable),
-		goqu.On(goqu.Ex{reservationTable + ".holidayID": goqu.I(holidaysTable + ".id")}),
+		goqu.On(goqu.Ex{
+			reservationTable + ".holidayID": goqu.I(holidaysTable + ".id"),
+			holidaysTable + ".tenantID":     goqu.I(reservationTable + ".tenantID"),
+		}),
 	).InnerJoin(
 		goqu.T(locationTable),
-		goqu.On(goqu.Ex{holidaysTable + ".locationID": goqu.I(locationTable + ".id")}),
-	).ToSQL()
+		goqu.On(goqu.Ex{
+			holidaysTable + ".locationID": goqu.I(locationTable + ".id"),
+			locationTable + ".tenantID":   goqu.I(holidaysTable + ".tenantID"),
+		}),
+	).Where(goqu.Ex{reservationTable + ".tenantID": tenantID})
+
+	if where != nil {
+		sql = sql.Where(where)
+	}
+
+	sqlStr, _, err := sql.ToSQL()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	fmt.Println(sqlStr)
+	s.logger.DebugContext(ctx, "query", "sql", sqlStr)
 
-	rows, err := s.db.Query(sqlStr)
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	defer rows.Close()
 
-	resultStruct := []ReservationResult{}
-
 	for rows.Next() {
 		var reservation Reservation
 		var holiday Holiday
@@ -57,38 +113,49 @@ func (s *Storage) ReservationGetAll() (interface{}, error) {
 		columns = append(columns, getColumnsForStruct(&holiday)...)
 		columns = append(columns, getColumnsForStruct(&location)...)
 		if err := rows.Scan(columns...); err != nil {
-			return nil, err
+			return err
 		}
-		resultStruct = append(resultStruct, ReservationResult{
+
+		err := fn(ReservationResult{
 			ID:          reservation.ID,
 			ContactName: reservation.ContactName,
 			PhoneNumber: reservation.PhoneNumber,
+			CustomerID:  reservation.CustomerID,
+			PhoneE164:   reservation.PhoneE164,
 			Holiday: HolidayWithLocation{
 				ID:        holiday.ID,
 				Title:     holiday.Title,
 				Duration:  holiday.Duration,
 				StartDate: holiday.StartDate,
-				Price:     holiday.Price,
+				Price:     holiday.Price(),
 				FreeSlots: holiday.FreeSlots,
 				Location:  location,
 			},
 		})
+		if err != nil {
+			return err
+		}
 	}
 
-	return resultStruct, nil
+	return rows.Err()
 }
 
-func (s *Storage) Reservation(reservationID int) (*Reservation, error) {
+func (s *Storage) Reservation(ctx context.Context, reservationID int) (*Reservation, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var reservation = &Reservation{}
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		From(reservationTable).
 		Select("*").
-		Where(goqu.C("id").Eq(reservationID)).ToSQL()
+		Where(goqu.C("id").Eq(reservationID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	row := s.db.QueryRow(sqlStr)
+	row := s.conn(ctx).QueryRowContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -102,40 +169,33 @@ func (s *Storage) Reservation(reservationID int) (*Reservation, error) {
 	return reservation, nil
 }
 
-func (s *Storage) InsertReservation(reservation *Reservation) (int64, error) {
-	sqlStr, _, err := goqu.Dialect(s.dialect).
-		From(reservationTable).
-		Insert().
-		Rows(reservation).ToSQL()
-	if err != nil {
+func (s *Storage) InsertReservation(ctx context.Context, reservation *Reservation) (int64, error) {
+	if err := s.scopeReservation(ctx, reservation); err != nil {
 		return 0, err
 	}
 
-	result, err := s.db.Exec(sqlStr)
-	if err != nil {
-		return 0, err
-	}
+	return s.insert(ctx, goqu.Dialect(s.dialect).
+		From(reservationTable).
+		Insert().
+		Rows(reservation))
+}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
+func (s *Storage) UpdateReservation(ctx context.Context, reservation *Reservation) (*Reservation, error) {
+	if err := s.scopeReservation(ctx, reservation); err != nil {
+		return nil, err
 	}
 
-	return id, nil
-}
-
-func (s *Storage) UpdateReservation(reservation *Reservation) (*Reservation, error) {
 	updateReservation := &Reservation{}
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		From(reservationTable).
 		Update().
 		Set(reservation).
-		Where(goqu.C("id").Eq(reservation.ID)).ToSQL()
+		Where(goqu.C("id").Eq(reservation.ID), goqu.C(tenantColumn).Eq(reservation.TenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	row := s.db.QueryRow(sqlStr)
+	row := s.conn(ctx).QueryRowContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
@@ -156,24 +216,98 @@ func (s *Storage) UpdateReservation(reservation *Reservation) (*Reservation, err
 	return reservation, nil
 }
 
-func (s *Storage) DeleteReservation(reservationID int) (*Reservation, error) {
+func (s *Storage) DeleteReservation(ctx context.Context, reservationID int) (*Reservation, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	sqlStr, _, err := goqu.Dialect(s.dialect).
 		From(reservationTable).
 		Delete().
-		Where(goqu.C("id").Eq(reservationID)).ToSQL()
+		Where(goqu.C("id").Eq(reservationID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
 	if err != nil {
 		return nil, err
 	}
 
-	reservation, err := s.Reservation(reservationID)
+	reservation, err := s.Reservation(ctx, reservationID)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = s.db.Exec(sqlStr)
+	_, err = s.conn(ctx).ExecContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
 
 	return reservation, nil
 }
+
+// LockReservation locks a reservation until the transaction of ctx ends, so
+// that it is not cancelled while it is being paid and the other way round.
+func (s *Storage) LockReservation(ctx context.Context, reservationID int) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	sqlStr, _, err := s.forUpdate(goqu.Dialect(s.dialect).
+		From(reservationTable).
+		Select("id").
+		Where(goqu.C("id").Eq(reservationID), goqu.C(tenantColumn).Eq(tenantID))).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	var id int
+	return s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&id)
+}
+
+// QuoteBooked tells whether a reservation was booked at the quote quoteID.
+func (s *Storage) QuoteBooked(ctx context.Context, quoteID string) (bool, error) {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select(goqu.COUNT("*")).
+		From(reservationTable).
+		Where(goqu.C("quoteID").Eq(quoteID), goqu.C(tenantColumn).Eq(tenantID)).ToSQL()
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&count)
+
+	return count > 0, err
+}
+
+// scopeReservation stamps the tenant on a reservation and makes sure the
+// holiday, customer and promo code it points to belong to that tenant too.
+func (s *Storage) scopeReservation(ctx context.Context, reservation *Reservation) error {
+	tenantID, err := tenantFrom(ctx)
+	if err != nil {
+		return err
+	}
+	reservation.TenantID = tenantID
+
+	if _, err := s.Holiday(ctx, reservation.HolidayID); err != nil {
+		return err
+	}
+
+	if reservation.CustomerID != nil {
+		if _, err := s.Customer(ctx, *reservation.CustomerID); err != nil {
+			return err
+		}
+	}
+
+	if reservation.PromoCodeID != nil {
+		if _, err := s.PromoCode(ctx, *reservation.PromoCodeID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9"
+)
+
+const (
+	roleTable           = "role"
+	permissionTable     = "permission"
+	rolePermissionTable = "role_permission"
+)
+
+// RolePermissions returns the names of the permissions granted to role.
+func (s *Storage) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	permissions := []string{}
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select(goqu.I(permissionTable+".name")).
+		From(permissionTable).
+		InnerJoin(
+			goqu.T(rolePermissionTable),
+			goqu.On(goqu.Ex{rolePermissionTable + ".permissionID": goqu.I(permissionTable + ".id")}),
+		).
+		InnerJoin(
+			goqu.T(roleTable),
+			goqu.On(goqu.Ex{rolePermissionTable + ".roleID": goqu.I(roleTable + ".id")}),
+		).
+		Where(goqu.Ex{roleTable + ".name": role}).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// RoleExists tells whether role is one of the roles of the role table.
+func (s *Storage) RoleExists(ctx context.Context, role string) (bool, error) {
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select(goqu.COUNT("*")).
+		From(roleTable).
+		Where(goqu.Ex{"name": role}).ToSQL()
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&count)
+
+	return count > 0, err
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+// HolidayStats are the booking figures of one holiday.
+type HolidayStats struct {
+	HolidayID int
+	Agency    string
+	FreeSlots int
+	SeatsSold int
+}
+
+// HolidayStats returns the figures of every holiday of every agency. Like
+// the other statistics it is not tenant scoped, it feeds /metrics only.
+func (s *Storage) HolidayStats(ctx context.Context) ([]HolidayStats, error) {
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select(
+			goqu.I(holidaysTable+".id"),
+			goqu.I(agencyTable+".slug"),
+			goqu.I(holidaysTable+".freeSlots"),
+			goqu.COUNT(goqu.I(reservationTable+".id")),
+		).
+		From(holidaysTable).
+		InnerJoin(
+			goqu.T(agencyTable),
+			goqu.On(goqu.Ex{agencyTable + ".id": goqu.I(holidaysTable + ".tenantID")}),
+		).
+		LeftJoin(
+			goqu.T(reservationTable),
+			goqu.On(goqu.Ex{reservationTable + ".holidayID": goqu.I(holidaysTable + ".id")}),
+		).
+		GroupBy(goqu.I(holidaysTable+".id"), goqu.I(agencyTable+".slug"), goqu.I(holidaysTable+".freeSlots")).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stats := []HolidayStats{}
+	for rows.Next() {
+		var holiday HolidayStats
+		if err := rows.Scan(getColumnsForStruct(&holiday)...); err != nil {
+			return nil, err
+		}
+		stats = append(stats, holiday)
+	}
+
+	return stats, rows.Err()
+}
+
+// ReservationsCreated counts the reservations ever created per agency. It is
+// read from the audit log, which is append-only, so deleting a reservation
+// does not lower the count.
+func (s *Storage) ReservationsCreated(ctx context.Context) (map[string]int, error) {
+	sqlStr, _, err := goqu.Dialect(s.dialect).
+		Select(goqu.I(agencyTable+".slug"), goqu.COUNT(goqu.Star())).
+		From(auditTable).
+		InnerJoin(
+			goqu.T(agencyTable),
+			goqu.On(goqu.Ex{agencyTable + ".id": goqu.I(auditTable + ".tenantID")}),
+		).
+		Where(goqu.Ex{auditTable + ".resource": "reservation", auditTable + ".action": "create"}).
+		GroupBy(goqu.I(agencyTable + ".slug")).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	created := map[string]int{}
+	for rows.Next() {
+		var agency string
+		var count int
+		if err := rows.Scan(&agency, &count); err != nil {
+			return nil, err
+		}
+		created[agency] = count
+	}
+
+	return created, rows.Err()
+}
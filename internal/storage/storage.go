@@ -1,19 +1,78 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"log/slog"
 	"reflect"
+	"travel/internal/tenant"
 
+	"github.com/doug-martin/goqu/v9"
 	_ "github.com/doug-martin/goqu/v9/dialect/mysql"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	_ "github.com/doug-martin/goqu/v9/dialect/sqlite3"
+)
+
+// Supported backends. The names are shared by the database/sql driver, the
+// goqu dialect and the directory of the backend's migrations.
+const (
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite3"
 )
 
 type Storage struct {
 	db      *sql.DB
 	dialect string
+	logger  *slog.Logger
 }
 
-func New(db *sql.DB, dialect string) *Storage {
-	return &Storage{db: db, dialect: dialect}
+func New(db *sql.DB, dialect string, logger *slog.Logger) *Storage {
+	return &Storage{db: db, dialect: dialect, logger: logger}
+}
+
+// insert runs an INSERT into a table with an auto increment id and returns
+// the id of the new row. PostgreSQL has no LastInsertId, the id is read back
+// with RETURNING there.
+func (s *Storage) insert(ctx context.Context, insert *goqu.InsertDataset) (int64, error) {
+	if s.dialect == DialectPostgres {
+		sqlStr, _, err := insert.Returning("id").ToSQL()
+		if err != nil {
+			return 0, err
+		}
+
+		var id int64
+		err = s.conn(ctx).QueryRowContext(ctx, sqlStr).Scan(&id)
+		return id, err
+	}
+
+	sqlStr, _, err := insert.ToSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.conn(ctx).ExecContext(ctx, sqlStr)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// ErrNoTenant is returned by every tenant owned table when the request
+// context carries no tenant, so nothing is ever read or written unscoped.
+var ErrNoTenant = errors.New("no tenant in context")
+
+const tenantColumn = "tenantID"
+
+func tenantFrom(ctx context.Context) (int, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return 0, ErrNoTenant
+	}
+
+	return tenantID, nil
 }
 
 // It must take pointer to the structure.
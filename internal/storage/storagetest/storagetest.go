@@ -0,0 +1,1228 @@
+// Package storagetest is the conformance suite every storage backend has to
+// pass, so the service behaves the same whichever backend is configured.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+	"travel/internal/service"
+	"travel/internal/storage"
+	"travel/internal/tenant"
+)
+
+// Storage is what a backend implements: everything the service uses plus the
+// lookups of the tenant resolver, the policy and the metrics.
+type Storage interface {
+	service.Storage
+	AgencyIDBySlug(ctx context.Context, slug string) (int, error)
+	InsertAgency(ctx context.Context, agency *storage.Agency) (int64, error)
+	RolePermissions(ctx context.Context, role string) ([]string, error)
+	HolidayStats(ctx context.Context) ([]storage.HolidayStats, error)
+	ReservationsCreated(ctx context.Context) (map[string]int, error)
+}
+
+// defaultTenant is the agency every migrated backend starts with.
+const defaultTenant = 1
+
+// Run runs the suite. newStorage is called once per test and has to return an
+// empty backend at the latest schema, holding only what the migrations seed.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s Storage)
+	}{
+		{"Agencies", testAgencies},
+		{"Roles", testRoles},
+		{"Locations", testLocations},
+		{"Holidays", testHolidays},
+		{"HolidayFilters", testHolidayFilters},
+		{"Customers", testCustomers},
+		{"Reservations", testReservations},
+		{"APIKeys", testAPIKeys},
+		{"PricingRuleSets", testPricingRuleSets},
+		{"ExchangeRates", testExchangeRates},
+		{"PromoCodes", testPromoCodes},
+		{"PromoCodeLimit", testPromoCodeLimit},
+		{"Payments", testPayments},
+		{"Invoices", testInvoices},
+		{"InvoiceSequence", testInvoiceSequence},
+		{"Transactions", testTransactions},
+		{"ConcurrentWrites", testConcurrentWrites},
+		{"AuditLog", testAuditLog},
+		{"Stats", testStats},
+		{"TenantIsolation", testTenantIsolation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fn(t, newStorage(t))
+		})
+	}
+}
+
+func tenantContext(tenantID int) context.Context {
+	return tenant.WithID(context.Background(), tenantID)
+}
+
+// must fails the test on err, it is called as must(s.Method(...))(t).
+func must[T any](value T, err error) func(t *testing.T) T {
+	return func(t *testing.T) T {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return value
+	}
+}
+
+func expectNotFound(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func insertAgency(t *testing.T, s Storage, slug string) int {
+	t.Helper()
+	return int(must(s.InsertAgency(context.Background(), &storage.Agency{Slug: slug, Name: slug}))(t))
+}
+
+func insertLocation(t *testing.T, ctx context.Context, s Storage, city string, country string) storage.Location {
+	t.Helper()
+	location := storage.Location{Street: "Main", Number: "1", City: city, Country: country}
+	location.ID = int(must(s.InsertLocation(ctx, &location))(t))
+	return location
+}
+
+func insertHoliday(t *testing.T, ctx context.Context, s Storage, locationID int, duration int, startDate time.Time) storage.Holiday {
+	t.Helper()
+	holiday := storage.Holiday{
+		Title:      "Holiday",
+		Duration:   duration,
+		StartDate:  startDate,
+		FreeSlots:  10,
+		LocationID: locationID,
+		// more cents than a float32 holds exactly
+		PriceMinor: 123456789,
+		Currency:   "EUR",
+	}
+	holiday.ID = int(must(s.InsertHolidays(ctx, &holiday))(t))
+	return holiday
+}
+
+func insertCustomer(t *testing.T, ctx context.Context, s Storage, phone string, email string) storage.Customer {
+	t.Helper()
+	customer := storage.Customer{Name: "Ivan", PhoneNumber: phone, Email: email, NormalizedPhone: phone}
+	customer.ID = int(must(s.InsertCustomer(ctx, &customer))(t))
+	return customer
+}
+
+func insertReservation(t *testing.T, ctx context.Context, s Storage, holidayID int, customerID int) storage.Reservation {
+	t.Helper()
+	reservation := storage.Reservation{
+		ContactName: "Ivan",
+		PhoneNumber: "+359888123456",
+		HolidayID:   holidayID,
+		CustomerID:  &customerID,
+		PhoneE164:   "+359888123456",
+	}
+	reservation.ID = int(must(s.InsertReservation(ctx, &reservation))(t))
+	return reservation
+}
+
+func testAgencies(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	if id := must(s.AgencyIDBySlug(ctx, "default"))(t); id != defaultTenant {
+		t.Fatalf("default agency has id %d, want %d", id, defaultTenant)
+	}
+
+	if id := must(s.AgencyIDBySlug(ctx, "missing"))(t); id != 0 {
+		t.Fatalf("unknown agency has id %d, want 0", id)
+	}
+
+	id := insertAgency(t, s, "sunny")
+	if id == defaultTenant {
+		t.Fatalf("new agency reused id %d", id)
+	}
+	if got := must(s.AgencyIDBySlug(ctx, "sunny"))(t); got != id {
+		t.Fatalf("sunny has id %d, want %d", got, id)
+	}
+
+	detailed := storage.Agency{Slug: "detailed", Name: "Detailed", Address: "Vitosha 1\nSofia", Email: "office@example.com", TaxNumber: "BG123456789"}
+	detailed.ID = int(must(s.InsertAgency(ctx, &detailed))(t))
+	if got := must(s.Agency(ctx, detailed.ID))(t); *got != detailed {
+		t.Fatalf("got %+v, want %+v", got, detailed)
+	}
+	_, err := s.Agency(ctx, detailed.ID+100)
+	expectNotFound(t, err)
+}
+
+func testRoles(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	admin := must(s.RolePermissions(ctx, "admin"))(t)
+	customer := must(s.RolePermissions(ctx, "customer"))(t)
+	unknown := must(s.RolePermissions(ctx, "nobody"))(t)
+
+	contains := func(permissions []string, permission string) bool {
+		for _, p := range permissions {
+			if p == permission {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, permission := range []string{"holiday:write", "apikey:manage", "audit:read", "pricing:write", "promo:write", "payment:refund", "invoice:read", "invoice:write"} {
+		if !contains(admin, permission) {
+			t.Errorf("admin lacks %s", permission)
+		}
+	}
+	if contains(admin, "reservation:read:own") {
+		t.Error("admin has an :own permission")
+	}
+	if !contains(customer, "reservation:create:own") || !contains(customer, "payment:create:own") || !contains(customer, "invoice:read:own") || !contains(customer, "invoice:create:own") || contains(customer, "holiday:write") || contains(customer, "payment:refund") {
+		t.Errorf("customer has %v", customer)
+	}
+	if len(unknown) != 0 {
+		t.Errorf("unknown role has %v", unknown)
+	}
+
+	for role, want := range map[string]bool{"admin": true, "agent": true, "customer": true, "nobody": false, "": false} {
+		if got := must(s.RoleExists(ctx, role))(t); got != want {
+			t.Errorf("role %q exists: %v", role, got)
+		}
+	}
+}
+
+func testLocations(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	sofia := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	varna := insertLocation(t, ctx, s, "Varna", "Bulgaria")
+	if sofia.ID == 0 || varna.ID == sofia.ID {
+		t.Fatalf("ids %d and %d", sofia.ID, varna.ID)
+	}
+
+	got := must(s.Location(ctx, sofia.ID))(t)
+	if got.City != "Sofia" || got.TenantID != defaultTenant {
+		t.Fatalf("got %+v", got)
+	}
+
+	if all := must(s.LocationGetAll(ctx))(t); len(all) != 2 {
+		t.Fatalf("got %d locations, want 2", len(all))
+	}
+
+	sofia.Street = "Vitosha"
+	must(s.UpdateLocation(ctx, &sofia))(t)
+	if got := must(s.Location(ctx, sofia.ID))(t); got.Street != "Vitosha" {
+		t.Fatalf("street not updated: %+v", got)
+	}
+
+	deleted := must(s.DeleteLocation(ctx, varna.ID))(t)
+	if deleted.City != "Varna" {
+		t.Fatalf("deleted %+v", deleted)
+	}
+
+	_, err := s.Location(ctx, varna.ID)
+	expectNotFound(t, err)
+
+	_, err = s.DeleteLocation(ctx, varna.ID)
+	expectNotFound(t, err)
+}
+
+func testHolidays(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, ctx, s, location.ID, 7, date(2025, time.June, 1))
+
+	got := must(s.Holiday(ctx, holiday.ID))(t)
+	if !got.StartDate.Equal(holiday.StartDate) || got.Price() != holiday.Price() || got.LocationID != location.ID || got.TenantID != defaultTenant {
+		t.Fatalf("got %+v, want %+v", got, holiday)
+	}
+
+	holiday.FreeSlots = 3
+	must(s.UpdateHolidays(ctx, &holiday))(t)
+	if got := must(s.Holiday(ctx, holiday.ID))(t); got.FreeSlots != 3 {
+		t.Fatalf("free slots not updated: %+v", got)
+	}
+
+	all := must(s.HolidaysGetAll(ctx, "", 0, time.Time{}))(t)
+	if len(all) != 1 || all[0].Location.ID != location.ID || all[0].Location.City != "Sofia" {
+		t.Fatalf("got %+v", all)
+	}
+
+	// a location that does not exist is rejected
+	_, err := s.InsertHolidays(ctx, &storage.Holiday{Title: "Nowhere", StartDate: date(2025, time.June, 1), LocationID: location.ID + 100})
+	expectNotFound(t, err)
+
+	// a location with holidays can not be deleted
+	if _, err := s.DeleteLocation(ctx, location.ID); err == nil {
+		t.Fatal("location of a holiday was deleted")
+	}
+
+	must(s.DeleteHolidays(ctx, holiday.ID))(t)
+	_, err = s.Holiday(ctx, holiday.ID)
+	expectNotFound(t, err)
+}
+
+func testHolidayFilters(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	sofia := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	rome := insertLocation(t, ctx, s, "Rome", "Italy")
+	june := date(2025, time.June, 1)
+	july := date(2025, time.July, 1)
+	a := insertHoliday(t, ctx, s, sofia.ID, 7, june)
+	b := insertHoliday(t, ctx, s, sofia.ID, 14, july)
+	c := insertHoliday(t, ctx, s, rome.ID, 7, july)
+
+	tests := []struct {
+		name      string
+		location  string
+		duration  int
+		startDate time.Time
+		want      []int
+	}{
+		{"none", "", 0, time.Time{}, []int{a.ID, b.ID, c.ID}},
+		{"country", "bulgaria", 0, time.Time{}, []int{a.ID, b.ID}},
+		{"city substring", "OM", 0, time.Time{}, []int{c.ID}},
+		{"duration", "", 7, time.Time{}, []int{a.ID, c.ID}},
+		{"start date", "", 0, july, []int{b.ID, c.ID}},
+		{"all", "Italy", 7, july, []int{c.ID}},
+		{"no match", "Spain", 0, time.Time{}, []int{}},
+	}
+
+	for _, test := range tests {
+		var ids []int
+		err := s.HolidaysEach(ctx, test.location, test.duration, test.startDate, func(holiday storage.HolidayWithLocation) error {
+			ids = append(ids, holiday.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		sort.Ints(ids)
+		if len(ids) != len(test.want) || (len(ids) > 0 && !reflect.DeepEqual(ids, test.want)) {
+			t.Errorf("%s: got %v, want %v", test.name, ids, test.want)
+		}
+	}
+}
+
+func testCustomers(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	ivan := insertCustomer(t, ctx, s, "+359888123456", "ivan@example.com")
+	maria := insertCustomer(t, ctx, s, "+359888654321", "")
+
+	byPhone := must(s.CustomerByContact(ctx, "+359888654321", "other@example.com"))(t)
+	if byPhone == nil || byPhone.ID != maria.ID {
+		t.Fatalf("by phone got %+v", byPhone)
+	}
+
+	byEmail := must(s.CustomerByContact(ctx, "+10000000000", "ivan@example.com"))(t)
+	if byEmail == nil || byEmail.ID != ivan.ID {
+		t.Fatalf("by email got %+v", byEmail)
+	}
+
+	if nobody := must(s.CustomerByContact(ctx, "+10000000000", "nobody@example.com"))(t); nobody != nil {
+		t.Fatalf("expected nobody, got %+v", nobody)
+	}
+
+	maria.Email = "maria@example.com"
+	must(s.UpdateCustomer(ctx, &maria))(t)
+	if got := must(s.Customer(ctx, maria.ID))(t); got.Email != "maria@example.com" {
+		t.Fatalf("email not updated: %+v", got)
+	}
+
+	if all := must(s.CustomerGetAll(ctx))(t); len(all) != 2 {
+		t.Fatalf("got %d customers, want 2", len(all))
+	}
+
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, ctx, s, location.ID, 7, date(2025, time.June, 1))
+	insertReservation(t, ctx, s, holiday.ID, ivan.ID)
+
+	reservations := must(s.CustomerReservations(ctx, ivan.ID))(t)
+	if len(reservations) != 1 || reservations[0].Holiday.ID != holiday.ID || reservations[0].Holiday.Location.City != "Sofia" {
+		t.Fatalf("got %+v", reservations)
+	}
+	if none := must(s.CustomerReservations(ctx, maria.ID))(t); len(none) != 0 {
+		t.Fatalf("maria has %+v", none)
+	}
+
+	must(s.DeleteCustomer(ctx, maria.ID))(t)
+	_, err := s.Customer(ctx, maria.ID)
+	expectNotFound(t, err)
+}
+
+func testReservations(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, ctx, s, location.ID, 7, date(2025, time.June, 1))
+	customer := insertCustomer(t, ctx, s, "+359888123456", "")
+	reservation := insertReservation(t, ctx, s, holiday.ID, customer.ID)
+
+	got := must(s.Reservation(ctx, reservation.ID))(t)
+	if got.HolidayID != holiday.ID || got.CustomerID == nil || *got.CustomerID != customer.ID || got.PhoneE164 != "+359888123456" {
+		t.Fatalf("got %+v", got)
+	}
+
+	all, ok := must(s.ReservationGetAll(ctx))(t).([]storage.ReservationResult)
+	if !ok || len(all) != 1 {
+		t.Fatalf("got %+v", all)
+	}
+	if all[0].Holiday.ID != holiday.ID || all[0].Holiday.Location.ID != location.ID || !all[0].Holiday.StartDate.Equal(holiday.StartDate) {
+		t.Fatalf("joined holiday %+v", all[0].Holiday)
+	}
+
+	reservation.ContactName = "Maria"
+	quote, quoteID := `{"holidayID":1,"party":{"adults":2,"children":[]},"total":{"amount":"1300.00","currency":"EUR"}}`, "q1"
+	reservation.Quote, reservation.QuoteID = &quote, &quoteID
+	if must(s.QuoteBooked(ctx, quoteID))(t) {
+		t.Fatal("quote booked before it was")
+	}
+	must(s.UpdateReservation(ctx, &reservation))(t)
+	if got := must(s.Reservation(ctx, reservation.ID))(t); got.ContactName != "Maria" || got.Quote == nil || !sameJSON(t, *got.Quote, quote) || got.QuoteID == nil || *got.QuoteID != quoteID {
+		t.Fatalf("reservation not updated: %+v", got)
+	}
+	if !must(s.QuoteBooked(ctx, quoteID))(t) {
+		t.Fatal("booked quote not found")
+	}
+
+	// a quote books one reservation, many reservations have none
+	if _, err := s.InsertReservation(ctx, &storage.Reservation{ContactName: "Ivan", HolidayID: holiday.ID, QuoteID: &quoteID}); err == nil {
+		t.Fatal("quote booked twice")
+	}
+	second := insertReservation(t, ctx, s, holiday.ID, customer.ID)
+	must(s.DeleteReservation(ctx, second.ID))(t)
+
+	// a holiday that does not exist is rejected
+	_, err := s.InsertReservation(ctx, &storage.Reservation{ContactName: "Ivan", HolidayID: holiday.ID + 100})
+	if err == nil {
+		t.Fatal("reservation of a missing holiday was stored")
+	}
+
+	// the holiday and the customer of a reservation can not be deleted
+	if _, err := s.DeleteHolidays(ctx, holiday.ID); err == nil {
+		t.Fatal("holiday of a reservation was deleted")
+	}
+	if _, err := s.DeleteCustomer(ctx, customer.ID); err == nil {
+		t.Fatal("customer of a reservation was deleted")
+	}
+
+	must(s.DeleteReservation(ctx, reservation.ID))(t)
+	_, err = s.Reservation(ctx, reservation.ID)
+	expectNotFound(t, err)
+}
+
+func testAPIKeys(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+	createdAt := time.Date(2025, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	key := storage.APIKey{Name: "partner", Prefix: "abcd1234", Hash: "hash", Role: "agent", CreatedBy: "admin", CreatedAt: createdAt}
+	key.ID = int(must(s.InsertAPIKey(ctx, &key))(t))
+	second := storage.APIKey{Name: "second", Prefix: "efgh5678", Hash: "hash", Role: "agent", CreatedAt: createdAt}
+	second.ID = int(must(s.InsertAPIKey(ctx, &second))(t))
+
+	// looked up before the tenant is known
+	got := must(s.APIKeyByPrefix(context.Background(), "abcd1234"))(t)
+	if got == nil || got.ID != key.ID || got.TenantID != defaultTenant || !got.CreatedAt.Equal(createdAt) || got.LastUsedAt != nil {
+		t.Fatalf("got %+v", got)
+	}
+
+	if missing := must(s.APIKeyByPrefix(context.Background(), "missing"))(t); missing != nil {
+		t.Fatalf("expected no key, got %+v", missing)
+	}
+
+	// two keys can not share a prefix
+	if _, err := s.InsertAPIKey(ctx, &storage.APIKey{Name: "dup", Prefix: "abcd1234", CreatedAt: createdAt}); err == nil {
+		t.Fatal("duplicate prefix was stored")
+	}
+
+	usedAt := createdAt.Add(time.Hour)
+	if err := s.TouchAPIKey(context.Background(), key.ID, usedAt); err != nil {
+		t.Fatal(err)
+	}
+	revokedAt := createdAt.Add(2 * time.Hour)
+	if err := s.RevokeAPIKey(ctx, key.ID, revokedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	got = must(s.APIKey(ctx, key.ID))(t)
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(usedAt) || got.RevokedAt == nil || !got.RevokedAt.Equal(revokedAt) {
+		t.Fatalf("got %+v", got)
+	}
+
+	all := must(s.APIKeyGetAll(ctx))(t)
+	if len(all) != 2 || all[0].ID != key.ID || all[1].ID != second.ID {
+		t.Fatalf("got %+v", all)
+	}
+
+	_, err := s.APIKey(ctx, key.ID+100)
+	expectNotFound(t, err)
+}
+
+func testPricingRuleSets(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	ruleSet := storage.PricingRuleSet{Name: "Summer", Rules: `[{"name":"High season","kind":"season","percent":20,"from":"06-15","to":"08-31"}]`}
+	ruleSet.ID = int(must(s.InsertPricingRuleSet(ctx, &ruleSet))(t))
+	second := storage.PricingRuleSet{Name: "Flat", Rules: `[]`}
+	second.ID = int(must(s.InsertPricingRuleSet(ctx, &second))(t))
+
+	// the rules come back as the same JSON, whitespace aside
+	got := must(s.PricingRuleSet(ctx, ruleSet.ID))(t)
+	if got.Name != "Summer" || got.TenantID != defaultTenant || !sameJSON(t, got.Rules, ruleSet.Rules) {
+		t.Fatalf("got %+v, want %+v", got, ruleSet)
+	}
+
+	ruleSet.Name = "High summer"
+	must(s.UpdatePricingRuleSet(ctx, &ruleSet))(t)
+	if got := must(s.PricingRuleSet(ctx, ruleSet.ID))(t); got.Name != "High summer" {
+		t.Fatalf("name not updated: %+v", got)
+	}
+
+	all := must(s.PricingRuleSetGetAll(ctx))(t)
+	if len(all) != 2 || all[0].ID != ruleSet.ID || all[1].ID != second.ID {
+		t.Fatalf("got %+v", all)
+	}
+
+	location := insertLocation(t, ctx, s, "Varna", "Bulgaria")
+	holiday := storage.Holiday{Title: "Black sea", Duration: 7, StartDate: date(2025, time.July, 1), FreeSlots: 4, LocationID: location.ID, PricingRuleSetID: &ruleSet.ID, PriceMinor: 90000, Currency: "EUR"}
+	holiday.ID = int(must(s.InsertHolidays(ctx, &holiday))(t))
+
+	if got := must(s.Holiday(ctx, holiday.ID))(t); got.PricingRuleSetID == nil || *got.PricingRuleSetID != ruleSet.ID {
+		t.Fatalf("rule set not stored: %+v", got)
+	}
+
+	// a rule set that does not exist is rejected
+	missing := ruleSet.ID + 100
+	holiday.PricingRuleSetID = &missing
+	_, err := s.UpdateHolidays(ctx, &holiday)
+	expectNotFound(t, err)
+
+	// a rule set with holidays can not be deleted
+	if _, err := s.DeletePricingRuleSet(ctx, ruleSet.ID); err == nil {
+		t.Fatal("rule set of a holiday was deleted")
+	}
+
+	holiday.PricingRuleSetID = nil
+	must(s.UpdateHolidays(ctx, &holiday))(t)
+	if got := must(s.Holiday(ctx, holiday.ID))(t); got.PricingRuleSetID != nil {
+		t.Fatalf("rule set not removed: %+v", got)
+	}
+
+	must(s.DeletePricingRuleSet(ctx, ruleSet.ID))(t)
+	_, err = s.PricingRuleSet(ctx, ruleSet.ID)
+	expectNotFound(t, err)
+}
+
+func testExchangeRates(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	usd := storage.ExchangeRate{Currency: "USD", Rate: 1.0866, Date: date(2024, time.May, 17)}
+	must(s.SaveExchangeRate(ctx, &usd))(t)
+	gbp := storage.ExchangeRate{Currency: "GBP", Rate: 0.8541, Date: date(2024, time.May, 17)}
+	must(s.SaveExchangeRate(ctx, &gbp))(t)
+
+	got := must(s.ExchangeRate(ctx, "USD"))(t)
+	if got.ID != usd.ID || got.Rate != 1.0866 || !got.Date.Equal(usd.Date) || got.TenantID != defaultTenant {
+		t.Fatalf("got %+v, want %+v", got, usd)
+	}
+
+	// saving a currency again replaces its rate
+	update := storage.ExchangeRate{Currency: "USD", Rate: 1.0912, Date: date(2024, time.May, 20)}
+	must(s.SaveExchangeRate(ctx, &update))(t)
+	if update.ID != usd.ID {
+		t.Fatalf("saved as %d, want %d", update.ID, usd.ID)
+	}
+
+	all := must(s.ExchangeRateGetAll(ctx))(t)
+	if len(all) != 2 || all[0].Currency != "GBP" || all[1].Currency != "USD" || all[1].Rate != 1.0912 || !all[1].Date.Equal(update.Date) {
+		t.Fatalf("got %+v", all)
+	}
+
+	// reservations keep the rates they were booked with
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, ctx, s, location.ID, 7, date(2025, time.June, 1))
+	rates := `{"base":"EUR","rates":{"GBP":0.8541,"USD":1.0912}}`
+	reservation := storage.Reservation{ContactName: "Ivan", HolidayID: holiday.ID, ExchangeRates: &rates}
+	reservation.ID = int(must(s.InsertReservation(ctx, &reservation))(t))
+	if got := must(s.Reservation(ctx, reservation.ID))(t); got.ExchangeRates == nil || !sameJSON(t, *got.ExchangeRates, rates) {
+		t.Fatalf("exchange rates not stored: %+v", got)
+	}
+
+	must(s.DeleteExchangeRate(ctx, "GBP"))(t)
+	_, err := s.ExchangeRate(ctx, "GBP")
+	expectNotFound(t, err)
+	_, err = s.DeleteExchangeRate(ctx, "GBP")
+	expectNotFound(t, err)
+}
+
+func testPromoCodes(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	amount, minSpend, currency := int64(5000), int64(50000), "EUR"
+	validTo := date(2030, time.September, 1)
+	code := storage.PromoCode{
+		Code:           "WELCOME50",
+		Description:    "Welcome",
+		AmountMinor:    &amount,
+		MinSpendMinor:  &minSpend,
+		Currency:       &currency,
+		ValidTo:        &validTo,
+		MaxPerCustomer: 1,
+		Holidays:       `[]`,
+		Locations:      `[2]`,
+	}
+	code.ID = int(must(s.InsertPromoCode(ctx, &code))(t))
+	second := storage.PromoCode{Code: "SUMMER10", Percent: 10, MaxRedemptions: 100, Holidays: `[]`, Locations: `[]`}
+	second.ID = int(must(s.InsertPromoCode(ctx, &second))(t))
+
+	got := must(s.PromoCode(ctx, code.ID))(t)
+	if got.Code != "WELCOME50" || got.AmountMinor == nil || *got.AmountMinor != amount || got.MinSpendMinor == nil || *got.MinSpendMinor != minSpend ||
+		got.Currency == nil || *got.Currency != currency || got.ValidFrom != nil || got.ValidTo == nil || !got.ValidTo.Equal(validTo) ||
+		got.MaxPerCustomer != 1 || got.TenantID != defaultTenant || !sameJSON(t, got.Locations, code.Locations) {
+		t.Fatalf("got %+v, want %+v", got, code)
+	}
+	if got := must(s.PromoCodeByCode(ctx, "SUMMER10"))(t); got.ID != second.ID || got.Percent != 10 || got.AmountMinor != nil || got.Currency != nil {
+		t.Fatalf("got %+v, want %+v", got, second)
+	}
+	_, err := s.PromoCodeByCode(ctx, "WINTER10")
+	expectNotFound(t, err)
+
+	// codes are unique
+	if _, err := s.InsertPromoCode(ctx, &storage.PromoCode{Code: "SUMMER10", Percent: 5, Holidays: `[]`, Locations: `[]`}); err == nil {
+		t.Fatal("a taken code was stored")
+	}
+
+	second.Percent = 15
+	must(s.UpdatePromoCode(ctx, &second))(t)
+	if got := must(s.PromoCode(ctx, second.ID))(t); got.Percent != 15 {
+		t.Fatalf("percent not updated: %+v", got)
+	}
+
+	all := must(s.PromoCodeGetAll(ctx))(t)
+	if len(all) != 2 || all[0].ID != code.ID || all[1].ID != second.ID {
+		t.Fatalf("got %+v", all)
+	}
+
+	// reservations record the codes redeemed on them
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, ctx, s, location.ID, 7, date(2025, time.June, 1))
+	maria := insertCustomer(t, ctx, s, "+359888123456", "")
+	petar := insertCustomer(t, ctx, s, "+359899654321", "")
+	promo := `{"code":"WELCOME50","discount":{"amount":"-50.00","currency":"EUR"}}`
+	for _, customerID := range []int{maria.ID, maria.ID, petar.ID} {
+		reservation := storage.Reservation{ContactName: "Ivan", HolidayID: holiday.ID, CustomerID: &customerID, PromoCodeID: &code.ID, Promo: &promo}
+		must(s.InsertReservation(ctx, &reservation))(t)
+	}
+	insertReservation(t, ctx, s, holiday.ID, petar.ID)
+
+	redemptions := must(s.PromoCodeRedemptions(ctx, code.ID))(t)
+	if len(redemptions) != 3 || redemptions[0].PromoCodeID == nil || *redemptions[0].PromoCodeID != code.ID || redemptions[0].Promo == nil || !sameJSON(t, *redemptions[0].Promo, promo) {
+		t.Fatalf("got %+v", redemptions)
+	}
+	if count := must(s.PromoCodeRedemptionCount(ctx, code.ID, nil))(t); count != 3 {
+		t.Errorf("got %d redemptions, want 3", count)
+	}
+	if count := must(s.PromoCodeRedemptionCount(ctx, code.ID, &maria.ID))(t); count != 2 {
+		t.Errorf("got %d redemptions by Maria, want 2", count)
+	}
+	if count := must(s.PromoCodeRedemptionCount(ctx, second.ID, nil))(t); count != 0 {
+		t.Errorf("got %d redemptions, want 0", count)
+	}
+
+	// a code that does not exist is rejected
+	missing := second.ID + 100
+	_, err = s.InsertReservation(ctx, &storage.Reservation{ContactName: "Ivan", HolidayID: holiday.ID, PromoCodeID: &missing})
+	if err == nil {
+		t.Fatal("reservation with a missing promo code was stored")
+	}
+
+	// a redeemed code can not be deleted
+	if _, err := s.DeletePromoCode(ctx, code.ID); err == nil {
+		t.Fatal("redeemed promo code was deleted")
+	}
+
+	must(s.DeletePromoCode(ctx, second.ID))(t)
+	_, err = s.PromoCode(ctx, second.ID)
+	expectNotFound(t, err)
+}
+
+// testPromoCodeLimit books a code with room for two redemptions from many
+// transactions at once, each counting the redemptions under the lock.
+func testPromoCodeLimit(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	code := storage.PromoCode{Code: "LAST2", Percent: 10, MaxRedemptions: 2, Holidays: `[]`, Locations: `[]`}
+	code.ID = int(must(s.InsertPromoCode(ctx, &code))(t))
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, ctx, s, location.ID, 7, date(2030, time.July, 1))
+
+	errFull := errors.New("promo code fully redeemed")
+	const bookings = 8
+	errs := make(chan error, bookings)
+	var wg sync.WaitGroup
+	for i := 0; i < bookings; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.InTx(ctx, func(ctx context.Context) error {
+				if err := s.LockPromoCode(ctx, code.ID); err != nil {
+					return err
+				}
+				count, err := s.PromoCodeRedemptionCount(ctx, code.ID, nil)
+				if err != nil {
+					return err
+				}
+				if count >= code.MaxRedemptions {
+					return errFull
+				}
+				_, err = s.InsertReservation(ctx, &storage.Reservation{ContactName: "Ivan", HolidayID: holiday.ID, PromoCodeID: &code.ID})
+				return err
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	booked := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			booked++
+		case !errors.Is(err, errFull):
+			t.Fatal(err)
+		}
+	}
+	if count := must(s.PromoCodeRedemptionCount(ctx, code.ID, nil))(t); booked != 2 || count != 2 {
+		t.Fatalf("%d bookings redeemed the code %d times, the limit is 2", booked, count)
+	}
+
+	expectNotFound(t, s.LockPromoCode(ctx, code.ID+100))
+	expectNotFound(t, s.LockPromoCode(tenantContext(insertAgency(t, s, "alpine")), code.ID))
+}
+
+func testPayments(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, ctx, s, location.ID, 7, date(2030, time.July, 1))
+	customer := insertCustomer(t, ctx, s, "+359888123456", "")
+	reservation := insertReservation(t, ctx, s, holiday.ID, customer.ID)
+	other := insertReservation(t, ctx, s, holiday.ID, customer.ID)
+
+	if payments := must(s.ReservationPayments(ctx, reservation.ID))(t); len(payments) != 0 {
+		t.Fatalf("got %+v", payments)
+	}
+
+	createdAt := time.Date(2030, time.January, 10, 15, 30, 0, 0, time.UTC)
+	deposit := storage.Payment{
+		ReservationID: reservation.ID,
+		Kind:          "deposit",
+		AmountMinor:   90015,
+		Currency:      "EUR",
+		DueDate:       date(2030, time.January, 10),
+		Status:        "pending",
+		Gateway:       "fake",
+		CreatedAt:     createdAt,
+		UpdatedAt:     createdAt,
+	}
+	deposit.ID = int(must(s.InsertPayment(ctx, &deposit))(t))
+
+	got := must(s.Payment(ctx, deposit.ID))(t)
+	if got.ReservationID != reservation.ID || got.Kind != "deposit" || got.AmountMinor != 90015 || got.Currency != "EUR" || !got.DueDate.Equal(deposit.DueDate) ||
+		got.Status != "pending" || got.IntentID != nil || !got.CreatedAt.Equal(createdAt) || got.TenantID != defaultTenant {
+		t.Fatalf("got %+v, want %+v", got, deposit)
+	}
+
+	intentID := "pi_1"
+	deposit.IntentID, deposit.Status, deposit.RefundedMinor, deposit.RefundingMinor = &intentID, "succeeded", 15, 30
+	must(s.UpdatePayment(ctx, &deposit))(t)
+	if got := must(s.Payment(ctx, deposit.ID))(t); got.IntentID == nil || *got.IntentID != intentID || got.Status != "succeeded" || got.RefundedMinor != 15 || got.RefundingMinor != 30 {
+		t.Fatalf("payment not updated: %+v", got)
+	}
+
+	balance := storage.Payment{ReservationID: reservation.ID, Kind: "balance", AmountMinor: 210035, Currency: "EUR", DueDate: date(2030, time.June, 1), Status: "pending", Gateway: "fake", CreatedAt: createdAt, UpdatedAt: createdAt}
+	balance.ID = int(must(s.InsertPayment(ctx, &balance))(t))
+	must(s.InsertPayment(ctx, &storage.Payment{ReservationID: other.ID, Kind: "full", AmountMinor: 300050, Currency: "EUR", DueDate: date(2030, time.January, 10), Status: "pending", Gateway: "fake", CreatedAt: createdAt, UpdatedAt: createdAt}))(t)
+
+	payments := must(s.ReservationPayments(ctx, reservation.ID))(t)
+	if len(payments) != 2 || payments[0].ID != deposit.ID || payments[1].ID != balance.ID {
+		t.Fatalf("got %+v", payments)
+	}
+
+	// an intent pays for one payment only
+	balance.IntentID = &intentID
+	if _, err := s.UpdatePayment(ctx, &balance); err == nil {
+		t.Error("an intent was stored twice")
+	}
+
+	// payments belong to a reservation and outlive it
+	missing := other.ID + 100
+	if _, err := s.InsertPayment(ctx, &storage.Payment{ReservationID: missing, Kind: "full", Currency: "EUR", DueDate: createdAt, Status: "pending", Gateway: "fake", CreatedAt: createdAt, UpdatedAt: createdAt}); err == nil {
+		t.Error("payment of a missing reservation was stored")
+	}
+	if err := s.LockReservation(ctx, reservation.ID); err != nil {
+		t.Fatal(err)
+	}
+	must(s.DeleteReservation(ctx, reservation.ID))(t)
+	if got := must(s.Payment(ctx, deposit.ID))(t); got.ReservationID != reservation.ID {
+		t.Fatalf("got %+v", got)
+	}
+	if payments := must(s.ReservationPayments(ctx, reservation.ID))(t); len(payments) != 2 {
+		t.Fatalf("got %+v", payments)
+	}
+	expectNotFound(t, s.LockReservation(ctx, reservation.ID))
+
+	_, err := s.Payment(ctx, deposit.ID+100)
+	expectNotFound(t, err)
+
+	err = s.InTx(ctx, func(ctx context.Context) error {
+		return s.LockPayment(ctx, deposit.ID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNotFound(t, s.LockPayment(ctx, deposit.ID+100))
+	expectNotFound(t, s.LockPayment(tenantContext(insertAgency(t, s, "alpine")), deposit.ID))
+}
+
+func testInvoices(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, ctx, s, location.ID, 7, date(2030, time.July, 1))
+	customer := insertCustomer(t, ctx, s, "+359888123456", "")
+	reservation := insertReservation(t, ctx, s, holiday.ID, customer.ID)
+	other := insertReservation(t, ctx, s, holiday.ID, customer.ID)
+
+	if invoices := must(s.ReservationInvoices(ctx, reservation.ID))(t); len(invoices) != 0 {
+		t.Fatalf("got %+v", invoices)
+	}
+	if next := must(s.NextInvoiceSequence(ctx, "invoice"))(t); next != 1 {
+		t.Fatalf("first invoice is %d", next)
+	}
+
+	issuedAt := time.Date(2030, time.January, 10, 15, 30, 0, 0, time.UTC)
+	issued := storage.Invoice{
+		ReservationID: reservation.ID,
+		CustomerID:    &customer.ID,
+		Kind:          "invoice",
+		Sequence:      1,
+		Number:        "INV-000001",
+		IssuedAt:      issuedAt,
+		TotalMinor:    90050,
+		Currency:      "EUR",
+		Data:          `{"number": "INV-000001"}`,
+		Document:      "%PDF-1.4\n%%EOF\n",
+	}
+	issued.ID = int(must(s.InsertInvoice(ctx, &issued))(t))
+
+	got := must(s.Invoice(ctx, issued.ID))(t)
+	if got.ReservationID != reservation.ID || got.CustomerID == nil || *got.CustomerID != customer.ID || got.Number != "INV-000001" || !got.IssuedAt.Equal(issuedAt) ||
+		got.TotalMinor != 90050 || got.CreditedInvoiceID != nil || !sameJSON(t, got.Data, issued.Data) || got.Document != issued.Document || got.TenantID != defaultTenant {
+		t.Fatalf("got %+v, want %+v", got, issued)
+	}
+
+	// invoices and credit notes are numbered apart
+	if next := must(s.NextInvoiceSequence(ctx, "invoice"))(t); next != 2 {
+		t.Fatalf("next invoice is %d", next)
+	}
+	if next := must(s.NextInvoiceSequence(ctx, "credit_note"))(t); next != 1 {
+		t.Fatalf("first credit note is %d", next)
+	}
+	if _, err := s.InsertInvoice(ctx, &storage.Invoice{ReservationID: other.ID, Kind: "invoice", Sequence: 1, Number: "INV-000001", IssuedAt: issuedAt, Currency: "EUR", Data: `{}`}); err == nil {
+		t.Error("an invoice number was issued twice")
+	}
+
+	credit := storage.Invoice{ReservationID: reservation.ID, Kind: "credit_note", Sequence: 1, Number: "CN-000001", IssuedAt: issuedAt, TotalMinor: -90050, Currency: "EUR", CreditedInvoiceID: &issued.ID, Data: `{}`}
+	credit.ID = int(must(s.InsertInvoice(ctx, &credit))(t))
+	if got := must(s.Invoice(ctx, credit.ID))(t); got.CreditedInvoiceID == nil || *got.CreditedInvoiceID != issued.ID || got.TotalMinor != -90050 || got.CustomerID != nil {
+		t.Fatalf("got %+v", got)
+	}
+	if _, err := s.InsertInvoice(ctx, &storage.Invoice{ReservationID: reservation.ID, Kind: "credit_note", Sequence: 2, Number: "CN-000002", IssuedAt: issuedAt, Currency: "EUR", CreditedInvoiceID: &issued.ID, Data: `{}`}); err == nil {
+		t.Error("an invoice was credited twice")
+	}
+
+	must(s.InsertInvoice(ctx, &storage.Invoice{ReservationID: other.ID, Kind: "invoice", Sequence: 2, Number: "INV-000002", IssuedAt: issuedAt, Currency: "EUR", Data: `{}`}))(t)
+	invoices := must(s.ReservationInvoices(ctx, reservation.ID))(t)
+	if len(invoices) != 2 || invoices[0].ID != issued.ID || invoices[1].ID != credit.ID {
+		t.Fatalf("got %+v", invoices)
+	}
+
+	// invoices outlive their reservation
+	must(s.DeleteReservation(ctx, reservation.ID))(t)
+	if invoices := must(s.ReservationInvoices(ctx, reservation.ID))(t); len(invoices) != 2 {
+		t.Fatalf("got %+v", invoices)
+	}
+
+	_, err := s.Invoice(ctx, issued.ID+100)
+	expectNotFound(t, err)
+}
+
+// sameJSON reports whether two JSON documents hold the same value.
+func sameJSON(t *testing.T, a string, b string) bool {
+	t.Helper()
+
+	var x, y interface{}
+	if err := json.Unmarshal([]byte(a), &x); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(b), &y); err != nil {
+		t.Fatal(err)
+	}
+
+	return reflect.DeepEqual(x, y)
+}
+
+func testInvoiceSequence(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+	issuedAt := time.Date(2030, time.January, 10, 15, 30, 0, 0, time.UTC)
+
+	// concurrent issuers take one number each, none is lost or repeated
+	const issuers = 8
+	errs := make(chan error, issuers)
+	var wg sync.WaitGroup
+	for i := 0; i < issuers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.InTx(ctx, func(ctx context.Context) error {
+				sequence, err := s.NextInvoiceSequence(ctx, "invoice")
+				if err != nil {
+					return err
+				}
+				_, err = s.InsertInvoice(ctx, &storage.Invoice{ReservationID: 1, Kind: "invoice", Sequence: sequence, Number: fmt.Sprintf("INV-%06d", sequence), IssuedAt: issuedAt, Currency: "EUR", Data: `{}`})
+				return err
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	invoices := must(s.ReservationInvoices(ctx, 1))(t)
+	if len(invoices) != issuers {
+		t.Fatalf("%d invoices issued, want %d", len(invoices), issuers)
+	}
+	taken := map[int]bool{}
+	for _, invoice := range invoices {
+		taken[invoice.Sequence] = true
+	}
+	for sequence := 1; sequence <= issuers; sequence++ {
+		if !taken[sequence] {
+			t.Errorf("invoice %d was skipped", sequence)
+		}
+	}
+
+	// a rolled back issue hands its number back
+	errRollback := errors.New("rollback")
+	err := s.InTx(ctx, func(ctx context.Context) error {
+		if next := must(s.NextInvoiceSequence(ctx, "invoice"))(t); next != issuers+1 {
+			t.Errorf("next invoice is %d", next)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatal(err)
+	}
+	if next := must(s.NextInvoiceSequence(ctx, "invoice"))(t); next != issuers+1 {
+		t.Errorf("next invoice after a rollback is %d", next)
+	}
+}
+
+func testTransactions(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+	failed := errors.New("failed")
+
+	var rolledBack int
+	err := s.InTx(ctx, func(ctx context.Context) error {
+		rolledBack = insertLocation(t, ctx, s, "Sofia", "Bulgaria").ID
+
+		// nested calls join the transaction and see its writes
+		return s.InTx(ctx, func(ctx context.Context) error {
+			if _, err := s.Location(ctx, rolledBack); err != nil {
+				return err
+			}
+			return failed
+		})
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+
+	_, err = s.Location(ctx, rolledBack)
+	expectNotFound(t, err)
+
+	var committed int
+	err = s.InTx(ctx, func(ctx context.Context) error {
+		committed = insertLocation(t, ctx, s, "Varna", "Bulgaria").ID
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	must(s.Location(ctx, committed))(t)
+}
+
+func testConcurrentWrites(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	const writers = 8
+	ids := make(chan int64, writers)
+	errs := make(chan error, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.InTx(ctx, func(ctx context.Context) error {
+				id, err := s.InsertLocation(ctx, &storage.Location{City: "Sofia", Country: "Bulgaria"})
+				ids <- id
+				return err
+			})
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := map[int64]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("id %d was handed out twice", id)
+		}
+		seen[id] = true
+	}
+
+	if all := must(s.LocationGetAll(ctx))(t); len(all) != writers {
+		t.Fatalf("got %d locations, want %d", len(all), writers)
+	}
+}
+
+func testAuditLog(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+	occurredAt := time.Date(2025, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	entries := []storage.AuditEntry{
+		{ActorType: "user", ActorID: "1", ActorName: "admin", OccurredAt: occurredAt, Resource: "holiday", ResourceID: 1, Action: "create", Diff: []byte(`{"title":{"after":"Sea"}}`)},
+		{ActorType: "user", ActorID: "1", ActorName: "admin", OccurredAt: occurredAt, Resource: "holiday", ResourceID: 1, Action: "update", Diff: []byte(`{"price":{"before":1,"after":2}}`)},
+		{ActorType: "apiKey", ActorID: "2", ActorName: "partner", OccurredAt: occurredAt, Resource: "holiday", ResourceID: 2, Action: "create", Diff: []byte(`{}`)},
+		{ActorType: "user", ActorID: "1", ActorName: "admin", OccurredAt: occurredAt, Resource: "location", ResourceID: 1, Action: "create", Diff: []byte(`{}`)},
+	}
+	for i := range entries {
+		if err := s.InsertAuditEntry(ctx, &entries[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := must(s.AuditEntries(ctx, "holiday", 1))(t)
+	if len(history) != 2 || history[0].Action != "create" || history[1].Action != "update" {
+		t.Fatalf("got %+v", history)
+	}
+	if history[0].ID >= history[1].ID || history[0].TenantID != defaultTenant || !history[0].OccurredAt.Equal(occurredAt) {
+		t.Fatalf("got %+v", history[0])
+	}
+
+	// backends may store JSON normalized, only its meaning has to survive
+	var got, want interface{}
+	if err := json.Unmarshal(history[1].Diff, &got); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(entries[1].Diff, &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diff %s, want %s", history[1].Diff, entries[1].Diff)
+	}
+
+	if all := must(s.AuditEntries(ctx, "holiday", 0))(t); len(all) != 3 {
+		t.Fatalf("got %d holiday entries, want 3", len(all))
+	}
+}
+
+func testStats(t *testing.T, s Storage) {
+	ctx := tenantContext(defaultTenant)
+
+	location := insertLocation(t, ctx, s, "Sofia", "Bulgaria")
+	booked := insertHoliday(t, ctx, s, location.ID, 7, date(2025, time.June, 1))
+	empty := insertHoliday(t, ctx, s, location.ID, 7, date(2025, time.July, 1))
+	customer := insertCustomer(t, ctx, s, "+359888123456", "")
+	for i := 0; i < 2; i++ {
+		reservation := insertReservation(t, ctx, s, booked.ID, customer.ID)
+		err := s.InsertAuditEntry(ctx, &storage.AuditEntry{
+			ActorType:  "user",
+			OccurredAt: time.Now().UTC(),
+			Resource:   "reservation",
+			ResourceID: reservation.ID,
+			Action:     "create",
+			Diff:       []byte(`{}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := map[int]storage.HolidayStats{}
+	for _, holiday := range must(s.HolidayStats(context.Background()))(t) {
+		stats[holiday.HolidayID] = holiday
+	}
+	if got := stats[booked.ID]; got.SeatsSold != 2 || got.FreeSlots != booked.FreeSlots || got.Agency != "default" {
+		t.Errorf("booked holiday %+v", got)
+	}
+	if got := stats[empty.ID]; got.SeatsSold != 0 || got.Agency != "default" {
+		t.Errorf("empty holiday %+v", got)
+	}
+
+	created := must(s.ReservationsCreated(context.Background()))(t)
+	if created["default"] != 2 {
+		t.Errorf("got %v", created)
+	}
+}
+
+func testTenantIsolation(t *testing.T, s Storage) {
+	own := tenantContext(defaultTenant)
+	other := tenantContext(insertAgency(t, s, "other"))
+
+	location := insertLocation(t, own, s, "Sofia", "Bulgaria")
+	holiday := insertHoliday(t, own, s, location.ID, 7, date(2025, time.June, 1))
+	customer := insertCustomer(t, own, s, "+359888123456", "ivan@example.com")
+	reservation := insertReservation(t, own, s, holiday.ID, customer.ID)
+
+	_, err := s.Location(other, location.ID)
+	expectNotFound(t, err)
+	_, err = s.Holiday(other, holiday.ID)
+	expectNotFound(t, err)
+	_, err = s.Customer(other, customer.ID)
+	expectNotFound(t, err)
+	_, err = s.Reservation(other, reservation.ID)
+	expectNotFound(t, err)
+	_, err = s.DeleteLocation(other, location.ID)
+	expectNotFound(t, err)
+
+	if all := must(s.LocationGetAll(other))(t); len(all) != 0 {
+		t.Errorf("other tenant sees %+v", all)
+	}
+	if all := must(s.HolidaysGetAll(other, "", 0, time.Time{}))(t); len(all) != 0 {
+		t.Errorf("other tenant sees %+v", all)
+	}
+	if match := must(s.CustomerByContact(other, "+359888123456", "ivan@example.com"))(t); match != nil {
+		t.Errorf("other tenant matched %+v", match)
+	}
+
+	// references can not point into another tenant
+	_, err = s.InsertHolidays(other, &storage.Holiday{Title: "Stolen", StartDate: date(2025, time.June, 1), LocationID: location.ID})
+	if err == nil {
+		t.Error("holiday in another tenant's location was stored")
+	}
+
+	ruleSet := storage.PricingRuleSet{Name: "Summer", Rules: `[]`}
+	ruleSet.ID = int(must(s.InsertPricingRuleSet(own, &ruleSet))(t))
+	_, err = s.PricingRuleSet(other, ruleSet.ID)
+	expectNotFound(t, err)
+	if all := must(s.PricingRuleSetGetAll(other))(t); len(all) != 0 {
+		t.Errorf("other tenant sees %+v", all)
+	}
+
+	rate := storage.ExchangeRate{Currency: "USD", Rate: 1.0866, Date: date(2024, time.May, 17)}
+	must(s.SaveExchangeRate(own, &rate))(t)
+	_, err = s.ExchangeRate(other, "USD")
+	expectNotFound(t, err)
+	_, err = s.DeleteExchangeRate(other, "USD")
+	expectNotFound(t, err)
+	// every agency has rates of its own
+	otherRate := storage.ExchangeRate{Currency: "USD", Rate: 1.1, Date: date(2024, time.May, 17)}
+	must(s.SaveExchangeRate(other, &otherRate))(t)
+	if got := must(s.ExchangeRate(own, "USD"))(t); got.Rate != 1.0866 {
+		t.Errorf("rate of another tenant replaced: %+v", got)
+	}
+
+	code := storage.PromoCode{Code: "SUMMER10", Percent: 10, Holidays: `[]`, Locations: `[]`}
+	code.ID = int(must(s.InsertPromoCode(own, &code))(t))
+	_, err = s.PromoCode(other, code.ID)
+	expectNotFound(t, err)
+	_, err = s.PromoCodeByCode(other, "SUMMER10")
+	expectNotFound(t, err)
+	// every agency has codes of its own
+	otherCode := storage.PromoCode{Code: "SUMMER10", Percent: 20, Holidays: `[]`, Locations: `[]`}
+	must(s.InsertPromoCode(other, &otherCode))(t)
+	if got := must(s.PromoCodeByCode(own, "SUMMER10"))(t); got.Percent != 10 {
+		t.Errorf("code of another tenant replaced: %+v", got)
+	}
+
+	createdAt := time.Date(2030, time.January, 10, 15, 30, 0, 0, time.UTC)
+	paid := storage.Payment{ReservationID: reservation.ID, Kind: "full", AmountMinor: 90000, Currency: "EUR", DueDate: date(2030, time.January, 10), Status: "pending", Gateway: "fake", CreatedAt: createdAt, UpdatedAt: createdAt}
+	paid.ID = int(must(s.InsertPayment(own, &paid))(t))
+	_, err = s.Payment(other, paid.ID)
+	expectNotFound(t, err)
+	if all := must(s.ReservationPayments(other, reservation.ID))(t); len(all) != 0 {
+		t.Errorf("other tenant sees %+v", all)
+	}
+	if _, err := s.InsertPayment(other, &storage.Payment{ReservationID: reservation.ID, Kind: "full", Currency: "EUR", DueDate: createdAt, Status: "pending", Gateway: "fake", CreatedAt: createdAt, UpdatedAt: createdAt}); err == nil {
+		t.Error("payment of another tenant's reservation was stored")
+	}
+
+	issued := storage.Invoice{ReservationID: reservation.ID, Kind: "invoice", Sequence: 1, Number: "INV-000001", IssuedAt: createdAt, Currency: "EUR", Data: `{}`}
+	issued.ID = int(must(s.InsertInvoice(own, &issued))(t))
+	_, err = s.Invoice(other, issued.ID)
+	expectNotFound(t, err)
+	if all := must(s.ReservationInvoices(other, reservation.ID))(t); len(all) != 0 {
+		t.Errorf("other tenant sees %+v", all)
+	}
+	// every agency numbers its invoices from 1
+	if next := must(s.NextInvoiceSequence(other, "invoice"))(t); next != 1 {
+		t.Errorf("other tenant's next invoice is %d", next)
+	}
+	if _, err := s.InsertInvoice(other, &storage.Invoice{ReservationID: reservation.ID, Kind: "credit_note", Sequence: 1, Number: "CN-000001", IssuedAt: createdAt, Currency: "EUR", CreditedInvoiceID: &issued.ID, Data: `{}`}); err == nil {
+		t.Error("credit note of another tenant's invoice was stored")
+	}
+
+	otherLocation := insertLocation(t, other, s, "Rome", "Italy")
+	_, err = s.InsertHolidays(other, &storage.Holiday{Title: "Stolen", StartDate: date(2025, time.June, 1), LocationID: otherLocation.ID, PricingRuleSetID: &ruleSet.ID, PriceMinor: 90000, Currency: "EUR"})
+	if err == nil {
+		t.Error("holiday with another tenant's rule set was stored")
+	}
+
+	otherHoliday := insertHoliday(t, other, s, otherLocation.ID, 7, date(2025, time.June, 1))
+	_, err = s.InsertReservation(other, &storage.Reservation{ContactName: "Ivan", HolidayID: otherHoliday.ID, PromoCodeID: &code.ID})
+	if err == nil {
+		t.Error("reservation with another tenant's promo code was stored")
+	}
+
+	// nothing is read or written without a tenant
+	if _, err := s.LocationGetAll(context.Background()); !errors.Is(err, storage.ErrNoTenant) {
+		t.Errorf("got %v, want %v", err, storage.ErrNoTenant)
+	}
+	if _, err := s.InsertLocation(context.Background(), &storage.Location{City: "Nowhere"}); !errors.Is(err, storage.ErrNoTenant) {
+		t.Errorf("got %v, want %v", err, storage.ErrNoTenant)
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+// sanitizeSQL replaces the literals of a statement with ?. Values are inlined
+// into the SQL, so statements carry customer data until this is applied.
+func sanitizeSQL(statement string) string {
+	statement = stringLiteral.ReplaceAllString(statement, "?")
+	return numericLiteral.ReplaceAllString(statement, "?")
+}
+
+// tracedQueryer starts a span for every statement. The span of a query ends
+// when the first rows are available, reading them is not included.
+type tracedQueryer struct {
+	next   queryer
+	system string
+}
+
+func (q tracedQueryer) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	ctx, span := q.start(ctx, query)
+	defer endSpan(span, &err)
+
+	return q.next.ExecContext(ctx, query, args...)
+}
+
+func (q tracedQueryer) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	ctx, span := q.start(ctx, query)
+	defer endSpan(span, &err)
+
+	return q.next.QueryContext(ctx, query, args...)
+}
+
+func (q tracedQueryer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := q.start(ctx, query)
+
+	row := q.next.QueryRowContext(ctx, query, args...)
+
+	err := row.Err()
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	endSpan(span, &err)
+
+	return row
+}
+
+func (q tracedQueryer) start(ctx context.Context, statement string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(statement, " ")
+	operation = strings.ToUpper(operation)
+
+	return otel.Tracer("travel/internal/storage").Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String(string(semconv.DBSystemKey), q.system),
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(sanitizeSQL(statement)),
+		),
+	)
+}
+
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// queryer is what *sql.DB and *sql.Tx have in common.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+// InTx runs fn in a transaction: every storage call made with the context fn
+// receives joins it. The transaction commits when fn returns nil and rolls
+// back otherwise. Nested calls join the outer transaction.
+func (s *Storage) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) conn(ctx context.Context) queryer {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tracedQueryer{next: tx, system: s.dialect}
+	}
+
+	return tracedQueryer{next: s.db, system: s.dialect}
+}
+
+// forUpdate locks the rows query reads until the transaction it runs in ends.
+// SQLite has no row locks, its transactions begin immediate and hold the
+// whole database instead.
+func (s *Storage) forUpdate(query *goqu.SelectDataset) *goqu.SelectDataset {
+	if s.dialect == DialectSQLite {
+		return query
+	}
+
+	return query.ForUpdate(exp.Wait)
+}
@@ -0,0 +1,112 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"travel/internal/auth"
+)
+
+const Header = "X-Tenant"
+
+var (
+	ErrTenantRequired = errors.New("tenant required: authenticate with an agency credential, or send the X-Tenant header")
+	ErrUnknownTenant  = errors.New("unknown tenant")
+	ErrTenantMismatch = errors.New("credential belongs to another tenant")
+)
+
+// Lookup resolves an agency slug to its tenant id, returning 0 for unknown
+// slugs.
+type Lookup interface {
+	AgencyIDBySlug(ctx context.Context, slug string) (int, error)
+}
+
+type Resolver struct {
+	lookup Lookup
+	// BaseDomain enables <slug>.<BaseDomain> hosts, e.g. "travel.example.com".
+	BaseDomain string
+}
+
+func NewResolver(lookup Lookup, baseDomain string) *Resolver {
+	return &Resolver{lookup: lookup, BaseDomain: strings.ToLower(baseDomain)}
+}
+
+// Middleware puts the tenant in the request context. It runs after
+// authentication: the tenant of the principal wins, and a header or
+// subdomain naming another agency is refused.
+func (t *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID, err := t.Resolve(r)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrTenantMismatch) {
+				status = http.StatusForbidden
+			}
+
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"type":   "about:blank",
+				"title":  http.StatusText(status),
+				"status": status,
+				"detail": err.Error(),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithID(r.Context(), tenantID)))
+	})
+}
+
+func (t *Resolver) Resolve(r *http.Request) (int, error) {
+	var requested int
+	if slug := t.requestedSlug(r); slug != "" {
+		id, err := t.lookup.AgencyIDBySlug(r.Context(), slug)
+		if err != nil {
+			return 0, err
+		}
+		if id == 0 {
+			return 0, fmt.Errorf("%w: %q", ErrUnknownTenant, slug)
+		}
+		requested = id
+	}
+
+	if principal := auth.FromContext(r.Context()); principal != nil && principal.TenantID != 0 {
+		if requested != 0 && requested != principal.TenantID {
+			return 0, ErrTenantMismatch
+		}
+		return principal.TenantID, nil
+	}
+
+	if requested == 0 {
+		return 0, ErrTenantRequired
+	}
+
+	return requested, nil
+}
+
+func (t *Resolver) requestedSlug(r *http.Request) string {
+	if slug := strings.TrimSpace(r.Header.Get(Header)); slug != "" {
+		return strings.ToLower(slug)
+	}
+
+	if t.BaseDomain == "" {
+		return ""
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	if slug, found := strings.CutSuffix(host, "."+t.BaseDomain); found && !strings.Contains(slug, ".") {
+		return slug
+	}
+
+	return ""
+}
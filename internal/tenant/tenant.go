@@ -0,0 +1,17 @@
+// Package tenant carries the travel agency a request acts for.
+package tenant
+
+import "context"
+
+type tenantKey struct{}
+
+func WithID(ctx context.Context, tenantID int) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext returns the tenant of the request and false when none was
+// resolved.
+func FromContext(ctx context.Context) (int, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(int)
+	return tenantID, ok && tenantID != 0
+}
@@ -0,0 +1,133 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"travel/internal/auth"
+	"travel/internal/tenant"
+)
+
+// agencies knows two agencies by slug.
+type agencies map[string]int
+
+func (a agencies) AgencyIDBySlug(ctx context.Context, slug string) (int, error) {
+	return a[slug], nil
+}
+
+func TestResolve(t *testing.T) {
+	resolver := tenant.NewResolver(agencies{"sunny": 1, "alpine": 2}, "Travel.example.com")
+
+	agent := &auth.Principal{Type: auth.PrincipalUser, ID: "agent", Role: "agent", TenantID: 1}
+	platform := &auth.Principal{Type: auth.PrincipalUser, ID: "ops", Role: "admin"}
+
+	tests := []struct {
+		name      string
+		host      string
+		header    string
+		principal *auth.Principal
+		want      int
+		err       error
+	}{
+		{"header", "api.example.com", "Alpine", nil, 2, nil},
+		{"subdomain", "alpine.travel.example.com:8080", "", nil, 2, nil},
+		{"header wins over the subdomain", "alpine.travel.example.com", "sunny", nil, 1, nil},
+		{"nested subdomain", "x.alpine.travel.example.com", "", nil, 0, tenant.ErrTenantRequired},
+		{"other domain", "alpine.example.org", "", nil, 0, tenant.ErrTenantRequired},
+		{"unknown header", "api.example.com", "nowhere", nil, 0, tenant.ErrUnknownTenant},
+		{"unknown subdomain", "nowhere.travel.example.com", "", nil, 0, tenant.ErrUnknownTenant},
+		{"nothing", "api.example.com", "", nil, 0, tenant.ErrTenantRequired},
+
+		// the credential decides, a request may only repeat its agency
+		{"credential", "api.example.com", "", agent, 1, nil},
+		{"credential and its header", "api.example.com", "sunny", agent, 1, nil},
+		{"credential and its subdomain", "sunny.travel.example.com", "", agent, 1, nil},
+		{"header of another agency", "api.example.com", "alpine", agent, 0, tenant.ErrTenantMismatch},
+		{"subdomain of another agency", "alpine.travel.example.com", "", agent, 0, tenant.ErrTenantMismatch},
+
+		// platform staff pick the agency
+		{"platform with header", "api.example.com", "alpine", platform, 2, nil},
+		{"platform with subdomain", "sunny.travel.example.com", "", platform, 1, nil},
+		{"platform without agency", "api.example.com", "", platform, 0, tenant.ErrTenantRequired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/holidays", nil)
+			r.Host = test.host
+			if test.header != "" {
+				r.Header.Set(tenant.Header, test.header)
+			}
+			if test.principal != nil {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), test.principal))
+			}
+
+			got, err := resolver.Resolve(r)
+			if !errors.Is(err, test.err) || got != test.want {
+				t.Errorf("got %d, %v, want %d, %v", got, err, test.want, test.err)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var tenantID int
+	handler := tenant.NewResolver(agencies{"sunny": 1, "alpine": 2}, "travel.example.com").Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID, _ = tenant.FromContext(r.Context())
+	}))
+	agent := &auth.Principal{Type: auth.PrincipalUser, ID: "agent", Role: "agent", TenantID: 1}
+
+	tests := []struct {
+		name      string
+		host      string
+		header    string
+		principal *auth.Principal
+		status    int
+		want      int
+	}{
+		{"resolved", "sunny.travel.example.com", "", nil, http.StatusOK, 1},
+		{"header mismatch", "api.example.com", "alpine", agent, http.StatusForbidden, 0},
+		{"subdomain mismatch", "alpine.travel.example.com", "", agent, http.StatusForbidden, 0},
+		{"unknown", "api.example.com", "nowhere", nil, http.StatusBadRequest, 0},
+		{"required", "api.example.com", "", nil, http.StatusBadRequest, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tenantID = 0
+			r := httptest.NewRequest(http.MethodGet, "/holidays", nil)
+			r.Host = test.host
+			if test.header != "" {
+				r.Header.Set(tenant.Header, test.header)
+			}
+			if test.principal != nil {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), test.principal))
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status || tenantID != test.want {
+				t.Errorf("got %d for tenant %d, want %d for tenant %d", w.Code, tenantID, test.status, test.want)
+			}
+			if test.status != http.StatusOK && (w.Header().Get("Content-Type") != "application/problem+json" || !strings.Contains(w.Body.String(), `"status":`)) {
+				t.Errorf("not a problem: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if _, ok := tenant.FromContext(context.Background()); ok {
+		t.Error("tenant without one set")
+	}
+	if _, ok := tenant.FromContext(tenant.WithID(context.Background(), 0)); ok {
+		t.Error("tenant 0 resolved")
+	}
+	if id, ok := tenant.FromContext(tenant.WithID(context.Background(), 3)); !ok || id != 3 {
+		t.Errorf("got %d, %v", id, ok)
+	}
+}
@@ -0,0 +1,336 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"travel/internal/handler"
+	"travel/internal/service"
+	"travel/internal/storage"
+)
+
+// Service starts a span for every call to the service it wraps.
+type Service struct {
+	next handler.Service
+}
+
+func NewService(next handler.Service) *Service {
+	return &Service{next: next}
+}
+
+func (s *Service) ReservationGetAll(ctx context.Context, currency string) (result interface{}, err error) {
+	ctx, span := tracer().Start(ctx, "Service.ReservationGetAll")
+	defer end(span, &err)
+	return s.next.ReservationGetAll(ctx, currency)
+}
+
+func (s *Service) ExportReservations(ctx context.Context, fn func(storage.ReservationResult) error) (err error) {
+	ctx, span := tracer().Start(ctx, "Service.ExportReservations")
+	defer end(span, &err)
+	return s.next.ExportReservations(ctx, fn)
+}
+
+func (s *Service) Reservation(ctx context.Context, reservationID int, currency string) (result *service.ReservationDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.Reservation")
+	defer end(span, &err)
+	return s.next.Reservation(ctx, reservationID, currency)
+}
+
+func (s *Service) InsertReservation(ctx context.Context, reservation service.ReservationDTO) (result int64, err error) {
+	ctx, span := tracer().Start(ctx, "Service.InsertReservation")
+	defer end(span, &err)
+	return s.next.InsertReservation(ctx, reservation)
+}
+
+func (s *Service) UpdateReservation(ctx context.Context, reservation service.ReservationDTO) (result *service.ReservationDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.UpdateReservation")
+	defer end(span, &err)
+	return s.next.UpdateReservation(ctx, reservation)
+}
+
+func (s *Service) DeleteReservation(ctx context.Context, reservationID int) (result *service.ReservationDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.DeleteReservation")
+	defer end(span, &err)
+	return s.next.DeleteReservation(ctx, reservationID)
+}
+
+func (s *Service) CustomerGetAll(ctx context.Context) (result []service.CustomerDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.CustomerGetAll")
+	defer end(span, &err)
+	return s.next.CustomerGetAll(ctx)
+}
+
+func (s *Service) Customer(ctx context.Context, customerID int) (result *service.CustomerDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.Customer")
+	defer end(span, &err)
+	return s.next.Customer(ctx, customerID)
+}
+
+func (s *Service) InsertCustomer(ctx context.Context, customer service.CustomerDTO) (result int64, err error) {
+	ctx, span := tracer().Start(ctx, "Service.InsertCustomer")
+	defer end(span, &err)
+	return s.next.InsertCustomer(ctx, customer)
+}
+
+func (s *Service) UpdateCustomer(ctx context.Context, customer service.CustomerDTO) (result *service.CustomerDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.UpdateCustomer")
+	defer end(span, &err)
+	return s.next.UpdateCustomer(ctx, customer)
+}
+
+func (s *Service) DeleteCustomer(ctx context.Context, customerID int) (result *service.CustomerDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.DeleteCustomer")
+	defer end(span, &err)
+	return s.next.DeleteCustomer(ctx, customerID)
+}
+
+func (s *Service) CustomerReservations(ctx context.Context, customerID int, currency string) (result []storage.ReservationResult, err error) {
+	ctx, span := tracer().Start(ctx, "Service.CustomerReservations")
+	defer end(span, &err)
+	return s.next.CustomerReservations(ctx, customerID, currency)
+}
+
+func (s *Service) APIKeyGetAll(ctx context.Context) (result []service.APIKeyDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.APIKeyGetAll")
+	defer end(span, &err)
+	return s.next.APIKeyGetAll(ctx)
+}
+
+func (s *Service) CreateAPIKey(ctx context.Context, name string, role string) (result *service.APIKeyDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.CreateAPIKey")
+	defer end(span, &err)
+	return s.next.CreateAPIKey(ctx, name, role)
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, keyID int) (result *service.APIKeyDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.RevokeAPIKey")
+	defer end(span, &err)
+	return s.next.RevokeAPIKey(ctx, keyID)
+}
+
+func (s *Service) RotateAPIKey(ctx context.Context, keyID int) (result *service.APIKeyDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.RotateAPIKey")
+	defer end(span, &err)
+	return s.next.RotateAPIKey(ctx, keyID)
+}
+
+func (s *Service) LocationGetAll(ctx context.Context) (result []service.LocationDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.LocationGetAll")
+	defer end(span, &err)
+	return s.next.LocationGetAll(ctx)
+}
+
+func (s *Service) Location(ctx context.Context, locationID int) (result *service.LocationDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.Location")
+	defer end(span, &err)
+	return s.next.Location(ctx, locationID)
+}
+
+func (s *Service) InsertLocation(ctx context.Context, location service.LocationDTO) (result int64, err error) {
+	ctx, span := tracer().Start(ctx, "Service.InsertLocation")
+	defer end(span, &err)
+	return s.next.InsertLocation(ctx, location)
+}
+
+func (s *Service) UpdateLocation(ctx context.Context, location service.LocationDTO) (result *service.LocationDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.UpdateLocation")
+	defer end(span, &err)
+	return s.next.UpdateLocation(ctx, location)
+}
+
+func (s *Service) DeleteLocation(ctx context.Context, locationID int) (result *service.LocationDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.DeleteLocation")
+	defer end(span, &err)
+	return s.next.DeleteLocation(ctx, locationID)
+}
+
+func (s *Service) HolidayGetAll(ctx context.Context, filterDTO service.FilterHolidays) (result interface{}, err error) {
+	ctx, span := tracer().Start(ctx, "Service.HolidayGetAll")
+	defer end(span, &err)
+	return s.next.HolidayGetAll(ctx, filterDTO)
+}
+
+func (s *Service) ExportHolidays(ctx context.Context, filterDTO service.FilterHolidays, fn func(storage.HolidayWithLocation) error) (err error) {
+	ctx, span := tracer().Start(ctx, "Service.ExportHolidays")
+	defer end(span, &err)
+	return s.next.ExportHolidays(ctx, filterDTO, fn)
+}
+
+func (s *Service) Holiday(ctx context.Context, holidayID int, currency string) (result *service.HolidayDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.Holiday")
+	defer end(span, &err)
+	return s.next.Holiday(ctx, holidayID, currency)
+}
+
+func (s *Service) Quote(ctx context.Context, holidayID int, request service.QuoteRequestDTO) (result *service.QuoteDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.Quote")
+	defer end(span, &err)
+	return s.next.Quote(ctx, holidayID, request)
+}
+
+func (s *Service) InsertHoliday(ctx context.Context, holiday service.HolidayDTO) (result int64, err error) {
+	ctx, span := tracer().Start(ctx, "Service.InsertHoliday")
+	defer end(span, &err)
+	return s.next.InsertHoliday(ctx, holiday)
+}
+
+func (s *Service) UpdateHoliday(ctx context.Context, holiday service.HolidayDTO) (result *service.HolidayDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.UpdateHoliday")
+	defer end(span, &err)
+	return s.next.UpdateHoliday(ctx, holiday)
+}
+
+func (s *Service) DeleteHoliday(ctx context.Context, holidayID int) (result *service.HolidayDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.DeleteHoliday")
+	defer end(span, &err)
+	return s.next.DeleteHoliday(ctx, holidayID)
+}
+
+func (s *Service) PricingRuleSetGetAll(ctx context.Context) (result []service.PricingRuleSetDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.PricingRuleSetGetAll")
+	defer end(span, &err)
+	return s.next.PricingRuleSetGetAll(ctx)
+}
+
+func (s *Service) PricingRuleSet(ctx context.Context, ruleSetID int) (result *service.PricingRuleSetDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.PricingRuleSet")
+	defer end(span, &err)
+	return s.next.PricingRuleSet(ctx, ruleSetID)
+}
+
+func (s *Service) InsertPricingRuleSet(ctx context.Context, ruleSet service.PricingRuleSetDTO) (result int64, err error) {
+	ctx, span := tracer().Start(ctx, "Service.InsertPricingRuleSet")
+	defer end(span, &err)
+	return s.next.InsertPricingRuleSet(ctx, ruleSet)
+}
+
+func (s *Service) UpdatePricingRuleSet(ctx context.Context, ruleSet service.PricingRuleSetDTO) (result *service.PricingRuleSetDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.UpdatePricingRuleSet")
+	defer end(span, &err)
+	return s.next.UpdatePricingRuleSet(ctx, ruleSet)
+}
+
+func (s *Service) DeletePricingRuleSet(ctx context.Context, ruleSetID int) (result *service.PricingRuleSetDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.DeletePricingRuleSet")
+	defer end(span, &err)
+	return s.next.DeletePricingRuleSet(ctx, ruleSetID)
+}
+
+func (s *Service) ExchangeRateGetAll(ctx context.Context) (result []service.ExchangeRateDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.ExchangeRateGetAll")
+	defer end(span, &err)
+	return s.next.ExchangeRateGetAll(ctx)
+}
+
+func (s *Service) SaveExchangeRate(ctx context.Context, rate service.ExchangeRateDTO) (result *service.ExchangeRateDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.SaveExchangeRate")
+	defer end(span, &err)
+	return s.next.SaveExchangeRate(ctx, rate)
+}
+
+func (s *Service) ImportExchangeRates(ctx context.Context, r io.Reader) (result []service.ExchangeRateDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.ImportExchangeRates")
+	defer end(span, &err)
+	return s.next.ImportExchangeRates(ctx, r)
+}
+
+func (s *Service) DeleteExchangeRate(ctx context.Context, currency string) (result *service.ExchangeRateDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.DeleteExchangeRate")
+	defer end(span, &err)
+	return s.next.DeleteExchangeRate(ctx, currency)
+}
+
+func (s *Service) PromoCodeGetAll(ctx context.Context) (result []service.PromoCodeDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.PromoCodeGetAll")
+	defer end(span, &err)
+	return s.next.PromoCodeGetAll(ctx)
+}
+
+func (s *Service) PromoCode(ctx context.Context, promoCodeID int) (result *service.PromoCodeDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.PromoCode")
+	defer end(span, &err)
+	return s.next.PromoCode(ctx, promoCodeID)
+}
+
+func (s *Service) PromoCodeRedemptions(ctx context.Context, promoCodeID int) (result *service.PromoRedemptionsDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.PromoCodeRedemptions")
+	defer end(span, &err)
+	return s.next.PromoCodeRedemptions(ctx, promoCodeID)
+}
+
+func (s *Service) InsertPromoCode(ctx context.Context, code service.PromoCodeDTO) (result int64, err error) {
+	ctx, span := tracer().Start(ctx, "Service.InsertPromoCode")
+	defer end(span, &err)
+	return s.next.InsertPromoCode(ctx, code)
+}
+
+func (s *Service) UpdatePromoCode(ctx context.Context, code service.PromoCodeDTO) (result *service.PromoCodeDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.UpdatePromoCode")
+	defer end(span, &err)
+	return s.next.UpdatePromoCode(ctx, code)
+}
+
+func (s *Service) DeletePromoCode(ctx context.Context, promoCodeID int) (result *service.PromoCodeDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.DeletePromoCode")
+	defer end(span, &err)
+	return s.next.DeletePromoCode(ctx, promoCodeID)
+}
+
+func (s *Service) ReservationPayments(ctx context.Context, reservationID int) (result *service.PaymentsDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.ReservationPayments")
+	defer end(span, &err)
+	return s.next.ReservationPayments(ctx, reservationID)
+}
+
+func (s *Service) Payment(ctx context.Context, paymentID int) (result *service.PaymentDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.Payment")
+	defer end(span, &err)
+	return s.next.Payment(ctx, paymentID)
+}
+
+func (s *Service) CreatePayment(ctx context.Context, reservationID int, request service.PaymentRequestDTO) (result *service.PaymentDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.CreatePayment")
+	defer end(span, &err)
+	return s.next.CreatePayment(ctx, reservationID, request)
+}
+
+func (s *Service) RefundPayment(ctx context.Context, paymentID int, request service.RefundRequestDTO) (result *service.PaymentDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.RefundPayment")
+	defer end(span, &err)
+	return s.next.RefundPayment(ctx, paymentID, request)
+}
+
+func (s *Service) HandlePaymentEvent(ctx context.Context, body []byte, signature string) (result *service.PaymentDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.HandlePaymentEvent")
+	defer end(span, &err)
+	return s.next.HandlePaymentEvent(ctx, body, signature)
+}
+
+func (s *Service) IssueInvoice(ctx context.Context, reservationID int) (result *service.InvoiceDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.IssueInvoice")
+	defer end(span, &err)
+	return s.next.IssueInvoice(ctx, reservationID)
+}
+
+func (s *Service) ReservationInvoice(ctx context.Context, reservationID int) (result *service.InvoiceDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.ReservationInvoice")
+	defer end(span, &err)
+	return s.next.ReservationInvoice(ctx, reservationID)
+}
+
+func (s *Service) ReservationInvoices(ctx context.Context, reservationID int) (result []service.InvoiceDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.ReservationInvoices")
+	defer end(span, &err)
+	return s.next.ReservationInvoices(ctx, reservationID)
+}
+
+func (s *Service) Invoice(ctx context.Context, invoiceID int) (result *service.InvoiceDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.Invoice")
+	defer end(span, &err)
+	return s.next.Invoice(ctx, invoiceID)
+}
+
+func (s *Service) AuditLog(ctx context.Context, resource string, resourceID int) (result []service.AuditEntryDTO, err error) {
+	ctx, span := tracer().Start(ctx, "Service.AuditLog")
+	defer end(span, &err)
+	return s.next.AuditLog(ctx, resource, resourceID)
+}
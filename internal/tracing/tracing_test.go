@@ -0,0 +1,130 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"travel/internal/config"
+	"travel/internal/handler"
+	"travel/internal/service"
+	"travel/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// customers answers Customer and fails for unknown customers, the other
+// methods are not called.
+type customers struct {
+	handler.Service
+}
+
+var errNotFound = errors.New("sql: no rows in result set")
+
+func (customers) Customer(ctx context.Context, customerID int) (*service.CustomerDTO, error) {
+	if customerID != 1 {
+		return nil, errNotFound
+	}
+	return &service.CustomerDTO{ID: 1, Name: "Maria"}, nil
+}
+
+func TestService(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	services := tracing.NewService(customers{})
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "GET /customers/{id}")
+	if _, err := services.Customer(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.Customer(ctx, 2); !errors.Is(err, errNotFound) {
+		t.Fatalf("got %v", err)
+	}
+	request.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+
+	found, missing := spans[0], spans[1]
+	for _, span := range []tracetest.SpanStub{found, missing} {
+		if span.Name != "Service.Customer" {
+			t.Errorf("span %q", span.Name)
+		}
+		// service spans hang below the span of the request
+		if span.Parent.SpanID() != request.SpanContext().SpanID() || span.SpanContext.TraceID() != request.SpanContext().TraceID() {
+			t.Errorf("%s is not a child of the request", span.Name)
+		}
+	}
+
+	if found.Status.Code != codes.Unset || len(found.Events) != 0 {
+		t.Errorf("successful call: %+v", found.Status)
+	}
+	if missing.Status.Code != codes.Error || missing.Status.Description != errNotFound.Error() || len(missing.Events) != 1 || missing.Events[0].Name != "exception" {
+		t.Errorf("failed call: %+v %+v", missing.Status, missing.Events)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	if _, err := tracing.Setup(context.Background(), config.Tracing{Exporter: "jaeger"}); err == nil || !strings.Contains(err.Error(), "jaeger") {
+		t.Errorf("unknown exporter: got %v", err)
+	}
+
+	shutdown, err := tracing.Setup(context.Background(), config.Tracing{Exporter: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	// the file exporter writes what is buffered on shutdown
+	file := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err = tracing.Setup(context.Background(), config.Tracing{Exporter: "file", File: file, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracing.NewService(customers{}).Customer(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"Service.Customer"`) || !strings.Contains(string(data), `"Value":"travel"`) {
+		t.Errorf("spans:\n%s", data)
+	}
+
+	// nothing is sampled at a ratio of 0
+	file = filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err = tracing.Setup(context.Background(), config.Tracing{Exporter: "file", File: file, SampleRatio: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracing.NewService(customers{}).Customer(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(file); err != nil || len(data) != 0 {
+		t.Errorf("sampled at 0: %s %v", data, err)
+	}
+}
@@ -1,109 +1,315 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"travel/internal/auth"
+	"travel/internal/config"
+	"travel/internal/database"
+	"travel/internal/exchange"
 	"travel/internal/handler"
+	"travel/internal/health"
+	"travel/internal/logging"
+	"travel/internal/metrics"
+	"travel/internal/payment"
+	"travel/internal/policy"
+	"travel/internal/quote"
 	"travel/internal/service"
 	"travel/internal/storage"
+	"travel/internal/storage/memory"
+	"travel/internal/tenant"
+	"travel/internal/tracing"
 
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/mysql"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
+// store is what the API needs from the storage of any driver.
+type store interface {
+	service.Storage
+	tenant.Lookup
+	policy.PermissionStore
+	metrics.StatsSource
+}
+
+const usage = `usage:
+  travel [serve] [flags]             run the API
+  travel migrate <command> [flags]   manage the database schema, see travel migrate help
+  travel seed [flags]                generate demo data, see the -seed flags
+
+Run a command with -h for its flags.
+`
+
 func main() {
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		runServe(args)
+	case "migrate":
+		runMigrate(args)
+	case "seed":
+		runSeed(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
 
-	dbName := "travel"
+// runServe runs the API until SIGINT or SIGTERM.
+func runServe(args []string) {
 
-	//create db connection
-	db, err := createDatabase(dbName)
+	//load configuration
+	cfg, err := config.Load(args, os.LookupEnv)
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
 	}
 
-	defer db.Close()
-
-	//create db migration
-	m, err := setUpMigrations(db, dbName)
+	//create logger
+	logger, err := logging.New(cfg.Log.Level, os.Stdout)
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
 	}
+	slog.SetDefault(logger)
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+	//create tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
 		log.Fatal(err)
 	}
 
-	//create storage
-	storage := storage.New(db, "mysql")
+	//create storage, in memory or in the configured database
+	var store store
+	var db *sql.DB
+	var m *migrate.Migrate
+	if cfg.Database.Driver == config.DriverMemory {
+		logger.Warn("keeping the data in memory, it is lost on exit")
+		store = memory.New()
+	} else {
+		db, err = database.Open(cfg.Database)
+		if err != nil {
+			fatal(logger, "connecting to the database", err)
+		}
+
+		m, err = openMigrate(db, cfg)
+		if err != nil {
+			fatal(logger, "reading the migrations", err)
+		}
+
+		//with automatic migrations off /readyz fails until "travel migrate up" ran
+		if cfg.Migrations.Auto {
+			if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+				fatal(logger, "migrating the database", err)
+			}
+		}
+
+		store = storage.New(db, cfg.Database.Driver, logger)
+	}
+
+	//create metrics
+	meters := metrics.New()
+	if db != nil {
+		meters.RegisterDB(db, cfg.Database.Name)
+	}
+	meters.RegisterBusiness(store)
 
 	//create service
-	service := service.New(storage)
+	service := service.New(metrics.NewStorage(store, meters), logger)
+	service.SetRounding(exchange.Rounding{Mode: cfg.Currency.Rounding, Step: int64(cfg.Currency.RoundingStep)})
+	service.SetQuotes(quote.NewSigner([]byte(cfg.Quote.Secret), cfg.Quote.TTL))
+
+	//create payments, the fake gateway stands in for a real one
+	var gateway *payment.Fake
+	if cfg.Payment.Gateway == config.GatewayFake {
+		gateway = payment.NewFake([]byte(cfg.Payment.WebhookSecret))
+		service.SetPayments(cfg.Payment.Gateway, gateway, payment.Schedule{DepositPercent: cfg.Payment.DepositPercent, BalanceDays: cfg.Payment.BalanceDays}, cfg.Payment.IntentTTL)
+	}
+
+	//create authentication
+	authenticator, err := createAuthenticator(service, cfg.Auth)
+	if err != nil {
+		fatal(logger, "creating the authenticator", err)
+	}
+
+	//create tenant resolution, a base domain enables <agency>.<domain> hosts
+	tenants := tenant.NewResolver(store, cfg.Tenant.BaseDomain)
 
 	//create handler
-	handler := handler.New(service)
+	services := tracing.NewService(policy.New(service, store))
+	options := handler.Options{
+		Exports:      cfg.Features.Enabled(config.FeatureExports),
+		MaxBodyBytes: int64(cfg.Server.MaxBodyBytes),
+		Middleware:   []mux.MiddlewareFunc{otelmux.Middleware(tracing.ServiceName), logging.RequestIDMiddleware, meters.Middleware, logging.AccessLog(logger)},
+		Logger:       logger,
+	}
+	api := handler.New(services, authenticator, tenants, options)
+	webhooks := handler.NewWebhooks(services, options)
+
+	//create health checks, served without authentication
+	checker, err := createChecker(db, m, cfg)
+	if err != nil {
+		fatal(logger, "creating the health checks", err)
+	}
 
-	srv := http.Server{
-		Addr:    ":8080",
-		Handler: handler,
+	probes := checker.Handler()
+	router := http.NewServeMux()
+	router.Handle("/healthz", probes)
+	router.Handle("/readyz", probes)
+	router.Handle("/status", probes)
+	router.Handle("/metrics", meters.Handler())
+	router.Handle("/webhooks/", webhooks)
+	if gateway != nil {
+		router.Handle("/fake-gateway/", http.StripPrefix("/fake-gateway", gateway.Handler(webhooks)))
 	}
+	router.Handle("/", api)
 
-	log.Println("I am running ... localhost:8080")
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		fatal(logger, "listening", err)
+	}
+
+	//stop on SIGINT/SIGTERM, the database is closed once the requests are
+	//drained
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var closers []io.Closer
+	if db != nil {
+		closers = append(closers, db)
+	}
+	if err := serve(ctx, srv, listener, cfg.Server.ShutdownTimeout, closers...); err != nil {
+		logger.Error("server stopped", "error", err)
+	}
+
+	//flush the spans still buffered
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
 
-	if err := srv.ListenAndServe(); err != nil {
-		panic(err)
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("flushing traces", "error", err)
 	}
 }
 
-func createDatabase(dbName string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", "root:root@tcp(db:3306)/?parseTime=true&multiStatements=true")
+// fatal logs err and exits, deferred calls do not run.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
+}
 
-	if err != nil {
-		return nil, err
+// serve runs srv on listener until ctx is cancelled, then stops accepting
+// connections and waits up to timeout for in-flight requests to finish.
+// Requests still running after that have their connections closed, which
+// cancels their contexts and rolls back their transactions. closers, the
+// database the requests use, are closed last.
+func serve(ctx context.Context, srv *http.Server, listener net.Listener, timeout time.Duration, closers ...io.Closer) error {
+	defer func() {
+		for _, closer := range closers {
+			if err := closer.Close(); err != nil {
+				slog.Error("closing", "error", err)
+			}
+		}
+	}()
+
+	errs := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", listener.Addr().String())
+		errs <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, err
+	slog.Info("shutting down, draining requests", "timeout", timeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return err
 	}
 
-	_, err = db.Exec("CREATE DATABASE IF NOT EXISTS " + dbName)
-	if err != nil {
-		return nil, err
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
 
-	db.Close()
+	return nil
+}
+
+// createAuthenticator accepts API keys from the database and staff JWTs
+// signed with the HMAC secret (HS256) and/or the key in the RSA public key
+// file (RS256). The issuer and audience are checked when set.
+func createAuthenticator(keys auth.APIKeyAuthenticator, cfg config.Auth) (*auth.Authenticator, error) {
+	verifier := &auth.JWTVerifier{
+		HMACSecret: []byte(cfg.JWTHMACSecret),
+		Issuer:     cfg.JWTIssuer,
+		Audience:   cfg.JWTAudience,
+	}
 
-	db, err = sql.Open("mysql", "root:root@tcp(db:3306)/"+dbName+"?parseTime=true&multiStatements=true")
+	if path := cfg.JWTRSAPublicKeyFile; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		return nil, err
+		verifier.RSAKey, err = auth.ParseRSAPublicKey(data)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, err
+	if len(verifier.HMACSecret) == 0 && verifier.RSAKey == nil {
+		slog.Warn("no JWT key configured, only API keys are accepted")
+		return auth.NewAuthenticator(keys, nil), nil
 	}
 
-	return db, nil
+	return auth.NewAuthenticator(keys, verifier), nil
 }
 
-func setUpMigrations(db *sql.DB, dbName string) (*migrate.Migrate, error) {
-	driver, err := mysql.WithInstance(db, &mysql.Config{DatabaseName: dbName})
-	if err != nil {
-		return nil, err
+// createChecker expects the database at the last migration of the configured
+// source. Without a database only the process is checked.
+func createChecker(db *sql.DB, m *migrate.Migrate, cfg *config.Config) (*health.Checker, error) {
+	if db == nil {
+		return health.New(nil, nil, nil, cfg.Server.ReadyTimeout)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"mysql",
-		driver,
-	)
+	src, err := database.OpenSource(cfg)
 	if err != nil {
-		fmt.Println("errorr")
 		return nil, err
 	}
 
-	return m, nil
+	defer src.Close()
+
+	return health.New(db, m, src, cfg.Server.ReadyTimeout)
 }
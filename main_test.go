@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeDB stands in for the *sql.DB closed by serve. It records whether the
+// request in flight had finished when it was closed.
+type fakeDB struct {
+	requestDone <-chan struct{}
+	closed      chan bool
+}
+
+func (d *fakeDB) Close() error {
+	select {
+	case <-d.requestDone:
+		d.closed <- true
+	default:
+		d.closed <- false
+	}
+
+	return nil
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "finished")
+		close(done)
+	})}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	db := &fakeDB{requestDone: done, closed: make(chan bool, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, listener, 10*time.Second, db)
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	// new connections are refused once the shutdown began, the slow request
+	// keeps running and the database stays open for it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("still accepting connections")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-db.closed:
+		t.Fatal("database closed with a request in flight")
+	case err := <-served:
+		t.Fatalf("stopped with a request in flight: %v", err)
+	default:
+	}
+
+	close(release)
+
+	got := <-responses
+	if got.err != nil || got.status != http.StatusOK || got.body != "finished" {
+		t.Errorf("in-flight request got %d %q, %v", got.status, got.body, got.err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("serve: %v", err)
+	}
+	if finished := <-db.closed; !finished {
+		t.Error("database closed before the request finished")
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"travel/internal/config"
+	"travel/internal/database"
+	"travel/internal/storage"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+const migrateUsage = `usage: travel migrate <command> [flags]
+
+commands:
+  up [N]          apply every pending migration, or the next N
+  down [N|all]    roll back the last N migrations, 1 by default
+  goto V          migrate up or down to version V
+  force V         set version V without migrating, after a failed migration
+                  left the database dirty and it was fixed by hand
+  version         print the current version
+  status          list the migrations and which of them are applied
+  create NAME     add empty up and down files with the next version for
+                  every database driver
+
+The flags are the configuration flags of travel serve, they follow the
+command: travel migrate up -db-driver sqlite3
+`
+
+// runMigrate runs a migrate command against the configured database.
+func runMigrate(args []string) {
+	params, flags := splitArgs(args)
+	if len(params) == 0 || params[0] == "help" {
+		fmt.Print(migrateUsage)
+		return
+	}
+
+	cfg, err := config.Load(flags, os.LookupEnv)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := migrateCommand(cfg, params[0], params[1:], os.Stdout); err != nil {
+		log.Fatalf("migrate %s: %v", params[0], err)
+	}
+}
+
+// splitArgs separates the arguments of a command from the flags following
+// them. Negative numbers are arguments, force takes -1.
+func splitArgs(args []string) (params []string, flags []string) {
+	for i, arg := range args {
+		if _, err := strconv.Atoi(arg); strings.HasPrefix(arg, "-") && err != nil {
+			return args[:i], args[i:]
+		}
+	}
+
+	return args, nil
+}
+
+var errUsage = errors.New("wrong arguments, see travel migrate help")
+
+func migrateCommand(cfg *config.Config, command string, args []string, out io.Writer) error {
+	if command == "create" {
+		if len(args) != 1 {
+			return errUsage
+		}
+		return createMigration(cfg, args[0], out)
+	}
+
+	if cfg.Database.Driver == config.DriverMemory {
+		return fmt.Errorf("the %s driver has no schema", config.DriverMemory)
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	src, err := database.OpenSource(cfg)
+	if err != nil {
+		return err
+	}
+
+	m, err := database.NewMigrate(db, cfg.Database, src)
+	if err != nil {
+		return err
+	}
+
+	defer m.Close()
+
+	m.Log = migrateLog{out: out}
+
+	switch {
+	case command == "up" && len(args) == 0:
+		err = m.Up()
+	case command == "up" && len(args) == 1:
+		err = steps(m, args[0], 1)
+	case command == "down" && len(args) == 0:
+		err = m.Steps(-1)
+	case command == "down" && len(args) == 1 && args[0] == "all":
+		err = m.Down()
+	case command == "down" && len(args) == 1:
+		err = steps(m, args[0], -1)
+	case command == "goto" && len(args) == 1:
+		var version uint64
+		version, err = strconv.ParseUint(args[0], 10, 0)
+		if err == nil {
+			err = m.Migrate(uint(version))
+		}
+	case command == "force" && len(args) == 1:
+		var version int
+		version, err = strconv.Atoi(args[0])
+		if err == nil {
+			err = m.Force(version)
+		}
+	case command == "version" && len(args) == 0:
+	case command == "status" && len(args) == 0:
+		return status(m, src, out)
+	default:
+		return errUsage
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Fprintln(out, "no change")
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return printVersion(m, out)
+}
+
+// openMigrate returns the configured migrations, applied to db.
+func openMigrate(db *sql.DB, cfg *config.Config) (*migrate.Migrate, error) {
+	src, err := database.OpenSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.NewMigrate(db, cfg.Database, src)
+}
+
+// steps migrates n migrations in direction, 1 for up and -1 for down.
+func steps(m *migrate.Migrate, n string, direction int) error {
+	count, err := strconv.Atoi(n)
+	if err != nil || count <= 0 {
+		return fmt.Errorf("%q is no positive number", n)
+	}
+
+	return m.Steps(direction * count)
+}
+
+func printVersion(m *migrate.Migrate, out io.Writer) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintln(out, "version: none, no migration applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Fprintf(out, "version: %d (dirty, fix the database and run migrate force %d)\n", version, version)
+		return nil
+	}
+
+	fmt.Fprintf(out, "version: %d\n", version)
+	return nil
+}
+
+// status lists every migration of src. Migrations run in order, so those up
+// to the current version are applied.
+func status(m *migrate.Migrate, src source.Driver, out io.Writer) error {
+	current, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	applied := err == nil
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	version, err := src.First()
+	for err == nil {
+		state := "pending"
+		switch {
+		case applied && version == current && dirty:
+			state = "dirty"
+		case applied && version <= current:
+			state = "applied"
+		}
+
+		name := ""
+		if r, identifier, err := src.ReadUp(version); err == nil {
+			r.Close()
+			name = identifier
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", version, name, state)
+
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return printVersion(m, out)
+}
+
+type migrateLog struct {
+	out io.Writer
+}
+
+func (l migrateLog) Printf(format string, v ...interface{}) {
+	fmt.Fprintf(l.out, format, v...)
+}
+
+func (l migrateLog) Verbose() bool {
+	return false
+}
+
+var (
+	migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+	migrationFile = regexp.MustCompile(`^([0-9]+)_.*\.(up|down)\.sql$`)
+)
+
+// createMigration adds empty up and down files to the migrations of every
+// driver, so all of them stay at the same version. A configured file:// path
+// gets them alone. The version is the highest one of any driver plus one.
+func createMigration(cfg *config.Config, name string, out io.Writer) error {
+	if !migrationName.MatchString(name) {
+		return fmt.Errorf("name %q may only contain lowercase letters, digits and _", name)
+	}
+
+	var dirs []string
+	switch path := cfg.Migrations.Path; {
+	case path == "":
+		for _, driver := range []string{storage.DialectMySQL, storage.DialectPostgres, storage.DialectSQLite} {
+			dirs = append(dirs, filepath.Join("migrations", driver))
+		}
+	case strings.HasPrefix(path, "file://"):
+		dirs = []string{strings.TrimPrefix(path, "file://")}
+	default:
+		return fmt.Errorf("migrations can only be created in a file:// path, not %s", path)
+	}
+
+	var last uint64
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if match := migrationFile.FindStringSubmatch(entry.Name()); match != nil {
+				version, err := strconv.ParseUint(match[1], 10, 0)
+				if err != nil {
+					return err
+				}
+				last = max(last, version)
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, fmt.Sprintf("%d_%s.%s.sql", last+1, name, direction))
+
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, path)
+		}
+	}
+
+	return nil
+}
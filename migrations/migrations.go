@@ -0,0 +1,10 @@
+// Package migrations holds the schema migrations of every database driver,
+// one directory per driver, compiled into the binary.
+package migrations
+
+import "embed"
+
+// FS holds the directories mysql, postgres and sqlite3.
+//
+//go:embed mysql/*.sql postgres/*.sql sqlite3/*.sql
+var FS embed.FS
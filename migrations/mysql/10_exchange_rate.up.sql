@@ -0,0 +1,15 @@
+-- Table for Exchange Rate, units of currency for one euro like the ECB
+-- reference rates, date is the day the rate was published for
+CREATE TABLE IF NOT EXISTS `exchange_rate` (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    currency CHAR(3) NOT NULL,
+    rate DOUBLE NOT NULL,
+    date DATE NOT NULL,
+    tenantID INT NOT NULL,
+    UNIQUE KEY uq_exchange_rate_currency (currency, tenantID),
+    FOREIGN KEY (tenantID) REFERENCES `agency`(id)
+);
+
+-- the exchange rates of the agency when the reservation was booked, its prices
+-- are converted with them later on
+ALTER TABLE `reservation` ADD COLUMN exchangeRates JSON NULL;
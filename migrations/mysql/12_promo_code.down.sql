@@ -0,0 +1,10 @@
+DELETE rp FROM `role_permission` rp
+INNER JOIN `permission` p ON p.id = rp.permissionID
+WHERE p.name IN ('promo:read', 'promo:write');
+DELETE FROM `permission` WHERE name IN ('promo:read', 'promo:write');
+
+ALTER TABLE `reservation` DROP FOREIGN KEY fk_reservation_promo_code_tenant;
+ALTER TABLE `reservation` DROP COLUMN promo;
+ALTER TABLE `reservation` DROP COLUMN promoCodeID;
+
+DROP TABLE promo_code;
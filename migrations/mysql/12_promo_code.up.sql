@@ -0,0 +1,39 @@
+-- Table for Promo Code, amountMinor and minSpendMinor are in currency;
+-- holidays and locations are the JSON arrays of the ids the code is
+-- restricted to
+CREATE TABLE IF NOT EXISTS `promo_code` (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    code VARCHAR(32) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    percent DOUBLE NOT NULL,
+    amountMinor BIGINT NULL,
+    minSpendMinor BIGINT NULL,
+    currency CHAR(3) NULL,
+    validFrom DATETIME NULL,
+    validTo DATETIME NULL,
+    maxRedemptions INT NOT NULL,
+    maxPerCustomer INT NOT NULL,
+    holidays JSON NOT NULL,
+    locations JSON NOT NULL,
+    tenantID INT NOT NULL,
+    UNIQUE KEY uq_promo_code_code (code, tenantID),
+    UNIQUE KEY uq_promo_code_tenant (id, tenantID),
+    FOREIGN KEY (tenantID) REFERENCES `agency`(id)
+);
+
+-- the promo code a reservation was booked with and the JSON of what it took
+-- off, the redemptions of a code are the reservations pointing to it
+ALTER TABLE `reservation` ADD COLUMN promoCodeID INT NULL;
+ALTER TABLE `reservation` ADD COLUMN promo JSON NULL;
+ALTER TABLE `reservation` ADD CONSTRAINT fk_reservation_promo_code_tenant
+    FOREIGN KEY (promoCodeID, tenantID) REFERENCES `promo_code`(id, tenantID);
+
+INSERT INTO `permission` (name) VALUES ('promo:read'), ('promo:write');
+
+INSERT INTO `role_permission` (roleID, permissionID)
+SELECT r.id, p.id FROM `role` r CROSS JOIN `permission` p
+WHERE r.name = 'admin' AND p.name IN ('promo:read', 'promo:write');
+
+INSERT INTO `role_permission` (roleID, permissionID)
+SELECT r.id, p.id FROM `role` r CROSS JOIN `permission` p
+WHERE r.name = 'agent' AND p.name = 'promo:read';
@@ -0,0 +1,8 @@
+DELETE rp FROM `role_permission` rp
+INNER JOIN `permission` p ON p.id = rp.permissionID
+WHERE p.name LIKE 'payment:%';
+DELETE FROM `permission` WHERE name LIKE 'payment:%';
+
+DROP TABLE `payment`;
+
+ALTER TABLE `reservation` DROP INDEX uq_reservation_tenant;
@@ -0,0 +1,10 @@
+DELETE rp FROM `role_permission` rp
+INNER JOIN `permission` p ON p.id = rp.permissionID
+WHERE p.name IN ('invoice:read', 'invoice:read:own');
+DELETE FROM `permission` WHERE name IN ('invoice:read', 'invoice:read:own');
+
+DROP TABLE `invoice`;
+
+ALTER TABLE `agency` DROP COLUMN taxNumber;
+ALTER TABLE `agency` DROP COLUMN email;
+ALTER TABLE `agency` DROP COLUMN address;
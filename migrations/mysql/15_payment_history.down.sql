@@ -0,0 +1,6 @@
+DELETE p FROM `payment` p
+LEFT JOIN `reservation` r ON r.id = p.reservationID AND r.tenantID = p.tenantID
+WHERE r.id IS NULL;
+
+ALTER TABLE `payment` ADD CONSTRAINT fk_payment_reservation_tenant
+    FOREIGN KEY (reservationID, tenantID) REFERENCES `reservation`(id, tenantID);
@@ -0,0 +1,3 @@
+-- a refund is stored before it is sent to the gateway and settled once the
+-- gateway confirms it, refundingMinor is the amount on its way
+ALTER TABLE `payment` ADD COLUMN refundingMinor BIGINT NOT NULL DEFAULT 0;
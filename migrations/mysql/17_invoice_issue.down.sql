@@ -0,0 +1,4 @@
+DELETE rp FROM `role_permission` rp
+INNER JOIN `permission` p ON p.id = rp.permissionID
+WHERE p.name IN ('invoice:write', 'invoice:create:own');
+DELETE FROM `permission` WHERE name IN ('invoice:write', 'invoice:create:own');
@@ -0,0 +1,12 @@
+-- the last number issued per agency and kind of document. Issuing locks the
+-- row, so invoices are numbered one after the other without gaps
+CREATE TABLE IF NOT EXISTS `invoice_sequence` (
+    tenantID INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    lastSequence INT NOT NULL,
+    PRIMARY KEY (tenantID, kind),
+    FOREIGN KEY (tenantID) REFERENCES `agency`(id)
+);
+
+INSERT INTO `invoice_sequence` (tenantID, kind, lastSequence)
+SELECT tenantID, kind, MAX(sequence) FROM `invoice` GROUP BY tenantID, kind;
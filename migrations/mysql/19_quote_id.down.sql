@@ -0,0 +1,2 @@
+ALTER TABLE `reservation` DROP INDEX uq_reservation_quote;
+ALTER TABLE `reservation` DROP COLUMN quoteID;
@@ -0,0 +1,3 @@
+ALTER TABLE `reservation` DROP FOREIGN KEY fk_reservation_customer;
+ALTER TABLE `reservation` DROP COLUMN customerID;
+DROP TABLE customer;
@@ -0,0 +1,36 @@
+-- Table for Customer
+CREATE TABLE IF NOT EXISTS `customer` (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    phoneNumber VARCHAR(20) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    normalizedPhone VARCHAR(20) NOT NULL,
+    INDEX idx_customer_normalized_phone (normalizedPhone),
+    INDEX idx_customer_email (email)
+);
+
+ALTER TABLE `reservation` ADD COLUMN customerID INT NULL;
+ALTER TABLE `reservation` ADD CONSTRAINT fk_reservation_customer FOREIGN KEY (customerID) REFERENCES `customer`(id);
+
+-- one customer per distinct phone number already used in reservations,
+-- normalized the same way the service does it (digits only, 00 -> +)
+CREATE TEMPORARY TABLE reservation_contact AS
+SELECT id, contactName, phoneNumber,
+    IF(digits LIKE '00%', CONCAT('+', SUBSTRING(digits, 3)), digits) AS normalized
+FROM (
+    SELECT id, contactName, phoneNumber,
+        CONCAT(IF(TRIM(phoneNumber) LIKE '+%', '+', ''), REGEXP_REPLACE(phoneNumber, '[^0-9]', '')) AS digits
+    FROM `reservation`
+) AS contacts;
+
+INSERT INTO `customer` (name, phoneNumber, email, normalizedPhone)
+SELECT MIN(contactName), MIN(phoneNumber), '', normalized
+FROM reservation_contact
+GROUP BY normalized;
+
+UPDATE `reservation` r
+INNER JOIN reservation_contact rc ON rc.id = r.id
+INNER JOIN `customer` c ON c.normalizedPhone = rc.normalized
+SET r.customerID = c.id;
+
+DROP TEMPORARY TABLE reservation_contact;
@@ -0,0 +1,3 @@
+-- canonical E.164 form next to the number as the customer typed it;
+-- rows created before this migration are parsed again when read
+ALTER TABLE `reservation` ADD COLUMN phoneE164 VARCHAR(16) NOT NULL DEFAULT '';
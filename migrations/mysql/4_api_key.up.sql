@@ -0,0 +1,11 @@
+-- Table for API keys of partner integrations, only the hash of a key is kept
+CREATE TABLE IF NOT EXISTS `api_key` (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    hash CHAR(64) NOT NULL,
+    createdBy VARCHAR(255) NOT NULL,
+    createdAt DATETIME NOT NULL,
+    lastUsedAt DATETIME NULL,
+    revokedAt DATETIME NULL
+);
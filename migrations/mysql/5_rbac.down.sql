@@ -0,0 +1,4 @@
+ALTER TABLE `api_key` DROP COLUMN role;
+DROP TABLE role_permission;
+DROP TABLE permission;
+DROP TABLE role;
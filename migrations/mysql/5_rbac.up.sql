@@ -0,0 +1,51 @@
+-- Tables for role based authorization
+CREATE TABLE IF NOT EXISTS `role` (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(32) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS `permission` (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS `role_permission` (
+    roleID INT NOT NULL,
+    permissionID INT NOT NULL,
+    PRIMARY KEY (roleID, permissionID),
+    FOREIGN KEY (roleID) REFERENCES `role`(id),
+    FOREIGN KEY (permissionID) REFERENCES `permission`(id)
+);
+
+ALTER TABLE `api_key` ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'agent';
+
+INSERT INTO `role` (name) VALUES ('admin'), ('agent'), ('customer');
+
+-- permissions ending in :own only cover records of the caller's customer
+INSERT INTO `permission` (name) VALUES
+    ('holiday:read'), ('holiday:write'),
+    ('location:read'), ('location:write'),
+    ('reservation:read'), ('reservation:write'),
+    ('reservation:read:own'), ('reservation:create:own'),
+    ('customer:read'), ('customer:write'), ('customer:read:own'),
+    ('apikey:manage');
+
+INSERT INTO `role_permission` (roleID, permissionID)
+SELECT r.id, p.id FROM `role` r CROSS JOIN `permission` p
+WHERE r.name = 'admin' AND p.name NOT LIKE '%:own';
+
+INSERT INTO `role_permission` (roleID, permissionID)
+SELECT r.id, p.id FROM `role` r CROSS JOIN `permission` p
+WHERE r.name = 'agent' AND p.name IN (
+    'holiday:read', 'location:read',
+    'reservation:read', 'reservation:write',
+    'customer:read', 'customer:write'
+);
+
+INSERT INTO `role_permission` (roleID, permissionID)
+SELECT r.id, p.id FROM `role` r CROSS JOIN `permission` p
+WHERE r.name = 'customer' AND p.name IN (
+    'holiday:read', 'location:read',
+    'reservation:read:own', 'reservation:create:own',
+    'customer:read:own'
+);
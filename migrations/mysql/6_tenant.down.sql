@@ -0,0 +1,23 @@
+ALTER TABLE `reservation` DROP FOREIGN KEY fk_reservation_customer_tenant;
+ALTER TABLE `reservation` DROP FOREIGN KEY fk_reservation_holiday_tenant;
+ALTER TABLE `holiday` DROP FOREIGN KEY fk_holiday_location_tenant;
+
+ALTER TABLE `api_key` DROP FOREIGN KEY fk_api_key_tenant;
+ALTER TABLE `api_key` DROP COLUMN tenantID;
+
+ALTER TABLE `reservation` DROP FOREIGN KEY fk_reservation_tenant;
+ALTER TABLE `reservation` DROP COLUMN tenantID;
+
+ALTER TABLE `customer` DROP FOREIGN KEY fk_customer_tenant;
+ALTER TABLE `customer` DROP INDEX uq_customer_tenant;
+ALTER TABLE `customer` DROP COLUMN tenantID;
+
+ALTER TABLE `holiday` DROP FOREIGN KEY fk_holiday_tenant;
+ALTER TABLE `holiday` DROP INDEX uq_holiday_tenant;
+ALTER TABLE `holiday` DROP COLUMN tenantID;
+
+ALTER TABLE `location` DROP FOREIGN KEY fk_location_tenant;
+ALTER TABLE `location` DROP INDEX uq_location_tenant;
+ALTER TABLE `location` DROP COLUMN tenantID;
+
+DROP TABLE agency;
@@ -0,0 +1,40 @@
+-- Table for Agency, the tenants of the platform
+CREATE TABLE IF NOT EXISTS `agency` (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL
+);
+
+-- everything created before tenants existed belongs to the first agency
+INSERT INTO `agency` (id, slug, name) VALUES (1, 'default', 'Default agency');
+
+ALTER TABLE `location` ADD COLUMN tenantID INT NOT NULL DEFAULT 1;
+ALTER TABLE `location` ALTER COLUMN tenantID DROP DEFAULT;
+ALTER TABLE `location` ADD CONSTRAINT fk_location_tenant FOREIGN KEY (tenantID) REFERENCES `agency`(id);
+ALTER TABLE `location` ADD UNIQUE KEY uq_location_tenant (id, tenantID);
+
+ALTER TABLE `holiday` ADD COLUMN tenantID INT NOT NULL DEFAULT 1;
+ALTER TABLE `holiday` ALTER COLUMN tenantID DROP DEFAULT;
+ALTER TABLE `holiday` ADD CONSTRAINT fk_holiday_tenant FOREIGN KEY (tenantID) REFERENCES `agency`(id);
+ALTER TABLE `holiday` ADD UNIQUE KEY uq_holiday_tenant (id, tenantID);
+
+ALTER TABLE `customer` ADD COLUMN tenantID INT NOT NULL DEFAULT 1;
+ALTER TABLE `customer` ALTER COLUMN tenantID DROP DEFAULT;
+ALTER TABLE `customer` ADD CONSTRAINT fk_customer_tenant FOREIGN KEY (tenantID) REFERENCES `agency`(id);
+ALTER TABLE `customer` ADD UNIQUE KEY uq_customer_tenant (id, tenantID);
+
+ALTER TABLE `reservation` ADD COLUMN tenantID INT NOT NULL DEFAULT 1;
+ALTER TABLE `reservation` ALTER COLUMN tenantID DROP DEFAULT;
+ALTER TABLE `reservation` ADD CONSTRAINT fk_reservation_tenant FOREIGN KEY (tenantID) REFERENCES `agency`(id);
+
+ALTER TABLE `api_key` ADD COLUMN tenantID INT NOT NULL DEFAULT 1;
+ALTER TABLE `api_key` ALTER COLUMN tenantID DROP DEFAULT;
+ALTER TABLE `api_key` ADD CONSTRAINT fk_api_key_tenant FOREIGN KEY (tenantID) REFERENCES `agency`(id);
+
+-- references may never cross tenants, whatever id the caller guesses
+ALTER TABLE `holiday` ADD CONSTRAINT fk_holiday_location_tenant
+    FOREIGN KEY (locationID, tenantID) REFERENCES `location`(id, tenantID);
+ALTER TABLE `reservation` ADD CONSTRAINT fk_reservation_holiday_tenant
+    FOREIGN KEY (holidayID, tenantID) REFERENCES `holiday`(id, tenantID);
+ALTER TABLE `reservation` ADD CONSTRAINT fk_reservation_customer_tenant
+    FOREIGN KEY (customerID, tenantID) REFERENCES `customer`(id, tenantID);
@@ -0,0 +1,8 @@
+DELETE rp FROM `role_permission` rp
+INNER JOIN `permission` p ON p.id = rp.permissionID
+WHERE p.name = 'audit:read';
+DELETE FROM `permission` WHERE name = 'audit:read';
+
+DROP TRIGGER audit_log_no_delete;
+DROP TRIGGER audit_log_no_update;
+DROP TABLE audit_log;
@@ -0,0 +1,27 @@
+-- Table for the audit log, rows are only ever inserted
+CREATE TABLE IF NOT EXISTS `audit_log` (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    tenantID INT NOT NULL,
+    actorType VARCHAR(16) NOT NULL,
+    actorID VARCHAR(255) NOT NULL,
+    actorName VARCHAR(255) NOT NULL,
+    occurredAt DATETIME(6) NOT NULL,
+    resource VARCHAR(32) NOT NULL,
+    resourceID INT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    diff JSON NOT NULL,
+    INDEX idx_audit_log_resource (tenantID, resource, resourceID),
+    FOREIGN KEY (tenantID) REFERENCES `agency`(id)
+);
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON `audit_log`
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON `audit_log`
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+INSERT INTO `permission` (name) VALUES ('audit:read');
+
+INSERT INTO `role_permission` (roleID, permissionID)
+SELECT r.id, p.id FROM `role` r CROSS JOIN `permission` p
+WHERE r.name = 'admin' AND p.name = 'audit:read';
@@ -0,0 +1,11 @@
+DELETE rp FROM `role_permission` rp
+INNER JOIN `permission` p ON p.id = rp.permissionID
+WHERE p.name IN ('pricing:read', 'pricing:write');
+DELETE FROM `permission` WHERE name IN ('pricing:read', 'pricing:write');
+
+ALTER TABLE `reservation` DROP COLUMN pricing;
+
+ALTER TABLE `holiday` DROP FOREIGN KEY fk_holiday_pricing_rule_set_tenant;
+ALTER TABLE `holiday` DROP COLUMN pricingRuleSetID;
+
+DROP TABLE pricing_rule_set;
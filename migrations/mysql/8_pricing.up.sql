@@ -0,0 +1,27 @@
+-- Table for Pricing Rule Set, rules is the JSON array of pricing.Rule
+CREATE TABLE IF NOT EXISTS `pricing_rule_set` (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(255) NOT NULL,
+    rules JSON NOT NULL,
+    tenantID INT NOT NULL,
+    UNIQUE KEY uq_pricing_rule_set_tenant (id, tenantID),
+    FOREIGN KEY (tenantID) REFERENCES `agency`(id)
+);
+
+ALTER TABLE `holiday` ADD COLUMN pricingRuleSetID INT NULL;
+ALTER TABLE `holiday` ADD CONSTRAINT fk_holiday_pricing_rule_set_tenant
+    FOREIGN KEY (pricingRuleSetID, tenantID) REFERENCES `pricing_rule_set`(id, tenantID);
+
+-- the quote a reservation was booked at, later changes of the rules or the
+-- price of the holiday do not touch it
+ALTER TABLE `reservation` ADD COLUMN pricing JSON NULL;
+
+INSERT INTO `permission` (name) VALUES ('pricing:read'), ('pricing:write');
+
+INSERT INTO `role_permission` (roleID, permissionID)
+SELECT r.id, p.id FROM `role` r CROSS JOIN `permission` p
+WHERE r.name = 'admin' AND p.name IN ('pricing:read', 'pricing:write');
+
+INSERT INTO `role_permission` (roleID, permissionID)
+SELECT r.id, p.id FROM `role` r CROSS JOIN `permission` p
+WHERE r.name = 'agent' AND p.name = 'pricing:read';
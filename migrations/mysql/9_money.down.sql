@@ -0,0 +1,6 @@
+ALTER TABLE `holiday` ADD COLUMN price FLOAT NOT NULL DEFAULT 0 AFTER startDate;
+
+UPDATE `holiday` SET price = priceMinor / 100;
+
+ALTER TABLE `holiday` DROP COLUMN currency;
+ALTER TABLE `holiday` DROP COLUMN priceMinor;
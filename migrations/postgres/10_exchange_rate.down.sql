@@ -0,0 +1,3 @@
+ALTER TABLE reservation DROP COLUMN "exchangeRates";
+
+DROP TABLE exchange_rate;
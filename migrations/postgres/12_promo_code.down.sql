@@ -0,0 +1,10 @@
+DELETE FROM role_permission WHERE "permissionID" IN (
+    SELECT id FROM permission WHERE name IN ('promo:read', 'promo:write')
+);
+DELETE FROM permission WHERE name IN ('promo:read', 'promo:write');
+
+ALTER TABLE reservation DROP CONSTRAINT fk_reservation_promo_code_tenant;
+ALTER TABLE reservation DROP COLUMN promo;
+ALTER TABLE reservation DROP COLUMN "promoCodeID";
+
+DROP TABLE promo_code;
@@ -0,0 +1,38 @@
+-- Table for Promo Code, amountMinor and minSpendMinor are in currency;
+-- holidays and locations are the JSON arrays of the ids the code is
+-- restricted to
+CREATE TABLE IF NOT EXISTS promo_code (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    percent DOUBLE PRECISION NOT NULL,
+    "amountMinor" BIGINT NULL,
+    "minSpendMinor" BIGINT NULL,
+    currency CHAR(3) NULL,
+    "validFrom" TIMESTAMP NULL,
+    "validTo" TIMESTAMP NULL,
+    "maxRedemptions" INT NOT NULL,
+    "maxPerCustomer" INT NOT NULL,
+    holidays JSONB NOT NULL,
+    locations JSONB NOT NULL,
+    "tenantID" INT NOT NULL REFERENCES agency(id),
+    UNIQUE (code, "tenantID"),
+    UNIQUE (id, "tenantID")
+);
+
+-- the promo code a reservation was booked with and the JSON of what it took
+-- off, the redemptions of a code are the reservations pointing to it
+ALTER TABLE reservation ADD COLUMN "promoCodeID" INT NULL;
+ALTER TABLE reservation ADD COLUMN promo JSONB NULL;
+ALTER TABLE reservation ADD CONSTRAINT fk_reservation_promo_code_tenant
+    FOREIGN KEY ("promoCodeID", "tenantID") REFERENCES promo_code(id, "tenantID");
+
+INSERT INTO permission (name) VALUES ('promo:read'), ('promo:write');
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'admin' AND p.name IN ('promo:read', 'promo:write');
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'agent' AND p.name = 'promo:read';
@@ -0,0 +1,41 @@
+-- Table for Payment, the installments reservations are paid with. Every
+-- payment is an intent at the gateway, intentID is its id there
+CREATE TABLE IF NOT EXISTS payment (
+    id SERIAL PRIMARY KEY,
+    "reservationID" INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    "amountMinor" BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    "dueDate" DATE NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    gateway VARCHAR(32) NOT NULL,
+    "intentID" VARCHAR(255) NULL,
+    "refundedMinor" BIGINT NOT NULL,
+    "createdAt" TIMESTAMP NOT NULL,
+    "updatedAt" TIMESTAMP NOT NULL,
+    "tenantID" INT NOT NULL REFERENCES agency(id),
+    UNIQUE (gateway, "intentID")
+);
+
+CREATE INDEX idx_payment_reservation ON payment ("reservationID");
+
+ALTER TABLE reservation ADD CONSTRAINT uq_reservation_tenant UNIQUE (id, "tenantID");
+ALTER TABLE payment ADD CONSTRAINT fk_payment_reservation_tenant
+    FOREIGN KEY ("reservationID", "tenantID") REFERENCES reservation(id, "tenantID");
+
+-- customers pay for their own reservations, refunds are up to admins
+INSERT INTO permission (name) VALUES
+    ('payment:read'), ('payment:write'), ('payment:refund'),
+    ('payment:read:own'), ('payment:create:own');
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'admin' AND p.name IN ('payment:read', 'payment:write', 'payment:refund');
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'agent' AND p.name IN ('payment:read', 'payment:write');
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'customer' AND p.name IN ('payment:read:own', 'payment:create:own');
@@ -0,0 +1,6 @@
+DELETE FROM payment WHERE NOT EXISTS (
+    SELECT 1 FROM reservation r WHERE r.id = payment."reservationID" AND r."tenantID" = payment."tenantID"
+);
+
+ALTER TABLE payment ADD CONSTRAINT fk_payment_reservation_tenant
+    FOREIGN KEY ("reservationID", "tenantID") REFERENCES reservation(id, "tenantID");
@@ -0,0 +1,4 @@
+-- payments are kept when their reservation is cancelled, like its invoices,
+-- so reservationID is no longer a foreign key. The service cancels only
+-- reservations whose payments failed or were refunded
+ALTER TABLE payment DROP CONSTRAINT fk_payment_reservation_tenant;
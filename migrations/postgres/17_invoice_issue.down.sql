@@ -0,0 +1,4 @@
+DELETE FROM role_permission WHERE "permissionID" IN (
+    SELECT id FROM permission WHERE name IN ('invoice:write', 'invoice:create:own')
+);
+DELETE FROM permission WHERE name IN ('invoice:write', 'invoice:create:own');
@@ -0,0 +1,10 @@
+-- invoices are issued on request, reading them does not issue them
+INSERT INTO permission (name) VALUES ('invoice:write'), ('invoice:create:own');
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name IN ('admin', 'agent') AND p.name = 'invoice:write';
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'customer' AND p.name = 'invoice:create:own';
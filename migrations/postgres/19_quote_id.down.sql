@@ -0,0 +1,2 @@
+DROP INDEX uq_reservation_quote;
+ALTER TABLE reservation DROP COLUMN "quoteID";
@@ -0,0 +1,4 @@
+-- the ID of the quote the reservation was booked at, a quote books one
+-- reservation only
+ALTER TABLE reservation ADD COLUMN "quoteID" VARCHAR(32) NULL;
+CREATE UNIQUE INDEX uq_reservation_quote ON reservation ("tenantID", "quoteID");
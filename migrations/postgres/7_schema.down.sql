@@ -0,0 +1,11 @@
+DROP TABLE audit_log;
+DROP FUNCTION audit_log_append_only;
+DROP TABLE role_permission;
+DROP TABLE permission;
+DROP TABLE role;
+DROP TABLE api_key;
+DROP TABLE reservation;
+DROP TABLE customer;
+DROP TABLE holiday;
+DROP TABLE location;
+DROP TABLE agency;
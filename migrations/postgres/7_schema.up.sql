@@ -0,0 +1,154 @@
+-- PostgreSQL starts at the schema the MySQL migrations 1 to 7 build up, so
+-- the versions of every backend stay the same from here on. Columns keep the
+-- order and camelCase names of the MySQL tables and are quoted for that.
+
+-- Table for Agency, the tenants of the platform
+CREATE TABLE IF NOT EXISTS agency (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL
+);
+
+INSERT INTO agency (slug, name) VALUES ('default', 'Default agency');
+
+-- Table for Location
+CREATE TABLE IF NOT EXISTS location (
+    id SERIAL PRIMARY KEY,
+    street VARCHAR(255) NOT NULL,
+    number VARCHAR(255) NOT NULL,
+    city VARCHAR(255) NOT NULL,
+    country VARCHAR(255) NOT NULL,
+    "tenantID" INT NOT NULL REFERENCES agency(id),
+    UNIQUE (id, "tenantID")
+);
+
+-- Table for Holiday
+CREATE TABLE IF NOT EXISTS holiday (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    duration INT NOT NULL,
+    "startDate" DATE NOT NULL,
+    price DOUBLE PRECISION NOT NULL,
+    "freeSlots" INT NOT NULL,
+    "locationID" INT NOT NULL REFERENCES location(id),
+    "tenantID" INT NOT NULL REFERENCES agency(id),
+    UNIQUE (id, "tenantID"),
+    FOREIGN KEY ("locationID", "tenantID") REFERENCES location(id, "tenantID")
+);
+
+-- Table for Customer
+CREATE TABLE IF NOT EXISTS customer (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    "phoneNumber" VARCHAR(20) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    "normalizedPhone" VARCHAR(20) NOT NULL,
+    "tenantID" INT NOT NULL REFERENCES agency(id),
+    UNIQUE (id, "tenantID")
+);
+
+CREATE INDEX idx_customer_normalized_phone ON customer ("normalizedPhone");
+CREATE INDEX idx_customer_email ON customer (email);
+
+-- Table for Reservation
+CREATE TABLE IF NOT EXISTS reservation (
+    id SERIAL PRIMARY KEY,
+    "contactName" VARCHAR(255) NOT NULL,
+    "phoneNumber" VARCHAR(20) NOT NULL,
+    "holidayID" INT NOT NULL REFERENCES holiday(id),
+    "customerID" INT NULL REFERENCES customer(id),
+    "phoneE164" VARCHAR(16) NOT NULL DEFAULT '',
+    "tenantID" INT NOT NULL REFERENCES agency(id),
+    FOREIGN KEY ("holidayID", "tenantID") REFERENCES holiday(id, "tenantID"),
+    FOREIGN KEY ("customerID", "tenantID") REFERENCES customer(id, "tenantID")
+);
+
+-- Table for API keys of partner integrations, only the hash of a key is kept
+CREATE TABLE IF NOT EXISTS api_key (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    hash CHAR(64) NOT NULL,
+    "createdBy" VARCHAR(255) NOT NULL,
+    "createdAt" TIMESTAMP NOT NULL,
+    "lastUsedAt" TIMESTAMP NULL,
+    "revokedAt" TIMESTAMP NULL,
+    role VARCHAR(32) NOT NULL DEFAULT 'agent',
+    "tenantID" INT NOT NULL REFERENCES agency(id)
+);
+
+-- Tables for role based authorization
+CREATE TABLE IF NOT EXISTS role (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(32) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS permission (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS role_permission (
+    "roleID" INT NOT NULL REFERENCES role(id),
+    "permissionID" INT NOT NULL REFERENCES permission(id),
+    PRIMARY KEY ("roleID", "permissionID")
+);
+
+INSERT INTO role (name) VALUES ('admin'), ('agent'), ('customer');
+
+-- permissions ending in :own only cover records of the caller's customer
+INSERT INTO permission (name) VALUES
+    ('holiday:read'), ('holiday:write'),
+    ('location:read'), ('location:write'),
+    ('reservation:read'), ('reservation:write'),
+    ('reservation:read:own'), ('reservation:create:own'),
+    ('customer:read'), ('customer:write'), ('customer:read:own'),
+    ('apikey:manage'), ('audit:read');
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'admin' AND p.name NOT LIKE '%:own';
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'agent' AND p.name IN (
+    'holiday:read', 'location:read',
+    'reservation:read', 'reservation:write',
+    'customer:read', 'customer:write'
+);
+
+INSERT INTO role_permission ("roleID", "permissionID")
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'customer' AND p.name IN (
+    'holiday:read', 'location:read',
+    'reservation:read:own', 'reservation:create:own',
+    'customer:read:own'
+);
+
+-- Table for the audit log, rows are only ever inserted
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    "tenantID" INT NOT NULL REFERENCES agency(id),
+    "actorType" VARCHAR(16) NOT NULL,
+    "actorID" VARCHAR(255) NOT NULL,
+    "actorName" VARCHAR(255) NOT NULL,
+    "occurredAt" TIMESTAMP(6) NOT NULL,
+    resource VARCHAR(32) NOT NULL,
+    "resourceID" INT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    diff JSONB NOT NULL
+);
+
+CREATE INDEX idx_audit_log_resource ON audit_log ("tenantID", resource, "resourceID");
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
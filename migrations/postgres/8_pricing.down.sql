@@ -0,0 +1,11 @@
+DELETE FROM role_permission WHERE "permissionID" IN (
+    SELECT id FROM permission WHERE name IN ('pricing:read', 'pricing:write')
+);
+DELETE FROM permission WHERE name IN ('pricing:read', 'pricing:write');
+
+ALTER TABLE reservation DROP COLUMN pricing;
+
+ALTER TABLE holiday DROP CONSTRAINT fk_holiday_pricing_rule_set_tenant;
+ALTER TABLE holiday DROP COLUMN "pricingRuleSetID";
+
+DROP TABLE pricing_rule_set;
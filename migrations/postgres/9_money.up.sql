@@ -0,0 +1,10 @@
+-- prices are whole minor units (cents) of their currency instead of a DOUBLE
+-- PRECISION, which could not hold most cent amounts exactly; all prices so far
+-- are euro. The quotes already stored on reservations keep their numbers as
+-- written.
+ALTER TABLE holiday ADD COLUMN "priceMinor" BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE holiday ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR';
+
+UPDATE holiday SET "priceMinor" = CAST(ROUND(price * 100) AS BIGINT);
+
+ALTER TABLE holiday DROP COLUMN price;
@@ -0,0 +1,14 @@
+-- Table for Exchange Rate, units of currency for one euro like the ECB
+-- reference rates, date is the day the rate was published for
+CREATE TABLE IF NOT EXISTS exchange_rate (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    currency CHAR(3) NOT NULL,
+    rate REAL NOT NULL,
+    date DATE NOT NULL,
+    tenantID INT NOT NULL REFERENCES agency(id),
+    UNIQUE (currency, tenantID)
+);
+
+-- the exchange rates of the agency when the reservation was booked, its prices
+-- are converted with them later on
+ALTER TABLE reservation ADD COLUMN exchangeRates TEXT NULL;
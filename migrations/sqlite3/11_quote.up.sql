@@ -0,0 +1,3 @@
+-- the quote the reservation was booked at, its party, extras and items,
+-- when it was booked with one
+ALTER TABLE reservation ADD COLUMN quote TEXT NULL;
@@ -0,0 +1,38 @@
+-- Table for Promo Code, amountMinor and minSpendMinor are in currency;
+-- holidays and locations are the JSON arrays of the ids the code is
+-- restricted to
+CREATE TABLE IF NOT EXISTS promo_code (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code VARCHAR(32) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    percent REAL NOT NULL,
+    amountMinor INTEGER NULL,
+    minSpendMinor INTEGER NULL,
+    currency CHAR(3) NULL,
+    validFrom DATETIME NULL,
+    validTo DATETIME NULL,
+    maxRedemptions INT NOT NULL,
+    maxPerCustomer INT NOT NULL,
+    holidays TEXT NOT NULL,
+    locations TEXT NOT NULL,
+    tenantID INT NOT NULL REFERENCES agency(id),
+    UNIQUE (code, tenantID),
+    UNIQUE (id, tenantID)
+);
+
+-- the promo code a reservation was booked with and the JSON of what it took
+-- off, the redemptions of a code are the reservations pointing to it. SQLite
+-- can not add a foreign key over two columns to a table, the storage checks
+-- that the code belongs to the tenant of the reservation
+ALTER TABLE reservation ADD COLUMN promoCodeID INT NULL REFERENCES promo_code(id);
+ALTER TABLE reservation ADD COLUMN promo TEXT NULL;
+
+INSERT INTO permission (name) VALUES ('promo:read'), ('promo:write');
+
+INSERT INTO role_permission (roleID, permissionID)
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'admin' AND p.name IN ('promo:read', 'promo:write');
+
+INSERT INTO role_permission (roleID, permissionID)
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'agent' AND p.name = 'promo:read';
@@ -0,0 +1,6 @@
+DELETE FROM role_permission WHERE permissionID IN (
+    SELECT id FROM permission WHERE name LIKE 'payment:%'
+);
+DELETE FROM permission WHERE name LIKE 'payment:%';
+
+DROP TABLE payment;
@@ -0,0 +1,10 @@
+DELETE FROM role_permission WHERE permissionID IN (
+    SELECT id FROM permission WHERE name IN ('invoice:read', 'invoice:read:own')
+);
+DELETE FROM permission WHERE name IN ('invoice:read', 'invoice:read:own');
+
+DROP TABLE invoice;
+
+ALTER TABLE agency DROP COLUMN taxNumber;
+ALTER TABLE agency DROP COLUMN email;
+ALTER TABLE agency DROP COLUMN address;
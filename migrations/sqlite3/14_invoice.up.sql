@@ -0,0 +1,38 @@
+-- details of the agency printed on its invoices
+ALTER TABLE agency ADD COLUMN address VARCHAR(512) NOT NULL DEFAULT '';
+ALTER TABLE agency ADD COLUMN email VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE agency ADD COLUMN taxNumber VARCHAR(32) NOT NULL DEFAULT '';
+
+-- Table for Invoice, the invoices of reservations and the credit notes
+-- cancelling them. Both are numbered per agency in sequences of their own.
+-- data is the JSON of the document as issued, document its PDF, which is
+-- plain ASCII. Invoices are kept when their reservation is deleted, so
+-- reservationID and customerID are not foreign keys
+CREATE TABLE IF NOT EXISTS invoice (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reservationID INT NOT NULL,
+    customerID INT NULL,
+    kind VARCHAR(16) NOT NULL,
+    sequence INT NOT NULL,
+    number VARCHAR(32) NOT NULL,
+    issuedAt DATETIME NOT NULL,
+    totalMinor INTEGER NOT NULL,
+    currency CHAR(3) NOT NULL,
+    creditedInvoiceID INT NULL UNIQUE REFERENCES invoice(id),
+    data TEXT NOT NULL,
+    document TEXT NOT NULL,
+    tenantID INT NOT NULL REFERENCES agency(id),
+    UNIQUE (tenantID, kind, sequence)
+);
+
+CREATE INDEX idx_invoice_reservation ON invoice (reservationID);
+
+INSERT INTO permission (name) VALUES ('invoice:read'), ('invoice:read:own');
+
+INSERT INTO role_permission (roleID, permissionID)
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name IN ('admin', 'agent') AND p.name = 'invoice:read';
+
+INSERT INTO role_permission (roleID, permissionID)
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'customer' AND p.name = 'invoice:read:own';
@@ -0,0 +1,23 @@
+CREATE TABLE payment_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reservationID INT NOT NULL REFERENCES reservation(id),
+    kind VARCHAR(16) NOT NULL,
+    amountMinor INTEGER NOT NULL,
+    currency CHAR(3) NOT NULL,
+    dueDate DATE NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    gateway VARCHAR(32) NOT NULL,
+    intentID VARCHAR(255) NULL,
+    refundedMinor INTEGER NOT NULL,
+    createdAt DATETIME NOT NULL,
+    updatedAt DATETIME NOT NULL,
+    tenantID INT NOT NULL REFERENCES agency(id),
+    UNIQUE (gateway, intentID)
+);
+
+INSERT INTO payment_history
+SELECT * FROM payment WHERE reservationID IN (SELECT id FROM reservation);
+DROP TABLE payment;
+ALTER TABLE payment_history RENAME TO payment;
+
+CREATE INDEX idx_payment_reservation ON payment (reservationID);
@@ -0,0 +1,26 @@
+-- payments are kept when their reservation is cancelled, like its invoices,
+-- so reservationID is no longer a foreign key. The service cancels only
+-- reservations whose payments failed or were refunded. SQLite can not drop
+-- a constraint, the table is copied instead
+CREATE TABLE payment_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reservationID INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    amountMinor INTEGER NOT NULL,
+    currency CHAR(3) NOT NULL,
+    dueDate DATE NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    gateway VARCHAR(32) NOT NULL,
+    intentID VARCHAR(255) NULL,
+    refundedMinor INTEGER NOT NULL,
+    createdAt DATETIME NOT NULL,
+    updatedAt DATETIME NOT NULL,
+    tenantID INT NOT NULL REFERENCES agency(id),
+    UNIQUE (gateway, intentID)
+);
+
+INSERT INTO payment_history SELECT * FROM payment;
+DROP TABLE payment;
+ALTER TABLE payment_history RENAME TO payment;
+
+CREATE INDEX idx_payment_reservation ON payment (reservationID);
@@ -0,0 +1,9 @@
+DELETE FROM role_permission WHERE permissionID IN (
+    SELECT id FROM permission WHERE name IN ('pricing:read', 'pricing:write')
+);
+DELETE FROM permission WHERE name IN ('pricing:read', 'pricing:write');
+
+ALTER TABLE reservation DROP COLUMN pricing;
+ALTER TABLE holiday DROP COLUMN pricingRuleSetID;
+
+DROP TABLE pricing_rule_set;
@@ -0,0 +1,26 @@
+-- Table for Pricing Rule Set, rules is the JSON array of pricing.Rule
+CREATE TABLE IF NOT EXISTS pricing_rule_set (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    rules TEXT NOT NULL,
+    tenantID INT NOT NULL REFERENCES agency(id),
+    UNIQUE (id, tenantID)
+);
+
+-- SQLite can not add a foreign key over two columns to a table, the storage
+-- checks that the rule set belongs to the tenant of the holiday
+ALTER TABLE holiday ADD COLUMN pricingRuleSetID INT NULL REFERENCES pricing_rule_set(id);
+
+-- the quote a reservation was booked at, later changes of the rules or the
+-- price of the holiday do not touch it
+ALTER TABLE reservation ADD COLUMN pricing TEXT NULL;
+
+INSERT INTO permission (name) VALUES ('pricing:read'), ('pricing:write');
+
+INSERT INTO role_permission (roleID, permissionID)
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'admin' AND p.name IN ('pricing:read', 'pricing:write');
+
+INSERT INTO role_permission (roleID, permissionID)
+SELECT r.id, p.id FROM role r CROSS JOIN permission p
+WHERE r.name = 'agent' AND p.name = 'pricing:read';
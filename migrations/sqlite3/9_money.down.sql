@@ -0,0 +1,9 @@
+-- SQLite can only add price back as the last column, a build from before this
+-- migration scans the columns in their old order and needs the table rebuilt
+-- by hand
+ALTER TABLE holiday ADD COLUMN price REAL NOT NULL DEFAULT 0;
+
+UPDATE holiday SET price = priceMinor / 100.0;
+
+ALTER TABLE holiday DROP COLUMN currency;
+ALTER TABLE holiday DROP COLUMN priceMinor;
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+	"travel/internal/config"
+	"travel/internal/database"
+	"travel/internal/logging"
+	"travel/internal/seed"
+	"travel/internal/storage"
+	"travel/internal/tenant"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// runSeed adds generated locations, holidays, customers and reservations to
+// the configured database, for the agency and in the volumes of the -seed
+// flags.
+func runSeed(args []string) {
+	cfg, err := config.Load(args, os.LookupEnv)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := seedCommand(cfg); err != nil {
+		log.Fatalf("seed: %v", err)
+	}
+}
+
+func seedCommand(cfg *config.Config) error {
+	if cfg.Database.Driver == config.DriverMemory {
+		return fmt.Errorf("data of the %s driver is lost on exit, seed a database", config.DriverMemory)
+	}
+
+	logger, err := logging.New(cfg.Log.Level, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	if cfg.Migrations.Auto {
+		m, err := openMigrate(db, cfg)
+		if err != nil {
+			return err
+		}
+		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+			return err
+		}
+	}
+
+	store := storage.New(db, cfg.Database.Driver, logger)
+
+	ctx := context.Background()
+	tenantID, err := store.AgencyIDBySlug(ctx, cfg.Seed.Agency)
+	if err != nil {
+		return err
+	}
+	if tenantID == 0 {
+		return fmt.Errorf("%w: %q", tenant.ErrUnknownTenant, cfg.Seed.Agency)
+	}
+
+	// holidays start in the coming year unless told otherwise
+	start := time.Date(time.Now().Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if cfg.Seed.Start != "" {
+		start, _ = time.Parse(time.DateOnly, cfg.Seed.Start)
+	}
+
+	result, err := seed.Run(tenant.WithID(ctx, tenantID), store, seed.Options{
+		Seed:         int64(cfg.Seed.Value),
+		Locations:    cfg.Seed.Locations,
+		Holidays:     cfg.Seed.Holidays,
+		Reservations: cfg.Seed.Reservations,
+		Start:        start,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("added %d locations, %d holidays, %d customers and %d reservations to agency %s\n",
+		result.Locations, result.Holidays, result.Customers, result.Reservations, cfg.Seed.Agency)
+
+	return nil
+}